@@ -19,10 +19,13 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/terraform"
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketcloud"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -34,38 +37,56 @@ import (
 // 3. Add your flag's description etc. to the stringFlags, intFlags, or boolFlags slices.
 const (
 	// Flag names.
-	AllowForkPRsFlag           = "allow-fork-prs"
-	AllowRepoConfigFlag        = "allow-repo-config"
-	AtlantisURLFlag            = "atlantis-url"
-	BitbucketBaseURLFlag       = "bitbucket-base-url"
-	BitbucketTokenFlag         = "bitbucket-token"
-	BitbucketUserFlag          = "bitbucket-user"
-	BitbucketWebhookSecretFlag = "bitbucket-webhook-secret"
-	ConfigFlag                 = "config"
-	DataDirFlag                = "data-dir"
-	GHHostnameFlag             = "gh-hostname"
-	GHTokenFlag                = "gh-token"
-	GHUserFlag                 = "gh-user"
-	GHWebhookSecretFlag        = "gh-webhook-secret" // nolint: gosec
-	GitlabHostnameFlag         = "gitlab-hostname"
-	GitlabTokenFlag            = "gitlab-token"
-	GitlabUserFlag             = "gitlab-user"
-	GitlabWebhookSecretFlag    = "gitlab-webhook-secret" // nolint: gosec
-	LogLevelFlag               = "log-level"
-	PortFlag                   = "port"
-	RepoWhitelistFlag          = "repo-whitelist"
-	RequireApprovalFlag        = "require-approval"
-	SilenceWhitelistErrorsFlag = "silence-whitelist-errors"
-	SSLCertFileFlag            = "ssl-cert-file"
-	SSLKeyFileFlag             = "ssl-key-file"
+	AllowForkPRsFlag                 = "allow-fork-prs"
+	AllowRepoConfigFlag              = "allow-repo-config"
+	AtlantisURLFlag                  = "atlantis-url"
+	BitbucketBaseURLFlag             = "bitbucket-base-url"
+	BitbucketTokenFlag               = "bitbucket-token"
+	BitbucketUserFlag                = "bitbucket-user"
+	BitbucketWebhookSecretFlag       = "bitbucket-webhook-secret"
+	ConfigFlag                       = "config"
+	DataDirFlag                      = "data-dir"
+	DisableAtlantisTFVarsFlag        = "disable-atlantis-tf-vars"
+	DrainTimeoutFlag                 = "drain-timeout"
+	GHHostnameFlag                   = "gh-hostname"
+	GHTokenFlag                      = "gh-token"
+	GHUserFlag                       = "gh-user"
+	GHWebhookSecretFlag              = "gh-webhook-secret" // nolint: gosec
+	GitlabHostnameFlag               = "gitlab-hostname"
+	GitlabTokenFlag                  = "gitlab-token"
+	GitlabUserFlag                   = "gitlab-user"
+	GitlabWebhookSecretFlag          = "gitlab-webhook-secret" // nolint: gosec
+	JobWorkersFlag                   = "job-workers"
+	LockKeyStrategyFlag              = "lock-key-strategy"
+	LockTTLFlag                      = "lock-ttl"
+	LogLevelFlag                     = "log-level"
+	MarkdownTemplateOverridesDirFlag = "markdown-template-overrides-dir"
+	OIDCClientIDFlag                 = "oidc-client-id"
+	OIDCClientSecretFlag             = "oidc-client-secret" // nolint: gosec
+	OIDCIssuerURLFlag                = "oidc-issuer-url"
+	PortFlag                         = "port"
+	RepoEnvVarWhitelistFlag          = "repo-env-var-whitelist"
+	RepoWhitelistFlag                = "repo-whitelist"
+	RequireApprovalFlag              = "require-approval"
+	SilenceWhitelistErrorsFlag       = "silence-whitelist-errors"
+	SSLCertFileFlag                  = "ssl-cert-file"
+	SSLKeyFileFlag                   = "ssl-key-file"
+	TFDownloadURLFlag                = "tf-download-url"
+	WebAdminGroupsFlag               = "web-admin-groups"
+	WebAdminUsersFlag                = "web-admin-users"
+	WebHtpasswdFileFlag              = "web-htpasswd-file"
 
 	// Flag defaults.
 	DefaultBitbucketBaseURL = bitbucketcloud.BaseURL
 	DefaultDataDir          = "~/.atlantis"
+	DefaultDrainTimeout     = "5m"
 	DefaultGHHostname       = "github.com"
 	DefaultGitlabHostname   = "gitlab.com"
+	DefaultJobWorkers       = events.DefaultJobWorkers
+	DefaultLockKeyStrategy  = events.DirLockKeyStrategy
 	DefaultLogLevel         = "info"
 	DefaultPort             = 4141
+	DefaultTFDownloadURL    = terraform.DefaultDownloadURL
 )
 
 const redTermStart = "\033[31m"
@@ -107,6 +128,12 @@ var stringFlags = []stringFlag{
 		description:  "Path to directory to store Atlantis data.",
 		defaultValue: DefaultDataDir,
 	},
+	{
+		name: DrainTimeoutFlag,
+		description: "How long to wait for running commands to finish when shutting down, ex. 10m. Commands still running after that are interrupted." +
+			" New commands aren't accepted while shutting down.",
+		defaultValue: DefaultDrainTimeout,
+	},
 	{
 		name:         GHHostnameFlag,
 		description:  "Hostname of your Github Enterprise installation. If using github.com, no need to set.",
@@ -147,11 +174,45 @@ var stringFlags = []stringFlag{
 			"This means that an attacker could spoof calls to Atlantis and cause it to perform malicious actions. " +
 			"Should be specified via the ATLANTIS_GITLAB_WEBHOOK_SECRET environment variable.",
 	},
+	{
+		name: LockKeyStrategyFlag,
+		description: "What projects are locked on. Either dir, to lock on the repo, dir and workspace, or backend, to lock on the Terraform state backend and workspace so projects sharing state can't be planned at the same time." +
+			" Projects whose backend can't be determined from their .tf files are locked on their dir. A project's lock_key in atlantis.yaml overrides this.",
+		defaultValue: DefaultLockKeyStrategy,
+	},
+	{
+		name: LockTTLFlag,
+		description: "How long locks are held for before they expire, ex. 72h. When a lock expires its plan is discarded and the next pull request waiting for it is re-planned." +
+			" If not set, locks are held until they're deleted or their pull request is closed.",
+	},
 	{
 		name:         LogLevelFlag,
 		description:  "Log level. Either debug, info, warn, or error.",
 		defaultValue: DefaultLogLevel,
 	},
+	{
+		name: MarkdownTemplateOverridesDirFlag,
+		description: "Directory of templates that replace the default templates of the comments Atlantis posts, ex. error_with_log.tmpl." +
+			" Templates in a subdirectory named after a VCS host, ex. gitlab/, only replace them for that host.",
+	},
+	{
+		name: OIDCClientIDFlag,
+		description: "Client ID of Atlantis in your OpenID Connect provider. Setting --" + OIDCIssuerURLFlag + " enables OIDC login to the web UI." +
+			" The provider must allow the redirect URI $atlantis-url/auth/oidc/callback.",
+	},
+	{
+		name:        OIDCClientSecretFlag,
+		description: "Client secret of Atlantis in your OpenID Connect provider. Should be specified via the ATLANTIS_OIDC_CLIENT_SECRET environment variable.",
+	},
+	{
+		name:        OIDCIssuerURLFlag,
+		description: "Issuer URL of your OpenID Connect provider, ex. https://accounts.google.com. Requires --" + OIDCClientIDFlag + " and --" + OIDCClientSecretFlag + ".",
+	},
+	{
+		name: RepoEnvVarWhitelistFlag,
+		description: "Comma separated list of env vars that atlantis.yaml files can set with env steps and a project's env key, ex. 'TF_VAR_*,AWS_PROFILE'." +
+			" '*' at the end of a name matches any suffix. If not set, any env var can be set.",
+	},
 	{
 		name: RepoWhitelistFlag,
 		description: "Comma separated list of repositories that Atlantis will operate on. " +
@@ -167,6 +228,25 @@ var stringFlags = []stringFlag{
 		name:        SSLKeyFileFlag,
 		description: fmt.Sprintf("File containing x509 private key matching --%s.", SSLCertFileFlag),
 	},
+	{
+		name: TFDownloadURLFlag,
+		description: "Base URL to download terraform versions from when a project's terraform_version isn't installed." +
+			" Must have the same layout as https://releases.hashicorp.com so it can be an internal mirror.",
+		defaultValue: DefaultTFDownloadURL,
+	},
+	{
+		name:        WebAdminGroupsFlag,
+		description: "Comma separated list of groups, from the OIDC groups claim, whose users can delete locks in the web UI.",
+	},
+	{
+		name:        WebAdminUsersFlag,
+		description: "Comma separated list of users who can delete locks in the web UI. If web UI authentication is enabled, everyone else can only view.",
+	},
+	{
+		name: WebHtpasswdFileFlag,
+		description: "Path to an htpasswd file of users who can log in to the web UI with HTTP basic auth. Passwords must be bcrypt hashed (htpasswd -B)." +
+			" If neither this nor --" + OIDCIssuerURLFlag + " is set, the web UI doesn't require authentication.",
+	},
 }
 var boolFlags = []boolFlag{
 	{
@@ -181,6 +261,12 @@ var boolFlags = []boolFlag{
 			" on the Atlantis server.",
 		defaultValue: false,
 	},
+	{
+		name: DisableAtlantisTFVarsFlag,
+		description: "Don't pass the atlantis_user, atlantis_repo, atlantis_repo_name, atlantis_repo_owner and atlantis_pull_num variables to terraform plan with -var." +
+			" Terraform 0.12 errors on variables that aren't declared. The same values are in the USER_NAME, BASE_REPO_NAME, BASE_REPO_OWNER and PULL_NUM env vars of every step.",
+		defaultValue: false,
+	},
 	{
 		name:         RequireApprovalFlag,
 		description:  "Require pull requests to be \"Approved\" before allowing the apply command to be run.",
@@ -193,6 +279,11 @@ var boolFlags = []boolFlag{
 	},
 }
 var intFlags = []intFlag{
+	{
+		name:         JobWorkersFlag,
+		description:  "Number of commands to run at the same time. Other commands wait in a queue that's kept across restarts.",
+		defaultValue: DefaultJobWorkers,
+	},
 	{
 		name:         PortFlag,
 		description:  "Port to bind to.",
@@ -357,6 +448,9 @@ func (s *ServerCmd) setDefaults(c *server.UserConfig) {
 	if c.DataDir == "" {
 		c.DataDir = DefaultDataDir
 	}
+	if c.DrainTimeout == "" {
+		c.DrainTimeout = DefaultDrainTimeout
+	}
 	if c.GithubHostname == "" {
 		c.GithubHostname = DefaultGHHostname
 	}
@@ -366,12 +460,21 @@ func (s *ServerCmd) setDefaults(c *server.UserConfig) {
 	if c.BitbucketBaseURL == "" {
 		c.BitbucketBaseURL = DefaultBitbucketBaseURL
 	}
+	if c.JobWorkers == 0 {
+		c.JobWorkers = DefaultJobWorkers
+	}
+	if c.LockKeyStrategy == "" {
+		c.LockKeyStrategy = DefaultLockKeyStrategy
+	}
 	if c.LogLevel == "" {
 		c.LogLevel = DefaultLogLevel
 	}
 	if c.Port == 0 {
 		c.Port = DefaultPort
 	}
+	if c.TFDownloadURL == "" {
+		c.TFDownloadURL = DefaultTFDownloadURL
+	}
 }
 
 func (s *ServerCmd) validate(userConfig server.UserConfig) error {
@@ -380,10 +483,36 @@ func (s *ServerCmd) validate(userConfig server.UserConfig) error {
 		return errors.New("invalid log level: not one of debug, info, warn, error")
 	}
 
+	if timeout, err := time.ParseDuration(userConfig.DrainTimeout); err != nil || timeout <= 0 {
+		return fmt.Errorf("invalid --%s %q: must be a positive duration, ex. 5m", DrainTimeoutFlag, userConfig.DrainTimeout)
+	}
+
+	if userConfig.JobWorkers < 1 {
+		return fmt.Errorf("invalid --%s %d: must be at least 1", JobWorkersFlag, userConfig.JobWorkers)
+	}
+
+	if userConfig.LockKeyStrategy != events.DirLockKeyStrategy && userConfig.LockKeyStrategy != events.BackendLockKeyStrategy {
+		return fmt.Errorf("invalid --%s: not one of %s, %s", LockKeyStrategyFlag, events.DirLockKeyStrategy, events.BackendLockKeyStrategy)
+	}
+
+	if userConfig.LockTTL != "" {
+		if ttl, err := time.ParseDuration(userConfig.LockTTL); err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid --%s %q: must be a positive duration, ex. 72h", LockTTLFlag, userConfig.LockTTL)
+		}
+	}
+
 	if (userConfig.SSLKeyFile == "") != (userConfig.SSLCertFile == "") {
 		return fmt.Errorf("--%s and --%s are both required for ssl", SSLKeyFileFlag, SSLCertFileFlag)
 	}
 
+	oidcFlags := []string{userConfig.OIDCIssuerURL, userConfig.OIDCClientID, userConfig.OIDCClientSecret}
+	if strings.Join(oidcFlags, "") != "" && (oidcFlags[0] == "" || oidcFlags[1] == "" || oidcFlags[2] == "") {
+		return fmt.Errorf("--%s, --%s and --%s are all required for OIDC", OIDCIssuerURLFlag, OIDCClientIDFlag, OIDCClientSecretFlag)
+	}
+	if (userConfig.WebAdminUsers != "" || userConfig.WebAdminGroups != "") && userConfig.WebHtpasswdFile == "" && userConfig.OIDCIssuerURL == "" {
+		return fmt.Errorf("--%s and --%s require web UI authentication to be enabled with --%s or --%s", WebAdminUsersFlag, WebAdminGroupsFlag, WebHtpasswdFileFlag, OIDCIssuerURLFlag)
+	}
+
 	// The following combinations are valid.
 	// 1. github user and token set
 	// 2. gitlab user and token set
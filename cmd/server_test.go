@@ -14,6 +14,7 @@
 package cmd_test
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -127,6 +128,46 @@ func TestExecute_ValidateLogLevel(t *testing.T) {
 	Equals(t, "invalid log level: not one of debug, info, warn, error", err.Error())
 }
 
+func TestExecute_ValidateLockKeyStrategy(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		cmd.LockKeyStrategyFlag: "state",
+	})
+	err := c.Execute()
+	ErrEquals(t, "invalid --lock-key-strategy: not one of dir, backend", err)
+}
+
+func TestExecute_ValidateDrainTimeout(t *testing.T) {
+	for _, timeout := range []string{"5 minutes", "-1m", "0s"} {
+		t.Run(timeout, func(t *testing.T) {
+			c := setupWithDefaults(map[string]interface{}{
+				cmd.DrainTimeoutFlag: timeout,
+			})
+			err := c.Execute()
+			ErrEquals(t, fmt.Sprintf("invalid --drain-timeout %q: must be a positive duration, ex. 5m", timeout), err)
+		})
+	}
+}
+
+func TestExecute_ValidateJobWorkers(t *testing.T) {
+	c := setupWithDefaults(map[string]interface{}{
+		cmd.JobWorkersFlag: -1,
+	})
+	err := c.Execute()
+	ErrEquals(t, "invalid --job-workers -1: must be at least 1", err)
+}
+
+func TestExecute_ValidateLockTTL(t *testing.T) {
+	for _, ttl := range []string{"3 days", "-1h", "0s"} {
+		t.Run(ttl, func(t *testing.T) {
+			c := setupWithDefaults(map[string]interface{}{
+				cmd.LockTTLFlag: ttl,
+			})
+			err := c.Execute()
+			ErrEquals(t, fmt.Sprintf("invalid --lock-ttl %q: must be a positive duration, ex. 72h", ttl), err)
+		})
+	}
+}
+
 func TestExecute_ValidateSSLConfig(t *testing.T) {
 	expErr := "--ssl-key-file and --ssl-cert-file are both required for ssl"
 	cases := []struct {
@@ -175,6 +216,71 @@ func TestExecute_ValidateSSLConfig(t *testing.T) {
 	}
 }
 
+func TestExecute_ValidateOIDCConfig(t *testing.T) {
+	expErr := "--oidc-issuer-url, --oidc-client-id and --oidc-client-secret are all required for OIDC"
+	cases := []struct {
+		description string
+		flags       map[string]interface{}
+		expectError bool
+	}{
+		{
+			"no options set",
+			make(map[string]interface{}),
+			false,
+		},
+		{
+			"just oidc-issuer-url set",
+			map[string]interface{}{
+				cmd.OIDCIssuerURLFlag: "https://issuer",
+			},
+			true,
+		},
+		{
+			"client id and secret set without issuer",
+			map[string]interface{}{
+				cmd.OIDCClientIDFlag:     "id",
+				cmd.OIDCClientSecretFlag: "secret",
+			},
+			true,
+		},
+		{
+			"all options set",
+			map[string]interface{}{
+				cmd.OIDCIssuerURLFlag:    "https://issuer",
+				cmd.OIDCClientIDFlag:     "id",
+				cmd.OIDCClientSecretFlag: "secret",
+			},
+			false,
+		},
+	}
+	for _, testCase := range cases {
+		t.Log("Should validate oidc config when " + testCase.description)
+		c := setupWithDefaults(testCase.flags)
+		err := c.Execute()
+		if testCase.expectError {
+			Assert(t, err != nil, "should be an error")
+			Equals(t, expErr, err.Error())
+		} else {
+			Ok(t, err)
+		}
+	}
+}
+
+func TestExecute_ValidateWebAdminsRequireAuth(t *testing.T) {
+	t.Log("Should error if admins are set but web UI authentication isn't enabled.")
+	c := setupWithDefaults(map[string]interface{}{
+		cmd.WebAdminUsersFlag: "alice",
+	})
+	err := c.Execute()
+	ErrEquals(t, "--web-admin-users and --web-admin-groups require web UI authentication to be enabled with --web-htpasswd-file or --oidc-issuer-url", err)
+
+	c = setupWithDefaults(map[string]interface{}{
+		cmd.WebAdminUsersFlag:   "alice",
+		cmd.WebHtpasswdFileFlag: "/htpasswd",
+	})
+	Ok(t, c.Execute())
+}
+
 func TestExecute_ValidateVCSConfig(t *testing.T) {
 	expErr := "--gh-user/--gh-token or --gitlab-user/--gitlab-token or --bitbucket-user/--bitbucket-token must be set"
 	cases := []struct {
@@ -330,6 +436,7 @@ func TestExecute_Defaults(t *testing.T) {
 	dataDir, err := homedir.Expand("~/.atlantis")
 	Ok(t, err)
 	Equals(t, dataDir, passedConfig.DataDir)
+	Equals(t, "5m", passedConfig.DrainTimeout)
 
 	Equals(t, "github.com", passedConfig.GithubHostname)
 	Equals(t, "token", passedConfig.GithubToken)
@@ -343,11 +450,20 @@ func TestExecute_Defaults(t *testing.T) {
 	Equals(t, "bitbucket-token", passedConfig.BitbucketToken)
 	Equals(t, "bitbucket-user", passedConfig.BitbucketUser)
 	Equals(t, "", passedConfig.BitbucketWebhookSecret)
+	Equals(t, 4, passedConfig.JobWorkers)
+	Equals(t, "dir", passedConfig.LockKeyStrategy)
 	Equals(t, "info", passedConfig.LogLevel)
+	Equals(t, "", passedConfig.OIDCClientID)
+	Equals(t, "", passedConfig.OIDCClientSecret)
+	Equals(t, "", passedConfig.OIDCIssuerURL)
 	Equals(t, 4141, passedConfig.Port)
 	Equals(t, false, passedConfig.RequireApproval)
 	Equals(t, "", passedConfig.SSLCertFile)
 	Equals(t, "", passedConfig.SSLKeyFile)
+	Equals(t, "https://releases.hashicorp.com", passedConfig.TFDownloadURL)
+	Equals(t, "", passedConfig.WebAdminGroups)
+	Equals(t, "", passedConfig.WebAdminUsers)
+	Equals(t, "", passedConfig.WebHtpasswdFile)
 }
 
 func TestExecute_ExpandHomeInDataDir(t *testing.T) {
@@ -423,28 +539,42 @@ func TestExecute_BitbucketUser(t *testing.T) {
 func TestExecute_Flags(t *testing.T) {
 	t.Log("Should use all flags that are set.")
 	c := setup(map[string]interface{}{
-		cmd.AtlantisURLFlag:            "url",
-		cmd.AllowForkPRsFlag:           true,
-		cmd.AllowRepoConfigFlag:        true,
-		cmd.BitbucketBaseURLFlag:       "https://bitbucket-base-url.com",
-		cmd.BitbucketTokenFlag:         "bitbucket-token",
-		cmd.BitbucketUserFlag:          "bitbucket-user",
-		cmd.BitbucketWebhookSecretFlag: "bitbucket-secret",
-		cmd.DataDirFlag:                "/path",
-		cmd.GHHostnameFlag:             "ghhostname",
-		cmd.GHTokenFlag:                "token",
-		cmd.GHUserFlag:                 "user",
-		cmd.GHWebhookSecretFlag:        "secret",
-		cmd.GitlabHostnameFlag:         "gitlab-hostname",
-		cmd.GitlabTokenFlag:            "gitlab-token",
-		cmd.GitlabUserFlag:             "gitlab-user",
-		cmd.GitlabWebhookSecretFlag:    "gitlab-secret",
-		cmd.LogLevelFlag:               "debug",
-		cmd.PortFlag:                   8181,
-		cmd.RepoWhitelistFlag:          "github.com/runatlantis/atlantis",
-		cmd.RequireApprovalFlag:        true,
-		cmd.SSLCertFileFlag:            "cert-file",
-		cmd.SSLKeyFileFlag:             "key-file",
+		cmd.AtlantisURLFlag:                  "url",
+		cmd.AllowForkPRsFlag:                 true,
+		cmd.AllowRepoConfigFlag:              true,
+		cmd.BitbucketBaseURLFlag:             "https://bitbucket-base-url.com",
+		cmd.BitbucketTokenFlag:               "bitbucket-token",
+		cmd.BitbucketUserFlag:                "bitbucket-user",
+		cmd.BitbucketWebhookSecretFlag:       "bitbucket-secret",
+		cmd.DataDirFlag:                      "/path",
+		cmd.DisableAtlantisTFVarsFlag:        true,
+		cmd.DrainTimeoutFlag:                 "10m",
+		cmd.GHHostnameFlag:                   "ghhostname",
+		cmd.GHTokenFlag:                      "token",
+		cmd.GHUserFlag:                       "user",
+		cmd.GHWebhookSecretFlag:              "secret",
+		cmd.GitlabHostnameFlag:               "gitlab-hostname",
+		cmd.GitlabTokenFlag:                  "gitlab-token",
+		cmd.GitlabUserFlag:                   "gitlab-user",
+		cmd.GitlabWebhookSecretFlag:          "gitlab-secret",
+		cmd.JobWorkersFlag:                   8,
+		cmd.LockKeyStrategyFlag:              "backend",
+		cmd.LockTTLFlag:                      "72h",
+		cmd.LogLevelFlag:                     "debug",
+		cmd.MarkdownTemplateOverridesDirFlag: "/templates",
+		cmd.OIDCClientIDFlag:                 "oidc-client-id",
+		cmd.OIDCClientSecretFlag:             "oidc-client-secret",
+		cmd.OIDCIssuerURLFlag:                "https://oidc-issuer-url",
+		cmd.PortFlag:                         8181,
+		cmd.RepoEnvVarWhitelistFlag:          "TF_VAR_*,AWS_PROFILE",
+		cmd.RepoWhitelistFlag:                "github.com/runatlantis/atlantis",
+		cmd.RequireApprovalFlag:              true,
+		cmd.SSLCertFileFlag:                  "cert-file",
+		cmd.SSLKeyFileFlag:                   "key-file",
+		cmd.TFDownloadURLFlag:                "https://tf-download-url",
+		cmd.WebAdminGroupsFlag:               "web-admin-groups",
+		cmd.WebAdminUsersFlag:                "web-admin-users",
+		cmd.WebHtpasswdFileFlag:              "/htpasswd",
 	})
 	err := c.Execute()
 	Ok(t, err)
@@ -457,6 +587,8 @@ func TestExecute_Flags(t *testing.T) {
 	Equals(t, "bitbucket-user", passedConfig.BitbucketUser)
 	Equals(t, "bitbucket-secret", passedConfig.BitbucketWebhookSecret)
 	Equals(t, "/path", passedConfig.DataDir)
+	Equals(t, true, passedConfig.DisableAtlantisTFVars)
+	Equals(t, "10m", passedConfig.DrainTimeout)
 	Equals(t, "ghhostname", passedConfig.GithubHostname)
 	Equals(t, "token", passedConfig.GithubToken)
 	Equals(t, "user", passedConfig.GithubUser)
@@ -465,12 +597,24 @@ func TestExecute_Flags(t *testing.T) {
 	Equals(t, "gitlab-token", passedConfig.GitlabToken)
 	Equals(t, "gitlab-user", passedConfig.GitlabUser)
 	Equals(t, "gitlab-secret", passedConfig.GitlabWebhookSecret)
+	Equals(t, 8, passedConfig.JobWorkers)
+	Equals(t, "backend", passedConfig.LockKeyStrategy)
+	Equals(t, "72h", passedConfig.LockTTL)
 	Equals(t, "debug", passedConfig.LogLevel)
+	Equals(t, "/templates", passedConfig.MarkdownTemplateOverridesDir)
+	Equals(t, "oidc-client-id", passedConfig.OIDCClientID)
+	Equals(t, "oidc-client-secret", passedConfig.OIDCClientSecret)
+	Equals(t, "https://oidc-issuer-url", passedConfig.OIDCIssuerURL)
 	Equals(t, 8181, passedConfig.Port)
+	Equals(t, "TF_VAR_*,AWS_PROFILE", passedConfig.RepoEnvVarWhitelist)
 	Equals(t, "github.com/runatlantis/atlantis", passedConfig.RepoWhitelist)
 	Equals(t, true, passedConfig.RequireApproval)
 	Equals(t, "cert-file", passedConfig.SSLCertFile)
 	Equals(t, "key-file", passedConfig.SSLKeyFile)
+	Equals(t, "https://tf-download-url", passedConfig.TFDownloadURL)
+	Equals(t, "web-admin-groups", passedConfig.WebAdminGroups)
+	Equals(t, "web-admin-users", passedConfig.WebAdminUsers)
+	Equals(t, "/htpasswd", passedConfig.WebHtpasswdFile)
 }
 
 func TestExecute_ConfigFile(t *testing.T) {
@@ -484,6 +628,8 @@ bitbucket-token: "bitbucket-token"
 bitbucket-user: "bitbucket-user"
 bitbucket-webhook-secret: "bitbucket-secret"
 data-dir: "/path"
+disable-atlantis-tf-vars: true
+drain-timeout: "10m"
 gh-hostname: "ghhostname"
 gh-token: "token"
 gh-user: "user"
@@ -492,12 +638,23 @@ gitlab-hostname: "gitlab-hostname"
 gitlab-token: "gitlab-token"
 gitlab-user: "gitlab-user"
 gitlab-webhook-secret: "gitlab-secret"
+job-workers: 8
+lock-ttl: "72h"
 log-level: "debug"
+markdown-template-overrides-dir: "/templates"
+oidc-client-id: "oidc-client-id"
+oidc-client-secret: "oidc-client-secret"
+oidc-issuer-url: "https://oidc-issuer-url"
 port: 8181
+repo-env-var-whitelist: "TF_VAR_*,AWS_PROFILE"
 repo-whitelist: "github.com/runatlantis/atlantis"
 require-approval: true
 ssl-cert-file: cert-file
 ssl-key-file: key-file
+tf-download-url: "https://tf-download-url"
+web-admin-groups: "web-admin-groups"
+web-admin-users: "web-admin-users"
+web-htpasswd-file: "/htpasswd"
 `)
 	defer os.Remove(tmpFile) // nolint: errcheck
 	c := setup(map[string]interface{}{
@@ -514,6 +671,8 @@ ssl-key-file: key-file
 	Equals(t, "bitbucket-user", passedConfig.BitbucketUser)
 	Equals(t, "bitbucket-secret", passedConfig.BitbucketWebhookSecret)
 	Equals(t, "/path", passedConfig.DataDir)
+	Equals(t, true, passedConfig.DisableAtlantisTFVars)
+	Equals(t, "10m", passedConfig.DrainTimeout)
 	Equals(t, "ghhostname", passedConfig.GithubHostname)
 	Equals(t, "token", passedConfig.GithubToken)
 	Equals(t, "user", passedConfig.GithubUser)
@@ -522,12 +681,23 @@ ssl-key-file: key-file
 	Equals(t, "gitlab-token", passedConfig.GitlabToken)
 	Equals(t, "gitlab-user", passedConfig.GitlabUser)
 	Equals(t, "gitlab-secret", passedConfig.GitlabWebhookSecret)
+	Equals(t, 8, passedConfig.JobWorkers)
+	Equals(t, "72h", passedConfig.LockTTL)
 	Equals(t, "debug", passedConfig.LogLevel)
+	Equals(t, "/templates", passedConfig.MarkdownTemplateOverridesDir)
+	Equals(t, "oidc-client-id", passedConfig.OIDCClientID)
+	Equals(t, "oidc-client-secret", passedConfig.OIDCClientSecret)
+	Equals(t, "https://oidc-issuer-url", passedConfig.OIDCIssuerURL)
 	Equals(t, 8181, passedConfig.Port)
+	Equals(t, "TF_VAR_*,AWS_PROFILE", passedConfig.RepoEnvVarWhitelist)
 	Equals(t, "github.com/runatlantis/atlantis", passedConfig.RepoWhitelist)
 	Equals(t, true, passedConfig.RequireApproval)
 	Equals(t, "cert-file", passedConfig.SSLCertFile)
 	Equals(t, "key-file", passedConfig.SSLKeyFile)
+	Equals(t, "https://tf-download-url", passedConfig.TFDownloadURL)
+	Equals(t, "web-admin-groups", passedConfig.WebAdminGroups)
+	Equals(t, "web-admin-users", passedConfig.WebAdminUsers)
+	Equals(t, "/htpasswd", passedConfig.WebHtpasswdFile)
 }
 
 func TestExecute_EnvironmentOverride(t *testing.T) {
@@ -550,11 +720,18 @@ gitlab-token: "gitlab-token"
 gitlab-user: "gitlab-user"
 gitlab-webhook-secret: "gitlab-secret"
 log-level: "debug"
+oidc-client-id: "oidc-client-id"
+oidc-client-secret: "oidc-client-secret"
+oidc-issuer-url: "https://oidc-issuer-url"
 port: 8181
 repo-whitelist: "github.com/runatlantis/atlantis"
 require-approval: true
 ssl-cert-file: cert-file
 ssl-key-file: key-file
+tf-download-url: "https://tf-download-url"
+web-admin-groups: "web-admin-groups"
+web-admin-users: "web-admin-users"
+web-htpasswd-file: "/htpasswd"
 `)
 	defer os.Remove(tmpFile) // nolint: errcheck
 
@@ -577,11 +754,18 @@ ssl-key-file: key-file
 		"GITLAB_USER":              "override-gitlab-user",
 		"GITLAB_WEBHOOK_SECRET":    "override-gitlab-webhook-secret",
 		"LOG_LEVEL":                "info",
+		"OIDC_CLIENT_ID":           "override-oidc-client-id",
+		"OIDC_CLIENT_SECRET":       "override-oidc-client-secret",
+		"OIDC_ISSUER_URL":          "https://override-oidc-issuer-url",
 		"PORT":                     "8282",
 		"REPO_WHITELIST":           "override,override",
 		"REQUIRE_APPROVAL":         "false",
 		"SSL_CERT_FILE":            "override-cert-file",
 		"SSL_KEY_FILE":             "override-key-file",
+		"TF_DOWNLOAD_URL":          "https://override-tf-download-url",
+		"WEB_ADMIN_GROUPS":         "override-web-admin-groups",
+		"WEB_ADMIN_USERS":          "override-web-admin-users",
+		"WEB_HTPASSWD_FILE":        "/override-htpasswd",
 	} {
 		os.Setenv("ATLANTIS_"+name, value) // nolint: errcheck
 	}
@@ -607,11 +791,18 @@ ssl-key-file: key-file
 	Equals(t, "override-gitlab-user", passedConfig.GitlabUser)
 	Equals(t, "override-gitlab-webhook-secret", passedConfig.GitlabWebhookSecret)
 	Equals(t, "info", passedConfig.LogLevel)
+	Equals(t, "override-oidc-client-id", passedConfig.OIDCClientID)
+	Equals(t, "override-oidc-client-secret", passedConfig.OIDCClientSecret)
+	Equals(t, "https://override-oidc-issuer-url", passedConfig.OIDCIssuerURL)
 	Equals(t, 8282, passedConfig.Port)
 	Equals(t, "override,override", passedConfig.RepoWhitelist)
 	Equals(t, false, passedConfig.RequireApproval)
 	Equals(t, "override-cert-file", passedConfig.SSLCertFile)
 	Equals(t, "override-key-file", passedConfig.SSLKeyFile)
+	Equals(t, "https://override-tf-download-url", passedConfig.TFDownloadURL)
+	Equals(t, "override-web-admin-groups", passedConfig.WebAdminGroups)
+	Equals(t, "override-web-admin-users", passedConfig.WebAdminUsers)
+	Equals(t, "/override-htpasswd", passedConfig.WebHtpasswdFile)
 }
 
 func TestExecute_FlagConfigOverride(t *testing.T) {
@@ -634,11 +825,18 @@ gitlab-token: "gitlab-token"
 gitlab-user: "gitlab-user"
 gitlab-webhook-secret: "gitlab-secret"
 log-level: "debug"
+oidc-client-id: "oidc-client-id"
+oidc-client-secret: "oidc-client-secret"
+oidc-issuer-url: "https://oidc-issuer-url"
 port: 8181
 repo-whitelist: "github.com/runatlantis/atlantis"
 require-approval: true
 ssl-cert-file: cert-file
 ssl-key-file: key-file
+tf-download-url: "https://tf-download-url"
+web-admin-groups: "web-admin-groups"
+web-admin-users: "web-admin-users"
+web-htpasswd-file: "/htpasswd"
 `)
 
 	defer os.Remove(tmpFile) // nolint: errcheck
@@ -660,11 +858,18 @@ ssl-key-file: key-file
 		cmd.GitlabUserFlag:             "override-gitlab-user",
 		cmd.GitlabWebhookSecretFlag:    "override-gitlab-webhook-secret",
 		cmd.LogLevelFlag:               "info",
+		cmd.OIDCClientIDFlag:           "override-oidc-client-id",
+		cmd.OIDCClientSecretFlag:       "override-oidc-client-secret",
+		cmd.OIDCIssuerURLFlag:          "https://override-oidc-issuer-url",
 		cmd.PortFlag:                   8282,
 		cmd.RepoWhitelistFlag:          "override,override",
 		cmd.RequireApprovalFlag:        false,
 		cmd.SSLCertFileFlag:            "override-cert-file",
 		cmd.SSLKeyFileFlag:             "override-key-file",
+		cmd.TFDownloadURLFlag:          "https://override-tf-download-url",
+		cmd.WebAdminGroupsFlag:         "override-web-admin-groups",
+		cmd.WebAdminUsersFlag:          "override-web-admin-users",
+		cmd.WebHtpasswdFileFlag:        "/override-htpasswd",
 	})
 	err := c.Execute()
 	Ok(t, err)
@@ -684,11 +889,18 @@ ssl-key-file: key-file
 	Equals(t, "override-gitlab-user", passedConfig.GitlabUser)
 	Equals(t, "override-gitlab-webhook-secret", passedConfig.GitlabWebhookSecret)
 	Equals(t, "info", passedConfig.LogLevel)
+	Equals(t, "override-oidc-client-id", passedConfig.OIDCClientID)
+	Equals(t, "override-oidc-client-secret", passedConfig.OIDCClientSecret)
+	Equals(t, "https://override-oidc-issuer-url", passedConfig.OIDCIssuerURL)
 	Equals(t, 8282, passedConfig.Port)
 	Equals(t, "override,override", passedConfig.RepoWhitelist)
 	Equals(t, false, passedConfig.RequireApproval)
 	Equals(t, "override-cert-file", passedConfig.SSLCertFile)
 	Equals(t, "override-key-file", passedConfig.SSLKeyFile)
+	Equals(t, "https://override-tf-download-url", passedConfig.TFDownloadURL)
+	Equals(t, "override-web-admin-groups", passedConfig.WebAdminGroups)
+	Equals(t, "override-web-admin-users", passedConfig.WebAdminUsers)
+	Equals(t, "/override-htpasswd", passedConfig.WebHtpasswdFile)
 }
 
 func TestExecute_FlagEnvVarOverride(t *testing.T) {
@@ -712,11 +924,18 @@ func TestExecute_FlagEnvVarOverride(t *testing.T) {
 		"GITLAB_USER":              "gitlab-user",
 		"GITLAB_WEBHOOK_SECRET":    "gitlab-webhook-secret",
 		"LOG_LEVEL":                "debug",
+		"OIDC_CLIENT_ID":           "oidc-client-id",
+		"OIDC_CLIENT_SECRET":       "oidc-client-secret",
+		"OIDC_ISSUER_URL":          "https://oidc-issuer-url",
 		"PORT":                     "8181",
 		"REPO_WHITELIST":           "*",
 		"REQUIRE_APPROVAL":         "true",
 		"SSL_CERT_FILE":            "cert-file",
 		"SSL_KEY_FILE":             "key-file",
+		"TF_DOWNLOAD_URL":          "https://tf-download-url",
+		"WEB_ADMIN_GROUPS":         "web-admin-groups",
+		"WEB_ADMIN_USERS":          "web-admin-users",
+		"WEB_HTPASSWD_FILE":        "/htpasswd",
 	}
 	for name, value := range envVars {
 		os.Setenv("ATLANTIS_"+name, value) // nolint: errcheck
@@ -746,11 +965,18 @@ func TestExecute_FlagEnvVarOverride(t *testing.T) {
 		cmd.GitlabUserFlag:             "override-gitlab-user",
 		cmd.GitlabWebhookSecretFlag:    "override-gitlab-webhook-secret",
 		cmd.LogLevelFlag:               "info",
+		cmd.OIDCClientIDFlag:           "override-oidc-client-id",
+		cmd.OIDCClientSecretFlag:       "override-oidc-client-secret",
+		cmd.OIDCIssuerURLFlag:          "https://override-oidc-issuer-url",
 		cmd.PortFlag:                   8282,
 		cmd.RepoWhitelistFlag:          "override,override",
 		cmd.RequireApprovalFlag:        false,
 		cmd.SSLCertFileFlag:            "override-cert-file",
 		cmd.SSLKeyFileFlag:             "override-key-file",
+		cmd.TFDownloadURLFlag:          "https://override-tf-download-url",
+		cmd.WebAdminGroupsFlag:         "override-web-admin-groups",
+		cmd.WebAdminUsersFlag:          "override-web-admin-users",
+		cmd.WebHtpasswdFileFlag:        "/override-htpasswd",
 	})
 	err := c.Execute()
 	Ok(t, err)
@@ -772,11 +998,18 @@ func TestExecute_FlagEnvVarOverride(t *testing.T) {
 	Equals(t, "override-gitlab-user", passedConfig.GitlabUser)
 	Equals(t, "override-gitlab-webhook-secret", passedConfig.GitlabWebhookSecret)
 	Equals(t, "info", passedConfig.LogLevel)
+	Equals(t, "override-oidc-client-id", passedConfig.OIDCClientID)
+	Equals(t, "override-oidc-client-secret", passedConfig.OIDCClientSecret)
+	Equals(t, "https://override-oidc-issuer-url", passedConfig.OIDCIssuerURL)
 	Equals(t, 8282, passedConfig.Port)
 	Equals(t, "override,override", passedConfig.RepoWhitelist)
 	Equals(t, false, passedConfig.RequireApproval)
 	Equals(t, "override-cert-file", passedConfig.SSLCertFile)
 	Equals(t, "override-key-file", passedConfig.SSLKeyFile)
+	Equals(t, "https://override-tf-download-url", passedConfig.TFDownloadURL)
+	Equals(t, "override-web-admin-groups", passedConfig.WebAdminGroups)
+	Equals(t, "override-web-admin-users", passedConfig.WebAdminUsers)
+	Equals(t, "/override-htpasswd", passedConfig.WebHtpasswdFile)
 }
 
 // If using bitbucket cloud, webhook secrets are not supported.
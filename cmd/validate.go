@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/spf13/cobra"
+)
+
+// ValidateCmd validates the atlantis.yaml file of a local repo so mistakes
+// can be found before pushing.
+type ValidateCmd struct{}
+
+// validateOutput is what's printed with --json.
+type validateOutput struct {
+	Projects  []validateProject           `json:"projects"`
+	Workflows map[string]validateWorkflow `json:"workflows"`
+}
+
+type validateProject struct {
+	Name              string                           `json:"name,omitempty"`
+	Dir               string                           `json:"dir"`
+	Workspace         string                           `json:"workspace"`
+	Workflow          string                           `json:"workflow,omitempty"`
+	TerraformVersion  string                           `json:"terraform_version,omitempty"`
+	Autoplan          validateAutoplan                 `json:"autoplan"`
+	ApplyRequirements []string                         `json:"apply_requirements,omitempty"`
+	StepTimeout       string                           `json:"step_timeout,omitempty"`
+	LockKey           string                           `json:"lock_key,omitempty"`
+	Env               map[string]string                `json:"env,omitempty"`
+	Authorization     map[string]validateAuthorization `json:"authorization,omitempty"`
+}
+
+type validateAutoplan struct {
+	Enabled      bool     `json:"enabled"`
+	WhenModified []string `json:"when_modified"`
+}
+
+type validateAuthorization struct {
+	Users []string `json:"users,omitempty"`
+	Teams []string `json:"teams,omitempty"`
+}
+
+type validateWorkflow struct {
+	Plan  []string `json:"plan"`
+	Apply []string `json:"apply"`
+}
+
+// Init returns the runnable cobra command.
+func (v *ValidateCmd) Init() *cobra.Command {
+	var jsonOutput, schema bool
+	c := &cobra.Command{
+		Use:   "validate [dir]",
+		Short: fmt.Sprintf("Validate the %s file in dir, or the current directory", yaml.AtlantisYAMLFilename),
+		Long: fmt.Sprintf("Validate the %s file in dir, or the current directory, and check that its projects' dirs exist.\n"+
+			"Prints the projects and workflows after project templates, globs and autodiscover are resolved.", yaml.AtlantisYAMLFilename),
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			if schema {
+				err = v.printSchema(cmd.OutOrStdout())
+			} else {
+				dir := "."
+				if len(args) > 0 {
+					dir = args[0]
+				}
+				err = v.validate(cmd.OutOrStdout(), dir, jsonOutput)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\033[31mError: %s\033[39m\n\n", err.Error())
+			}
+			return err
+		},
+		SilenceErrors: true,
+		SilenceUsage:  true,
+	}
+	c.Flags().BoolVar(&jsonOutput, "json", false, "Print the resolved projects and workflows as JSON.")
+	c.Flags().BoolVar(&schema, "schema", false, fmt.Sprintf("Print the JSON schema for %s files instead of validating one.", yaml.AtlantisYAMLFilename))
+	return c
+}
+
+func (v *ValidateCmd) printSchema(out io.Writer) error {
+	schema, err := yaml.JSONSchema()
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(schema)
+	return err
+}
+
+func (v *ValidateCmd) validate(out io.Writer, dir string, jsonOutput bool) error {
+	parser := &yaml.ParserValidator{}
+	config, err := parser.ReadConfig(dir)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no %s file in %q", yaml.AtlantisYAMLFilename, dir)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Projects' dirs are only checked when they're planned so we check them
+	// here too.
+	var missing []string
+	checked := make(map[string]bool)
+	for _, p := range config.Projects {
+		if checked[p.Dir] {
+			continue
+		}
+		checked[p.Dir] = true
+		info, err := os.Stat(filepath.Join(dir, p.Dir))
+		if err != nil || !info.IsDir() {
+			missing = append(missing, fmt.Sprintf("%q", p.Dir))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("project dirs don't exist: %s", strings.Join(missing, ", "))
+	}
+
+	output := v.toOutput(config)
+	if jsonOutput {
+		bytes, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "marshalling output")
+		}
+		_, err = fmt.Fprintf(out, "%s\n", bytes)
+		return err
+	}
+
+	fmt.Fprintf(out, "%s is valid\n\nProjects:\n", yaml.AtlantisYAMLFilename)
+	for _, p := range output.Projects {
+		fmt.Fprintf(out, "  dir: %s workspace: %s", p.Dir, p.Workspace)
+		if p.Name != "" {
+			fmt.Fprintf(out, " name: %s", p.Name)
+		}
+		if p.Workflow != "" {
+			fmt.Fprintf(out, " workflow: %s", p.Workflow)
+		}
+		fmt.Fprintln(out)
+	}
+	var names []string
+	for name := range output.Workflows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(out, "\nWorkflows:")
+	for _, name := range names {
+		w := output.Workflows[name]
+		fmt.Fprintf(out, "  %s:\n    plan: %s\n    apply: %s\n", name, strings.Join(w.Plan, ", "), strings.Join(w.Apply, ", "))
+	}
+	return nil
+}
+
+func (v *ValidateCmd) toOutput(config valid.Config) validateOutput {
+	output := validateOutput{
+		Projects:  []validateProject{},
+		Workflows: make(map[string]validateWorkflow),
+	}
+	for _, p := range config.Projects {
+		vp := validateProject{
+			Dir:               p.Dir,
+			Workspace:         p.Workspace,
+			Autoplan:          validateAutoplan{Enabled: p.Autoplan.Enabled, WhenModified: p.Autoplan.WhenModified},
+			ApplyRequirements: p.ApplyRequirements,
+			LockKey:           p.LockKey,
+			Env:               p.Env,
+		}
+		if p.Name != nil {
+			vp.Name = *p.Name
+		}
+		if p.Workflow != nil {
+			vp.Workflow = *p.Workflow
+		}
+		if p.TerraformVersion != nil {
+			vp.TerraformVersion = p.TerraformVersion.String()
+		} else if p.TerraformVersionConstraint != nil {
+			vp.TerraformVersion = p.TerraformVersionConstraint.String()
+		}
+		if p.StepTimeout != 0 {
+			vp.StepTimeout = p.StepTimeout.String()
+		}
+		if p.Authorization != nil {
+			vp.Authorization = make(map[string]validateAuthorization)
+			for command, a := range p.Authorization {
+				vp.Authorization[command] = validateAuthorization{Users: a.Users, Teams: a.Teams}
+			}
+		}
+		output.Projects = append(output.Projects, vp)
+	}
+	for name, w := range config.Workflows {
+		// Stages that aren't set use the default steps.
+		plan := []string{raw.InitStepName, raw.PlanStepName}
+		if w.Plan != nil {
+			plan = v.stepStrings(w.Plan.Steps)
+		}
+		apply := []string{raw.ApplyStepName}
+		if w.Apply != nil {
+			apply = v.stepStrings(w.Apply.Steps)
+		}
+		output.Workflows[name] = validateWorkflow{Plan: plan, Apply: apply}
+	}
+	return output
+}
+
+// stepStrings returns each step as it would be written in atlantis.yaml, ex.
+// plan -var-file=staging.tfvars.
+func (v *ValidateCmd) stepStrings(steps []valid.Step) []string {
+	strs := []string{}
+	for _, s := range steps {
+		switch s.StepName {
+		case raw.RunStepName:
+			strs = append(strs, fmt.Sprintf("%s: %s", raw.RunStepName, strings.Join(s.RunCommand, " ")))
+		case raw.EnvStepName:
+			if s.RunCommand != nil {
+				strs = append(strs, fmt.Sprintf("%s: %s=$(%s)", raw.EnvStepName, s.EnvVarName, strings.Join(s.RunCommand, " ")))
+			} else {
+				strs = append(strs, fmt.Sprintf("%s: %s=%s", raw.EnvStepName, s.EnvVarName, s.EnvVarValue))
+			}
+		default:
+			strs = append(strs, strings.Join(append([]string{s.StepName}, s.ExtraArgs...), " "))
+		}
+	}
+	return strs
+}
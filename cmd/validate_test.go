@@ -0,0 +1,126 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/runatlantis/atlantis/cmd"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+const validateConfig = `
+version: 2
+project_templates:
+  envs:
+    workspaces: [staging, prod]
+    workflow: custom
+projects:
+- dir: network
+  extends: envs
+- name: db
+  dir: db
+  terraform_version: v0.11.0
+workflows:
+  custom:
+    plan:
+      steps:
+      - env:
+          name: TF_VAR_pull
+          value: "1"
+      - init
+      - plan:
+          extra_args: ["-var-file={{.WORKSPACE}}.tfvars"]
+      - run: echo done
+`
+
+func TestValidate(t *testing.T) {
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, ioutil.WriteFile(filepath.Join(repoDir, "atlantis.yaml"), []byte(validateConfig), 0600))
+	for _, dir := range []string{"network", "db"} {
+		Ok(t, os.Mkdir(filepath.Join(repoDir, dir), 0700))
+	}
+
+	out, err := runValidate(repoDir)
+	Ok(t, err)
+	Equals(t, `atlantis.yaml is valid
+
+Projects:
+  dir: network workspace: staging workflow: custom
+  dir: network workspace: prod workflow: custom
+  dir: db workspace: default name: db
+
+Workflows:
+  custom:
+    plan: env: TF_VAR_pull=1, init, plan -var-file={{.WORKSPACE}}.tfvars, run: echo done
+    apply: apply
+`, out)
+}
+
+func TestValidate_JSON(t *testing.T) {
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, ioutil.WriteFile(filepath.Join(repoDir, "atlantis.yaml"), []byte(validateConfig), 0600))
+	for _, dir := range []string{"network", "db"} {
+		Ok(t, os.Mkdir(filepath.Join(repoDir, dir), 0700))
+	}
+
+	out, err := runValidate(repoDir, "--json")
+	Ok(t, err)
+	var parsed struct {
+		Projects []struct {
+			Name             string `json:"name"`
+			Dir              string `json:"dir"`
+			Workspace        string `json:"workspace"`
+			TerraformVersion string `json:"terraform_version"`
+		} `json:"projects"`
+		Workflows map[string]struct {
+			Plan  []string `json:"plan"`
+			Apply []string `json:"apply"`
+		} `json:"workflows"`
+	}
+	Ok(t, json.Unmarshal([]byte(out), &parsed))
+	Equals(t, 3, len(parsed.Projects))
+	Equals(t, "db", parsed.Projects[2].Name)
+	Equals(t, "0.11.0", parsed.Projects[2].TerraformVersion)
+	Equals(t, []string{"apply"}, parsed.Workflows["custom"].Apply)
+}
+
+func TestValidate_Errors(t *testing.T) {
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+
+	t.Log("a missing config file should error")
+	_, err := runValidate(repoDir)
+	ErrEquals(t, "no atlantis.yaml file in \""+repoDir+"\"", err)
+
+	t.Log("an invalid config file should error")
+	Ok(t, ioutil.WriteFile(filepath.Join(repoDir, "atlantis.yaml"), []byte("version: 1"), 0600))
+	_, err = runValidate(repoDir)
+	ErrEquals(t, "parsing atlantis.yaml: version: must equal 2.", err)
+
+	t.Log("project dirs that don't exist should error")
+	Ok(t, ioutil.WriteFile(filepath.Join(repoDir, "atlantis.yaml"), []byte(validateConfig), 0600))
+	Ok(t, os.Mkdir(filepath.Join(repoDir, "db"), 0700))
+	_, err = runValidate(repoDir)
+	ErrEquals(t, "project dirs don't exist: \"network\"", err)
+}
+
+func TestValidate_Schema(t *testing.T) {
+	out, err := runValidate("--schema")
+	Ok(t, err)
+	Assert(t, json.Valid([]byte(out)), "exp schema to be valid JSON")
+}
+
+func runValidate(args ...string) (string, error) {
+	c := (&cmd.ValidateCmd{}).Init()
+	buf := &bytes.Buffer{}
+	c.SetOutput(buf)
+	c.SetArgs(args)
+	err := c.Execute()
+	return buf.String(), err
+}
@@ -45,6 +45,7 @@ module.exports = {
                 ['atlantis-yaml-reference', 'atlantis.yaml Reference'],
                 'upgrading-atlantis-yaml-to-version-2',
                 'security',
+                'api',
                 'faq',
             ],
             '/guide/': [
@@ -0,0 +1,651 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// APIPathPrefix is the path that all JSON API routes are under.
+const APIPathPrefix = "/api/v1"
+
+// apiRunRetention is how long completed runs can be polled for.
+const apiRunRetention = 24 * time.Hour
+
+// APIToken is a token that can be used to call the API.
+type APIToken struct {
+	// Name identifies the token in logs.
+	Name  string
+	Token string
+	// Repos limits the repos the token can be used with. If nil, the token
+	// can be used with every repo.
+	Repos *events.RepoWhitelistChecker
+}
+
+// allows returns true if the token can be used with repoFullName on
+// vcsHostname.
+func (t APIToken) allows(repoFullName string, vcsHostname string) bool {
+	return t.Repos == nil || t.Repos.IsWhitelisted(repoFullName, vcsHostname)
+}
+
+type apiTokenKey struct{}
+
+// APIController handles requests to the JSON API. Every request must have an
+// "Authorization: Bearer {token}" header with one of Tokens.
+type APIController struct {
+	Tokens            []APIToken
+	Locker            locking.Locker
+	LocksController   *LocksController
+	Logger            *logging.SimpleLogger
+	WorkingDir        events.WorkingDir
+	PendingPlanFinder *events.PendingPlanFinder
+	OutputStore       *events.ProjectOutputStore
+	// CommandRunner runs plan and apply on pull requests.
+	CommandRunner events.APICommandRunner
+	// IsDraining is optional. If it returns true, Atlantis is restarting and
+	// new runs are rejected.
+	IsDraining           func() bool
+	RepoWhitelistChecker *events.RepoWhitelistChecker
+	// NewRepo returns the repo fullName on the VCS host vcs, ex. gitlab.
+	NewRepo func(fullName string, vcs string) (models.Repo, error)
+
+	runsMutex sync.Mutex
+	// runs maps from run ID to the plans and applies started via the API.
+	runs map[string]*APIRun
+}
+
+// APIRunRequest is the body of POST /api/v1/plan and POST /api/v1/apply.
+type APIRunRequest struct {
+	Repo string `json:"repo"`
+	// VCS is the VCS host of the repo: github, gitlab, bitbucket-cloud or
+	// bitbucket-server. Defaults to github.
+	VCS string `json:"vcs"`
+	// Pull is the pull request to run the command on. Exactly one of Pull and
+	// Ref must be set.
+	Pull int `json:"pull"`
+	// Ref is the branch to run plan on. Plans of a ref don't lock projects
+	// and can't be applied.
+	Ref       string `json:"ref"`
+	Dir       string `json:"dir"`
+	Workspace string `json:"workspace"`
+	Project   string `json:"project"`
+	Verbose   bool   `json:"verbose"`
+}
+
+// APIRun is a plan or apply started via the API.
+type APIRun struct {
+	ID      string `json:"id"`
+	Command string `json:"command"`
+	Repo    string `json:"repo"`
+	Pull    int    `json:"pull,omitempty"`
+	Ref     string `json:"ref,omitempty"`
+	User    string `json:"user"`
+	// Status is running or complete.
+	Status    string     `json:"status"`
+	StartTime time.Time  `json:"start_time"`
+	EndTime   *time.Time `json:"end_time,omitempty"`
+	// Error is set if the command couldn't be run, ex. because the pull
+	// request couldn't be fetched.
+	Error string `json:"error,omitempty"`
+	// Failure is set if the command wasn't allowed, ex. because the pull
+	// request is closed.
+	Failure  string          `json:"failure,omitempty"`
+	Projects []APIRunProject `json:"projects"`
+}
+
+// APIRunProject is the result of running a command on one project.
+type APIRunProject struct {
+	Dir       string `json:"dir"`
+	Workspace string `json:"workspace"`
+	Project   string `json:"project,omitempty"`
+	// Status is success, failure or error.
+	Status  string `json:"status"`
+	Output  string `json:"output,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Failure string `json:"failure,omitempty"`
+}
+
+// APILock is a lock returned by the API.
+type APILock struct {
+	ID         string    `json:"id"`
+	Repo       string    `json:"repo"`
+	Dir        string    `json:"dir"`
+	Workspace  string    `json:"workspace"`
+	PullNum    int       `json:"pull_num"`
+	PullURL    string    `json:"pull_url"`
+	PullAuthor string    `json:"pull_author"`
+	User       string    `json:"user"`
+	Time       time.Time `json:"time"`
+	// ExpiresAt is only set if the lock expires.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIPendingPlan is a plan that hasn't been applied yet.
+type APIPendingPlan struct {
+	Dir       string `json:"dir"`
+	Workspace string `json:"workspace"`
+}
+
+// APIPullStatus is the state of the projects in a pull request.
+type APIPullStatus struct {
+	Repo     string             `json:"repo"`
+	PullNum  int                `json:"pull_num"`
+	Projects []APIProjectStatus `json:"projects"`
+}
+
+// APIProjectStatus is the state of one project in a pull request. A project
+// is included if it's locked by the pull request, has a pending plan, or had a
+// command run on it since Atlantis started.
+type APIProjectStatus struct {
+	Dir       string `json:"dir"`
+	Workspace string `json:"workspace"`
+	// Project is the project's name. It's only known if a command has run on
+	// the project since Atlantis started.
+	Project     string `json:"project,omitempty"`
+	LockID      string `json:"lock_id,omitempty"`
+	PendingPlan bool   `json:"pending_plan"`
+	// LastCommand is the last command run on the project since Atlantis
+	// started, ex. plan.
+	LastCommand          string     `json:"last_command,omitempty"`
+	LastCommandRunning   bool       `json:"last_command_running"`
+	LastCommandStartTime *time.Time `json:"last_command_start_time,omitempty"`
+}
+
+// apiError is the body of error responses.
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// RequireToken wraps handler so it responds with 401 unless the request has
+// a valid API token.
+func (a *APIController) RequireToken(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := a.authenticate(r)
+		if !ok {
+			a.respondErr(w, logging.Warn, http.StatusUnauthorized, "missing or invalid API token")
+			return
+		}
+		a.Logger.Debug("API request %s %s authenticated with token %q", r.Method, r.URL.Path, token.Name)
+		handler(w, r.WithContext(context.WithValue(r.Context(), apiTokenKey{}, token)))
+	}
+}
+
+// ListLocks is the GET /api/v1/locks route. It lists locks, optionally
+// filtered by the repo, pull, dir and workspace query parameters.
+func (a *APIController) ListLocks(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	pullNum := 0
+	if pull := query.Get("pull"); pull != "" {
+		var err error
+		if pullNum, err = strconv.Atoi(pull); err != nil {
+			a.respondErr(w, logging.Warn, http.StatusBadRequest, "invalid pull %q: must be a number", pull)
+			return
+		}
+	}
+	locks, err := a.Locker.List()
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "listing locks: %s", err)
+		return
+	}
+	apiLocks := []APILock{}
+	for id, lock := range locks {
+		if (query.Get("repo") != "" && query.Get("repo") != lock.Project.RepoFullName) ||
+			(pullNum != 0 && pullNum != lock.Pull.Num) ||
+			(query.Get("dir") != "" && query.Get("dir") != lock.Project.Path) ||
+			(query.Get("workspace") != "" && query.Get("workspace") != lock.Workspace) ||
+			!a.token(r).allows(lock.Project.RepoFullName, lock.Pull.BaseRepo.VCSHost.Hostname) {
+			continue
+		}
+		apiLocks = append(apiLocks, newAPILock(id, lock))
+	}
+	sort.Slice(apiLocks, func(i, j int) bool { return apiLocks[i].ID < apiLocks[j].ID })
+	a.respond(w, http.StatusOK, map[string][]APILock{"locks": apiLocks})
+}
+
+// GetLock is the GET /api/v1/lock route. It returns the lock whose ID is the
+// id query parameter.
+func (a *APIController) GetLock(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		a.respondErr(w, logging.Warn, http.StatusBadRequest, "id query parameter is required")
+		return
+	}
+	lock, err := a.Locker.GetLock(id)
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "getting lock: %s", err)
+		return
+	}
+	if lock == nil || !a.token(r).allows(lock.Project.RepoFullName, lock.Pull.BaseRepo.VCSHost.Hostname) {
+		a.respondErr(w, logging.Info, http.StatusNotFound, "no lock found at id %q", id)
+		return
+	}
+	a.respond(w, http.StatusOK, newAPILock(id, *lock))
+}
+
+// DeleteLock is the DELETE /api/v1/lock route. Like deleting a lock in the
+// UI, it discards the lock's plan and comments on the pull request.
+func (a *APIController) DeleteLock(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		a.respondErr(w, logging.Warn, http.StatusBadRequest, "id query parameter is required")
+		return
+	}
+	// If the token is limited to some repos, check it can be used with the
+	// lock's repo before deleting it.
+	if token := a.token(r); token.Repos != nil {
+		lock, err := a.Locker.GetLock(id)
+		if err != nil {
+			a.respondErr(w, logging.Error, http.StatusInternalServerError, "getting lock: %s", err)
+			return
+		}
+		if lock == nil || !token.allows(lock.Project.RepoFullName, lock.Pull.BaseRepo.VCSHost.Hostname) {
+			a.respondErr(w, logging.Info, http.StatusNotFound, "no lock found at id %q", id)
+			return
+		}
+	}
+	lock, commentErr, err := a.LocksController.deleteLock(id, "the Atlantis API")
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "deleting lock: %s", err)
+		return
+	}
+	if lock == nil {
+		a.respondErr(w, logging.Info, http.StatusNotFound, "no lock found at id %q", id)
+		return
+	}
+	if commentErr != nil {
+		// The lock is deleted so the request succeeded.
+		a.Logger.Err("commenting on pull request after deleting lock %q: %s", id, commentErr)
+	}
+	a.Logger.Info("deleted lock %q via the API", id)
+	a.respond(w, http.StatusOK, newAPILock(id, *lock))
+}
+
+// ListPendingPlans is the GET /api/v1/pull/plans route. It lists the plans
+// that haven't been applied for the pull request in the repo and pull query
+// parameters.
+func (a *APIController) ListPendingPlans(w http.ResponseWriter, r *http.Request) {
+	repo, pull, ok := a.parsePull(w, r)
+	if !ok {
+		return
+	}
+	plans, err := a.pendingPlans(repo, pull)
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "finding pending plans: %s", err)
+		return
+	}
+	apiPlans := []APIPendingPlan{}
+	for _, plan := range plans {
+		apiPlans = append(apiPlans, APIPendingPlan{Dir: plan.RepoRelDir, Workspace: plan.Workspace})
+	}
+	a.respond(w, http.StatusOK, map[string][]APIPendingPlan{"plans": apiPlans})
+}
+
+// GetPullStatus is the GET /api/v1/pull route. It returns the state of each
+// project in the pull request in the repo and pull query parameters.
+func (a *APIController) GetPullStatus(w http.ResponseWriter, r *http.Request) {
+	repo, pull, ok := a.parsePull(w, r)
+	if !ok {
+		return
+	}
+	statuses := make(map[string]*APIProjectStatus)
+	project := func(dir string, workspace string) *APIProjectStatus {
+		key := dir + "/" + workspace
+		if statuses[key] == nil {
+			statuses[key] = &APIProjectStatus{Dir: dir, Workspace: workspace}
+		}
+		return statuses[key]
+	}
+
+	locks, err := a.Locker.List()
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "listing locks: %s", err)
+		return
+	}
+	for id, lock := range locks {
+		// Locks created by old versions of Atlantis don't have a VCS host so
+		// they match repos with the same name on any host.
+		sameHost := lock.Project.VCSHost == repo.VCSHost || lock.Project.VCSHost.Hostname == ""
+		if lock.Project.RepoFullName == repo.FullName && sameHost && lock.Pull.Num == pull {
+			project(lock.Project.Path, lock.Workspace).LockID = id
+		}
+	}
+	plans, err := a.pendingPlans(repo, pull)
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "finding pending plans: %s", err)
+		return
+	}
+	for _, plan := range plans {
+		project(plan.RepoRelDir, plan.Workspace).PendingPlan = true
+	}
+	outputs, _ := a.OutputStore.ListForPull(repo, pull)
+	for _, output := range outputs {
+		status := project(output.RepoRelDir, output.Workspace)
+		startTime := output.StartTime
+		status.Project = output.ProjectName
+		status.LastCommand = output.Command.String()
+		status.LastCommandRunning = !output.Buffer.Closed()
+		status.LastCommandStartTime = &startTime
+	}
+
+	pullStatus := APIPullStatus{Repo: repo.FullName, PullNum: pull, Projects: []APIProjectStatus{}}
+	for _, status := range statuses {
+		pullStatus.Projects = append(pullStatus.Projects, *status)
+	}
+	sort.Slice(pullStatus.Projects, func(i, j int) bool {
+		if pullStatus.Projects[i].Dir != pullStatus.Projects[j].Dir {
+			return pullStatus.Projects[i].Dir < pullStatus.Projects[j].Dir
+		}
+		return pullStatus.Projects[i].Workspace < pullStatus.Projects[j].Workspace
+	})
+	a.respond(w, http.StatusOK, pullStatus)
+}
+
+// pendingPlans returns the pending plans of pull. If Atlantis hasn't cloned
+// the pull request, there are none.
+func (a *APIController) pendingPlans(repo models.Repo, pull int) ([]events.PendingPlan, error) {
+	pullDir, err := a.WorkingDir.GetPullDir(repo, models.PullRequest{Num: pull, BaseRepo: repo})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a.PendingPlanFinder.Find(pullDir)
+}
+
+// parsePull parses the repo and pull query parameters. The optional vcs query
+// parameter is used to find the repo's host, ex. gitlab.
+func (a *APIController) parsePull(w http.ResponseWriter, r *http.Request) (models.Repo, int, bool) {
+	repoFullName := r.URL.Query().Get("repo")
+	if repoFullName == "" {
+		a.respondErr(w, logging.Warn, http.StatusBadRequest, "repo query parameter is required")
+		return models.Repo{}, 0, false
+	}
+	pull, err := strconv.Atoi(r.URL.Query().Get("pull"))
+	if err != nil || pull <= 0 {
+		a.respondErr(w, logging.Warn, http.StatusBadRequest, "pull query parameter must be a pull request number")
+		return models.Repo{}, 0, false
+	}
+	repo, err := a.NewRepo(repoFullName, r.URL.Query().Get("vcs"))
+	if err != nil {
+		a.respondErr(w, logging.Warn, http.StatusBadRequest, "%s", err)
+		return models.Repo{}, 0, false
+	}
+	if token := a.token(r); token.Repos != nil && !token.allows(repo.FullName, repo.VCSHost.Hostname) {
+		a.respondErr(w, logging.Warn, http.StatusForbidden, "token %q can't be used with repo %q", token.Name, repoFullName)
+		return models.Repo{}, 0, false
+	}
+	return repo, pull, true
+}
+
+// token returns the token the request was authenticated with by
+// RequireToken.
+func (a *APIController) token(r *http.Request) APIToken {
+	token, _ := r.Context().Value(apiTokenKey{}).(APIToken)
+	return token
+}
+
+// Plan is the POST /api/v1/plan route. It starts running plan as described
+// by the APIRunRequest body and responds with the run's ID.
+func (a *APIController) Plan(w http.ResponseWriter, r *http.Request) {
+	a.startRun(w, r, events.PlanCommand)
+}
+
+// Apply is the POST /api/v1/apply route. It starts running apply as
+// described by the APIRunRequest body and responds with the run's ID.
+func (a *APIController) Apply(w http.ResponseWriter, r *http.Request) {
+	a.startRun(w, r, events.ApplyCommand)
+}
+
+// GetRun is the GET /api/v1/run route. It returns the run whose ID is the id
+// query parameter. Runs can be polled until they're complete and are kept for
+// 24h after.
+func (a *APIController) GetRun(w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		a.respondErr(w, logging.Warn, http.StatusBadRequest, "id query parameter is required")
+		return
+	}
+	a.runsMutex.Lock()
+	run, ok := a.runs[id]
+	var copied APIRun
+	if ok {
+		copied = *run
+	}
+	a.runsMutex.Unlock()
+	// Tokens can only see their own runs.
+	if !ok || copied.User != apiUser(a.token(r)).Username {
+		a.respondErr(w, logging.Info, http.StatusNotFound, "no run found with id %q", id)
+		return
+	}
+	a.respond(w, http.StatusOK, copied)
+}
+
+func (a *APIController) startRun(w http.ResponseWriter, r *http.Request, name events.CommandName) {
+	if a.IsDraining != nil && a.IsDraining() {
+		a.respondErr(w, logging.Info, http.StatusServiceUnavailable, "Atlantis is restarting, try again once it's back")
+		return
+	}
+	var req APIRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		a.respondErr(w, logging.Warn, http.StatusBadRequest, "parsing request body: %s", err)
+		return
+	}
+	cmd, err := a.validateRunRequest(req, name)
+	if err != nil {
+		a.respondErr(w, logging.Warn, http.StatusBadRequest, "%s", err)
+		return
+	}
+	repo, err := a.NewRepo(req.Repo, req.VCS)
+	if err != nil {
+		a.respondErr(w, logging.Warn, http.StatusBadRequest, "%s", err)
+		return
+	}
+	if !a.RepoWhitelistChecker.IsWhitelisted(repo.FullName, repo.VCSHost.Hostname) {
+		a.respondErr(w, logging.Warn, http.StatusForbidden, "repo %q is not whitelisted", req.Repo)
+		return
+	}
+	token := a.token(r)
+	if !token.allows(repo.FullName, repo.VCSHost.Hostname) {
+		a.respondErr(w, logging.Warn, http.StatusForbidden, "token %q can't be used with repo %q", token.Name, req.Repo)
+		return
+	}
+
+	id, err := newRunID()
+	if err != nil {
+		a.respondErr(w, logging.Error, http.StatusInternalServerError, "generating run ID: %s", err)
+		return
+	}
+	user := apiUser(token)
+	run := &APIRun{
+		ID:        id,
+		Command:   name.String(),
+		Repo:      repo.FullName,
+		Pull:      req.Pull,
+		Ref:       req.Ref,
+		User:      user.Username,
+		Status:    "running",
+		StartTime: time.Now(),
+		Projects:  []APIRunProject{},
+	}
+	a.runsMutex.Lock()
+	a.pruneRuns()
+	if a.runs == nil {
+		a.runs = make(map[string]*APIRun)
+	}
+	a.runs[id] = run
+	a.runsMutex.Unlock()
+
+	a.Logger.Info("starting %s run %s on %s for token %q", name.String(), id, repo.FullName, token.Name)
+	go func() {
+		var result events.CommandResult
+		if req.Ref != "" {
+			result = a.CommandRunner.RunAPIRefPlan(repo, user, req.Ref, cmd)
+		} else {
+			result = a.CommandRunner.RunAPICommand(repo, user, req.Pull, cmd)
+		}
+		a.completeRun(id, newAPIRunProjects(result), result.Error, result.Failure)
+	}()
+	a.respond(w, http.StatusAccepted, map[string]string{"id": id})
+}
+
+// validateRunRequest validates req like the comment parser validates
+// comments and returns the equivalent comment command.
+func (a *APIController) validateRunRequest(req APIRunRequest, name events.CommandName) (*events.CommentCommand, error) {
+	if req.Repo == "" {
+		return nil, errors.New("repo is required")
+	}
+	if (req.Pull == 0) == (req.Ref == "") {
+		return nil, errors.New("exactly one of pull and ref is required")
+	}
+	if req.Pull < 0 {
+		return nil, errors.New("pull must be a pull request number")
+	}
+	if req.Ref != "" && name == events.ApplyCommand {
+		return nil, errors.New("apply requires a pull request, refs can only be planned")
+	}
+	if req.Project != "" && (req.Dir != "" || req.Workspace != "") {
+		return nil, errors.New("project can't be used at the same time as dir or workspace")
+	}
+	dir := req.Dir
+	if dir != "" {
+		dir = filepath.Clean(filepath.Join(".", filepath.Clean(dir)))
+		if strings.HasPrefix(dir, "..") {
+			return nil, fmt.Errorf("using a relative path %q for dir is not allowed", req.Dir)
+		}
+	}
+	if req.Workspace != url.PathEscape(req.Workspace) || strings.Contains(req.Workspace, "..") {
+		return nil, fmt.Errorf("invalid workspace: %q", req.Workspace)
+	}
+	return events.NewCommentCommand(dir, nil, name, req.Verbose, req.Workspace, req.Project), nil
+}
+
+func (a *APIController) completeRun(id string, projects []APIRunProject, err error, failure string) {
+	now := time.Now()
+	a.runsMutex.Lock()
+	defer a.runsMutex.Unlock()
+	run := a.runs[id]
+	run.Status = "complete"
+	run.EndTime = &now
+	run.Failure = failure
+	if err != nil {
+		run.Error = err.Error()
+	}
+	if projects != nil {
+		run.Projects = projects
+	}
+	a.Logger.Info("%s run %s on %s is complete", run.Command, id, run.Repo)
+}
+
+// pruneRuns deletes runs that completed more than apiRunRetention ago. It
+// must be called with runsMutex held.
+func (a *APIController) pruneRuns() {
+	for id, run := range a.runs {
+		if run.EndTime != nil && time.Since(*run.EndTime) > apiRunRetention {
+			delete(a.runs, id)
+		}
+	}
+}
+
+func (a *APIController) authenticate(r *http.Request) (APIToken, bool) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return APIToken{}, false
+	}
+	given := []byte(strings.TrimPrefix(header, "Bearer "))
+	for _, token := range a.Tokens {
+		if subtle.ConstantTimeCompare(given, []byte(token.Token)) == 1 {
+			return token, true
+		}
+	}
+	return APIToken{}, false
+}
+
+func (a *APIController) respond(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		a.Logger.Err("writing API response: %s", err)
+	}
+}
+
+func (a *APIController) respondErr(w http.ResponseWriter, lvl logging.LogLevel, code int, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	a.Logger.Log(lvl, "API: %s", msg)
+	a.respond(w, code, apiError{Error: msg})
+}
+
+// apiUser is the user that commands run with token are run as.
+func apiUser(token APIToken) models.User {
+	return models.User{Username: "api:" + token.Name}
+}
+
+func newRunID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func newAPIRunProjects(result events.CommandResult) []APIRunProject {
+	var projects []APIRunProject
+	for _, res := range result.ProjectResults {
+		project := APIRunProject{
+			Dir:       res.RepoRelDir,
+			Workspace: res.Workspace,
+			Project:   res.ProjectName,
+			Status:    "success",
+			Failure:   res.Failure,
+		}
+		switch {
+		case res.Error != nil:
+			project.Status = "error"
+			project.Error = res.Error.Error()
+		case res.Failure != "":
+			project.Status = "failure"
+		case res.PlanSuccess != nil:
+			project.Output = res.PlanSuccess.TerraformOutput
+		default:
+			project.Output = res.ApplySuccess
+		}
+		projects = append(projects, project)
+	}
+	return projects
+}
+
+func newAPILock(id string, lock models.ProjectLock) APILock {
+	apiLock := APILock{
+		ID:         id,
+		Repo:       lock.Project.RepoFullName,
+		Dir:        lock.Project.Path,
+		Workspace:  lock.Workspace,
+		PullNum:    lock.Pull.Num,
+		PullURL:    lock.Pull.URL,
+		PullAuthor: lock.Pull.Author,
+		User:       lock.User.Username,
+		Time:       lock.Time,
+	}
+	if !lock.ExpiresAt.IsZero() {
+		apiLock.ExpiresAt = &lock.ExpiresAt
+	}
+	return apiLock
+}
@@ -0,0 +1,526 @@
+package server_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/locking/mocks"
+	mocks2 "github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var apiLockTime = time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+
+var apiLocks = map[string]models.ProjectLock{
+	"owner/repo/./default": {
+		Project:   models.NewProject("owner/repo", "."),
+		Workspace: "default",
+		Pull:      models.PullRequest{Num: 1, URL: "url1", Author: "alice"},
+		User:      models.User{Username: "alice"},
+		Time:      apiLockTime,
+	},
+	"owner/repo/sub/staging": {
+		Project:   models.NewProject("owner/repo", "sub"),
+		Workspace: "staging",
+		Pull:      models.PullRequest{Num: 2, URL: "url2", Author: "bob"},
+		User:      models.User{Username: "bob"},
+		Time:      apiLockTime,
+	},
+	"owner/other/./default": {
+		Project:   models.NewProject("owner/other", "."),
+		Workspace: "default",
+		Pull:      models.PullRequest{Num: 1, URL: "url3", Author: "alice"},
+		User:      models.User{Username: "alice"},
+		Time:      apiLockTime,
+	},
+}
+
+func TestAPIController_RequireToken(t *testing.T) {
+	a := server.APIController{
+		Tokens: []server.APIToken{{Name: "dashboard", Token: "secret"}},
+		Logger: logging.NewNoopLogger(),
+	}
+	cases := []struct {
+		header  string
+		expCode int
+	}{
+		{"", http.StatusUnauthorized},
+		{"secret", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Bearer secret", http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.header, func(t *testing.T) {
+			called := false
+			handler := a.RequireToken(func(w http.ResponseWriter, r *http.Request) { called = true })
+			r := httptest.NewRequest("GET", "/api/v1/locks", nil)
+			if c.header != "" {
+				r.Header.Set("Authorization", c.header)
+			}
+			w := httptest.NewRecorder()
+			handler(w, r)
+			Equals(t, c.expCode == http.StatusOK, called)
+			if c.expCode != http.StatusOK {
+				responseContains(t, w, c.expCode, `{"error":"missing or invalid API token"}`)
+			}
+		})
+	}
+}
+
+func TestAPIController_RequireTokenNoTokens(t *testing.T) {
+	t.Log("if no tokens are configured every request should be rejected")
+	a := server.APIController{Logger: logging.NewNoopLogger()}
+	r := httptest.NewRequest("GET", "/api/v1/locks", nil)
+	r.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	a.RequireToken(func(w http.ResponseWriter, r *http.Request) { t.Fatal("handler should not be called") })(w, r)
+	Equals(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAPIController_ListLocks(t *testing.T) {
+	cases := []struct {
+		query  string
+		expIDs []string
+	}{
+		{"", []string{"owner/other/./default", "owner/repo/./default", "owner/repo/sub/staging"}},
+		{"?repo=owner/repo", []string{"owner/repo/./default", "owner/repo/sub/staging"}},
+		{"?pull=1", []string{"owner/other/./default", "owner/repo/./default"}},
+		{"?repo=owner/repo&pull=1", []string{"owner/repo/./default"}},
+		{"?dir=sub", []string{"owner/repo/sub/staging"}},
+		{"?workspace=default&repo=owner/other", []string{"owner/other/./default"}},
+		{"?repo=owner/none", []string{}},
+	}
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			l := mocks.NewMockLocker()
+			When(l.List()).ThenReturn(apiLocks, nil)
+			a := server.APIController{Locker: l, Logger: logging.NewNoopLogger()}
+			w := httptest.NewRecorder()
+			a.ListLocks(w, httptest.NewRequest("GET", "/api/v1/locks"+c.query, nil))
+
+			Equals(t, http.StatusOK, w.Code)
+			Equals(t, "application/json", w.Header().Get("Content-Type"))
+			var body struct{ Locks []server.APILock }
+			Ok(t, json.NewDecoder(w.Body).Decode(&body))
+			ids := []string{}
+			for _, lock := range body.Locks {
+				ids = append(ids, lock.ID)
+			}
+			Equals(t, c.expIDs, ids)
+		})
+	}
+}
+
+func TestAPIController_ListLocksErrors(t *testing.T) {
+	RegisterMockTestingT(t)
+	l := mocks.NewMockLocker()
+	When(l.List()).ThenReturn(nil, errors.New("err"))
+	a := server.APIController{Locker: l, Logger: logging.NewNoopLogger()}
+
+	w := httptest.NewRecorder()
+	a.ListLocks(w, httptest.NewRequest("GET", "/api/v1/locks?pull=abc", nil))
+	responseContains(t, w, http.StatusBadRequest, `{"error":"invalid pull \"abc\": must be a number"}`)
+
+	w = httptest.NewRecorder()
+	a.ListLocks(w, httptest.NewRequest("GET", "/api/v1/locks", nil))
+	responseContains(t, w, http.StatusInternalServerError, `{"error":"listing locks: err"}`)
+}
+
+func TestAPIController_GetLock(t *testing.T) {
+	RegisterMockTestingT(t)
+	l := mocks.NewMockLocker()
+	lock := apiLocks["owner/repo/sub/staging"]
+	When(l.GetLock("owner/repo/sub/staging")).ThenReturn(&lock, nil)
+	a := server.APIController{Locker: l, Logger: logging.NewNoopLogger()}
+
+	w := httptest.NewRecorder()
+	a.GetLock(w, httptest.NewRequest("GET", "/api/v1/lock?id=owner%2Frepo%2Fsub%2Fstaging", nil))
+	Equals(t, http.StatusOK, w.Code)
+	var body server.APILock
+	Ok(t, json.NewDecoder(w.Body).Decode(&body))
+	Equals(t, server.APILock{
+		ID:         "owner/repo/sub/staging",
+		Repo:       "owner/repo",
+		Dir:        "sub",
+		Workspace:  "staging",
+		PullNum:    2,
+		PullURL:    "url2",
+		PullAuthor: "bob",
+		User:       "bob",
+		Time:       apiLockTime,
+	}, body)
+
+	w = httptest.NewRecorder()
+	a.GetLock(w, httptest.NewRequest("GET", "/api/v1/lock?id=missing", nil))
+	responseContains(t, w, http.StatusNotFound, `{"error":"no lock found at id \"missing\""}`)
+
+	w = httptest.NewRecorder()
+	a.GetLock(w, httptest.NewRequest("GET", "/api/v1/lock", nil))
+	responseContains(t, w, http.StatusBadRequest, `{"error":"id query parameter is required"}`)
+}
+
+func TestAPIController_DeleteLock(t *testing.T) {
+	RegisterMockTestingT(t)
+	l := mocks.NewMockLocker()
+	repo := models.Repo{FullName: "owner/repo"}
+	lock := apiLocks["owner/repo/sub/staging"]
+	lock.Pull.BaseRepo = repo
+	When(l.Unlock("owner/repo/sub/staging")).ThenReturn(&lock, nil)
+	cp := vcsmocks.NewMockClientProxy()
+	workingDir := mocks2.NewMockWorkingDir()
+	workingDirLocker := events.NewDefaultWorkingDirLocker()
+	a := server.APIController{
+		Locker: l,
+		LocksController: &server.LocksController{
+			Locker:           l,
+			Logger:           logging.NewNoopLogger(),
+			VCSClient:        cp,
+			WorkingDir:       workingDir,
+			WorkingDirLocker: workingDirLocker,
+		},
+		Logger: logging.NewNoopLogger(),
+	}
+
+	w := httptest.NewRecorder()
+	a.DeleteLock(w, httptest.NewRequest("DELETE", "/api/v1/lock?id=owner%2Frepo%2Fsub%2Fstaging", nil))
+	responseContains(t, w, http.StatusOK, `"id":"owner/repo/sub/staging"`)
+	workingDir.VerifyWasCalledOnce().DeleteForWorkspace(repo, lock.Pull, "staging")
+	cp.VerifyWasCalledOnce().CreateComment(repo, 2, "**Warning**: The plan for dir: `sub` workspace: `staging` was **discarded** via the Atlantis API.\n\n"+
+		"To `apply` this plan you must run `plan` again.")
+
+	w = httptest.NewRecorder()
+	a.DeleteLock(w, httptest.NewRequest("DELETE", "/api/v1/lock?id=missing", nil))
+	responseContains(t, w, http.StatusNotFound, `{"error":"no lock found at id \"missing\""}`)
+}
+
+func TestAPIController_PullStatus(t *testing.T) {
+	RegisterMockTestingT(t)
+	pullDir, cleanup := TempDir(t)
+	defer cleanup()
+	// A pending plan in a project that isn't locked and one in a project that
+	// is.
+	for _, plan := range []string{"default/other/default.tfplan", "default/default.tfplan"} {
+		Ok(t, os.MkdirAll(filepath.Dir(filepath.Join(pullDir, plan)), 0700))
+		Ok(t, ioutil.WriteFile(filepath.Join(pullDir, plan), nil, 0600))
+	}
+	gitInit := exec.Command("git", "init")
+	gitInit.Dir = filepath.Join(pullDir, "default")
+	out, err := gitInit.CombinedOutput()
+	Ok(t, err)
+	Assert(t, gitInit.ProcessState.Success(), "git init failed: %s", out)
+
+	// A lock for a repo with the same name on another host shouldn't be
+	// included.
+	locks := map[string]models.ProjectLock{
+		"Gitlab/gitlab.com/owner/repo/sub/default": {
+			Project:   models.Project{RepoFullName: "owner/repo", Path: "sub", VCSHost: models.VCSHost{Hostname: "gitlab.com", Type: models.Gitlab}},
+			Workspace: "default",
+			Pull:      models.PullRequest{Num: 1},
+		},
+	}
+	for id, lock := range apiLocks {
+		locks[id] = lock
+	}
+	l := mocks.NewMockLocker()
+	When(l.List()).ThenReturn(locks, nil)
+	workingDir := mocks2.NewMockWorkingDir()
+	When(workingDir.GetPullDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).ThenReturn(pullDir, nil)
+	outputStore := events.NewProjectOutputStore(10, nil)
+	repo, err := newGithubRepo("owner/repo", "")
+	Ok(t, err)
+	buf := outputStore.Start(models.ProjectCommandContext{
+		BaseRepo:   repo,
+		Pull:       models.PullRequest{Num: 1},
+		RepoRelDir: "sub",
+		Workspace:  "default",
+	}, events.PlanCommand)
+	defer buf.Close()
+	a := server.APIController{
+		Locker:            l,
+		Logger:            logging.NewNoopLogger(),
+		WorkingDir:        workingDir,
+		PendingPlanFinder: &events.PendingPlanFinder{},
+		OutputStore:       outputStore,
+		NewRepo:           newGithubRepo,
+	}
+
+	w := httptest.NewRecorder()
+	a.GetPullStatus(w, httptest.NewRequest("GET", "/api/v1/pull?repo=owner/repo&pull=1", nil))
+	Equals(t, http.StatusOK, w.Code)
+	var status server.APIPullStatus
+	Ok(t, json.NewDecoder(w.Body).Decode(&status))
+	Equals(t, "owner/repo", status.Repo)
+	Equals(t, 1, status.PullNum)
+	Equals(t, 3, len(status.Projects))
+	Equals(t, server.APIProjectStatus{Dir: ".", Workspace: "default", LockID: "owner/repo/./default", PendingPlan: true}, status.Projects[0])
+	Equals(t, server.APIProjectStatus{Dir: "other", Workspace: "default", PendingPlan: true}, status.Projects[1])
+	Equals(t, "sub", status.Projects[2].Dir)
+	Equals(t, "plan", status.Projects[2].LastCommand)
+	Equals(t, true, status.Projects[2].LastCommandRunning)
+	Assert(t, status.Projects[2].LastCommandStartTime != nil, "exp start time")
+
+	w = httptest.NewRecorder()
+	a.ListPendingPlans(w, httptest.NewRequest("GET", "/api/v1/pull/plans?repo=owner/repo&pull=1", nil))
+	responseContains(t, w, http.StatusOK, `{"plans":[{"dir":".","workspace":"default"},{"dir":"other","workspace":"default"}]}`)
+
+	// The pull dir must be looked up with the repo's host since it's part
+	// of the path.
+	pullDirRepo, _ := workingDir.VerifyWasCalled(Times(2)).GetPullDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest()).GetCapturedArguments()
+	Equals(t, "Github/github.com/owner/repo", pullDirRepo.ID())
+}
+
+func TestAPIController_PullNotCloned(t *testing.T) {
+	RegisterMockTestingT(t)
+	workingDir := mocks2.NewMockWorkingDir()
+	When(workingDir.GetPullDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest())).ThenReturn("", &os.PathError{Op: "stat", Path: "/dir", Err: os.ErrNotExist})
+	a := server.APIController{
+		Logger:            logging.NewNoopLogger(),
+		WorkingDir:        workingDir,
+		PendingPlanFinder: &events.PendingPlanFinder{},
+		NewRepo:           newGithubRepo,
+	}
+	w := httptest.NewRecorder()
+	a.ListPendingPlans(w, httptest.NewRequest("GET", "/api/v1/pull/plans?repo=owner/repo&pull=1", nil))
+	responseContains(t, w, http.StatusOK, `{"plans":[]}`)
+
+	for _, query := range []string{"pull=1", "repo=owner/repo", "repo=owner/repo&pull=abc"} {
+		w = httptest.NewRecorder()
+		a.ListPendingPlans(w, httptest.NewRequest("GET", "/api/v1/pull/plans?"+query, nil))
+		Equals(t, http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestAPIController_RunPull(t *testing.T) {
+	RegisterMockTestingT(t)
+	a, runner := newRunController(t)
+	When(runner.RunAPICommand(matchers.AnyModelsRepo(), matchers.AnyModelsUser(), AnyInt(), matchers.AnyPtrToEventsCommentCommand())).ThenReturn(events.CommandResult{
+		ProjectResults: []events.ProjectResult{
+			{RepoRelDir: "sub", Workspace: "default", PlanSuccess: &events.PlanSuccess{TerraformOutput: "Plan: 1 to add"}},
+			{RepoRelDir: "other", Workspace: "default", Error: errors.New("init failed")},
+		},
+	})
+
+	w := apiRequest(a, "dashboard-token", "POST", "/api/v1/plan", `{"repo":"owner/repo","pull":5,"dir":"sub/"}`)
+	Equals(t, http.StatusAccepted, w.Code)
+	var started struct{ ID string }
+	Ok(t, json.NewDecoder(w.Body).Decode(&started))
+	run := waitForRun(t, a, "dashboard-token", started.ID)
+
+	Equals(t, "plan", run.Command)
+	Equals(t, "owner/repo", run.Repo)
+	Equals(t, 5, run.Pull)
+	Equals(t, "api:dashboard", run.User)
+	Equals(t, "", run.Error)
+	Assert(t, run.EndTime != nil, "exp end time")
+	Equals(t, []server.APIRunProject{
+		{Dir: "sub", Workspace: "default", Status: "success", Output: "Plan: 1 to add"},
+		{Dir: "other", Workspace: "default", Status: "error", Error: "init failed"},
+	}, run.Projects)
+	repo, user, pull, cmd := runner.VerifyWasCalledOnce().RunAPICommand(matchers.AnyModelsRepo(), matchers.AnyModelsUser(), AnyInt(), matchers.AnyPtrToEventsCommentCommand()).GetCapturedArguments()
+	Equals(t, "github.com", repo.VCSHost.Hostname)
+	Equals(t, models.User{Username: "api:dashboard"}, user)
+	Equals(t, 5, pull)
+	Equals(t, events.NewCommentCommand("sub", nil, events.PlanCommand, false, "", ""), cmd)
+
+	t.Log("runs should only be visible to the token that started them")
+	w = apiRequest(a, "ci-token", "GET", "/api/v1/run?id="+started.ID, "")
+	responseContains(t, w, http.StatusNotFound, "no run found")
+}
+
+func TestAPIController_RunFailure(t *testing.T) {
+	RegisterMockTestingT(t)
+	a, runner := newRunController(t)
+	When(runner.RunAPICommand(matchers.AnyModelsRepo(), matchers.AnyModelsUser(), AnyInt(), matchers.AnyPtrToEventsCommentCommand())).ThenReturn(events.CommandResult{
+		Failure: "Atlantis commands can't be run on closed pull requests",
+	})
+	w := apiRequest(a, "dashboard-token", "POST", "/api/v1/apply", `{"repo":"owner/repo","pull":5,"project":"proj"}`)
+	Equals(t, http.StatusAccepted, w.Code)
+	var started struct{ ID string }
+	Ok(t, json.NewDecoder(w.Body).Decode(&started))
+	run := waitForRun(t, a, "dashboard-token", started.ID)
+	Equals(t, "apply", run.Command)
+	Equals(t, "Atlantis commands can't be run on closed pull requests", run.Failure)
+	Equals(t, []server.APIRunProject{}, run.Projects)
+	_, _, _, cmd := runner.VerifyWasCalledOnce().RunAPICommand(matchers.AnyModelsRepo(), matchers.AnyModelsUser(), AnyInt(), matchers.AnyPtrToEventsCommentCommand()).GetCapturedArguments()
+	Equals(t, events.NewCommentCommand("", nil, events.ApplyCommand, false, "", "proj"), cmd)
+}
+
+func TestAPIController_RunRef(t *testing.T) {
+	RegisterMockTestingT(t)
+	a, runner := newRunController(t)
+	When(runner.RunAPIRefPlan(matchers.AnyModelsRepo(), matchers.AnyModelsUser(), AnyString(), matchers.AnyPtrToEventsCommentCommand())).ThenReturn(events.CommandResult{
+		ProjectResults: []events.ProjectResult{
+			{RepoRelDir: "sub", Workspace: "default", ProjectName: "sub", PlanSuccess: &events.PlanSuccess{TerraformOutput: "Plan: 1 to add"}},
+			{RepoRelDir: "prod", Workspace: "default", Failure: "User `api:dashboard` is not authorized to run `plan` on this project."},
+		},
+	})
+
+	w := apiRequest(a, "dashboard-token", "POST", "/api/v1/plan", `{"repo":"owner/repo","ref":"feature","workspace":"default"}`)
+	Equals(t, http.StatusAccepted, w.Code)
+	var started struct{ ID string }
+	Ok(t, json.NewDecoder(w.Body).Decode(&started))
+	run := waitForRun(t, a, "dashboard-token", started.ID)
+	Equals(t, "feature", run.Ref)
+	Equals(t, "", run.Error)
+	Equals(t, []server.APIRunProject{
+		{Dir: "sub", Workspace: "default", Project: "sub", Status: "success", Output: "Plan: 1 to add"},
+		{Dir: "prod", Workspace: "default", Status: "failure", Failure: "User `api:dashboard` is not authorized to run `plan` on this project."},
+	}, run.Projects)
+	repo, user, ref, cmd := runner.VerifyWasCalledOnce().RunAPIRefPlan(matchers.AnyModelsRepo(), matchers.AnyModelsUser(), AnyString(), matchers.AnyPtrToEventsCommentCommand()).GetCapturedArguments()
+	Equals(t, "owner/repo", repo.FullName)
+	Equals(t, models.User{Username: "api:dashboard"}, user)
+	Equals(t, "feature", ref)
+	Equals(t, events.NewCommentCommand("", nil, events.PlanCommand, false, "default", ""), cmd)
+	runner.VerifyWasCalled(Never()).RunAPICommand(matchers.AnyModelsRepo(), matchers.AnyModelsUser(), AnyInt(), matchers.AnyPtrToEventsCommentCommand())
+}
+
+func TestAPIController_RunErrors(t *testing.T) {
+	cases := []struct {
+		description string
+		token       string
+		path        string
+		body        string
+		expCode     int
+		expErr      string
+	}{
+		{"invalid json", "dashboard-token", "/api/v1/plan", `{`, http.StatusBadRequest, "parsing request body"},
+		{"no repo", "dashboard-token", "/api/v1/plan", `{"pull":1}`, http.StatusBadRequest, "repo is required"},
+		{"no pull or ref", "dashboard-token", "/api/v1/plan", `{"repo":"owner/repo"}`, http.StatusBadRequest, "exactly one of pull and ref is required"},
+		{"pull and ref", "dashboard-token", "/api/v1/plan", `{"repo":"owner/repo","pull":1,"ref":"master"}`, http.StatusBadRequest, "exactly one of pull and ref is required"},
+		{"apply ref", "dashboard-token", "/api/v1/apply", `{"repo":"owner/repo","ref":"master"}`, http.StatusBadRequest, "apply requires a pull request, refs can only be planned"},
+		{"project and dir", "dashboard-token", "/api/v1/plan", `{"repo":"owner/repo","pull":1,"project":"p","dir":"."}`, http.StatusBadRequest, "project can't be used at the same time as dir or workspace"},
+		{"relative dir", "dashboard-token", "/api/v1/plan", `{"repo":"owner/repo","pull":1,"dir":"../other"}`, http.StatusBadRequest, `using a relative path \"../other\" for dir is not allowed`},
+		{"invalid workspace", "dashboard-token", "/api/v1/plan", `{"repo":"owner/repo","pull":1,"workspace":"a/b"}`, http.StatusBadRequest, `invalid workspace: \"a/b\"`},
+		{"invalid vcs", "dashboard-token", "/api/v1/plan", `{"repo":"owner/repo","pull":1,"vcs":"svn"}`, http.StatusBadRequest, `vcs \"svn\" for repo \"owner/repo\" not supported`},
+		{"not whitelisted", "dashboard-token", "/api/v1/plan", `{"repo":"other/repo","pull":1}`, http.StatusForbidden, `repo \"other/repo\" is not whitelisted`},
+		{"token not allowed", "ci-token", "/api/v1/plan", `{"repo":"owner/repo","pull":1}`, http.StatusForbidden, `token \"ci\" can't be used with repo \"owner/repo\"`},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			a, runner := newRunController(t)
+			w := apiRequest(a, c.token, "POST", c.path, c.body)
+			responseContains(t, w, c.expCode, c.expErr)
+			runner.VerifyWasCalled(Never()).RunAPICommand(matchers.AnyModelsRepo(), matchers.AnyModelsUser(), AnyInt(), matchers.AnyPtrToEventsCommentCommand())
+		})
+	}
+}
+
+func TestAPIController_RunDraining(t *testing.T) {
+	RegisterMockTestingT(t)
+	a, runner := newRunController(t)
+	a.IsDraining = func() bool { return true }
+	w := apiRequest(a, "dashboard-token", "POST", "/api/v1/plan", `{"repo":"owner/repo","pull":1}`)
+	responseContains(t, w, http.StatusServiceUnavailable, "Atlantis is restarting")
+	runner.VerifyWasCalled(Never()).RunAPICommand(matchers.AnyModelsRepo(), matchers.AnyModelsUser(), AnyInt(), matchers.AnyPtrToEventsCommentCommand())
+}
+
+func TestAPIController_TokenRepos(t *testing.T) {
+	RegisterMockTestingT(t)
+	a, _ := newRunController(t)
+	l := mocks.NewMockLocker()
+	locks := make(map[string]models.ProjectLock)
+	for id, lock := range apiLocks {
+		lock.Pull.BaseRepo = models.Repo{FullName: lock.Project.RepoFullName, VCSHost: models.VCSHost{Hostname: "github.com"}}
+		locks[id] = lock
+	}
+	When(l.List()).ThenReturn(locks, nil)
+	When(l.GetLock("owner/repo/./default")).ThenReturn(&models.ProjectLock{}, nil)
+	a.Locker = l
+
+	t.Log("ci can only be used with owner/other")
+	w := apiRequest(a, "ci-token", "GET", "/api/v1/locks", "")
+	responseContains(t, w, http.StatusOK, `"id":"owner/other/./default"`)
+	Assert(t, !strings.Contains(w.Body.String(), `"id":"owner/repo`), "exp owner/repo locks to be hidden, got %s", w.Body.String())
+
+	w = apiRequest(a, "ci-token", "GET", "/api/v1/lock?id=owner/repo/./default", "")
+	Equals(t, http.StatusNotFound, w.Code)
+	w = apiRequest(a, "ci-token", "DELETE", "/api/v1/lock?id=owner/repo/./default", "")
+	Equals(t, http.StatusNotFound, w.Code)
+	l.VerifyWasCalled(Never()).Unlock(AnyString())
+
+	w = apiRequest(a, "ci-token", "GET", "/api/v1/pull?repo=owner/repo&pull=1", "")
+	responseContains(t, w, http.StatusForbidden, `token \"ci\" can't be used with repo \"owner/repo\"`)
+}
+
+// newRunController returns a controller with two tokens: dashboard, which can
+// be used with every repo, and ci, which can only be used with owner/other.
+func newRunController(t *testing.T) (*server.APIController, *mocks2.MockAPICommandRunner) {
+	ciRepos, err := events.NewRepoWhitelistChecker("github.com/owner/other")
+	Ok(t, err)
+	whitelist, err := events.NewRepoWhitelistChecker("github.com/owner/*")
+	Ok(t, err)
+	runner := mocks2.NewMockAPICommandRunner()
+	return &server.APIController{
+		Tokens: []server.APIToken{
+			{Name: "dashboard", Token: "dashboard-token"},
+			{Name: "ci", Token: "ci-token", Repos: ciRepos},
+		},
+		Logger:               logging.NewNoopLogger(),
+		CommandRunner:        runner,
+		RepoWhitelistChecker: whitelist,
+		NewRepo:              newGithubRepo,
+	}, runner
+}
+
+// newGithubRepo is APIController.NewRepo for a server that's only configured
+// for GitHub.
+func newGithubRepo(fullName string, vcs string) (models.Repo, error) {
+	if vcs != "" && vcs != "github" {
+		return models.Repo{}, fmt.Errorf("vcs %q for repo %q not supported", vcs, fullName)
+	}
+	return models.NewRepo(models.Github, fullName, "https://github.com/"+fullName+".git", "user", "token")
+}
+
+// apiRequest serves a request authenticated with token through the API
+// routes.
+func apiRequest(a *server.APIController, token string, method string, path string, body string) *httptest.ResponseRecorder {
+	routes := map[string]http.HandlerFunc{
+		"GET /api/v1/locks":   a.ListLocks,
+		"GET /api/v1/lock":    a.GetLock,
+		"DELETE /api/v1/lock": a.DeleteLock,
+		"GET /api/v1/pull":    a.GetPullStatus,
+		"POST /api/v1/plan":   a.Plan,
+		"POST /api/v1/apply":  a.Apply,
+		"GET /api/v1/run":     a.GetRun,
+	}
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	a.RequireToken(routes[method+" "+r.URL.Path])(w, r)
+	return w
+}
+
+// waitForRun polls the run until it's complete.
+func waitForRun(t *testing.T, a *server.APIController, token string, id string) server.APIRun {
+	for i := 0; i < 100; i++ {
+		w := apiRequest(a, token, "GET", "/api/v1/run?id="+id, "")
+		Equals(t, http.StatusOK, w.Code)
+		var run server.APIRun
+		Ok(t, json.NewDecoder(w.Body).Decode(&run))
+		if run.Status == "complete" {
+			return run
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("run %s didn't complete", id)
+	return server.APIRun{}
+}
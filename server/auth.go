@@ -0,0 +1,142 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// Role is what a user of the web UI is allowed to do.
+type Role int
+
+const (
+	// ViewerRole users can view the UI but not change anything.
+	ViewerRole Role = iota
+	// AdminRole users can also delete locks.
+	AdminRole
+)
+
+// String returns the name of the role.
+func (r Role) String() string {
+	switch r {
+	case AdminRole:
+		return "admin"
+	default:
+		return "viewer"
+	}
+}
+
+// AuthUser is an authenticated user of the web UI.
+type AuthUser struct {
+	Username string
+	Role     Role
+}
+
+type authUserKey struct{}
+
+// AuthUserFromContext returns the user authenticated by AuthMiddleware. ok is
+// false if authentication is disabled or the route doesn't require it.
+func AuthUserFromContext(ctx context.Context) (user AuthUser, ok bool) {
+	user, ok = ctx.Value(authUserKey{}).(AuthUser)
+	return user, ok
+}
+
+//go:generate pegomock generate --use-experimental-model-gen --package mocks -o mocks/mock_authenticator.go Authenticator
+
+// Authenticator authenticates requests to the web UI.
+type Authenticator interface {
+	// Authenticate returns the username and groups of the user making r. ok
+	// is false if r isn't authenticated by this authenticator.
+	Authenticate(r *http.Request) (username string, groups []string, ok bool)
+	// Challenge responds to an unauthenticated request, ex. by asking for
+	// credentials or redirecting to a login page.
+	Challenge(w http.ResponseWriter, r *http.Request)
+}
+
+// AuthMiddleware is negroni middleware that requires requests to the web UI
+// to be authenticated. If it has no Authenticators, authentication is
+// disabled and every request is allowed.
+type AuthMiddleware struct {
+	// Authenticators are tried in order. The first one also challenges
+	// unauthenticated requests.
+	Authenticators []Authenticator
+	// AdminUsers and AdminGroups are the users and groups with the admin
+	// role. Everyone else is a viewer.
+	AdminUsers  []string
+	AdminGroups []string
+	// ExemptPaths are paths that don't require authentication. A path ending
+	// in '/' exempts every path under it.
+	ExemptPaths []string
+	Logger      *logging.SimpleLogger
+}
+
+// Enabled returns true if authentication is required.
+func (a *AuthMiddleware) Enabled() bool {
+	return len(a.Authenticators) > 0
+}
+
+// ServeHTTP implements negroni.Handler. It calls next with the authenticated
+// user stored in the request's context.
+func (a *AuthMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if !a.Enabled() || a.isExempt(r.URL.Path) {
+		next(w, r)
+		return
+	}
+	for _, authenticator := range a.Authenticators {
+		username, groups, ok := authenticator.Authenticate(r)
+		if !ok {
+			continue
+		}
+		user := AuthUser{Username: username, Role: a.role(username, groups)}
+		next(w, r.WithContext(context.WithValue(r.Context(), authUserKey{}, user)))
+		return
+	}
+	a.Logger.Debug("unauthenticated request to %s %s", r.Method, r.URL.Path)
+	a.Authenticators[0].Challenge(w, r)
+}
+
+// RequireAdmin wraps handler so it responds with 403 unless the user has the
+// admin role. If authentication is disabled, handler is returned as is.
+func (a *AuthMiddleware) RequireAdmin(handler http.HandlerFunc) http.HandlerFunc {
+	if !a.Enabled() {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := AuthUserFromContext(r.Context())
+		if !ok || user.Role != AdminRole {
+			a.Logger.Warn("denied %s %s to non-admin user %q", r.Method, r.URL.Path, user.Username)
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintln(w, "Forbidden: only admins can do this")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (a *AuthMiddleware) isExempt(path string) bool {
+	for _, exempt := range a.ExemptPaths {
+		if path == exempt || (strings.HasSuffix(exempt, "/") && strings.HasPrefix(path, exempt)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *AuthMiddleware) role(username string, groups []string) Role {
+	for _, admin := range a.AdminUsers {
+		if admin == username {
+			return AdminRole
+		}
+	}
+	for _, adminGroup := range a.AdminGroups {
+		for _, group := range groups {
+			if adminGroup == group {
+				return AdminRole
+			}
+		}
+	}
+	return ViewerRole
+}
@@ -0,0 +1,126 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/logging"
+	sMocks "github.com/runatlantis/atlantis/server/mocks"
+	"github.com/runatlantis/atlantis/server/mocks/matchers"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestAuthMiddleware_Disabled(t *testing.T) {
+	t.Log("with no authenticators every request should be allowed")
+	m := server.AuthMiddleware{Logger: logging.NewNoopLogger()}
+	w, called, _ := serveAuth(&m, httptest.NewRequest("DELETE", "/locks?id=abc", nil), m.RequireAdmin)
+	Assert(t, called, "exp next to be called")
+	Equals(t, http.StatusOK, w.Code)
+}
+
+func TestAuthMiddleware_ExemptPaths(t *testing.T) {
+	RegisterMockTestingT(t)
+	authenticator := sMocks.NewMockAuthenticator()
+	m := server.AuthMiddleware{
+		Authenticators: []server.Authenticator{authenticator},
+		ExemptPaths:    []string{"/events", "/static/"},
+		Logger:         logging.NewNoopLogger(),
+	}
+	for _, path := range []string{"/events", "/static/atlantis.css"} {
+		t.Run(path, func(t *testing.T) {
+			_, called, _ := serveAuth(&m, httptest.NewRequest("POST", path, nil), nil)
+			Assert(t, called, "exp next to be called")
+		})
+	}
+	authenticator.VerifyWasCalled(Never()).Authenticate(matchers.AnyPtrToHttpRequest())
+}
+
+func TestAuthMiddleware_Unauthenticated(t *testing.T) {
+	RegisterMockTestingT(t)
+	first := sMocks.NewMockAuthenticator()
+	second := sMocks.NewMockAuthenticator()
+	m := server.AuthMiddleware{
+		Authenticators: []server.Authenticator{first, second},
+		ExemptPaths:    []string{"/events"},
+		Logger:         logging.NewNoopLogger(),
+	}
+	_, called, _ := serveAuth(&m, httptest.NewRequest("GET", "/events/other", nil), nil)
+	Assert(t, !called, "exp next not to be called")
+	second.VerifyWasCalledOnce().Authenticate(matchers.AnyPtrToHttpRequest())
+	// Only the first authenticator should challenge.
+	first.VerifyWasCalledOnce().Challenge(matchers.AnyHttpResponseWriter(), matchers.AnyPtrToHttpRequest())
+	second.VerifyWasCalled(Never()).Challenge(matchers.AnyHttpResponseWriter(), matchers.AnyPtrToHttpRequest())
+}
+
+func TestAuthMiddleware_Roles(t *testing.T) {
+	cases := []struct {
+		description string
+		username    string
+		groups      []string
+		expRole     server.Role
+	}{
+		{
+			description: "viewer",
+			username:    "bob",
+			groups:      []string{"devs"},
+			expRole:     server.ViewerRole,
+		},
+		{
+			description: "admin user",
+			username:    "alice",
+			expRole:     server.AdminRole,
+		},
+		{
+			description: "admin group",
+			username:    "bob",
+			groups:      []string{"devs", "ops"},
+			expRole:     server.AdminRole,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			authenticator := sMocks.NewMockAuthenticator()
+			When(authenticator.Authenticate(matchers.AnyPtrToHttpRequest())).ThenReturn(c.username, c.groups, true)
+			m := server.AuthMiddleware{
+				Authenticators: []server.Authenticator{authenticator},
+				AdminUsers:     []string{"alice"},
+				AdminGroups:    []string{"ops"},
+				Logger:         logging.NewNoopLogger(),
+			}
+
+			_, called, user := serveAuth(&m, httptest.NewRequest("GET", "/", nil), nil)
+			Assert(t, called, "exp next to be called")
+			Equals(t, server.AuthUser{Username: c.username, Role: c.expRole}, user)
+
+			w, called, _ := serveAuth(&m, httptest.NewRequest("DELETE", "/locks?id=abc", nil), m.RequireAdmin)
+			Equals(t, c.expRole == server.AdminRole, called)
+			if c.expRole == server.AdminRole {
+				Equals(t, http.StatusOK, w.Code)
+			} else {
+				Equals(t, http.StatusForbidden, w.Code)
+			}
+		})
+	}
+}
+
+// serveAuth serves r through m. If wrap is set, the handler is wrapped in it,
+// ex. with RequireAdmin. It returns whether the handler was called and the
+// user it was called with.
+func serveAuth(m *server.AuthMiddleware, r *http.Request, wrap func(http.HandlerFunc) http.HandlerFunc) (*httptest.ResponseRecorder, bool, server.AuthUser) {
+	var called bool
+	var user server.AuthUser
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		user, _ = server.AuthUserFromContext(r.Context())
+	}
+	if wrap != nil {
+		handler = wrap(handler)
+	}
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r, handler)
+	return w, called, user
+}
@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// DriftController handles requests for the results of drift detection.
+type DriftController struct {
+	AtlantisVersion string
+	AtlantisURL     *url.URL
+	Logger          *logging.SimpleLogger
+	DriftDetector   *events.DriftDetector
+	DriftTemplate   TemplateWriter
+}
+
+// GetDrift is the GET /drift route. It renders the latest drift detection
+// result for each configured repo.
+func (d *DriftController) GetDrift(w http.ResponseWriter, _ *http.Request) {
+	var repos []DriftRepoData
+	for _, result := range d.DriftDetector.Results() {
+		repo := DriftRepoData{
+			RepoFullName: result.RepoFullName,
+			Branch:       result.Branch,
+			Time:         result.Time.Format("2006-01-02 15:04:05 MST"),
+		}
+		if result.Error != nil {
+			repo.Error = result.Error.Error()
+		}
+		for _, p := range result.Projects {
+			project := DriftProjectData{
+				RepoRelDir:  p.RepoRelDir,
+				Workspace:   p.Workspace,
+				ProjectName: p.ProjectName,
+				Status:      "No drift",
+				Output:      p.Output,
+			}
+			if p.Drifted {
+				project.Status = "Drifted"
+			}
+			if p.Error != nil {
+				project.Status = "Error"
+				project.Error = p.Error.Error()
+			}
+			repo.Projects = append(repo.Projects, project)
+		}
+		repos = append(repos, repo)
+	}
+
+	err := d.DriftTemplate.Execute(w, DriftIndexData{
+		Repos:           repos,
+		AtlantisVersion: d.AtlantisVersion,
+		CleanedBasePath: d.AtlantisURL.Path,
+	})
+	if err != nil {
+		d.Logger.Err("rendering drift page: %s", err)
+	}
+}
@@ -0,0 +1,82 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events"
+	eventmocks "github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	sMocks "github.com/runatlantis/atlantis/server/mocks"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestGetDrift_NoResults(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpl := sMocks.NewMockTemplateWriter()
+	u, err := url.Parse("https://example.com/basepath")
+	Ok(t, err)
+	dc := server.DriftController{
+		AtlantisVersion: "1.0.0",
+		AtlantisURL:     u,
+		Logger:          logging.NewNoopLogger(),
+		DriftDetector:   &events.DriftDetector{},
+		DriftTemplate:   tmpl,
+	}
+	req, _ := http.NewRequest("GET", "/drift", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	dc.GetDrift(w, req)
+	tmpl.VerifyWasCalledOnce().Execute(w, server.DriftIndexData{
+		AtlantisVersion: "1.0.0",
+		CleanedBasePath: "/basepath",
+	})
+	responseContains(t, w, http.StatusOK, "")
+}
+
+func TestGetDrift_RepoError(t *testing.T) {
+	t.Log("repos that couldn't be checked should be rendered with their error")
+	RegisterMockTestingT(t)
+	tmpl := sMocks.NewMockTemplateWriter()
+	u, err := url.Parse("https://example.com")
+	Ok(t, err)
+	workingDir := eventmocks.NewMockWorkingDir()
+	When(workingDir.DeleteForWorkspace(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())).ThenReturn(errors.New("err"))
+	detector := &events.DriftDetector{
+		WorkingDir:       workingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		Logger:           logging.NewNoopLogger(),
+	}
+	result := detector.DetectDrift(context.Background(), models.Repo{FullName: "owner/repo"}, "master")
+
+	dc := server.DriftController{
+		AtlantisVersion: "1.0.0",
+		AtlantisURL:     u,
+		Logger:          logging.NewNoopLogger(),
+		DriftDetector:   detector,
+		DriftTemplate:   tmpl,
+	}
+	req, _ := http.NewRequest("GET", "/drift", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	dc.GetDrift(w, req)
+	tmpl.VerifyWasCalledOnce().Execute(w, server.DriftIndexData{
+		Repos: []server.DriftRepoData{
+			{
+				RepoFullName: "owner/repo",
+				Branch:       "master",
+				Time:         result.Time.Format("2006-01-02 15:04:05 MST"),
+				Error:        "deleting previous clone: err",
+			},
+		},
+		AtlantisVersion: "1.0.0",
+		CleanedBasePath: "",
+	})
+}
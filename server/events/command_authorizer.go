@@ -0,0 +1,170 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// DefaultTeamMembershipCacheTTL is how long team memberships are cached for
+// if DefaultCommandAuthorizer.MembershipCacheTTL isn't set.
+const DefaultTeamMembershipCacheTTL = 5 * time.Minute
+
+// CommandAuthorizationRule restricts who can run commands on the projects it
+// matches. Rules are configured in the server config.
+type CommandAuthorizationRule struct {
+	// Repos matches the repos the rule applies to. If nil, the rule applies to
+	// every repo.
+	Repos *RepoWhitelistChecker
+	// Dir, Workspace and ProjectName match the projects the rule applies to.
+	// If empty, they match every project.
+	Dir         string
+	Workspace   string
+	ProjectName string
+	// Commands are the commands the rule restricts. If empty, it restricts
+	// plan and apply.
+	Commands []CommandName
+	// Authorization is who can run the commands.
+	Authorization valid.Authorization
+}
+
+// Matches returns true if the rule restricts running cmdName on ctx's
+// project.
+func (r CommandAuthorizationRule) Matches(ctx models.ProjectCommandContext, cmdName CommandName) bool {
+	if r.Repos != nil && !r.Repos.IsWhitelisted(ctx.BaseRepo.FullName, ctx.BaseRepo.VCSHost.Hostname) {
+		return false
+	}
+	if (r.Dir != "" && r.Dir != ctx.RepoRelDir) || (r.Workspace != "" && r.Workspace != ctx.Workspace) {
+		return false
+	}
+	if r.ProjectName != "" && (ctx.ProjectConfig == nil || r.ProjectName != ctx.ProjectConfig.GetName()) {
+		return false
+	}
+	if len(r.Commands) == 0 {
+		return true
+	}
+	for _, c := range r.Commands {
+		if c == cmdName {
+			return true
+		}
+	}
+	return false
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_command_authorizer.go CommandAuthorizer
+
+// CommandAuthorizer checks if users can run commands on projects.
+type CommandAuthorizer interface {
+	// Authorize returns why ctx.User can't run cmdName on ctx's project or an
+	// empty string if they can.
+	Authorize(ctx models.ProjectCommandContext, cmdName CommandName) (string, error)
+}
+
+// DefaultCommandAuthorizer implements CommandAuthorizer. Users can run a
+// command if they're allowed by every server rule that matches the command
+// and by the project's authorization config in atlantis.yaml. If nothing
+// restricts the command, anyone can run it.
+type DefaultCommandAuthorizer struct {
+	Rules     []CommandAuthorizationRule
+	VCSClient vcs.ClientProxy
+	// MembershipCacheTTL is how long team memberships are cached for. If 0,
+	// DefaultTeamMembershipCacheTTL is used.
+	MembershipCacheTTL time.Duration
+
+	mutex sync.Mutex
+	// memberships maps from host, team and user to whether the user is a
+	// member of the team.
+	memberships map[string]cachedMembership
+}
+
+type cachedMembership struct {
+	member  bool
+	expires time.Time
+}
+
+// Authorize returns why ctx.User can't run cmdName on ctx's project or an
+// empty string if they can.
+func (d *DefaultCommandAuthorizer) Authorize(ctx models.ProjectCommandContext, cmdName CommandName) (string, error) {
+	for _, rule := range d.Rules {
+		if !rule.Matches(ctx, cmdName) {
+			continue
+		}
+		if failure, err := d.check(ctx, cmdName, rule.Authorization, "The Atlantis server config"); failure != "" || err != nil {
+			return failure, err
+		}
+	}
+	if ctx.ProjectConfig != nil {
+		if auth, ok := ctx.ProjectConfig.Authorization[cmdName.String()]; ok {
+			return d.check(ctx, cmdName, auth, "This project's config in atlantis.yaml")
+		}
+	}
+	return "", nil
+}
+
+// check returns a failure if ctx.User isn't allowed by auth. source describes
+// where auth was configured, ex. "The Atlantis server config".
+func (d *DefaultCommandAuthorizer) check(ctx models.ProjectCommandContext, cmdName CommandName, auth valid.Authorization, source string) (string, error) {
+	for _, user := range auth.Users {
+		if strings.EqualFold(user, ctx.User.Username) {
+			return "", nil
+		}
+	}
+	for _, team := range auth.Teams {
+		member, err := d.isTeamMember(ctx.BaseRepo, ctx.User, team)
+		if err != nil {
+			return "", errors.Wrapf(err, "checking if %s is a member of %s", ctx.User.Username, team)
+		}
+		if member {
+			return "", nil
+		}
+	}
+	return fmt.Sprintf("User `%s` is not authorized to run `%s` on this project. %s only allows %s.", ctx.User.Username, cmdName.String(), source, describeAuthorization(auth)), nil
+}
+
+func (d *DefaultCommandAuthorizer) isTeamMember(repo models.Repo, user models.User, team string) (bool, error) {
+	key := fmt.Sprintf("%s/%s/%s", repo.VCSHost.Hostname, team, strings.ToLower(user.Username))
+	d.mutex.Lock()
+	cached, ok := d.memberships[key]
+	d.mutex.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.member, nil
+	}
+
+	member, err := d.VCSClient.IsTeamMember(repo, user, team)
+	if err != nil {
+		return false, err
+	}
+	ttl := d.MembershipCacheTTL
+	if ttl == 0 {
+		ttl = DefaultTeamMembershipCacheTTL
+	}
+	d.mutex.Lock()
+	if d.memberships == nil {
+		d.memberships = make(map[string]cachedMembership)
+	}
+	d.memberships[key] = cachedMembership{member: member, expires: time.Now().Add(ttl)}
+	d.mutex.Unlock()
+	return member, nil
+}
+
+// describeAuthorization describes who auth allows, ex. "users `alice` or
+// members of teams `owner/infra`".
+func describeAuthorization(auth valid.Authorization) string {
+	quote := func(items []string) string {
+		return "`" + strings.Join(items, "`, `") + "`"
+	}
+	var parts []string
+	if len(auth.Users) > 0 {
+		parts = append(parts, "users "+quote(auth.Users))
+	}
+	if len(auth.Teams) > 0 {
+		parts = append(parts, "members of teams "+quote(auth.Teams))
+	}
+	return strings.Join(parts, " or ")
+}
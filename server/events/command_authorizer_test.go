@@ -0,0 +1,175 @@
+package events_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	vcsmatchers "github.com/runatlantis/atlantis/server/events/vcs/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var authRepo = models.Repo{
+	FullName: "owner/repo",
+	VCSHost:  models.VCSHost{Hostname: "github.com", Type: models.Github},
+}
+
+func TestDefaultCommandAuthorizer_Authorize(t *testing.T) {
+	prodName := "prod"
+	prod := valid.Project{Dir: "prod", Workspace: "default", Name: &prodName}
+	ownerRepos, err := events.NewRepoWhitelistChecker("github.com/owner/*")
+	Ok(t, err)
+	otherRepos, err := events.NewRepoWhitelistChecker("github.com/other/*")
+	Ok(t, err)
+
+	cases := []struct {
+		description string
+		rules       []events.CommandAuthorizationRule
+		project     valid.Project
+		user        string
+		cmd         events.CommandName
+		expFailure  string
+	}{
+		{
+			description: "no rules",
+			project:     prod,
+			user:        "bob",
+			cmd:         events.ApplyCommand,
+		},
+		{
+			description: "server rule allows user",
+			rules: []events.CommandAuthorizationRule{
+				{ProjectName: "prod", Commands: []events.CommandName{events.ApplyCommand}, Authorization: valid.Authorization{Users: []string{"Alice"}}},
+			},
+			project: prod,
+			user:    "alice",
+			cmd:     events.ApplyCommand,
+		},
+		{
+			description: "server rule denies user",
+			rules: []events.CommandAuthorizationRule{
+				{Repos: ownerRepos, Dir: "prod", Commands: []events.CommandName{events.ApplyCommand}, Authorization: valid.Authorization{Users: []string{"alice"}, Teams: []string{"owner/infra", "owner/sre"}}},
+			},
+			project:    prod,
+			user:       "bob",
+			cmd:        events.ApplyCommand,
+			expFailure: "User `bob` is not authorized to run `apply` on this project. The Atlantis server config only allows users `alice` or members of teams `owner/infra`, `owner/sre`.",
+		},
+		{
+			description: "server rule allows team member",
+			rules: []events.CommandAuthorizationRule{
+				{Authorization: valid.Authorization{Teams: []string{"owner/devs", "owner/infra"}}},
+			},
+			project: prod,
+			user:    "carol",
+			cmd:     events.PlanCommand,
+		},
+		{
+			description: "server rule for other command",
+			rules: []events.CommandAuthorizationRule{
+				{Commands: []events.CommandName{events.ApplyCommand}, Authorization: valid.Authorization{Users: []string{"alice"}}},
+			},
+			project: prod,
+			user:    "bob",
+			cmd:     events.PlanCommand,
+		},
+		{
+			description: "server rule for other repos",
+			rules: []events.CommandAuthorizationRule{
+				{Repos: otherRepos, Authorization: valid.Authorization{Users: []string{"alice"}}},
+			},
+			project: prod,
+			user:    "bob",
+			cmd:     events.ApplyCommand,
+		},
+		{
+			description: "server rule for other workspace",
+			rules: []events.CommandAuthorizationRule{
+				{Workspace: "staging", Authorization: valid.Authorization{Users: []string{"alice"}}},
+			},
+			project: prod,
+			user:    "bob",
+			cmd:     events.ApplyCommand,
+		},
+		{
+			description: "project config denies user",
+			project: valid.Project{
+				Dir:       "prod",
+				Workspace: "default",
+				Authorization: map[string]valid.Authorization{
+					"apply": {Users: []string{"alice"}},
+				},
+			},
+			user:       "bob",
+			cmd:        events.ApplyCommand,
+			expFailure: "User `bob` is not authorized to run `apply` on this project. This project's config in atlantis.yaml only allows users `alice`.",
+		},
+		{
+			description: "project config can't loosen server rules",
+			rules: []events.CommandAuthorizationRule{
+				{Authorization: valid.Authorization{Users: []string{"alice"}}},
+			},
+			project: valid.Project{
+				Dir:       "prod",
+				Workspace: "default",
+				Authorization: map[string]valid.Authorization{
+					"apply": {Users: []string{"bob"}},
+				},
+			},
+			user:       "bob",
+			cmd:        events.ApplyCommand,
+			expFailure: "User `bob` is not authorized to run `apply` on this project. The Atlantis server config only allows users `alice`.",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			vcsClient := vcsmocks.NewMockClientProxy()
+			When(vcsClient.IsTeamMember(authRepo, models.User{Username: "carol"}, "owner/infra")).ThenReturn(true, nil)
+			authorizer := events.DefaultCommandAuthorizer{Rules: c.rules, VCSClient: vcsClient}
+			project := c.project
+			failure, err := authorizer.Authorize(models.ProjectCommandContext{
+				BaseRepo:      authRepo,
+				User:          models.User{Username: c.user},
+				RepoRelDir:    project.Dir,
+				Workspace:     project.Workspace,
+				ProjectConfig: &project,
+			}, c.cmd)
+			Ok(t, err)
+			Equals(t, c.expFailure, failure)
+		})
+	}
+}
+
+func TestDefaultCommandAuthorizer_CachesMemberships(t *testing.T) {
+	RegisterMockTestingT(t)
+	vcsClient := vcsmocks.NewMockClientProxy()
+	When(vcsClient.IsTeamMember(vcsmatchers.AnyModelsRepo(), vcsmatchers.AnyModelsUser(), AnyString())).ThenReturn(true, nil)
+	authorizer := events.DefaultCommandAuthorizer{
+		Rules:     []events.CommandAuthorizationRule{{Authorization: valid.Authorization{Teams: []string{"owner/infra"}}}},
+		VCSClient: vcsClient,
+	}
+	ctx := models.ProjectCommandContext{BaseRepo: authRepo, User: models.User{Username: "carol"}}
+	for i := 0; i < 2; i++ {
+		failure, err := authorizer.Authorize(ctx, events.ApplyCommand)
+		Ok(t, err)
+		Equals(t, "", failure)
+	}
+	vcsClient.VerifyWasCalledOnce().IsTeamMember(authRepo, models.User{Username: "carol"}, "owner/infra")
+}
+
+func TestDefaultCommandAuthorizer_MembershipErr(t *testing.T) {
+	RegisterMockTestingT(t)
+	vcsClient := vcsmocks.NewMockClientProxy()
+	When(vcsClient.IsTeamMember(vcsmatchers.AnyModelsRepo(), vcsmatchers.AnyModelsUser(), AnyString())).ThenReturn(false, errors.New("api error"))
+	authorizer := events.DefaultCommandAuthorizer{
+		Rules:     []events.CommandAuthorizationRule{{Authorization: valid.Authorization{Teams: []string{"owner/infra"}}}},
+		VCSClient: vcsClient,
+	}
+	_, err := authorizer.Authorize(models.ProjectCommandContext{BaseRepo: authRepo, User: models.User{Username: "carol"}}, events.ApplyCommand)
+	ErrEquals(t, "checking if carol is a member of owner/infra: api error", err)
+}
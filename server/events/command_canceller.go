@@ -0,0 +1,176 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// RunningCommand is a command that's been registered with a CommandCanceller.
+type RunningCommand struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	autoplan   bool
+	done       chan struct{}
+	finishOnce sync.Once
+	// superseded is set under the CommandCanceller's mutex.
+	superseded bool
+	canceller  *CommandCanceller
+	key        string
+	// cmdName and projects are what the command is running. They're set under
+	// the CommandCanceller's mutex once the projects have been built.
+	cmdName  CommandName
+	projects []models.ProjectCommandContext
+}
+
+// Context returns the context that's cancelled when this command is
+// cancelled.
+func (r *RunningCommand) Context() context.Context {
+	return r.ctx
+}
+
+// Finish marks the command as done. It's safe to call multiple times.
+func (r *RunningCommand) Finish() {
+	r.finishOnce.Do(func() {
+		r.canceller.remove(r)
+		r.cancel()
+		close(r.done)
+	})
+}
+
+// SetProjects records that the command is running cmdName on projects so
+// cancelling it can be authorized like running it.
+func (r *RunningCommand) SetProjects(cmdName CommandName, projects []models.ProjectCommandContext) {
+	r.canceller.mutex.Lock()
+	defer r.canceller.mutex.Unlock()
+	r.cmdName = cmdName
+	r.projects = projects
+}
+
+// Projects returns the command and the projects it's running on, or no
+// projects if they haven't been built yet.
+func (r *RunningCommand) Projects() (CommandName, []models.ProjectCommandContext) {
+	r.canceller.mutex.Lock()
+	defer r.canceller.mutex.Unlock()
+	return r.cmdName, r.projects
+}
+
+// Cancel cancels the command. It returns false if it was already cancelled.
+func (r *RunningCommand) Cancel() bool {
+	r.canceller.mutex.Lock()
+	defer r.canceller.mutex.Unlock()
+	if r.ctx.Err() != nil {
+		return false
+	}
+	r.cancel()
+	return true
+}
+
+// Superseded returns true if this command was cancelled because a newer
+// autoplan was started on the same pull request.
+func (r *RunningCommand) Superseded() bool {
+	r.canceller.mutex.Lock()
+	defer r.canceller.mutex.Unlock()
+	return r.superseded
+}
+
+// CommandCanceller keeps track of the commands running on each pull request
+// so they can be cancelled.
+type CommandCanceller struct {
+	mutex sync.Mutex
+	// running maps from pull key to the commands running on that pull.
+	running map[string][]*RunningCommand
+}
+
+// NewCommandCanceller returns a new CommandCanceller.
+func NewCommandCanceller() *CommandCanceller {
+	return &CommandCanceller{
+		running: make(map[string][]*RunningCommand),
+	}
+}
+
+// Start registers a new command running on pull request pullNum of repo. If autoplan is true, any autoplans already running on the
+// pull request are superseded: they're cancelled and Start waits for them to
+// finish before returning.
+// The returned RunningCommand's Finish() must be called once the command is
+// done.
+func (c *CommandCanceller) Start(repo models.Repo, pullNum int, autoplan bool) *RunningCommand {
+	key := c.pullKey(repo, pullNum)
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := &RunningCommand{
+		ctx:       ctx,
+		cancel:    cancel,
+		autoplan:  autoplan,
+		done:      make(chan struct{}),
+		canceller: c,
+		key:       key,
+	}
+
+	c.mutex.Lock()
+	var superseded []*RunningCommand
+	if autoplan {
+		for _, r := range c.running[key] {
+			if r.autoplan {
+				r.superseded = true
+				r.cancel()
+				superseded = append(superseded, r)
+			}
+		}
+	}
+	c.running[key] = append(c.running[key], cmd)
+	c.mutex.Unlock()
+
+	// Wait for the superseded autoplans to exit so they release their
+	// working dir locks before we try to clone.
+	for _, r := range superseded {
+		<-r.done
+	}
+	return cmd
+}
+
+// Running returns the commands running on the pull request.
+func (c *CommandCanceller) Running(repo models.Repo, pullNum int) []*RunningCommand {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]*RunningCommand(nil), c.running[c.pullKey(repo, pullNum)]...)
+}
+
+// Cancel cancels all the commands running on the pull request and returns
+// how many were cancelled.
+func (c *CommandCanceller) Cancel(repo models.Repo, pullNum int) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	cancelled := 0
+	for _, r := range c.running[c.pullKey(repo, pullNum)] {
+		if r.ctx.Err() == nil {
+			r.cancel()
+			cancelled++
+		}
+	}
+	return cancelled
+}
+
+func (c *CommandCanceller) remove(cmd *RunningCommand) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	cmds := c.running[cmd.key]
+	for i, r := range cmds {
+		if r == cmd {
+			cmds = append(cmds[:i], cmds[i+1:]...)
+			break
+		}
+	}
+	if len(cmds) == 0 {
+		delete(c.running, cmd.key)
+	} else {
+		c.running[cmd.key] = cmds
+	}
+}
+
+// pullKey uses the repo's ID so pulls with the same number in repos with the
+// same name on different VCS hosts don't collide.
+func (c *CommandCanceller) pullKey(repo models.Repo, pullNum int) string {
+	return fmt.Sprintf("%s/%d", repo.ID(), pullNum)
+}
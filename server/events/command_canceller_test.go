@@ -0,0 +1,93 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var cancellerRepo = models.Repo{
+	FullName: "owner/repo",
+	VCSHost:  models.VCSHost{Type: models.Github, Hostname: "github.com"},
+}
+
+func TestCommandCanceller_CancelNoneRunning(t *testing.T) {
+	c := events.NewCommandCanceller()
+	Equals(t, 0, c.Cancel(cancellerRepo, 1))
+}
+
+func TestCommandCanceller_Cancel(t *testing.T) {
+	c := events.NewCommandCanceller()
+	first := c.Start(cancellerRepo, 1, false)
+	second := c.Start(cancellerRepo, 1, true)
+	other := c.Start(cancellerRepo, 2, false)
+
+	Equals(t, 2, c.Cancel(cancellerRepo, 1))
+	Assert(t, first.Context().Err() != nil, "first command should be cancelled")
+	Assert(t, second.Context().Err() != nil, "second command should be cancelled")
+	Ok(t, other.Context().Err())
+	Assert(t, !second.Superseded(), "cancelled command should not be superseded")
+
+	// Already cancelled commands aren't counted again.
+	Equals(t, 0, c.Cancel(cancellerRepo, 1))
+
+	first.Finish()
+	second.Finish()
+	other.Finish()
+	Equals(t, 0, c.Cancel(cancellerRepo, 2))
+}
+
+func TestCommandCanceller_CancelOtherHost(t *testing.T) {
+	c := events.NewCommandCanceller()
+	cmd := c.Start(cancellerRepo, 1, false)
+	defer cmd.Finish()
+
+	otherHost := cancellerRepo
+	otherHost.VCSHost = models.VCSHost{Type: models.Gitlab, Hostname: "gitlab.com"}
+	Equals(t, 0, c.Cancel(otherHost, 1))
+	Ok(t, cmd.Context().Err())
+}
+
+func TestCommandCanceller_FinishTwice(t *testing.T) {
+	c := events.NewCommandCanceller()
+	cmd := c.Start(cancellerRepo, 1, false)
+	cmd.Finish()
+	cmd.Finish()
+	Equals(t, 0, c.Cancel(cancellerRepo, 1))
+}
+
+func TestCommandCanceller_AutoplanSupersedes(t *testing.T) {
+	c := events.NewCommandCanceller()
+	oldAutoplan := c.Start(cancellerRepo, 1, true)
+	comment := c.Start(cancellerRepo, 1, false)
+	defer comment.Finish()
+
+	// The old autoplan finishes once it sees it's been cancelled.
+	go func() {
+		<-oldAutoplan.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+		oldAutoplan.Finish()
+	}()
+
+	started := make(chan *events.RunningCommand)
+	go func() {
+		started <- c.Start(cancellerRepo, 1, true)
+	}()
+
+	var newAutoplan *events.RunningCommand
+	select {
+	case newAutoplan = <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for superseded autoplan to finish")
+	}
+	defer newAutoplan.Finish()
+
+	Assert(t, oldAutoplan.Superseded(), "old autoplan should be superseded")
+	Assert(t, !newAutoplan.Superseded(), "new autoplan should not be superseded")
+	Ok(t, newAutoplan.Context().Err())
+	// Comment commands aren't cancelled by new autoplans.
+	Ok(t, comment.Context().Err())
+}
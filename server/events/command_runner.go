@@ -21,6 +21,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
 	"github.com/runatlantis/atlantis/server/recovery"
 )
@@ -36,6 +37,20 @@ type CommandRunner interface {
 	RunAutoplanCommand(baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User)
 }
 
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_api_command_runner.go APICommandRunner
+
+// APICommandRunner runs commands requested through the API instead of a
+// comment.
+type APICommandRunner interface {
+	// RunAPICommand runs cmd on pull request pullNum like RunCommentCommand
+	// and also returns the result. It blocks until the command is complete.
+	RunAPICommand(baseRepo models.Repo, user models.User, pullNum int, cmd *CommentCommand) CommandResult
+	// RunAPIRefPlan runs plan on the projects in ref, a branch or commit,
+	// that match cmd's dir, workspace and project. It blocks until the plan
+	// is complete.
+	RunAPIRefPlan(baseRepo models.Repo, user models.User, ref string, cmd *CommentCommand) CommandResult
+}
+
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_github_pull_getter.go GithubPullGetter
 
 // GithubPullGetter makes API calls to get pull requests.
@@ -69,6 +84,18 @@ type DefaultCommandRunner struct {
 	AllowForkPRsFlag      string
 	ProjectCommandBuilder ProjectCommandBuilder
 	ProjectCommandRunner  ProjectCommandRunner
+	// CommandCanceller tracks running commands so they can be cancelled by
+	// the cancel command or superseded by newer autoplans.
+	CommandCanceller *CommandCanceller
+	// CommandAuthorizer is optional. If set, users can only cancel commands
+	// they're authorized to run.
+	CommandAuthorizer CommandAuthorizer
+	// DriftDetector plans refs for RunAPIRefPlan. It should check the same
+	// authorization as ProjectCommandRunner.
+	DriftDetector *DriftDetector
+	// Redactor, if set, removes secrets from the results and log history
+	// before they're commented back to the pull request or returned.
+	Redactor *Redactor
 }
 
 // RunAutoplanCommand runs plan when a pull request is opened or updated.
@@ -82,16 +109,26 @@ func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo
 		BaseRepo: baseRepo,
 	}
 	defer c.logPanics(ctx)
-	if !c.validateCtxAndComment(ctx) {
+	if failure := c.validateCtxAndComment(ctx); failure != "" {
 		return
 	}
+
+	// Starting the command will cancel any autoplan that's still running for
+	// an older commit and wait for it to exit.
+	running := c.CommandCanceller.Start(baseRepo, pull.Num, true)
+	defer running.Finish()
+
 	if err := c.CommitStatusUpdater.Update(ctx.BaseRepo, ctx.Pull, models.PendingCommitStatus, PlanCommand); err != nil {
 		ctx.Log.Warn("unable to update commit status: %s", err)
 	}
 
 	projectCmds, err := c.ProjectCommandBuilder.BuildAutoplanCommands(ctx)
+	if running.Superseded() {
+		log.Info("autoplan was superseded by a newer autoplan")
+		return
+	}
 	if err != nil {
-		c.updatePull(ctx, AutoplanCommand{}, CommandResult{Error: err})
+		c.updatePull(ctx, AutoplanCommand{}, c.Redactor.RedactResult(CommandResult{Error: err}))
 		return
 	}
 	if len(projectCmds) == 0 {
@@ -102,8 +139,14 @@ func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo
 		return
 	}
 
-	results := c.runProjectCmds(projectCmds, PlanCommand)
-	c.updatePull(ctx, AutoplanCommand{}, CommandResult{ProjectResults: results})
+	results := c.runProjectCmds(running, projectCmds, PlanCommand)
+	// If a newer commit was pushed while we were planning, the newer autoplan
+	// will comment so we don't comment with our cancelled results.
+	if running.Superseded() {
+		log.Info("autoplan was superseded by a newer autoplan")
+		return
+	}
+	c.updatePull(ctx, AutoplanCommand{}, c.Redactor.RedactResult(CommandResult{ProjectResults: results}))
 }
 
 // RunCommentCommand executes the command.
@@ -112,6 +155,55 @@ func (c *DefaultCommandRunner) RunAutoplanCommand(baseRepo models.Repo, headRepo
 // the event is further validated before making an additional (potentially
 // wasteful) call to get the necessary data.
 func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, cmd *CommentCommand) {
+	c.runCommentCommand(baseRepo, maybeHeadRepo, maybePull, user, pullNum, cmd)
+}
+
+// RunAPICommand runs cmd on pull request pullNum and returns the result. The
+// result is also commented on the pull request. Bitbucket pull requests
+// aren't supported since there's no API call to get them.
+func (c *DefaultCommandRunner) RunAPICommand(baseRepo models.Repo, user models.User, pullNum int, cmd *CommentCommand) CommandResult {
+	if baseRepo.VCSHost.Type == models.BitbucketCloud || baseRepo.VCSHost.Type == models.BitbucketServer {
+		return CommandResult{Error: errors.New("running commands on Bitbucket pull requests via the API is not supported")}
+	}
+	return c.runCommentCommand(baseRepo, nil, nil, user, pullNum, cmd)
+}
+
+// RunAPIRefPlan runs plan on the projects in ref that match cmd's dir,
+// workspace and project and returns the result. Since there's no pull request
+// nothing is commented. The plan is registered with the CommandCanceller
+// under DriftDetectionPullNum so it's interrupted if Atlantis restarts.
+func (c *DefaultCommandRunner) RunAPIRefPlan(baseRepo models.Repo, user models.User, ref string, cmd *CommentCommand) CommandResult {
+	running := c.CommandCanceller.Start(baseRepo, DriftDetectionPullNum, false)
+	defer running.Finish()
+	results, err := c.DriftDetector.PlanBranch(running.Context(), baseRepo, ref, user, func(p valid.Project) bool {
+		return (cmd.RepoRelDir == "" || cmd.RepoRelDir == p.Dir) &&
+			(cmd.Workspace == "" || cmd.Workspace == p.Workspace) &&
+			(cmd.ProjectName == "" || cmd.ProjectName == p.GetName())
+	})
+	var projectResults []ProjectResult
+	for _, res := range results {
+		projectResult := ProjectResult{
+			RepoRelDir:  res.RepoRelDir,
+			Workspace:   res.Workspace,
+			ProjectName: res.ProjectName,
+			Failure:     res.Failure,
+		}
+		switch {
+		case res.Error != nil:
+			// Errors include the output like they do for pull requests.
+			projectResult.Error = res.Error
+			if res.Output != "" {
+				projectResult.Error = fmt.Errorf("%s\n%s", res.Error, res.Output)
+			}
+		case res.Failure == "":
+			projectResult.PlanSuccess = &PlanSuccess{TerraformOutput: res.Output}
+		}
+		projectResults = append(projectResults, projectResult)
+	}
+	return CommandResult{ProjectResults: projectResults, Error: err}
+}
+
+func (c *DefaultCommandRunner) runCommentCommand(baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, cmd *CommentCommand) (result CommandResult) {
 	log := c.buildLogger(baseRepo.FullName, pullNum)
 	var headRepo models.Repo
 	if maybeHeadRepo != nil {
@@ -128,14 +220,15 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 	case models.BitbucketCloud, models.BitbucketServer:
 		if maybePull == nil {
 			err = errors.New("pull request should not be nil–this is a bug")
+		} else {
+			pull = *maybePull
 		}
-		pull = *maybePull
 	default:
 		err = errors.New("Unknown VCS type–this is a bug")
 	}
 	if err != nil {
 		log.Err(err.Error())
-		return
+		return CommandResult{Error: err}
 	}
 	ctx := &CommandContext{
 		User:     user,
@@ -145,9 +238,17 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 		BaseRepo: baseRepo,
 	}
 	defer c.logPanics(ctx)
-	if !c.validateCtxAndComment(ctx) {
-		return
+	if failure := c.validateCtxAndComment(ctx); failure != "" {
+		return CommandResult{Failure: failure}
+	}
+
+	if cmd.Name == CancelCommand {
+		c.cancelCommands(ctx)
+		return CommandResult{}
 	}
+	running := c.CommandCanceller.Start(baseRepo, pull.Num, false)
+	defer running.Finish()
+
 	if err = c.CommitStatusUpdater.Update(ctx.BaseRepo, ctx.Pull, models.PendingCommitStatus, cmd.CommandName()); err != nil {
 		ctx.Log.Warn("unable to update commit status: %s", err)
 	}
@@ -160,23 +261,91 @@ func (c *DefaultCommandRunner) RunCommentCommand(baseRepo models.Repo, maybeHead
 		projectCmds, err = c.ProjectCommandBuilder.BuildApplyCommands(ctx, cmd)
 	default:
 		ctx.Log.Err("failed to determine desired command, neither plan nor apply")
-		return
+		return CommandResult{Error: errors.New("failed to determine desired command, neither plan nor apply")}
 	}
 	if err != nil {
-		c.updatePull(ctx, cmd, CommandResult{Error: err})
-		return
+		result = c.Redactor.RedactResult(CommandResult{Error: err})
+		c.updatePull(ctx, cmd, result)
+		return result
+	}
+	results := c.runProjectCmds(running, projectCmds, cmd.Name)
+	// The result is redacted before it's returned so it's also redacted when
+	// it's returned by the API.
+	result = c.Redactor.RedactResult(CommandResult{ProjectResults: results})
+	c.updatePull(ctx, cmd, result)
+	return result
+}
+
+// cancelCommands cancels all the commands running on the pull request and
+// comments back with how many were cancelled. If ctx.User isn't authorized to
+// run one of the commands, none of them are cancelled.
+func (c *DefaultCommandRunner) cancelCommands(ctx *CommandContext) {
+	running := c.CommandCanceller.Running(ctx.BaseRepo, ctx.Pull.Num)
+	for _, r := range running {
+		failure, err := c.authorizeCancel(ctx, r)
+		if err != nil {
+			ctx.Log.Err("checking authorization: %s", err)
+			failure = fmt.Sprintf("Unable to check if you're authorized to cancel: %s", err)
+		}
+		if failure != "" {
+			if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, "Not cancelling any commands. "+failure); err != nil {
+				ctx.Log.Err("unable to comment: %s", err)
+			}
+			return
+		}
+	}
+
+	cancelled := 0
+	for _, r := range running {
+		if r.Cancel() {
+			cancelled++
+		}
+	}
+	ctx.Log.Info("cancelled %d running commands", cancelled)
+	comment := "There are no running commands to cancel."
+	if cancelled > 0 {
+		comment = fmt.Sprintf("Cancelled %d running command(s). Terraform has been interrupted so it can release any state locks before exiting.", cancelled)
+	}
+	if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, comment); err != nil {
+		ctx.Log.Err("unable to comment: %s", err)
 	}
-	results := c.runProjectCmds(projectCmds, cmd.Name)
-	c.updatePull(
-		ctx,
-		cmd,
-		CommandResult{
-			ProjectResults: results})
 }
 
-func (c *DefaultCommandRunner) runProjectCmds(cmds []models.ProjectCommandContext, cmdName CommandName) []ProjectResult {
+// authorizeCancel returns a failure if ctx.User isn't authorized to run the
+// command that r is running on any of its projects.
+func (c *DefaultCommandRunner) authorizeCancel(ctx *CommandContext, r *RunningCommand) (string, error) {
+	if c.CommandAuthorizer == nil {
+		return "", nil
+	}
+	cmdName, projects := r.Projects()
+	for _, p := range projects {
+		p.User = ctx.User
+		failure, err := c.CommandAuthorizer.Authorize(p, cmdName)
+		if failure != "" || err != nil {
+			if failure != "" {
+				ctx.Log.Info("%s isn't authorized to cancel %s", ctx.User.Username, cmdName.String())
+			}
+			return failure, err
+		}
+	}
+	return "", nil
+}
+
+func (c *DefaultCommandRunner) runProjectCmds(running *RunningCommand, cmds []models.ProjectCommandContext, cmdName CommandName) []ProjectResult {
+	running.SetProjects(cmdName, cmds)
+	cmdCtx := running.Context()
 	var results []ProjectResult
 	for _, pCmd := range cmds {
+		// If the command was cancelled, don't start running any more projects.
+		if cmdCtx.Err() != nil {
+			results = append(results, ProjectResult{
+				RepoRelDir: pCmd.RepoRelDir,
+				Workspace:  pCmd.Workspace,
+				Error:      errors.New("command was cancelled before this project was run"),
+			})
+			continue
+		}
+		pCmd.Context = cmdCtx
 		var res ProjectResult
 		switch cmdName {
 		case PlanCommand:
@@ -221,23 +390,27 @@ func (c *DefaultCommandRunner) buildLogger(repoFullName string, pullNum int) *lo
 	return logging.NewSimpleLogger(src, c.Logger.Underlying(), true, c.Logger.GetLevel())
 }
 
-func (c *DefaultCommandRunner) validateCtxAndComment(ctx *CommandContext) bool {
+// validateCtxAndComment comments on the pull request if commands can't be run
+// on it and returns why. It returns an empty string if commands can be run.
+func (c *DefaultCommandRunner) validateCtxAndComment(ctx *CommandContext) string {
 	if !c.AllowForkPRs && ctx.HeadRepo.Owner != ctx.BaseRepo.Owner {
 		ctx.Log.Info("command was run on a fork pull request which is disallowed")
-		if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, fmt.Sprintf("Atlantis commands can't be run on fork pull requests. To enable, set --%s", c.AllowForkPRsFlag)); err != nil {
+		failure := fmt.Sprintf("Atlantis commands can't be run on fork pull requests. To enable, set --%s", c.AllowForkPRsFlag)
+		if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, failure); err != nil {
 			ctx.Log.Err("unable to comment: %s", err)
 		}
-		return false
+		return failure
 	}
 
 	if ctx.Pull.State != models.OpenPullState {
 		ctx.Log.Info("command was run on closed pull request")
-		if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, "Atlantis commands can't be run on closed pull requests"); err != nil {
+		failure := "Atlantis commands can't be run on closed pull requests"
+		if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, failure); err != nil {
 			ctx.Log.Err("unable to comment: %s", err)
 		}
-		return false
+		return failure
 	}
-	return true
+	return ""
 }
 
 func (c *DefaultCommandRunner) updatePull(ctx *CommandContext, command PullCommand, res CommandResult) {
@@ -252,7 +425,9 @@ func (c *DefaultCommandRunner) updatePull(ctx *CommandContext, command PullComma
 	if err := c.CommitStatusUpdater.UpdateProjectResult(ctx, command.CommandName(), res); err != nil {
 		ctx.Log.Warn("unable to update commit status: %s", err)
 	}
-	comment := c.MarkdownRenderer.Render(res, command.CommandName(), ctx.Log.History.String(), command.IsVerbose(), ctx.BaseRepo.VCSHost.Type)
+	log := ctx.Log.History.String()
+	log = c.Redactor.Redact(log)
+	comment := c.MarkdownRenderer.Render(res, command.CommandName(), log, command.IsVerbose(), ctx.BaseRepo.VCSHost.Type)
 	if err := c.VCSClient.CreateComment(ctx.BaseRepo, ctx.Pull.Num, comment); err != nil {
 		ctx.Log.Err("unable to comment: %s", err)
 	}
@@ -21,6 +21,9 @@ const (
 	ApplyCommand CommandName = iota
 	// PlanCommand is a command to run terraform plan.
 	PlanCommand
+	// CancelCommand is a command to cancel the commands running on a pull
+	// request.
+	CancelCommand
 	// Adding more? Don't forget to update String() below
 )
 
@@ -31,6 +34,8 @@ func (c CommandName) String() string {
 		return "apply"
 	case PlanCommand:
 		return "plan"
+	case CancelCommand:
+		return "cancel"
 	}
 	return ""
 }
@@ -141,8 +141,8 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 		return CommentParseResult{CommentResponse: HelpComment}
 	}
 
-	// Need to have a plan or apply at this point.
-	if !e.stringInSlice(command, []string{PlanCommand.String(), ApplyCommand.String()}) {
+	// Need to have a plan, apply or cancel at this point.
+	if !e.stringInSlice(command, []string{PlanCommand.String(), ApplyCommand.String(), CancelCommand.String()}) {
 		return CommentParseResult{CommentResponse: fmt.Sprintf("```\nError: unknown command %q.\nRun 'atlantis --help' for usage.\n```", command)}
 	}
 
@@ -172,6 +172,10 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 		flagSet.StringVarP(&dir, dirFlagLong, dirFlagShort, "", "Apply the plan for this directory, relative to root of repo, ex. 'child/dir'.")
 		flagSet.StringVarP(&project, projectFlagLong, projectFlagShort, "", fmt.Sprintf("Apply the plan for this project. Refers to the name of the project configured in %s. Cannot be used at same time as workspace or dir flags.", yaml.AtlantisYAMLFilename))
 		flagSet.BoolVarP(&verbose, verboseFlagLong, verboseFlagShort, false, "Append Atlantis log to comment.")
+	case CancelCommand.String():
+		name = CancelCommand
+		flagSet = pflag.NewFlagSet(CancelCommand.String(), pflag.ContinueOnError)
+		flagSet.SetOutput(ioutil.Discard)
 	default:
 		return CommentParseResult{CommentResponse: fmt.Sprintf("Error: unknown command %q – this is a bug", command)}
 	}
@@ -196,6 +200,15 @@ func (e *CommentParser) Parse(comment string, vcsHost models.VCSHostType) Commen
 		return CommentParseResult{CommentResponse: e.errMarkdown(fmt.Sprintf("unknown argument(s) – %s", strings.Join(unusedArgs, " ")), command, flagSet)}
 	}
 
+	if name == CancelCommand {
+		if flagSet.ArgsLenAtDash() != -1 {
+			return CommentParseResult{CommentResponse: e.errMarkdown("cancel does not accept extra arguments", command, flagSet)}
+		}
+		return CommentParseResult{
+			Command: NewCommentCommand("", nil, name, false, "", ""),
+		}
+	}
+
 	if flagSet.ArgsLenAtDash() != -1 {
 		extraArgsUnsafe := flagSet.Args()[flagSet.ArgsLenAtDash():]
 		// Quote all extra args so there isn't a security issue when we append
@@ -331,6 +344,7 @@ Commands:
          To plan a specific project, use the -d, -w and -p flags.
   apply  Runs 'terraform apply' on all unapplied plans from this pull request.
          To only apply a specific plan, use the -d, -w and -p flags.
+  cancel Cancels all plans and applies running on this pull request.
   help   View help.
 
 Flags:
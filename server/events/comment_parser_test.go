@@ -170,6 +170,8 @@ func TestParse_SubcommandUsage(t *testing.T) {
 		"atlantis plan --help",
 		"atlantis apply -h",
 		"atlantis apply --help",
+		"atlantis cancel -h",
+		"atlantis cancel --help",
 	}
 	for _, c := range comments {
 		r := commentParser.Parse(c, models.Github)
@@ -204,6 +206,14 @@ func TestParse_InvalidFlags(t *testing.T) {
 			"atlantis apply --abc",
 			"Error: unknown flag: --abc",
 		},
+		{
+			"atlantis cancel -d .",
+			"Error: unknown shorthand flag: 'd' in -d",
+		},
+		{
+			"atlantis cancel -- -target=resource",
+			"Error: cancel does not accept extra arguments",
+		},
 	}
 	for _, c := range cases {
 		r := commentParser.Parse(c.comment, models.Github)
@@ -214,6 +224,14 @@ func TestParse_InvalidFlags(t *testing.T) {
 	}
 }
 
+func TestParse_Cancel(t *testing.T) {
+	r := commentParser.Parse("atlantis cancel", models.Github)
+	Equals(t, "", r.CommentResponse)
+	Equals(t, &events.CommentCommand{
+		Name: events.CancelCommand,
+	}, r.Command)
+}
+
 func TestParse_RelativeDirPath(t *testing.T) {
 	t.Log("if -d is used with a relative path, should return an error")
 	comments := []string{
@@ -33,15 +33,30 @@ type CommitStatusUpdater interface {
 	UpdateProjectResult(ctx *CommandContext, commandName CommandName, res CommandResult) error
 }
 
+// PullOutputURLGenerator generates URLs to view the live output of the
+// commands running on a pull request.
+type PullOutputURLGenerator interface {
+	// GeneratePullOutputURL returns the full URL to the output of the
+	// commands running on pull request pullNum of repo.
+	GeneratePullOutputURL(repo models.Repo, pullNum int) string
+}
+
 // DefaultCommitStatusUpdater implements CommitStatusUpdater.
 type DefaultCommitStatusUpdater struct {
 	Client vcs.ClientProxy
+	// OutputURLGenerator is optional. If set, pending statuses will link to
+	// the live output of the command that's running.
+	OutputURLGenerator PullOutputURLGenerator
 }
 
 // Update updates the commit status.
 func (d *DefaultCommitStatusUpdater) Update(repo models.Repo, pull models.PullRequest, status models.CommitStatus, command CommandName) error {
 	description := fmt.Sprintf("%s %s", strings.Title(command.String()), strings.Title(status.String()))
-	return d.Client.UpdateStatus(repo, pull, status, description)
+	var url string
+	if status == models.PendingCommitStatus && d.OutputURLGenerator != nil {
+		url = d.OutputURLGenerator.GeneratePullOutputURL(repo, pull.Num)
+	}
+	return d.Client.UpdateStatus(repo, pull, status, description, url)
 }
 
 // UpdateProjectResult updates the commit status based on the status of res.
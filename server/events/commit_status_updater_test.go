@@ -15,6 +15,7 @@ package events_test
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -35,7 +36,31 @@ func TestUpdate(t *testing.T) {
 	s := events.DefaultCommitStatusUpdater{Client: client}
 	err := s.Update(repoModel, pullModel, status, events.PlanCommand)
 	Ok(t, err)
-	client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, status, "Plan Success")
+	client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, status, "Plan Success", "")
+}
+
+func TestUpdate_PendingOutputURL(t *testing.T) {
+	t.Log("pending statuses should link to the pull's live output")
+	RegisterMockTestingT(t)
+	client := mocks.NewMockClientProxy()
+	s := events.DefaultCommitStatusUpdater{Client: client, OutputURLGenerator: mockOutputURLGenerator{}}
+	pull := models.PullRequest{Num: 2}
+	repo := models.Repo{FullName: "owner/repo"}
+
+	err := s.Update(repo, pull, models.PendingCommitStatus, events.PlanCommand)
+	Ok(t, err)
+	client.VerifyWasCalledOnce().UpdateStatus(repo, pull, models.PendingCommitStatus, "Plan Pending", "https://atlantis/output/owner/repo/2")
+
+	// Other statuses shouldn't link to the output.
+	err = s.Update(repo, pull, models.SuccessCommitStatus, events.PlanCommand)
+	Ok(t, err)
+	client.VerifyWasCalledOnce().UpdateStatus(repo, pull, models.SuccessCommitStatus, "Plan Success", "")
+}
+
+type mockOutputURLGenerator struct{}
+
+func (m mockOutputURLGenerator) GeneratePullOutputURL(repo models.Repo, pullNum int) string {
+	return fmt.Sprintf("https://atlantis/output/%s/%d", repo.FullName, pullNum)
 }
 
 func TestUpdateProjectResult_Error(t *testing.T) {
@@ -48,7 +73,7 @@ func TestUpdateProjectResult_Error(t *testing.T) {
 	s := events.DefaultCommitStatusUpdater{Client: client}
 	err := s.UpdateProjectResult(ctx, events.PlanCommand, events.CommandResult{Error: errors.New("err")})
 	Ok(t, err)
-	client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, models.FailedCommitStatus, "Plan Failed")
+	client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, models.FailedCommitStatus, "Plan Failed", "")
 }
 
 func TestUpdateProjectResult_Failure(t *testing.T) {
@@ -61,7 +86,7 @@ func TestUpdateProjectResult_Failure(t *testing.T) {
 	s := events.DefaultCommitStatusUpdater{Client: client}
 	err := s.UpdateProjectResult(ctx, events.PlanCommand, events.CommandResult{Failure: "failure"})
 	Ok(t, err)
-	client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, models.FailedCommitStatus, "Plan Failed")
+	client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, models.FailedCommitStatus, "Plan Failed", "")
 }
 
 func TestUpdateProjectResult(t *testing.T) {
@@ -131,7 +156,7 @@ func TestUpdateProjectResult(t *testing.T) {
 			s := events.DefaultCommitStatusUpdater{Client: client}
 			err := s.UpdateProjectResult(ctx, events.PlanCommand, resp)
 			Ok(t, err)
-			client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, c.Expected, "Plan "+strings.Title(c.Expected.String()))
+			client.VerifyWasCalledOnce().UpdateStatus(repoModel, pullModel, c.Expected, "Plan "+strings.Title(c.Expected.String()), "")
 		})
 	}
 }
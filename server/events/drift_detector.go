@@ -0,0 +1,377 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/webhooks"
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// DriftDetectionPullNum is the pull request number used for the working dirs
+// of drift detection runs. Real pull requests start at 1 so it can't clash.
+// Each branch has its own working dirs under it, see branchWorkspace.
+const DriftDetectionPullNum = 0
+
+// DriftDetectionUser is the user that drift detection plans are run as.
+const DriftDetectionUser = "atlantis"
+
+// noChangesOutputs are the strings Terraform outputs when a plan has no
+// changes.
+var noChangesOutputs = []string{
+	"No changes. Infrastructure is up-to-date.",
+	"No changes. Your infrastructure matches the configuration.",
+}
+
+// DriftSchedule configures a repo to be periodically checked for drift.
+type DriftSchedule struct {
+	Repo models.Repo
+	// Branch is the branch that's checked, usually the default branch.
+	Branch string
+	// Interval is how often the branch is checked.
+	Interval time.Duration
+}
+
+// DriftRepoResult is the result of checking a repo's branch for drift.
+type DriftRepoResult struct {
+	RepoFullName string
+	Branch       string
+	Time         time.Time
+	// Error is set if the repo couldn't be checked, ex. because cloning failed.
+	Error    error
+	Projects []DriftProjectResult
+}
+
+// DriftProjectResult is the result of checking a project for drift.
+type DriftProjectResult struct {
+	RepoRelDir  string
+	Workspace   string
+	ProjectName string
+	// Drifted is true if the plan showed changes.
+	Drifted bool
+	// Error is set if the plan failed.
+	Error error
+	// Failure is set if the user isn't authorized to plan the project.
+	Failure string
+	// Output is the output of the plan.
+	Output string
+}
+
+// DriftDetector periodically runs plan on the branches of repos to detect
+// drift: changes made outside of Atlantis. It doesn't take any locks on the
+// projects it plans since it never applies.
+type DriftDetector struct {
+	WorkingDir       WorkingDir
+	WorkingDirLocker WorkingDirLocker
+	ParserValidator  *yaml.ParserValidator
+	InitStepRunner   StepRunner
+	PlanStepRunner   StepRunner
+	RunStepRunner    StepRunner
+	EnvStepRunner    EnvStepRunner
+	Webhooks         webhooks.DriftSender
+	Logger           *logging.SimpleLogger
+	// Redactor, if set, removes secrets from the plans' output.
+	Redactor *Redactor
+	// CommandAuthorizer is optional. If set, PlanBranch only plans the
+	// projects the user is authorized to plan. Drift detection isn't
+	// authorized since it's configured by the server.
+	CommandAuthorizer CommandAuthorizer
+
+	mutex sync.Mutex
+	// results maps from repo and branch to the latest result for that branch.
+	results map[string]DriftRepoResult
+}
+
+// Start checks each schedule's repo for drift at the schedule's interval until
+// ctx is cancelled. Each repo is checked as soon as Start is called. Start
+// doesn't block.
+func (d *DriftDetector) Start(ctx context.Context, schedules []DriftSchedule) {
+	for _, s := range schedules {
+		go func(s DriftSchedule) {
+			ticker := time.NewTicker(s.Interval)
+			defer ticker.Stop()
+			for {
+				d.DetectDrift(ctx, s.Repo, s.Branch)
+				select {
+				case <-ticker.C:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(s)
+	}
+}
+
+// DetectDrift runs plan for every project in branch's atlantis.yaml file,
+// sends webhooks for projects that have drifted and stores the results.
+func (d *DriftDetector) DetectDrift(ctx context.Context, repo models.Repo, branch string) DriftRepoResult {
+	log := logging.NewSimpleLogger(fmt.Sprintf("%s@%s", repo.FullName, branch), d.Logger.Underlying(), false, d.Logger.GetLevel())
+	log.Info("checking for drift")
+	result := DriftRepoResult{
+		RepoFullName: repo.FullName,
+		Branch:       branch,
+		Time:         time.Now(),
+	}
+	result.Projects, result.Error = d.detectDrift(ctx, log, repo, branch)
+	if result.Error != nil {
+		log.Err("checking for drift: %s", result.Error)
+	}
+
+	d.mutex.Lock()
+	if d.results == nil {
+		d.results = make(map[string]DriftRepoResult)
+	}
+	d.results[fmt.Sprintf("%s@%s", repo.FullName, branch)] = result
+	d.mutex.Unlock()
+	return result
+}
+
+// Results returns the latest result for each repo and branch that's been
+// checked, sorted by repo and branch.
+func (d *DriftDetector) Results() []DriftRepoResult {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	var results []DriftRepoResult
+	for _, r := range d.results {
+		results = append(results, r)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].RepoFullName != results[j].RepoFullName {
+			return results[i].RepoFullName < results[j].RepoFullName
+		}
+		return results[i].Branch < results[j].Branch
+	})
+	return results
+}
+
+// PlanBranch runs plan for the projects in branch's atlantis.yaml file that
+// match filter, or every project if filter is nil. Unlike DetectDrift it
+// doesn't send webhooks or store the results, and projects user isn't
+// authorized to plan get a Failure instead of being planned. It shares its
+// working dirs with drift detection so it fails if the same branch is being
+// checked for drift.
+func (d *DriftDetector) PlanBranch(ctx context.Context, repo models.Repo, branch string, user models.User, filter func(valid.Project) bool) ([]DriftProjectResult, error) {
+	log := logging.NewSimpleLogger(fmt.Sprintf("%s@%s", repo.FullName, branch), d.Logger.Underlying(), false, d.Logger.GetLevel())
+	log.Info("running plan for %s", user.Username)
+	return d.planBranch(ctx, log, repo, branch, user, filter, true)
+}
+
+func (d *DriftDetector) detectDrift(ctx context.Context, log *logging.SimpleLogger, repo models.Repo, branch string) ([]DriftProjectResult, error) {
+	results, err := d.planBranch(ctx, log, repo, branch, models.User{Username: DriftDetectionUser}, nil, false)
+	for _, res := range results {
+		if res.Drifted || res.Error != nil {
+			d.Webhooks.SendDrift(log, webhooks.DriftResult{ // nolint: errcheck
+				Workspace:   res.Workspace,
+				Repo:        repo,
+				Branch:      branch,
+				RepoRelDir:  res.RepoRelDir,
+				ProjectName: res.ProjectName,
+				Drifted:     res.Drifted,
+				Success:     res.Error == nil,
+			})
+		}
+	}
+	if err == errNoConfig {
+		return nil, fmt.Errorf("no %s file found, drift detection requires projects to be configured", yaml.AtlantisYAMLFilename)
+	}
+	if err != nil && err == ctx.Err() {
+		return results, errors.Wrap(err, "drift detection was stopped")
+	}
+	return results, err
+}
+
+// errNoConfig is returned by planBranch if the branch has no atlantis.yaml.
+var errNoConfig = fmt.Errorf("no %s file found, planning a branch requires projects to be configured", yaml.AtlantisYAMLFilename)
+
+// planBranch plans the projects in branch that match filter. If authorize is
+// true, projects that user isn't authorized to plan aren't planned.
+func (d *DriftDetector) planBranch(ctx context.Context, log *logging.SimpleLogger, repo models.Repo, branch string, user models.User, filter func(valid.Project) bool, authorize bool) ([]DriftProjectResult, error) {
+	pull := d.pull(repo, branch)
+
+	// We clone into the default workspace first to read the config.
+	repoDir, unlockFn, err := d.clone(log, repo, pull, DefaultWorkspace)
+	if err != nil {
+		return nil, err
+	}
+	config, err := d.ParserValidator.ReadConfig(repoDir)
+	unlockFn()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errNoConfig
+		}
+		return nil, err
+	}
+
+	// Workspaces other than default need their own clones so we only clone
+	// them once per run.
+	cloned := map[string]bool{DefaultWorkspace: true}
+	var results []DriftProjectResult
+	for _, project := range config.Projects {
+		if filter != nil && !filter(project) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+		// Copy project since the context refers to it.
+		project := project
+		projCtx := models.ProjectCommandContext{
+			BaseRepo:      repo,
+			HeadRepo:      repo,
+			Pull:          pull,
+			User:          user,
+			Log:           log,
+			RepoRelDir:    project.Dir,
+			ProjectConfig: &project,
+			GlobalConfig:  &config,
+			Workspace:     project.Workspace,
+			Context:       ctx,
+		}
+		if authorize {
+			if failure, err := d.stepRunner().authorize(projCtx, PlanCommand); failure != "" || err != nil {
+				results = append(results, DriftProjectResult{
+					RepoRelDir:  project.Dir,
+					Workspace:   project.Workspace,
+					ProjectName: project.GetName(),
+					Failure:     failure,
+					Error:       err,
+				})
+				continue
+			}
+		}
+		res := d.planProject(projCtx, !cloned[project.Workspace])
+		cloned[project.Workspace] = true
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func (d *DriftDetector) planProject(projCtx models.ProjectCommandContext, clone bool) DriftProjectResult {
+	project := *projCtx.ProjectConfig
+	result := DriftProjectResult{
+		RepoRelDir:  project.Dir,
+		Workspace:   project.Workspace,
+		ProjectName: project.GetName(),
+	}
+
+	var repoDir string
+	var unlockFn func()
+	var err error
+	if clone {
+		repoDir, unlockFn, err = d.clone(projCtx.Log, projCtx.BaseRepo, projCtx.Pull, project.Workspace)
+	} else {
+		repoDir, unlockFn, err = d.lockWorkingDir(projCtx.BaseRepo, projCtx.Pull, project.Workspace)
+	}
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	defer unlockFn()
+
+	stepRunner := d.stepRunner()
+	stage := stepRunner.defaultPlanStage()
+	if project.Workflow != nil {
+		if configuredStage := projCtx.GlobalConfig.GetPlanStage(*project.Workflow); configuredStage != nil {
+			stage = *configuredStage
+		}
+	}
+	outputs, err := stepRunner.runSteps(stage.Steps, projCtx, filepath.Join(repoDir, project.Dir), PlanCommand)
+	result.Output = d.Redactor.Redact(strings.Join(outputs, "\n"))
+	if err != nil {
+		result.Error = d.Redactor.redactErr(err)
+		return result
+	}
+	result.Drifted = planHasChanges(result.Output)
+	return result
+}
+
+// stepRunner returns the runner that runs the plan steps. It doesn't lock
+// the projects.
+func (d *DriftDetector) stepRunner() *DefaultProjectCommandRunner {
+	return &DefaultProjectCommandRunner{
+		InitStepRunner:    d.InitStepRunner,
+		PlanStepRunner:    d.PlanStepRunner,
+		RunStepRunner:     d.RunStepRunner,
+		EnvStepRunner:     d.EnvStepRunner,
+		CommandAuthorizer: d.CommandAuthorizer,
+	}
+}
+
+// clone deletes any existing clone of the branch for workspace and clones it
+// again so we're planning the latest commit. The returned function must be
+// called to unlock the working dir.
+func (d *DriftDetector) clone(log *logging.SimpleLogger, repo models.Repo, pull models.PullRequest, workspace string) (string, func(), error) {
+	workspace = branchWorkspace(pull.Branch, workspace)
+	unlockFn, err := d.WorkingDirLocker.TryLock(repo, pull.Num, workspace)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := d.WorkingDir.DeleteForWorkspace(repo, pull, workspace); err != nil {
+		unlockFn()
+		return "", nil, errors.Wrap(err, "deleting previous clone")
+	}
+	repoDir, err := d.WorkingDir.Clone(log, repo, repo, pull, workspace)
+	if err != nil {
+		unlockFn()
+		return "", nil, err
+	}
+	return repoDir, unlockFn, nil
+}
+
+// lockWorkingDir locks the existing clone for workspace and returns its path.
+// The returned function must be called to unlock it.
+func (d *DriftDetector) lockWorkingDir(repo models.Repo, pull models.PullRequest, workspace string) (string, func(), error) {
+	workspace = branchWorkspace(pull.Branch, workspace)
+	unlockFn, err := d.WorkingDirLocker.TryLock(repo, pull.Num, workspace)
+	if err != nil {
+		return "", nil, err
+	}
+	repoDir, err := d.WorkingDir.GetWorkingDir(repo, pull, workspace)
+	if err != nil {
+		unlockFn()
+		return "", nil, err
+	}
+	return repoDir, unlockFn, nil
+}
+
+// pull returns the pull request that's used to clone branch. It's not a real
+// pull request.
+func (d *DriftDetector) pull(repo models.Repo, branch string) models.PullRequest {
+	return models.PullRequest{
+		Num:      DriftDetectionPullNum,
+		Branch:   branch,
+		BaseRepo: repo,
+		Author:   DriftDetectionUser,
+		State:    models.OpenPullState,
+	}
+}
+
+// branchWorkspace returns the name of the working dir used for workspace when
+// planning branch. All branches use the DriftDetectionPullNum working dirs so
+// they need their own dir under it, otherwise planning one branch would
+// delete the clone of another. The branch is escaped since it can contain
+// slashes.
+func branchWorkspace(branch string, workspace string) string {
+	return filepath.Join(url.PathEscape(branch), workspace)
+}
+
+// planHasChanges returns true if the output of terraform plan shows changes.
+func planHasChanges(output string) bool {
+	for _, noChanges := range noChangesOutputs {
+		if strings.Contains(output, noChanges) {
+			return false
+		}
+	}
+	return true
+}
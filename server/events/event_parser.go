@@ -319,6 +319,7 @@ func (e *EventParser) parseCommonBitbucketCloudEventData(event bitbucketcloud.Co
 		HeadCommit: *event.PullRequest.Source.Commit.Hash,
 		URL:        *event.PullRequest.Links.HTML.HREF,
 		Branch:     *event.PullRequest.Source.Branch.Name,
+		Title:      bitbucketTitle(event.PullRequest.Title),
 		Author:     *event.Actor.Username,
 		State:      prState,
 		BaseRepo:   baseRepo,
@@ -450,6 +451,7 @@ func (e *EventParser) ParseGithubPull(pull *github.PullRequest) (pullModel model
 	pullModel = models.PullRequest{
 		Author:     authorUsername,
 		Branch:     branch,
+		Title:      pull.GetTitle(),
 		HeadCommit: commit,
 		URL:        url,
 		Num:        num,
@@ -492,6 +494,7 @@ func (e *EventParser) ParseGitlabMergeRequestEvent(event gitlab.MergeEvent) (pul
 		Num:        event.ObjectAttributes.IID,
 		HeadCommit: event.ObjectAttributes.LastCommit.ID,
 		Branch:     event.ObjectAttributes.SourceBranch,
+		Title:      event.ObjectAttributes.Title,
 		State:      modelState,
 		BaseRepo:   baseRepo,
 	}
@@ -554,6 +557,7 @@ func (e *EventParser) ParseGitlabMergeRequest(mr *gitlab.MergeRequest, baseRepo
 		Num:        mr.IID,
 		HeadCommit: mr.SHA,
 		Branch:     mr.SourceBranch,
+		Title:      mr.Title,
 		State:      pullState,
 		BaseRepo:   baseRepo,
 	}
@@ -634,6 +638,7 @@ func (e *EventParser) parseCommonBitbucketServerEventData(event bitbucketserver.
 		HeadCommit: *event.PullRequest.FromRef.LatestCommit,
 		URL:        fmt.Sprintf("%s/projects/%s/repos/%s/pull-requests/%d", e.BitbucketServerURL, *event.PullRequest.ToRef.Repository.Project.Key, *event.PullRequest.ToRef.Repository.Slug, *event.PullRequest.ID),
 		Branch:     *event.PullRequest.FromRef.DisplayID,
+		Title:      bitbucketTitle(event.PullRequest.Title),
 		Author:     *event.Actor.Username,
 		State:      prState,
 		BaseRepo:   baseRepo,
@@ -660,3 +665,12 @@ func (e *EventParser) ParseBitbucketServerPullEvent(body []byte) (pull models.Pu
 	pull, baseRepo, headRepo, user, err = e.parseCommonBitbucketServerEventData(event.CommonEventData)
 	return
 }
+
+// bitbucketTitle returns the title of a Bitbucket pull request or an empty
+// string if it wasn't set.
+func bitbucketTitle(title *string) string {
+	if title == nil {
+		return ""
+	}
+	return *title
+}
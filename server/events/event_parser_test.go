@@ -295,6 +295,7 @@ func TestParseGitlabMergeEvent(t *testing.T) {
 		Num:        1,
 		HeadCommit: "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
 		Branch:     "ms-viewport",
+		Title:      "MS-Viewport",
 		State:      models.OpenPullState,
 		BaseRepo:   expBaseRepo,
 	}, pull)
@@ -351,6 +352,7 @@ func TestParseGitlabMergeEvent_Subgroup(t *testing.T) {
 		Num:        2,
 		HeadCommit: "901d9770ef1a6862e2a73ec1bacc73590abb9aff",
 		Branch:     "patch",
+		Title:      "Update main.tf",
 		State:      models.OpenPullState,
 		BaseRepo:   expBaseRepo,
 	}, pull)
@@ -446,6 +448,7 @@ func TestParseGitlabMergeRequest(t *testing.T) {
 		Num:        8,
 		HeadCommit: "0b4ac85ea3063ad5f2974d10cd68dd1f937aaac2",
 		Branch:     "abc",
+		Title:      "Update main.tf",
 		State:      models.OpenPullState,
 		BaseRepo:   repo,
 	}, pull)
@@ -484,6 +487,7 @@ func TestParseGitlabMergeRequest_Subgroup(t *testing.T) {
 		Num:        2,
 		HeadCommit: "901d9770ef1a6862e2a73ec1bacc73590abb9aff",
 		Branch:     "patch",
+		Title:      "Update main.tf",
 		State:      models.OpenPullState,
 		BaseRepo:   repo,
 	}, pull)
@@ -708,6 +712,7 @@ func TestParseBitbucketCloudCommentEvent_ValidEvent(t *testing.T) {
 		HeadCommit: "e0624da46d3a",
 		URL:        "https://bitbucket.org/lkysow/atlantis-example/pull-requests/2",
 		Branch:     "lkysow/maintf-edited-online-with-bitbucket-1532029690581",
+		Title:      "main.tf edited online with Bitbucket",
 		Author:     "lkysow",
 		State:      models.ClosedPullState,
 		BaseRepo:   expBaseRepo,
@@ -793,6 +798,7 @@ func TestParseBitbucketCloudPullEvent_ValidEvent(t *testing.T) {
 		HeadCommit: "e0624da46d3a",
 		URL:        "https://bitbucket.org/lkysow/atlantis-example/pull-requests/2",
 		Branch:     "lkysow/maintf-edited-online-with-bitbucket-1532029690581",
+		Title:      "main.tf edited online with Bitbucket",
 		Author:     "lkysow",
 		State:      models.ClosedPullState,
 		BaseRepo:   expBaseRepo,
@@ -893,6 +899,7 @@ func TestParseBitbucketServerCommentEvent_ValidEvent(t *testing.T) {
 		HeadCommit: "bfb1af1ba9c2a2fa84cd61af67e6e1b60a22e060",
 		URL:        "http://mycorp.com:7490/projects/AT/repos/atlantis-example/pull-requests/1",
 		Branch:     "branch",
+		Title:      "Null resource",
 		Author:     "lkysow",
 		State:      models.OpenPullState,
 		BaseRepo:   expBaseRepo,
@@ -974,6 +981,7 @@ func TestParseBitbucketServerPullEvent_ValidEvent(t *testing.T) {
 		HeadCommit: "86a574157f5a2dadaf595b9f06c70fdfdd039912",
 		URL:        "http://mycorp.com:7490/projects/AT/repos/atlantis-example/pull-requests/2",
 		Branch:     "branch",
+		Title:      "Branch",
 		Author:     "lkysow",
 		State:      models.ClosedPullState,
 		BaseRepo:   expBaseRepo,
@@ -0,0 +1,635 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// DefaultJobWorkers is how many jobs a JobQueue runs at the same time by
+// default.
+const DefaultJobWorkers = 4
+
+// interruptWait is how long Drain waits for jobs to exit after interrupting
+// them.
+const interruptWait = 30 * time.Second
+
+// errRestarting is the error of API commands that weren't run because
+// Atlantis is restarting.
+var errRestarting = errors.New("not run since Atlantis is restarting")
+
+// JobStore persists the jobs in a JobQueue so they survive restarts. Jobs are
+// stored serialized.
+type JobStore interface {
+	// NextID returns a new job ID. IDs increase so sorting jobs by their ID
+	// sorts them in the order they were created.
+	NextID() (uint64, error)
+	// Put stores job at id, replacing any job already stored there.
+	Put(id uint64, job []byte) error
+	// Delete deletes the job at id.
+	Delete(id uint64) error
+	// List returns the stored jobs sorted by their ID.
+	List() ([][]byte, error)
+}
+
+// Job is a command queued in a JobQueue.
+type Job struct {
+	ID uint64
+	// Command is the comment command to run. If nil, the job is an autoplan.
+	Command       *CommentCommand
+	BaseRepo      models.Repo
+	MaybeHeadRepo *models.Repo
+	MaybePull     *models.PullRequest
+	PullNum       int
+	// Ref is set for plans of a branch or commit requested through the API.
+	// Their PullNum is DriftDetectionPullNum.
+	Ref  string
+	User models.User
+	// QueuedAt is when the job was queued.
+	QueuedAt time.Time
+	// StartedAt is when a worker started running the job. It's zero while
+	// the job is queued.
+	StartedAt time.Time
+	// result is set for jobs queued by RunAPICommand and receives their
+	// result. They aren't stored since nothing would be waiting for their
+	// result after a restart.
+	result chan CommandResult
+}
+
+// IsAutoplan returns true if the job is an autoplan.
+func (j Job) IsAutoplan() bool {
+	return j.Command == nil
+}
+
+// Running returns true if a worker is running the job.
+func (j Job) Running() bool {
+	return !j.StartedAt.IsZero()
+}
+
+// Comment returns the comment that runs the same command as the job, ex.
+// "atlantis plan -d dir". Autoplans are the same as "atlantis plan".
+func (j Job) Comment() string {
+	if j.IsAutoplan() {
+		return fmt.Sprintf("%s %s", atlantisExecutable, PlanCommand)
+	}
+	parts := []string{atlantisExecutable, j.Command.Name.String()}
+	if j.Command.ProjectName != "" {
+		parts = append(parts, "-"+projectFlagShort, j.Command.ProjectName)
+	}
+	if j.Command.RepoRelDir != "" {
+		parts = append(parts, "-"+dirFlagShort, j.Command.RepoRelDir)
+	}
+	if j.Command.Workspace != "" {
+		parts = append(parts, "-"+workspaceFlagShort, j.Command.Workspace)
+	}
+	if j.Command.Verbose {
+		parts = append(parts, "--"+verboseFlagLong)
+	}
+	if len(j.Command.Flags) > 0 {
+		parts = append(append(parts, "--"), j.Command.Flags...)
+	}
+	return strings.Join(parts, " ")
+}
+
+// hasPull returns true if the job is for a pull request that can be
+// commented on.
+func (j Job) hasPull() bool {
+	return j.Ref == ""
+}
+
+// stored returns true if the job is kept in the JobStore.
+func (j Job) stored() bool {
+	return j.ID != 0 && j.result == nil
+}
+
+// isPull returns true if the job is for pullNum in repo.
+func (j Job) isPull(repo models.Repo, pullNum int) bool {
+	return j.BaseRepo.ID() == repo.ID() && j.PullNum == pullNum
+}
+
+// JobQueue is a CommandRunner that queues commands in a JobStore and runs
+// them with a fixed number of workers so only that many commands run at the
+// same time. When it's started, jobs that were queued before Atlantis
+// restarted are run and jobs that were running are reported as failed on
+// their pull requests.
+// Cancel commands aren't queued since they cancel the commands that are
+// running.
+type JobQueue struct {
+	CommandRunner CommandRunner
+	// APICommandRunner runs the commands queued by RunAPICommand.
+	APICommandRunner    APICommandRunner
+	Store               JobStore
+	VCSClient           vcs.ClientProxy
+	CommitStatusUpdater CommitStatusUpdater
+	// CommandCanceller is used by Drain to interrupt jobs that don't finish in
+	// time. It must be the one used by CommandRunner.
+	CommandCanceller *CommandCanceller
+	Logger           *logging.SimpleLogger
+	// Workers is how many jobs are run at the same time. If it's less than 1,
+	// DefaultJobWorkers is used.
+	Workers int
+
+	mutex   sync.Mutex
+	queued  []Job
+	running map[uint64]Job
+	// ready is signalled when a job is queued. Use readyChan to get it.
+	ready    chan struct{}
+	initOnce sync.Once
+	// draining is closed when Drain is called. Use drainingChan to get it.
+	draining  chan struct{}
+	drainOnce sync.Once
+	// workers tracks the running workers.
+	workers sync.WaitGroup
+}
+
+// RunAutoplanCommand queues an autoplan. Autoplans for the same pull request
+// that haven't started yet are replaced since they're for older commits.
+func (q *JobQueue) RunAutoplanCommand(baseRepo models.Repo, headRepo models.Repo, pull models.PullRequest, user models.User) {
+	if q.IsDraining() {
+		q.commentRestarting(baseRepo, pull.Num, "This pull request wasn't autoplanned", fmt.Sprintf("%s %s", atlantisExecutable, PlanCommand))
+		return
+	}
+	q.mutex.Lock()
+	var kept []Job
+	for _, job := range q.queued {
+		if job.IsAutoplan() && job.isPull(baseRepo, pull.Num) {
+			q.deleteFromStore(job)
+			continue
+		}
+		kept = append(kept, job)
+	}
+	q.queued = kept
+	q.mutex.Unlock()
+
+	q.enqueue(Job{
+		BaseRepo:      baseRepo,
+		MaybeHeadRepo: &headRepo,
+		MaybePull:     &pull,
+		PullNum:       pull.Num,
+		User:          user,
+	})
+}
+
+// RunCommentCommand queues cmd. Cancel commands are run immediately and
+// remove the pull request's queued jobs.
+func (q *JobQueue) RunCommentCommand(baseRepo models.Repo, maybeHeadRepo *models.Repo, maybePull *models.PullRequest, user models.User, pullNum int, cmd *CommentCommand) {
+	if cmd.Name == CancelCommand {
+		if removed := q.removeQueued(baseRepo, pullNum); removed > 0 {
+			comment := fmt.Sprintf("Removed %d queued command(s) that hadn't started yet.", removed)
+			if err := q.VCSClient.CreateComment(baseRepo, pullNum, comment); err != nil {
+				q.Logger.Err("unable to comment on %s#%d: %s", baseRepo.FullName, pullNum, err)
+			}
+		}
+		go q.CommandRunner.RunCommentCommand(baseRepo, maybeHeadRepo, maybePull, user, pullNum, cmd)
+		return
+	}
+	job := Job{
+		Command:       cmd,
+		BaseRepo:      baseRepo,
+		MaybeHeadRepo: maybeHeadRepo,
+		MaybePull:     maybePull,
+		PullNum:       pullNum,
+		User:          user,
+	}
+	if q.IsDraining() {
+		q.commentRestarting(baseRepo, pullNum, fmt.Sprintf("`%s` wasn't run", job.Comment()), job.Comment())
+		return
+	}
+	q.enqueue(job)
+}
+
+// RunAPICommand queues cmd, ex. a plan requested through the API, and waits
+// for its result. If Atlantis is restarting, cmd isn't run and the pull
+// request is commented on instead.
+func (q *JobQueue) RunAPICommand(baseRepo models.Repo, user models.User, pullNum int, cmd *CommentCommand) CommandResult {
+	job := Job{
+		Command:  cmd,
+		BaseRepo: baseRepo,
+		PullNum:  pullNum,
+		User:     user,
+		result:   make(chan CommandResult, 1),
+	}
+	if !q.enqueue(job) {
+		q.commentRestarting(baseRepo, pullNum, fmt.Sprintf("`%s` wasn't run", job.Comment()), job.Comment())
+		return CommandResult{Error: errRestarting}
+	}
+	return <-job.result
+}
+
+// RunAPIRefPlan queues a plan of ref requested through the API and waits for
+// its result. If Atlantis is restarting, the plan isn't run.
+func (q *JobQueue) RunAPIRefPlan(baseRepo models.Repo, user models.User, ref string, cmd *CommentCommand) CommandResult {
+	job := Job{
+		Command:  cmd,
+		BaseRepo: baseRepo,
+		PullNum:  DriftDetectionPullNum,
+		Ref:      ref,
+		User:     user,
+		result:   make(chan CommandResult, 1),
+	}
+	if !q.enqueue(job) {
+		return CommandResult{Error: errRestarting}
+	}
+	return <-job.result
+}
+
+// Start recovers the jobs in the store and starts the workers. The workers
+// stop once ctx is done and the jobs they're running have finished.
+func (q *JobQueue) Start(ctx context.Context) {
+	q.recover()
+	workers := q.Workers
+	if workers < 1 {
+		workers = DefaultJobWorkers
+	}
+	for i := 0; i < workers; i++ {
+		q.workers.Add(1)
+		go q.work(ctx)
+	}
+}
+
+// Wait waits for the workers to stop after the context passed to Start is
+// done.
+func (q *JobQueue) Wait() {
+	q.workers.Wait()
+}
+
+// Drain is called when Atlantis is shutting down. It stops the workers from
+// starting jobs and comments on new commands that Atlantis is restarting.
+// Jobs that haven't started stay in the store so they're run after the
+// restart, except for the ones queued by RunAPICommand, which fail. It then
+// waits up to timeout for the running jobs to finish. Jobs
+// still running after that are interrupted so Terraform can release its state
+// locks and are reported on their pull requests.
+func (q *JobQueue) Drain(timeout time.Duration) {
+	q.drainOnce.Do(func() {
+		// Close it with the mutex held so enqueue doesn't queue API jobs
+		// after we've removed them.
+		q.mutex.Lock()
+		close(q.drainingChan())
+		q.mutex.Unlock()
+	})
+	q.failQueuedAPIJobs()
+	if q.waitForWorkers(timeout) {
+		return
+	}
+
+	q.mutex.Lock()
+	var interrupted []Job
+	for _, job := range q.running {
+		interrupted = append(interrupted, job)
+		// Delete it now so it isn't reported again after the restart if it
+		// doesn't exit in time.
+		q.deleteFromStore(job)
+	}
+	q.mutex.Unlock()
+	sort.Slice(interrupted, func(i, j int) bool { return interrupted[i].ID < interrupted[j].ID })
+	for _, job := range interrupted {
+		if q.CommandCanceller != nil {
+			q.CommandCanceller.Cancel(job.BaseRepo, job.PullNum)
+		}
+		q.reportInterrupted(job, fmt.Sprintf("Atlantis is restarting and %s this pull request didn't finish within %s so it was interrupted.", q.describe(job), timeout))
+	}
+	if !q.waitForWorkers(interruptWait) {
+		q.Logger.Warn("%d jobs didn't exit after being interrupted", len(interrupted))
+	}
+}
+
+// Jobs returns the running jobs followed by the queued jobs in the order
+// they'll run.
+func (q *JobQueue) Jobs() []Job {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	var jobs []Job
+	for _, job := range q.running {
+		jobs = append(jobs, job)
+	}
+	// Running jobs are kept in a map so sort them by when they were queued.
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return append(jobs, q.queued...)
+}
+
+// enqueue queues job. It returns false if job was queued by RunAPICommand
+// and Atlantis is restarting since it would never be run.
+func (q *JobQueue) enqueue(job Job) bool {
+	job.QueuedAt = time.Now()
+	id, err := q.Store.NextID()
+	if err != nil {
+		q.Logger.Err("unable to get an ID for the job, it will be lost if Atlantis restarts: %s", err)
+	}
+	job.ID = id
+
+	q.mutex.Lock()
+	if job.result != nil && q.IsDraining() {
+		q.mutex.Unlock()
+		return false
+	}
+	// The ID is 0 if the store failed so the job isn't stored.
+	if job.stored() {
+		q.saveToStore(job)
+	}
+	q.queued = append(q.queued, job)
+	q.mutex.Unlock()
+	if job.hasPull() {
+		q.Logger.Info("queued %q on %s#%d", job.Comment(), job.BaseRepo.FullName, job.PullNum)
+	} else {
+		q.Logger.Info("queued %q on %s@%s", job.Comment(), job.BaseRepo.FullName, job.Ref)
+	}
+	q.signal()
+	return true
+}
+
+// failQueuedAPIJobs removes the queued jobs from RunAPICommand, which won't
+// be run after the restart, and comments that they weren't run.
+func (q *JobQueue) failQueuedAPIJobs() {
+	q.mutex.Lock()
+	var kept, failed []Job
+	for _, job := range q.queued {
+		if job.result != nil {
+			failed = append(failed, job)
+			continue
+		}
+		kept = append(kept, job)
+	}
+	q.queued = kept
+	q.mutex.Unlock()
+	for _, job := range failed {
+		if job.hasPull() {
+			q.commentRestarting(job.BaseRepo, job.PullNum, fmt.Sprintf("`%s` wasn't run", job.Comment()), job.Comment())
+		}
+		job.result <- CommandResult{Error: errRestarting}
+	}
+}
+
+// removeQueued removes the jobs for the pull request that haven't started
+// and returns how many were removed.
+func (q *JobQueue) removeQueued(repo models.Repo, pullNum int) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	var kept []Job
+	for _, job := range q.queued {
+		if job.hasPull() && job.isPull(repo, pullNum) {
+			q.Logger.Info("removed %q on %s#%d from the queue", job.Comment(), repo.FullName, pullNum)
+			q.deleteFromStore(job)
+			if job.result != nil {
+				job.result <- CommandResult{Error: errors.New("cancelled before it started")}
+			}
+			continue
+		}
+		kept = append(kept, job)
+	}
+	removed := len(q.queued) - len(kept)
+	q.queued = kept
+	return removed
+}
+
+// recover queues the jobs in the store that hadn't started and reports the
+// jobs that were running as failed.
+func (q *JobQueue) recover() {
+	stored, err := q.Store.List()
+	if err != nil {
+		q.Logger.Err("unable to list stored jobs, jobs queued before Atlantis restarted won't be run: %s", err)
+		return
+	}
+	// Jobs queued since Atlantis started are already in the store.
+	q.mutex.Lock()
+	known := make(map[uint64]bool)
+	for _, job := range q.queued {
+		known[job.ID] = true
+	}
+	for id := range q.running {
+		known[id] = true
+	}
+	q.mutex.Unlock()
+
+	var recovered []Job
+	for _, serialized := range stored {
+		var job Job
+		if err := json.Unmarshal(serialized, &job); err != nil {
+			q.Logger.Err("unable to deserialize stored job: %s", err)
+			continue
+		}
+		if known[job.ID] {
+			continue
+		}
+		if job.Running() {
+			q.reportInterrupted(job, fmt.Sprintf("Atlantis restarted while %s this pull request so it was interrupted.", q.describe(job)))
+			if err := q.Store.Delete(job.ID); err != nil {
+				q.Logger.Err("unable to delete interrupted job: %s", err)
+			}
+			continue
+		}
+		recovered = append(recovered, job)
+	}
+	if len(recovered) == 0 {
+		return
+	}
+	q.Logger.Info("queued %d jobs from before Atlantis restarted", len(recovered))
+
+	q.mutex.Lock()
+	// Jobs that were queued before Atlantis restarted go first.
+	q.queued = append(recovered, q.queued...)
+	q.mutex.Unlock()
+	q.signal()
+}
+
+// describe returns what job does for use in comments, ex. "autoplanning".
+// It's followed by "this pull request".
+func (q *JobQueue) describe(job Job) string {
+	if job.IsAutoplan() {
+		return "autoplanning"
+	}
+	return fmt.Sprintf("running `%s` on", job.Comment())
+}
+
+// reportInterrupted comments why on the pull request of a job that was
+// interrupted by Atlantis restarting and fails its commit status.
+func (q *JobQueue) reportInterrupted(job Job, why string) {
+	// Plans of refs have no pull request to report on. Their API run gets
+	// the error instead.
+	if !job.hasPull() {
+		q.Logger.Warn("%q on %s@%s was interrupted by Atlantis restarting", job.Comment(), job.BaseRepo.FullName, job.Ref)
+		return
+	}
+	q.Logger.Warn("%q on %s#%d was interrupted by Atlantis restarting", job.Comment(), job.BaseRepo.FullName, job.PullNum)
+	comment := fmt.Sprintf("**Error**: %s\n\nComment `%s` to run it again.", why, job.Comment())
+	if err := q.VCSClient.CreateComment(job.BaseRepo, job.PullNum, comment); err != nil {
+		q.Logger.Err("unable to comment on %s#%d that its job was interrupted: %s", job.BaseRepo.FullName, job.PullNum, err)
+	}
+	// We can only update the commit status if we know the pull request's
+	// head commit.
+	if job.MaybePull == nil {
+		return
+	}
+	cmdName := PlanCommand
+	if !job.IsAutoplan() {
+		cmdName = job.Command.Name
+	}
+	if err := q.CommitStatusUpdater.Update(job.BaseRepo, *job.MaybePull, models.FailedCommitStatus, cmdName); err != nil {
+		q.Logger.Warn("unable to update commit status: %s", err)
+	}
+}
+
+// commentRestarting comments on a pull request that a command wasn't run
+// because Atlantis is restarting. what is the start of the comment, ex. "This
+// pull request wasn't autoplanned", and retry is the comment to run the
+// command again.
+func (q *JobQueue) commentRestarting(repo models.Repo, pullNum int, what string, retry string) {
+	q.Logger.Info("not running command on %s#%d since Atlantis is restarting", repo.FullName, pullNum)
+	comment := fmt.Sprintf("%s since Atlantis is restarting. Comment `%s` to run it once Atlantis is back.", what, retry)
+	if err := q.VCSClient.CreateComment(repo, pullNum, comment); err != nil {
+		q.Logger.Err("unable to comment on %s#%d: %s", repo.FullName, pullNum, err)
+	}
+}
+
+// IsDraining returns true once Drain has been called.
+func (q *JobQueue) IsDraining() bool {
+	select {
+	case <-q.drainingChan():
+		return true
+	default:
+		return false
+	}
+}
+
+// waitForWorkers waits up to timeout for the workers to stop and returns
+// true if they did.
+func (q *JobQueue) waitForWorkers(timeout time.Duration) bool {
+	stopped := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (q *JobQueue) work(ctx context.Context) {
+	defer q.workers.Done()
+	for {
+		// Check ctx first so workers stop even if there are queued jobs.
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.drainingChan():
+			return
+		default:
+		}
+		job, ok := q.next()
+		if !ok {
+			select {
+			case <-q.readyChan():
+			case <-ctx.Done():
+				return
+			case <-q.drainingChan():
+				return
+			}
+			continue
+		}
+		q.run(job)
+	}
+}
+
+// next moves the next queued job to running and returns it. It returns false
+// if there are no queued jobs.
+func (q *JobQueue) next() (Job, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.queued) == 0 {
+		return Job{}, false
+	}
+	job := q.queued[0]
+	q.queued = q.queued[1:]
+	job.StartedAt = time.Now()
+	if q.running == nil {
+		q.running = make(map[uint64]Job)
+	}
+	q.running[job.ID] = job
+	if job.stored() {
+		q.saveToStore(job)
+	}
+	// Wake up another worker if there are more jobs.
+	if len(q.queued) > 0 {
+		q.signal()
+	}
+	return job, true
+}
+
+func (q *JobQueue) run(job Job) {
+	defer func() {
+		q.mutex.Lock()
+		delete(q.running, job.ID)
+		q.deleteFromStore(job)
+		q.mutex.Unlock()
+	}()
+	if job.result != nil && !job.hasPull() {
+		job.result <- q.APICommandRunner.RunAPIRefPlan(job.BaseRepo, job.User, job.Ref, job.Command)
+		return
+	}
+	if job.result != nil {
+		job.result <- q.APICommandRunner.RunAPICommand(job.BaseRepo, job.User, job.PullNum, job.Command)
+		return
+	}
+	if job.IsAutoplan() {
+		q.CommandRunner.RunAutoplanCommand(job.BaseRepo, *job.MaybeHeadRepo, *job.MaybePull, job.User)
+		return
+	}
+	q.CommandRunner.RunCommentCommand(job.BaseRepo, job.MaybeHeadRepo, job.MaybePull, job.User, job.PullNum, job.Command)
+}
+
+// signal wakes up a worker that's waiting for a job. It's safe to call with
+// the mutex held.
+func (q *JobQueue) signal() {
+	select {
+	case q.readyChan() <- struct{}{}:
+	default:
+	}
+}
+
+func (q *JobQueue) readyChan() chan struct{} {
+	q.initOnce.Do(q.initChans)
+	return q.ready
+}
+
+func (q *JobQueue) drainingChan() chan struct{} {
+	q.initOnce.Do(q.initChans)
+	return q.draining
+}
+
+func (q *JobQueue) initChans() {
+	q.ready = make(chan struct{}, 1)
+	q.draining = make(chan struct{})
+}
+
+// saveToStore must be called with the mutex held.
+func (q *JobQueue) saveToStore(job Job) {
+	serialized, err := json.Marshal(job)
+	if err == nil {
+		err = q.Store.Put(job.ID, serialized)
+	}
+	if err != nil {
+		q.Logger.Err("unable to store job, it will be lost if Atlantis restarts: %s", err)
+	}
+}
+
+// deleteFromStore must be called with the mutex held.
+func (q *JobQueue) deleteFromStore(job Job) {
+	if !job.stored() {
+		return
+	}
+	if err := q.Store.Delete(job.ID); err != nil {
+		q.Logger.Err("unable to delete stored job: %s", err)
+	}
+}
@@ -0,0 +1,463 @@
+package events_test
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestJobQueue_RunsJobsInOrder(t *testing.T) {
+	runner := newBlockingRunner()
+	store := &memoryJobStore{}
+	q := newTestJobQueue(runner, store, 1)
+	pull := models.PullRequest{Num: 1, BaseRepo: queueRepo}
+	q.RunCommentCommand(queueRepo, nil, nil, models.User{Username: "bob"}, 1, &events.CommentCommand{Name: events.PlanCommand, RepoRelDir: "prod"})
+	q.RunAutoplanCommand(queueRepo, queueRepo, pull, models.User{Username: "carol"})
+	Equals(t, 2, len(store.jobs))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q.Start(ctx)
+	Equals(t, "atlantis plan -d prod", runner.next(t))
+	Equals(t, "autoplan", runner.next(t))
+	cancel()
+	q.Wait()
+	Equals(t, 0, len(q.Jobs()))
+	Equals(t, 0, len(store.jobs))
+}
+
+func TestJobQueue_BoundedConcurrency(t *testing.T) {
+	runner := newBlockingRunner()
+	runner.block = true
+	q := newTestJobQueue(runner, &memoryJobStore{}, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q.Start(ctx)
+	for i := 1; i <= 3; i++ {
+		q.RunCommentCommand(queueRepo, nil, nil, models.User{}, i, &events.CommentCommand{Name: events.ApplyCommand})
+	}
+
+	runner.next(t)
+	runner.next(t)
+	select {
+	case started := <-runner.started:
+		t.Fatalf("expected only 2 jobs to run at the same time but %q started", started)
+	case <-time.After(50 * time.Millisecond):
+	}
+	jobs := q.Jobs()
+	Equals(t, 3, len(jobs))
+	Assert(t, jobs[0].Running() && jobs[1].Running(), "expected the first 2 jobs to be running")
+	Assert(t, !jobs[2].Running(), "expected the last job to be queued")
+	Equals(t, 3, jobs[2].PullNum)
+
+	runner.release <- struct{}{}
+	runner.next(t)
+	runner.release <- struct{}{}
+	runner.release <- struct{}{}
+	cancel()
+	q.Wait()
+}
+
+func TestJobQueue_RunAPICommand(t *testing.T) {
+	runner := newBlockingRunner()
+	runner.block = true
+	store := &memoryJobStore{}
+	q := newTestJobQueue(runner, store, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q.Start(ctx)
+	q.RunCommentCommand(queueRepo, nil, nil, models.User{}, 1, &events.CommentCommand{Name: events.ApplyCommand})
+	runner.next(t)
+
+	results := make(chan events.CommandResult)
+	go func() {
+		results <- q.RunAPICommand(queueRepo, models.User{}, 2, &events.CommentCommand{Name: events.PlanCommand})
+	}()
+	// The API command should wait for the worker like other commands.
+	for len(q.Jobs()) != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case started := <-runner.started:
+		t.Fatalf("expected the API command to be queued but %q started", started)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// Only the comment command is stored since nothing would be waiting for
+	// the API command's result after a restart.
+	Equals(t, 1, len(store.jobs))
+
+	runner.release <- struct{}{}
+	Equals(t, "api: atlantis plan", runner.next(t))
+	runner.release <- struct{}{}
+	select {
+	case result := <-results:
+		Equals(t, "ran", result.Failure)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the API command's result")
+	}
+}
+
+func TestJobQueue_RunAPIRefPlan(t *testing.T) {
+	RegisterMockTestingT(t)
+	runner := newBlockingRunner()
+	runner.block = true
+	vcsClient := vcsmocks.NewMockClientProxy()
+	q := newTestJobQueue(runner, &memoryJobStore{}, 1)
+	q.VCSClient = vcsClient
+	q.Start(context.Background())
+	q.RunCommentCommand(queueRepo, nil, nil, models.User{}, 1, &events.CommentCommand{Name: events.ApplyCommand})
+	runner.next(t)
+
+	results := make(chan events.CommandResult)
+	go func() {
+		results <- q.RunAPIRefPlan(queueRepo, models.User{}, "feature", &events.CommentCommand{Name: events.PlanCommand})
+	}()
+	// Plans of refs should wait for a worker like other commands.
+	for len(q.Jobs()) != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	Equals(t, "feature", q.Jobs()[1].Ref)
+	runner.release <- struct{}{}
+	Equals(t, "ref feature: atlantis plan", runner.next(t))
+	runner.release <- struct{}{}
+	select {
+	case result := <-results:
+		Equals(t, "ran", result.Failure)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the ref plan's result")
+	}
+
+	t.Log("plans of refs should fail without commenting while Atlantis is restarting")
+	q.Drain(5 * time.Second)
+	result := q.RunAPIRefPlan(queueRepo, models.User{}, "feature", &events.CommentCommand{Name: events.PlanCommand})
+	ErrEquals(t, "not run since Atlantis is restarting", result.Error)
+	vcsClient.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString())
+}
+
+func TestJobQueue_ReplacesQueuedAutoplans(t *testing.T) {
+	runner := newBlockingRunner()
+	q := newTestJobQueue(runner, &memoryJobStore{}, 1)
+	old := models.PullRequest{Num: 1, BaseRepo: queueRepo, HeadCommit: "old"}
+	updated := models.PullRequest{Num: 1, BaseRepo: queueRepo, HeadCommit: "new"}
+	q.RunAutoplanCommand(queueRepo, queueRepo, old, models.User{})
+	q.RunAutoplanCommand(queueRepo, queueRepo, models.PullRequest{Num: 2, BaseRepo: queueRepo}, models.User{})
+	q.RunAutoplanCommand(queueRepo, queueRepo, updated, models.User{})
+
+	jobs := q.Jobs()
+	Equals(t, 2, len(jobs))
+	Equals(t, 2, jobs[0].PullNum)
+	Equals(t, "new", jobs[1].MaybePull.HeadCommit)
+}
+
+func TestJobQueue_Cancel(t *testing.T) {
+	RegisterMockTestingT(t)
+	runner := newBlockingRunner()
+	vcsClient := vcsmocks.NewMockClientProxy()
+	q := newTestJobQueue(runner, &memoryJobStore{}, 1)
+	q.VCSClient = vcsClient
+	q.RunCommentCommand(queueRepo, nil, nil, models.User{}, 1, &events.CommentCommand{Name: events.PlanCommand})
+	q.RunCommentCommand(queueRepo, nil, nil, models.User{}, 1, &events.CommentCommand{Name: events.ApplyCommand})
+	q.RunCommentCommand(queueRepo, nil, nil, models.User{}, 2, &events.CommentCommand{Name: events.PlanCommand})
+
+	q.RunCommentCommand(queueRepo, nil, nil, models.User{}, 1, &events.CommentCommand{Name: events.CancelCommand})
+	Equals(t, "atlantis cancel", runner.next(t))
+	jobs := q.Jobs()
+	Equals(t, 1, len(jobs))
+	Equals(t, 2, jobs[0].PullNum)
+	vcsClient.VerifyWasCalledOnce().CreateComment(queueRepo, 1, "Removed 2 queued command(s) that hadn't started yet.")
+}
+
+func TestJobQueue_Recover(t *testing.T) {
+	RegisterMockTestingT(t)
+	runner := newBlockingRunner()
+	vcsClient := vcsmocks.NewMockClientProxy()
+	commitStatusUpdater := mocks.NewMockCommitStatusUpdater()
+	store := &memoryJobStore{}
+	pull := models.PullRequest{Num: 1, BaseRepo: queueRepo}
+	store.add(t, events.Job{
+		ID:        1,
+		Command:   &events.CommentCommand{Name: events.ApplyCommand, Workspace: "staging"},
+		BaseRepo:  queueRepo,
+		MaybePull: &pull,
+		PullNum:   1,
+		StartedAt: time.Now(),
+	})
+	store.add(t, events.Job{
+		ID:            2,
+		BaseRepo:      queueRepo,
+		MaybeHeadRepo: &queueRepo,
+		MaybePull:     &models.PullRequest{Num: 2, BaseRepo: queueRepo},
+		PullNum:       2,
+		StartedAt:     time.Now(),
+	})
+	store.add(t, events.Job{
+		ID:       3,
+		Command:  &events.CommentCommand{Name: events.PlanCommand},
+		BaseRepo: queueRepo,
+		PullNum:  3,
+	})
+	q := newTestJobQueue(runner, store, 1)
+	q.VCSClient = vcsClient
+	q.CommitStatusUpdater = commitStatusUpdater
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q.Start(ctx)
+	Equals(t, "atlantis plan", runner.next(t))
+	cancel()
+	q.Wait()
+
+	vcsClient.VerifyWasCalledOnce().CreateComment(queueRepo, 1, "**Error**: Atlantis restarted while running `atlantis apply -w staging` on this pull request so it was interrupted.\n\nComment `atlantis apply -w staging` to run it again.")
+	vcsClient.VerifyWasCalledOnce().CreateComment(queueRepo, 2, "**Error**: Atlantis restarted while autoplanning this pull request so it was interrupted.\n\nComment `atlantis plan` to run it again.")
+	commitStatusUpdater.VerifyWasCalledOnce().Update(queueRepo, pull, models.FailedCommitStatus, events.ApplyCommand)
+	Equals(t, 0, len(store.jobs))
+}
+
+func TestJobQueue_Drain(t *testing.T) {
+	RegisterMockTestingT(t)
+	runner := newBlockingRunner()
+	runner.block = true
+	vcsClient := vcsmocks.NewMockClientProxy()
+	store := &memoryJobStore{}
+	q := newTestJobQueue(runner, store, 1)
+	q.VCSClient = vcsClient
+	q.Start(context.Background())
+	q.RunCommentCommand(queueRepo, nil, nil, models.User{}, 1, &events.CommentCommand{Name: events.PlanCommand})
+	runner.next(t)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		runner.release <- struct{}{}
+	}()
+	q.Drain(5 * time.Second)
+	Equals(t, 0, len(q.Jobs()))
+	Equals(t, 0, len(store.jobs))
+
+	// Commands received after draining aren't run.
+	q.RunCommentCommand(queueRepo, nil, nil, models.User{}, 2, &events.CommentCommand{Name: events.ApplyCommand})
+	q.RunAutoplanCommand(queueRepo, queueRepo, models.PullRequest{Num: 3, BaseRepo: queueRepo}, models.User{})
+	Equals(t, 0, len(q.Jobs()))
+	vcsClient.VerifyWasCalledOnce().CreateComment(queueRepo, 2, "`atlantis apply` wasn't run since Atlantis is restarting. Comment `atlantis apply` to run it once Atlantis is back.")
+	vcsClient.VerifyWasCalledOnce().CreateComment(queueRepo, 3, "This pull request wasn't autoplanned since Atlantis is restarting. Comment `atlantis plan` to run it once Atlantis is back.")
+
+	result := q.RunAPICommand(queueRepo, models.User{}, 4, &events.CommentCommand{Name: events.PlanCommand, RepoRelDir: "dir"})
+	ErrEquals(t, "not run since Atlantis is restarting", result.Error)
+	vcsClient.VerifyWasCalledOnce().CreateComment(queueRepo, 4, "`atlantis plan -d dir` wasn't run since Atlantis is restarting. Comment `atlantis plan -d dir` to run it once Atlantis is back.")
+}
+
+func TestJobQueue_DrainFailsQueuedAPICommands(t *testing.T) {
+	RegisterMockTestingT(t)
+	runner := newBlockingRunner()
+	runner.block = true
+	vcsClient := vcsmocks.NewMockClientProxy()
+	q := newTestJobQueue(runner, &memoryJobStore{}, 1)
+	q.VCSClient = vcsClient
+	q.Start(context.Background())
+	q.RunCommentCommand(queueRepo, nil, nil, models.User{}, 1, &events.CommentCommand{Name: events.ApplyCommand})
+	runner.next(t)
+
+	results := make(chan events.CommandResult)
+	go func() {
+		results <- q.RunAPICommand(queueRepo, models.User{}, 2, &events.CommentCommand{Name: events.PlanCommand})
+	}()
+	for len(q.Jobs()) != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		runner.release <- struct{}{}
+	}()
+	q.Drain(5 * time.Second)
+
+	select {
+	case result := <-results:
+		ErrEquals(t, "not run since Atlantis is restarting", result.Error)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the API command's result")
+	}
+	Equals(t, 0, len(q.Jobs()))
+	vcsClient.VerifyWasCalledOnce().CreateComment(queueRepo, 2, "`atlantis plan` wasn't run since Atlantis is restarting. Comment `atlantis plan` to run it once Atlantis is back.")
+}
+
+func TestJobQueue_DrainTimeout(t *testing.T) {
+	RegisterMockTestingT(t)
+	runner := newBlockingRunner()
+	runner.block = true
+	runner.canceller = events.NewCommandCanceller()
+	vcsClient := vcsmocks.NewMockClientProxy()
+	store := &memoryJobStore{}
+	q := newTestJobQueue(runner, store, 1)
+	q.VCSClient = vcsClient
+	q.CommandCanceller = runner.canceller
+	q.Start(context.Background())
+	q.RunCommentCommand(queueRepo, nil, nil, models.User{}, 1, &events.CommentCommand{Name: events.ApplyCommand})
+	q.RunCommentCommand(queueRepo, nil, nil, models.User{}, 2, &events.CommentCommand{Name: events.PlanCommand})
+	runner.next(t)
+
+	q.Drain(10 * time.Millisecond)
+	vcsClient.VerifyWasCalledOnce().CreateComment(queueRepo, 1, "**Error**: Atlantis is restarting and running `atlantis apply` on this pull request didn't finish within 10ms so it was interrupted.\n\nComment `atlantis apply` to run it again.")
+	select {
+	case started := <-runner.started:
+		t.Fatalf("expected queued jobs not to start while draining but %q started", started)
+	default:
+	}
+	// The queued job should still be stored so it's run after the restart.
+	jobs := q.Jobs()
+	Equals(t, 1, len(jobs))
+	Equals(t, 2, jobs[0].PullNum)
+	Equals(t, 1, len(store.jobs))
+}
+
+func TestJob_Comment(t *testing.T) {
+	cases := map[string]*events.CommentCommand{
+		"atlantis plan":         nil,
+		"atlantis apply":        {Name: events.ApplyCommand},
+		"atlantis plan -p proj": {Name: events.PlanCommand, ProjectName: "proj"},
+		"atlantis plan -d dir -w ws --verbose -- -target=a.b -no-color": {Name: events.PlanCommand, RepoRelDir: "dir", Workspace: "ws", Verbose: true, Flags: []string{"-target=a.b", "-no-color"}},
+	}
+	for exp, cmd := range cases {
+		t.Run(exp, func(t *testing.T) {
+			Equals(t, exp, events.Job{Command: cmd}.Comment())
+		})
+	}
+}
+
+func newTestJobQueue(runner *blockingRunner, store events.JobStore, workers int) *events.JobQueue {
+	return &events.JobQueue{
+		CommandRunner:       runner,
+		APICommandRunner:    runner,
+		Store:               store,
+		VCSClient:           vcsmocks.NewMockClientProxy(),
+		CommitStatusUpdater: mocks.NewMockCommitStatusUpdater(),
+		Logger:              logging.NewNoopLogger(),
+		Workers:             workers,
+	}
+}
+
+// blockingRunner is a CommandRunner and APICommandRunner that reports when commands start and,
+// if block is true, doesn't return until they're released or cancelled with
+// canceller.
+type blockingRunner struct {
+	block     bool
+	canceller *events.CommandCanceller
+	started   chan string
+	release   chan struct{}
+}
+
+func newBlockingRunner() *blockingRunner {
+	return &blockingRunner{
+		started: make(chan string, 10),
+		release: make(chan struct{}),
+	}
+}
+
+func (b *blockingRunner) RunCommentCommand(repo models.Repo, _ *models.Repo, _ *models.PullRequest, _ models.User, pullNum int, cmd *events.CommentCommand) {
+	b.run(repo, pullNum, events.Job{Command: cmd}.Comment())
+}
+
+func (b *blockingRunner) RunAutoplanCommand(repo models.Repo, _ models.Repo, pull models.PullRequest, _ models.User) {
+	b.run(repo, pull.Num, "autoplan")
+}
+
+func (b *blockingRunner) RunAPICommand(repo models.Repo, _ models.User, pullNum int, cmd *events.CommentCommand) events.CommandResult {
+	b.run(repo, pullNum, "api: "+events.Job{Command: cmd}.Comment())
+	return events.CommandResult{Failure: "ran"}
+}
+
+func (b *blockingRunner) RunAPIRefPlan(repo models.Repo, _ models.User, ref string, cmd *events.CommentCommand) events.CommandResult {
+	b.run(repo, events.DriftDetectionPullNum, "ref "+ref+": "+events.Job{Command: cmd}.Comment())
+	return events.CommandResult{Failure: "ran"}
+}
+
+func (b *blockingRunner) run(repo models.Repo, pullNum int, cmd string) {
+	cancelled := make(<-chan struct{})
+	if b.canceller != nil {
+		running := b.canceller.Start(repo, pullNum, false)
+		defer running.Finish()
+		cancelled = running.Context().Done()
+	}
+	b.started <- cmd
+	if b.block {
+		select {
+		case <-b.release:
+		case <-cancelled:
+		}
+	}
+}
+
+// next waits for the next command to start and returns it.
+func (b *blockingRunner) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case cmd := <-b.started:
+		return cmd
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a command to start")
+	}
+	return ""
+}
+
+// memoryJobStore is a JobStore that stores jobs in memory.
+type memoryJobStore struct {
+	mutex  sync.Mutex
+	lastID uint64
+	jobs   map[uint64][]byte
+}
+
+func (m *memoryJobStore) add(t *testing.T, job events.Job) {
+	serialized, err := json.Marshal(job)
+	Ok(t, err)
+	Ok(t, m.Put(job.ID, serialized))
+	if job.ID > m.lastID {
+		m.lastID = job.ID
+	}
+}
+
+func (m *memoryJobStore) NextID() (uint64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.lastID++
+	return m.lastID, nil
+}
+
+func (m *memoryJobStore) Put(id uint64, job []byte) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.jobs == nil {
+		m.jobs = make(map[uint64][]byte)
+	}
+	m.jobs[id] = job
+	return nil
+}
+
+func (m *memoryJobStore) Delete(id uint64) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.jobs, id)
+	return nil
+}
+
+func (m *memoryJobStore) List() ([][]byte, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var ids []uint64
+	for id := range m.jobs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	var jobs [][]byte
+	for _, id := range ids {
+		jobs = append(jobs, m.jobs[id])
+	}
+	return jobs, nil
+}
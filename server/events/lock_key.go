@@ -0,0 +1,157 @@
+package events
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/parser"
+	"github.com/hashicorp/hcl/hcl/token"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DirLockKeyStrategy locks projects on their repo and dir.
+	DirLockKeyStrategy = "dir"
+	// BackendLockKeyStrategy locks projects on the identity of their
+	// Terraform state backend so projects that share state can't be locked
+	// at the same time.
+	BackendLockKeyStrategy = "backend"
+)
+
+// overrideLockKey returns the models.Project.LockKey for a lock_key set in
+// atlantis.yaml. Keys that aren't already in the ex. s3://bucket/key format
+// are prefixed with lock_key:// so they can't be mistaken for repo names.
+func overrideLockKey(lockKey string) string {
+	if strings.Contains(lockKey, "://") {
+		return lockKey
+	}
+	return "lock_key://" + lockKey
+}
+
+// backendIdentities maps from a backend type to the settings that identify
+// the state it stores. Settings with a default or that are optional don't
+// have to be set.
+var backendIdentities = map[string][]backendSetting{
+	"s3":      {{name: "bucket"}, {name: "key"}},
+	"gcs":     {{name: "bucket"}, {name: "prefix", optional: true}},
+	"azurerm": {{name: "storage_account_name"}, {name: "container_name"}, {name: "key"}},
+	"consul":  {{name: "address", def: "127.0.0.1:8500"}, {name: "path"}},
+	"remote":  {{name: "hostname", def: "app.terraform.io"}, {name: "organization"}, {name: "workspaces.name", alt: "workspaces.prefix"}},
+}
+
+type backendSetting struct {
+	name string
+	// alt is used if name isn't set.
+	alt string
+	// def is used if neither name nor alt is set.
+	def      string
+	optional bool
+}
+
+// backendLockKey returns an identity for the Terraform state backend
+// configured in the .tf files in dir, ex. s3://bucket/key. It returns an empty
+// string if there's no backend or its identity can't be determined, ex.
+// because it's a local backend or some of its settings are passed with
+// -backend-config. Files that can't be parsed are skipped since terraform
+// itself will report the error.
+func backendLockKey(dir string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		contents, err := ioutil.ReadFile(file) // nolint: gosec
+		if err != nil {
+			return "", errors.Wrapf(err, "reading %s", file)
+		}
+		hclFile, err := parser.Parse(contents)
+		if err != nil {
+			continue
+		}
+		list, ok := hclFile.Node.(*ast.ObjectList)
+		if !ok {
+			continue
+		}
+		for _, block := range list.Filter("terraform").Items {
+			obj, ok := block.Val.(*ast.ObjectType)
+			if !ok {
+				continue
+			}
+			for _, backend := range obj.List.Filter("backend").Items {
+				settings, ok := backend.Val.(*ast.ObjectType)
+				if !ok || len(backend.Keys) != 1 {
+					continue
+				}
+				backendType, ok := backend.Keys[0].Token.Value().(string)
+				if !ok {
+					continue
+				}
+				// Terraform only allows one backend so we're done.
+				return backendIdentity(backendType, settings.List), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// backendIdentity returns the identity of a backendType backend with
+// settings or an empty string if it can't be determined.
+func backendIdentity(backendType string, settings *ast.ObjectList) string {
+	identitySettings, ok := backendIdentities[backendType]
+	if !ok {
+		return ""
+	}
+	var parts []string
+	for _, s := range identitySettings {
+		value, ok := stringSetting(settings, s.name)
+		if !ok && s.alt != "" {
+			value, ok = stringSetting(settings, s.alt)
+		}
+		if !ok {
+			value, ok = s.def, s.def != "" || s.optional
+		}
+		if !ok {
+			return ""
+		}
+		if value = strings.Trim(value, "/"); value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return fmt.Sprintf("%s://%s", backendType, strings.Join(parts, "/"))
+}
+
+// stringSetting returns the value of the literal string setting at path,
+// ex. workspaces.name, in settings. It returns false if it isn't set or isn't
+// a literal, ex. because it's interpolated.
+func stringSetting(settings *ast.ObjectList, path string) (string, bool) {
+	keys := strings.Split(path, ".")
+	for _, nested := range keys[:len(keys)-1] {
+		items := settings.Filter(nested).Items
+		if len(items) == 0 {
+			return "", false
+		}
+		obj, ok := items[0].Val.(*ast.ObjectType)
+		if !ok {
+			return "", false
+		}
+		settings = obj.List
+	}
+	items := settings.Filter(keys[len(keys)-1]).Items
+	if len(items) == 0 {
+		return "", false
+	}
+	lit, ok := items[0].Val.(*ast.LiteralType)
+	if !ok || lit.Token.Type != token.STRING {
+		return "", false
+	}
+	value, ok := lit.Token.Value().(string)
+	if !ok || strings.Contains(value, "${") {
+		return "", false
+	}
+	return value, true
+}
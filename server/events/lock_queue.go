@@ -0,0 +1,221 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// QueuedPull is a pull request waiting for a project's lock.
+type QueuedPull struct {
+	Pull models.PullRequest
+	// Project and Workspace are what the pull request was blocked from
+	// planning. If the lock has a lock key, the project may be different from
+	// the one holding the lock.
+	Project   models.Project
+	Workspace string
+	// User is the user whose command was blocked by the lock. Re-plans are
+	// run as them.
+	User models.User
+	// Time is when the pull request was queued.
+	Time time.Time
+}
+
+// lockQueueKey identifies the lock a queue is for.
+type lockQueueKey struct {
+	vcsHost      models.VCSHost
+	repoFullName string
+	path         string
+	lockKey      string
+	workspace    string
+}
+
+func newLockQueueKey(project models.Project, workspace string) lockQueueKey {
+	if project.LockKey != "" {
+		return lockQueueKey{lockKey: project.LockKey, workspace: workspace}
+	}
+	return lockQueueKey{vcsHost: project.VCSHost, repoFullName: project.RepoFullName, path: project.Path, workspace: workspace}
+}
+
+// isPull returns true if queued is for pullNum in repo.
+func (q QueuedPull) isPull(repo models.Repo, pullNum int) bool {
+	return q.Pull.BaseRepo.FullName == repo.FullName && q.Pull.BaseRepo.VCSHost == repo.VCSHost && q.Pull.Num == pullNum
+}
+
+// LockQueue is a FIFO queue per lock of the pull requests waiting for it.
+// When a lock is released, the next pull request in its queue is notified and
+// re-planned. Queues are kept in memory so they're lost when Atlantis
+// restarts.
+type LockQueue struct {
+	VCSClient vcs.ClientProxy
+	// CommandRunner re-plans the next pull request when a lock is released.
+	// It's set after construction since the command runner depends on the
+	// queue through the project locker.
+	CommandRunner APICommandRunner
+	Logger        *logging.SimpleLogger
+
+	mutex  sync.Mutex
+	queues map[lockQueueKey][]QueuedPull
+	// wg tracks running re-plans so tests can wait for them.
+	wg sync.WaitGroup
+}
+
+// Enqueue adds pull to the end of the queue for project and workspace's lock
+// and returns its position in the queue, starting at 1. If pull is already
+// queued, its current position is returned.
+func (q *LockQueue) Enqueue(project models.Project, workspace string, pull models.PullRequest, user models.User) int {
+	key := newLockQueueKey(project, workspace)
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for i, queued := range q.queues[key] {
+		if queued.isPull(pull.BaseRepo, pull.Num) {
+			return i + 1
+		}
+	}
+	if q.queues == nil {
+		q.queues = make(map[lockQueueKey][]QueuedPull)
+	}
+	q.queues[key] = append(q.queues[key], QueuedPull{
+		Pull:      pull,
+		Project:   project,
+		Workspace: workspace,
+		User:      user,
+		Time:      time.Now(),
+	})
+	return len(q.queues[key])
+}
+
+// List returns the pull requests queued for project and workspace's lock in
+// the order they'll get it.
+func (q *LockQueue) List(project models.Project, workspace string) []QueuedPull {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return append([]QueuedPull(nil), q.queues[newLockQueueKey(project, workspace)]...)
+}
+
+// RemovePull removes the pull request from every queue, ex. because it was
+// closed.
+func (q *LockQueue) RemovePull(repo models.Repo, pullNum int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for key, queue := range q.queues {
+		var kept []QueuedPull
+		for _, queued := range queue {
+			if !queued.isPull(repo, pullNum) {
+				kept = append(kept, queued)
+			}
+		}
+		if len(kept) == 0 {
+			delete(q.queues, key)
+		} else {
+			q.queues[key] = kept
+		}
+	}
+}
+
+// Release should be called after lock has been released. It removes the next
+// pull request from the lock's queue, comments on it and re-plans it in the
+// background.
+func (q *LockQueue) Release(lock models.ProjectLock) {
+	key := newLockQueueKey(lock.Project, lock.Workspace)
+	q.mutex.Lock()
+	var next *QueuedPull
+	for len(q.queues[key]) > 0 && next == nil {
+		queued := q.queues[key][0]
+		q.queues[key] = q.queues[key][1:]
+		if !queued.isPull(lock.Pull.BaseRepo, lock.Pull.Num) {
+			next = &queued
+		}
+	}
+	if len(q.queues[key]) == 0 {
+		delete(q.queues, key)
+	}
+	q.mutex.Unlock()
+	if next == nil {
+		return
+	}
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		q.replan(*next)
+	}()
+}
+
+// Wait waits for re-plans started by Release to finish.
+func (q *LockQueue) Wait() {
+	q.wg.Wait()
+}
+
+// Locker returns a locking.Locker that unlocks with locker and then calls
+// Release for every lock it released. Pull requests whose locks are all
+// released with UnlockByPull, ex. because they were closed, are also removed
+// from the queues.
+func (q *LockQueue) Locker(locker locking.Locker) locking.Locker {
+	return &queueReleasingLocker{Locker: locker, queue: q}
+}
+
+func (q *LockQueue) replan(next QueuedPull) {
+	repo := next.Pull.BaseRepo
+	replan := fmt.Sprintf("atlantis plan -d %s -w %s", next.Project.Path, next.Workspace)
+	if !q.canReplan(repo) {
+		comment := fmt.Sprintf("The lock for dir: `%s` workspace: `%s` was released and this pull request is next in the queue for it.\n\nComment `%s` to re-plan.",
+			next.Project.Path, next.Workspace, replan)
+		if err := q.VCSClient.CreateComment(repo, next.Pull.Num, comment); err != nil {
+			q.Logger.Err("commenting on %s#%d that a lock was released: %s", repo.FullName, next.Pull.Num, err)
+		}
+		return
+	}
+
+	comment := fmt.Sprintf("The lock for dir: `%s` workspace: `%s` was released and this pull request is next in the queue for it. Running `%s`.",
+		next.Project.Path, next.Workspace, replan)
+	if err := q.VCSClient.CreateComment(repo, next.Pull.Num, comment); err != nil {
+		q.Logger.Err("commenting on %s#%d that a lock was released: %s", repo.FullName, next.Pull.Num, err)
+	}
+	result := q.CommandRunner.RunAPICommand(repo, next.User, next.Pull.Num, &CommentCommand{
+		Name:       PlanCommand,
+		RepoRelDir: next.Project.Path,
+		Workspace:  next.Workspace,
+	})
+	if result.Error != nil {
+		q.Logger.Err("re-planning %s#%d after a lock was released: %s", repo.FullName, next.Pull.Num, result.Error)
+	}
+}
+
+// canReplan returns true if pull requests in repo can be re-planned
+// automatically. Bitbucket pull requests can't since there's no API call to
+// get them.
+func (q *LockQueue) canReplan(repo models.Repo) bool {
+	return q.CommandRunner != nil && repo.VCSHost.Type != models.BitbucketCloud && repo.VCSHost.Type != models.BitbucketServer
+}
+
+// queueReleasingLocker is returned by LockQueue.Locker.
+type queueReleasingLocker struct {
+	locking.Locker
+	queue *LockQueue
+}
+
+func (l *queueReleasingLocker) Unlock(key string) (*models.ProjectLock, error) {
+	lock, err := l.Locker.Unlock(key)
+	if err == nil && lock != nil {
+		l.queue.Release(*lock)
+	}
+	return lock, err
+}
+
+func (l *queueReleasingLocker) UnlockByPull(repo models.Repo, pullNum int) ([]models.ProjectLock, error) {
+	locks, err := l.Locker.UnlockByPull(repo, pullNum)
+	if err != nil {
+		return locks, err
+	}
+	l.queue.RemovePull(repo, pullNum)
+	for _, lock := range locks {
+		l.queue.Release(lock)
+	}
+	return locks, nil
+}
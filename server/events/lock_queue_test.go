@@ -0,0 +1,123 @@
+package events_test
+
+import (
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	lockmocks "github.com/runatlantis/atlantis/server/events/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var queueRepo = models.Repo{
+	FullName: "owner/repo",
+	VCSHost:  models.VCSHost{Hostname: "github.com", Type: models.Github},
+}
+var queueProject = models.NewProject("owner/repo", "prod")
+
+func queuePull(num int) models.PullRequest {
+	return models.PullRequest{Num: num, BaseRepo: queueRepo}
+}
+
+func TestLockQueue_Enqueue(t *testing.T) {
+	q := events.LockQueue{}
+	Equals(t, 1, q.Enqueue(queueProject, "default", queuePull(2), models.User{Username: "bob"}))
+	Equals(t, 2, q.Enqueue(queueProject, "default", queuePull(3), models.User{Username: "carol"}))
+	Equals(t, 1, q.Enqueue(queueProject, "default", queuePull(2), models.User{Username: "bob"}))
+	Equals(t, 1, q.Enqueue(queueProject, "staging", queuePull(3), models.User{Username: "carol"}))
+
+	queued := q.List(queueProject, "default")
+	Equals(t, 2, len(queued))
+	Equals(t, 2, queued[0].Pull.Num)
+	Equals(t, "bob", queued[0].User.Username)
+	Equals(t, 3, queued[1].Pull.Num)
+
+	q.RemovePull(queueRepo, 3)
+	Equals(t, 1, len(q.List(queueProject, "default")))
+	Equals(t, 0, len(q.List(queueProject, "staging")))
+}
+
+func TestLockQueue_Release(t *testing.T) {
+	RegisterMockTestingT(t)
+	vcsClient := vcsmocks.NewMockClientProxy()
+	runner := mocks.NewMockAPICommandRunner()
+	q := events.LockQueue{
+		VCSClient:     vcsClient,
+		CommandRunner: runner,
+		Logger:        logging.NewNoopLogger(),
+	}
+	q.Enqueue(queueProject, "default", queuePull(2), models.User{Username: "bob"})
+	q.Enqueue(queueProject, "default", queuePull(3), models.User{Username: "carol"})
+
+	q.Release(models.ProjectLock{Project: queueProject, Workspace: "default", Pull: queuePull(1)})
+	q.Wait()
+
+	vcsClient.VerifyWasCalledOnce().CreateComment(queueRepo, 2, "The lock for dir: `prod` workspace: `default` was released and this pull request is next in the queue for it. Running `atlantis plan -d prod -w default`.")
+	runner.VerifyWasCalledOnce().RunAPICommand(queueRepo, models.User{Username: "bob"}, 2, &events.CommentCommand{
+		Name:       events.PlanCommand,
+		RepoRelDir: "prod",
+		Workspace:  "default",
+	})
+	runner.VerifyWasCalled(Never()).RunAPICommand(matchers.AnyModelsRepo(), matchers.AnyModelsUser(), EqInt(3), matchers.AnyPtrToEventsCommentCommand())
+	queued := q.List(queueProject, "default")
+	Equals(t, 1, len(queued))
+	Equals(t, 3, queued[0].Pull.Num)
+}
+
+func TestLockQueue_ReleaseBitbucket(t *testing.T) {
+	RegisterMockTestingT(t)
+	vcsClient := vcsmocks.NewMockClientProxy()
+	runner := mocks.NewMockAPICommandRunner()
+	q := events.LockQueue{
+		VCSClient:     vcsClient,
+		CommandRunner: runner,
+		Logger:        logging.NewNoopLogger(),
+	}
+	repo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Type: models.BitbucketCloud}}
+	pull := models.PullRequest{Num: 2, BaseRepo: repo}
+	q.Enqueue(queueProject, "default", pull, models.User{Username: "bob"})
+
+	q.Release(models.ProjectLock{Project: queueProject, Workspace: "default", Pull: queuePull(1)})
+	q.Wait()
+
+	vcsClient.VerifyWasCalledOnce().CreateComment(repo, 2, "The lock for dir: `prod` workspace: `default` was released and this pull request is next in the queue for it.\n\nComment `atlantis plan -d prod -w default` to re-plan.")
+	runner.VerifyWasCalled(Never()).RunAPICommand(matchers.AnyModelsRepo(), matchers.AnyModelsUser(), AnyInt(), matchers.AnyPtrToEventsCommentCommand())
+}
+
+func TestLockQueue_Locker(t *testing.T) {
+	RegisterMockTestingT(t)
+	vcsClient := vcsmocks.NewMockClientProxy()
+	runner := mocks.NewMockAPICommandRunner()
+	q := events.LockQueue{
+		VCSClient:     vcsClient,
+		CommandRunner: runner,
+		Logger:        logging.NewNoopLogger(),
+	}
+	underlying := lockmocks.NewMockLocker()
+	lock := models.ProjectLock{Project: queueProject, Workspace: "default", Pull: queuePull(1)}
+	When(underlying.Unlock("owner/repo/prod/default")).ThenReturn(&lock, nil)
+	When(underlying.UnlockByPull(queueRepo, 2)).ThenReturn(nil, nil)
+	locker := q.Locker(underlying)
+
+	// Closing a pull request removes it from the queues.
+	q.Enqueue(queueProject, "default", queuePull(2), models.User{Username: "bob"})
+	q.Enqueue(queueProject, "default", queuePull(3), models.User{Username: "carol"})
+	_, err := locker.UnlockByPull(queueRepo, 2)
+	Ok(t, err)
+
+	unlocked, err := locker.Unlock("owner/repo/prod/default")
+	Ok(t, err)
+	Equals(t, &lock, unlocked)
+	q.Wait()
+	runner.VerifyWasCalledOnce().RunAPICommand(queueRepo, models.User{Username: "carol"}, 3, &events.CommentCommand{
+		Name:       events.PlanCommand,
+		RepoRelDir: "prod",
+		Workspace:  "default",
+	})
+	Equals(t, 0, len(q.List(queueProject, "default")))
+}
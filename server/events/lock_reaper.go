@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/vcs"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// DefaultLockReapInterval is how often LockReaper checks for expired locks.
+const DefaultLockReapInterval = time.Minute
+
+// LockReaper releases expired locks. The plans of expired locks are deleted
+// and their pull requests are commented on.
+type LockReaper struct {
+	// Locker should be the LockQueue's locker so the next pull request in the
+	// queue is re-planned.
+	Locker           locking.Locker
+	WorkingDir       WorkingDir
+	WorkingDirLocker WorkingDirLocker
+	VCSClient        vcs.ClientProxy
+	Logger           *logging.SimpleLogger
+}
+
+// Start reaps expired locks every interval until ctx is done.
+func (r *LockReaper) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := r.Reap(time.Now()); err != nil {
+					r.Logger.Err("reaping expired locks: %s", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Reap releases the locks that have expired by now and returns them.
+func (r *LockReaper) Reap(now time.Time) ([]models.ProjectLock, error) {
+	locks, err := r.Locker.List()
+	if err != nil {
+		return nil, err
+	}
+	var reaped []models.ProjectLock
+	for key, lock := range locks {
+		if !lock.Expired(now) {
+			continue
+		}
+		unlocked, err := r.Locker.Unlock(key)
+		if err != nil {
+			r.Logger.Err("releasing expired lock %q: %s", key, err)
+			continue
+		}
+		// The lock may have been released since we listed the locks.
+		if unlocked == nil {
+			continue
+		}
+		r.Logger.Info("released expired lock %q", key)
+		reaped = append(reaped, *unlocked)
+		r.cleanUp(*unlocked)
+	}
+	return reaped, nil
+}
+
+// cleanUp deletes the plan of an expired lock and comments on its pull
+// request.
+func (r *LockReaper) cleanUp(lock models.ProjectLock) {
+	// Locks created by old versions of Atlantis don't have a BaseRepo.
+	if lock.Pull.BaseRepo == (models.Repo{}) {
+		return
+	}
+	unlock, err := r.WorkingDirLocker.TryLock(lock.Pull.BaseRepo, lock.Pull.Num, lock.Workspace)
+	if err != nil {
+		r.Logger.Err("unable to obtain working dir lock when trying to delete expired plan: %s", err)
+	} else {
+		err = r.WorkingDir.DeleteForWorkspace(lock.Pull.BaseRepo, lock.Pull, lock.Workspace)
+		unlock()
+		if err != nil {
+			r.Logger.Err("unable to delete workspace: %s", err)
+		}
+	}
+
+	comment := fmt.Sprintf("**Warning**: The lock for dir: `%s` workspace: `%s` **expired** after %s so its plan was discarded.\n\n"+
+		"To `apply` this plan you must run `plan` again.", lock.Project.Path, lock.Workspace, lock.ExpiresAt.Sub(lock.Time).Round(time.Second))
+	if err := r.VCSClient.CreateComment(lock.Pull.BaseRepo, lock.Pull.Num, comment); err != nil {
+		r.Logger.Err("commenting on %s#%d that its lock expired: %s", lock.Pull.BaseRepo.FullName, lock.Pull.Num, err)
+	}
+}
@@ -0,0 +1,89 @@
+package events_test
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events"
+	lockmocks "github.com/runatlantis/atlantis/server/events/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	vcsmocks "github.com/runatlantis/atlantis/server/events/vcs/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestLockReaper_Reap(t *testing.T) {
+	RegisterMockTestingT(t)
+	locker := lockmocks.NewMockLocker()
+	workingDir := mocks.NewMockWorkingDir()
+	vcsClient := vcsmocks.NewMockClientProxy()
+	reaper := events.LockReaper{
+		Locker:           locker,
+		WorkingDir:       workingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		VCSClient:        vcsClient,
+		Logger:           logging.NewNoopLogger(),
+	}
+	now := time.Now()
+	expired := models.ProjectLock{
+		Project:   queueProject,
+		Workspace: "default",
+		Pull:      queuePull(1),
+		Time:      now.Add(-3 * time.Hour),
+		ExpiresAt: now.Add(-time.Hour),
+	}
+	notExpired := models.ProjectLock{
+		Project:   models.NewProject("owner/repo", "staging"),
+		Workspace: "default",
+		Pull:      queuePull(2),
+		Time:      now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+	noExpiry := models.ProjectLock{
+		Project:   models.NewProject("owner/repo", "dev"),
+		Workspace: "default",
+		Pull:      queuePull(3),
+		Time:      now.Add(-24 * time.Hour),
+	}
+	When(locker.List()).ThenReturn(map[string]models.ProjectLock{
+		"owner/repo/prod/default":    expired,
+		"owner/repo/staging/default": notExpired,
+		"owner/repo/dev/default":     noExpiry,
+	}, nil)
+	When(locker.Unlock("owner/repo/prod/default")).ThenReturn(&expired, nil)
+
+	reaped, err := reaper.Reap(now)
+	Ok(t, err)
+	Equals(t, []models.ProjectLock{expired}, reaped)
+	locker.VerifyWasCalledOnce().Unlock("owner/repo/prod/default")
+	locker.VerifyWasCalled(Never()).Unlock("owner/repo/staging/default")
+	locker.VerifyWasCalled(Never()).Unlock("owner/repo/dev/default")
+	workingDir.VerifyWasCalledOnce().DeleteForWorkspace(queueRepo, queuePull(1), "default")
+	vcsClient.VerifyWasCalledOnce().CreateComment(queueRepo, 1, "**Warning**: The lock for dir: `prod` workspace: `default` **expired** after 2h0m0s so its plan was discarded.\n\nTo `apply` this plan you must run `plan` again.")
+}
+
+func TestLockReaper_ReapAlreadyUnlocked(t *testing.T) {
+	RegisterMockTestingT(t)
+	locker := lockmocks.NewMockLocker()
+	vcsClient := vcsmocks.NewMockClientProxy()
+	reaper := events.LockReaper{
+		Locker:           locker,
+		WorkingDir:       mocks.NewMockWorkingDir(),
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		VCSClient:        vcsClient,
+		Logger:           logging.NewNoopLogger(),
+	}
+	now := time.Now()
+	When(locker.List()).ThenReturn(map[string]models.ProjectLock{
+		"owner/repo/prod/default": {Project: queueProject, Workspace: "default", Pull: queuePull(1), ExpiresAt: now.Add(-time.Minute)},
+	}, nil)
+	When(locker.Unlock("owner/repo/prod/default")).ThenReturn(nil, nil)
+
+	reaped, err := reaper.Reap(now)
+	Ok(t, err)
+	Equals(t, 0, len(reaped))
+	vcsClient.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString())
+}
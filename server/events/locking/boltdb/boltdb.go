@@ -11,13 +11,12 @@
 // limitations under the License.
 // Modified hereafter by contributors to runatlantis/atlantis.
 //
-// Package boltdb provides a locking implementation using Bolt.
+// Package boltdb provides a locking implementation and a job store using Bolt.
 // Bolt is a key/value store that writes all data to a file.
 // See https://github.com/boltdb/bolt for more information.
 package boltdb
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -50,17 +49,19 @@ func New(dataDir string) (*BoltLocker, error) {
 		}
 		return nil, errors.Wrap(err, "starting BoltDB")
 	}
+	locker := &BoltLocker{db, []byte(bucketName)}
 	err = db.Update(func(tx *bolt.Tx) error {
-		if _, err = tx.CreateBucketIfNotExists([]byte(bucketName)); err != nil {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(bucketName))
+		if err != nil {
 			return errors.Wrapf(err, "creating %q bucketName", bucketName)
 		}
-		return nil
+		return errors.Wrap(locker.migrateKeys(bucket), "migrating locks")
 	})
 	if err != nil {
 		return nil, errors.Wrap(err, "starting BoltDB")
 	}
 	// todo: close BoltDB when server is sigtermed
-	return &BoltLocker{db, []byte(bucketName)}, nil
+	return locker, nil
 }
 
 // NewWithDB is used for testing.
@@ -71,7 +72,9 @@ func NewWithDB(db *bolt.DB, bucket string) (*BoltLocker, error) {
 // TryLock attempts to create a new lock. If the lock is
 // acquired, it will return true and the lock returned will be newLock.
 // If the lock is not acquired, it will return false and the current
-// lock that is preventing this lock from being acquired.
+// lock that is preventing this lock from being acquired. If the current lock
+// is held by newLock's pull request and newLock expires later, the current
+// lock's expiry is extended to newLock's.
 func (b *BoltLocker) TryLock(newLock models.ProjectLock) (bool, models.ProjectLock, error) {
 	var lockAcquired bool
 	var currLock models.ProjectLock
@@ -95,6 +98,14 @@ func (b *BoltLocker) TryLock(newLock models.ProjectLock) (bool, models.ProjectLo
 			return errors.Wrap(err, "failed to deserialize current lock")
 		}
 		lockAcquired = false
+
+		// The pull request holding the lock is planning again so its lock
+		// shouldn't expire before the new plan can be applied.
+		if b.heldByPull(currLock, newLock.Pull) && !currLock.ExpiresAt.IsZero() && newLock.ExpiresAt.After(currLock.ExpiresAt) {
+			currLock.ExpiresAt = newLock.ExpiresAt
+			currLockSerialized, _ = json.Marshal(currLock)
+			return bucket.Put([]byte(key), currLockSerialized)
+		}
 		return nil
 	})
 
@@ -160,18 +171,22 @@ func (b BoltLocker) List() ([]models.ProjectLock, error) {
 }
 
 // UnlockByPull deletes all locks associated with that pull request and returns them.
-func (b BoltLocker) UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error) {
+func (b BoltLocker) UnlockByPull(repo models.Repo, pullNum int) ([]models.ProjectLock, error) {
 	var locks []models.ProjectLock
 	err := b.db.View(func(tx *bolt.Tx) error {
 		c := tx.Bucket(b.bucket).Cursor()
 
-		// we can use the repoFullName as a prefix search since that's the first part of the key
-		for k, v := c.Seek([]byte(repoFullName)); k != nil && bytes.HasPrefix(k, []byte(repoFullName)); k, v = c.Next() {
+		// We can't search by prefix since projects locked on a lock key
+		// aren't keyed by their repo.
+		for k, v := c.First(); k != nil; k, v = c.Next() {
 			var lock models.ProjectLock
 			if err := json.Unmarshal(v, &lock); err != nil {
 				return errors.Wrapf(err, "deserializing lock at key %q", string(k))
 			}
-			if lock.Pull.Num == pullNum {
+			// Locks created by old versions of Atlantis don't have a VCS host
+			// so they match repos with the same name on any host.
+			sameHost := lock.Project.VCSHost == repo.VCSHost || lock.Project.VCSHost.Hostname == ""
+			if lock.Project.RepoFullName == repo.FullName && sameHost && lock.Pull.Num == pullNum {
 				locks = append(locks, lock)
 			}
 		}
@@ -218,6 +233,60 @@ func (b BoltLocker) GetLock(p models.Project, workspace string) (*models.Project
 	return &lock, nil
 }
 
+// heldByPull returns true if lock was created by pull. Lock keys can be
+// shared across repos so the repo must match too.
+func (b BoltLocker) heldByPull(lock models.ProjectLock, pull models.PullRequest) bool {
+	lockRepo := lock.Pull.BaseRepo
+	return lockRepo.FullName == pull.BaseRepo.FullName && lockRepo.VCSHost == pull.BaseRepo.VCSHost && lock.Pull.Num == pull.Num
+}
+
 func (b BoltLocker) key(p models.Project, workspace string) string {
-	return fmt.Sprintf("%s/%s/%s", p.RepoFullName, p.Path, workspace)
+	if p.LockKey != "" {
+		return fmt.Sprintf("%s/%s", p.LockKey, workspace)
+	}
+	if p.VCSHost.Hostname == "" {
+		return fmt.Sprintf("%s/%s/%s", p.RepoFullName, p.Path, workspace)
+	}
+	return fmt.Sprintf("%s/%s/%s/%s/%s", p.VCSHost.Type, p.VCSHost.Hostname, p.RepoFullName, p.Path, workspace)
+}
+
+// migrateKeys re-keys locks created before keys included the VCS host. The
+// host is taken from the lock's pull request. Locks created by old versions
+// of Atlantis that don't have the pull request's repo are left as is.
+func (b BoltLocker) migrateKeys(bucket *bolt.Bucket) error {
+	migrated := make(map[string][]byte)
+	var oldKeys []string
+	err := bucket.ForEach(func(k []byte, v []byte) error {
+		var lock models.ProjectLock
+		if err := json.Unmarshal(v, &lock); err != nil {
+			return errors.Wrapf(err, "deserializing lock at key %q", string(k))
+		}
+		if lock.Project.LockKey != "" || lock.Project.VCSHost.Hostname != "" || lock.Pull.BaseRepo.VCSHost.Hostname == "" {
+			return nil
+		}
+		lock.Project.VCSHost = lock.Pull.BaseRepo.VCSHost
+		serialized, err := json.Marshal(lock)
+		if err != nil {
+			return errors.Wrapf(err, "serializing lock at key %q", string(k))
+		}
+		migrated[b.key(lock.Project, lock.Workspace)] = serialized
+		oldKeys = append(oldKeys, string(k))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Buckets can't be modified while iterating over them.
+	for _, k := range oldKeys {
+		if err := bucket.Delete([]byte(k)); err != nil {
+			return err
+		}
+	}
+	for k, v := range migrated {
+		if err := bucket.Put([]byte(k), v); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -14,8 +14,10 @@
 package boltdb_test
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -27,6 +29,7 @@ import (
 )
 
 var lockBucket = "bucket"
+var repo = models.Repo{FullName: "owner/repo"}
 var project = models.NewProject("owner/repo", "parent/child")
 var workspace = "default"
 var pullNum = 1
@@ -244,7 +247,7 @@ func TestUnlockByPullNone(t *testing.T) {
 	db, b := newTestDB()
 	defer cleanupDB(db)
 
-	_, err := b.UnlockByPull("any/repo", 1)
+	_, err := b.UnlockByPull(models.Repo{FullName: "any/repo"}, 1)
 	Ok(t, err)
 }
 
@@ -257,7 +260,7 @@ func TestUnlockByPullOne(t *testing.T) {
 
 	t.Log("...delete nothing when its the same repo but a different pull")
 	{
-		_, err := b.UnlockByPull(project.RepoFullName, pullNum+1)
+		_, err := b.UnlockByPull(repo, pullNum+1)
 		Ok(t, err)
 		ls, err := b.List()
 		Ok(t, err)
@@ -265,7 +268,7 @@ func TestUnlockByPullOne(t *testing.T) {
 	}
 	t.Log("...delete nothing when its the same pull but a different repo")
 	{
-		_, err := b.UnlockByPull("different/repo", pullNum)
+		_, err := b.UnlockByPull(models.Repo{FullName: "different/repo"}, pullNum)
 		Ok(t, err)
 		ls, err := b.List()
 		Ok(t, err)
@@ -273,7 +276,7 @@ func TestUnlockByPullOne(t *testing.T) {
 	}
 	t.Log("...delete the lock when its the same repo and pull")
 	{
-		_, err := b.UnlockByPull(project.RepoFullName, pullNum)
+		_, err := b.UnlockByPull(repo, pullNum)
 		Ok(t, err)
 		ls, err := b.List()
 		Ok(t, err)
@@ -290,7 +293,7 @@ func TestUnlockByPullAfterUnlock(t *testing.T) {
 	_, err = b.Unlock(project, workspace)
 	Ok(t, err)
 
-	_, err = b.UnlockByPull(project.RepoFullName, pullNum)
+	_, err = b.UnlockByPull(repo, pullNum)
 	Ok(t, err)
 	ls, err := b.List()
 	Ok(t, err)
@@ -320,7 +323,7 @@ func TestUnlockByPullMatching(t *testing.T) {
 	Equals(t, 3, len(ls))
 
 	// should all be unlocked
-	_, err = b.UnlockByPull(project.RepoFullName, pullNum)
+	_, err = b.UnlockByPull(repo, pullNum)
 	Ok(t, err)
 	ls, err = b.List()
 	Ok(t, err)
@@ -352,6 +355,123 @@ func TestGetLock(t *testing.T) {
 	Equals(t, lock.User, l.User)
 }
 
+func TestLockingLockKey(t *testing.T) {
+	t.Log("projects with the same lock key should share a lock")
+	db, b := newTestDB()
+	defer cleanupDB(db)
+	lockedProject := models.Project{RepoFullName: "owner/repo", Path: "prod", LockKey: "s3://bucket/key"}
+	_, _, err := b.TryLock(models.ProjectLock{Project: lockedProject, Workspace: workspace, Pull: models.PullRequest{Num: 1}})
+	Ok(t, err)
+
+	otherProject := models.Project{RepoFullName: "owner/other-repo", Path: "network", LockKey: "s3://bucket/key"}
+	acquired, currLock, err := b.TryLock(models.ProjectLock{Project: otherProject, Workspace: workspace, Pull: models.PullRequest{Num: 2}})
+	Ok(t, err)
+	Equals(t, false, acquired)
+	Equals(t, lockedProject, currLock.Project)
+
+	acquired, _, err = b.TryLock(models.ProjectLock{Project: otherProject, Workspace: "staging", Pull: models.PullRequest{Num: 2}})
+	Ok(t, err)
+	Equals(t, true, acquired)
+
+	l, err := b.GetLock(models.Project{LockKey: "s3://bucket/key"}, workspace)
+	Ok(t, err)
+	Equals(t, lockedProject, l.Project)
+}
+
+func TestUnlockByPullLockKey(t *testing.T) {
+	t.Log("UnlockByPull should delete locks keyed by a lock key")
+	db, b := newTestDB()
+	defer cleanupDB(db)
+	lockedProject := models.Project{RepoFullName: "owner/repo", Path: "prod", LockKey: "s3://bucket/key"}
+	_, _, err := b.TryLock(models.ProjectLock{Project: lockedProject, Workspace: workspace, Pull: models.PullRequest{Num: 1}})
+	Ok(t, err)
+
+	locks, err := b.UnlockByPull(repo, 1)
+	Ok(t, err)
+	Equals(t, 1, len(locks))
+	ls, err := b.List()
+	Ok(t, err)
+	Equals(t, 0, len(ls))
+}
+
+func TestLockingVCSHost(t *testing.T) {
+	t.Log("repos with the same name on different hosts shouldn't share locks")
+	db, b := newTestDB()
+	defer cleanupDB(db)
+	githubRepo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com", Type: models.Github}}
+	gitlabRepo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "gitlab.com", Type: models.Gitlab}}
+	githubProject := models.Project{RepoFullName: "owner/repo", Path: "prod", VCSHost: githubRepo.VCSHost}
+	gitlabProject := models.Project{RepoFullName: "owner/repo", Path: "prod", VCSHost: gitlabRepo.VCSHost}
+
+	acquired, _, err := b.TryLock(models.ProjectLock{Project: githubProject, Workspace: workspace, Pull: models.PullRequest{Num: 1, BaseRepo: githubRepo}})
+	Ok(t, err)
+	Equals(t, true, acquired)
+	acquired, _, err = b.TryLock(models.ProjectLock{Project: gitlabProject, Workspace: workspace, Pull: models.PullRequest{Num: 1, BaseRepo: gitlabRepo}})
+	Ok(t, err)
+	Equals(t, true, acquired)
+
+	locks, err := b.UnlockByPull(gitlabRepo, 1)
+	Ok(t, err)
+	Equals(t, 1, len(locks))
+	Equals(t, gitlabProject, locks[0].Project)
+	l, err := b.GetLock(githubProject, workspace)
+	Ok(t, err)
+	Assert(t, l != nil, "expected the GitHub lock to still exist")
+}
+
+func TestNew_MigratesKeys(t *testing.T) {
+	t.Log("locks created before keys included the VCS host should be re-keyed")
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	db, err := bolt.Open(filepath.Join(tmp, "atlantis.db"), 0600, nil)
+	Ok(t, err)
+	githubRepo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com", Type: models.Github}}
+	oldLocks := map[string]models.ProjectLock{
+		"owner/repo/prod/default": {
+			Project:   models.Project{RepoFullName: "owner/repo", Path: "prod"},
+			Workspace: "default",
+			Pull:      models.PullRequest{Num: 1, BaseRepo: githubRepo},
+		},
+		// Very old locks don't have the pull request's repo.
+		"owner/repo/staging/default": {
+			Project:   models.Project{RepoFullName: "owner/repo", Path: "staging"},
+			Workspace: "default",
+			Pull:      models.PullRequest{Num: 1},
+		},
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte("runLocks"))
+		if err != nil {
+			return err
+		}
+		for k, v := range oldLocks {
+			serialized, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(k), serialized); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	Ok(t, err)
+	Ok(t, db.Close())
+
+	b, err := boltdb.New(tmp)
+	Ok(t, err)
+	l, err := b.GetLock(models.Project{RepoFullName: "owner/repo", Path: "prod", VCSHost: githubRepo.VCSHost}, "default")
+	Ok(t, err)
+	Assert(t, l != nil, "expected the lock to be migrated")
+	Equals(t, githubRepo.VCSHost, l.Project.VCSHost)
+	l, err = b.GetLock(models.Project{RepoFullName: "owner/repo", Path: "staging"}, "default")
+	Ok(t, err)
+	Assert(t, l != nil, "expected the lock without a repo to be left as is")
+	ls, err := b.List()
+	Ok(t, err)
+	Equals(t, 2, len(ls))
+}
+
 // newTestDB returns a TestDB using a temporary path.
 func newTestDB() (*bolt.DB, *boltdb.BoltLocker) {
 	// Retrieve a temporary path.
@@ -0,0 +1,81 @@
+package boltdb
+
+import (
+	"encoding/binary"
+
+	"github.com/boltdb/bolt"
+	"github.com/pkg/errors"
+)
+
+const jobsBucketName = "jobs"
+
+// JobStore stores the jobs of an events.JobQueue in the same BoltDB database
+// as the locks. Jobs are stored serialized so it doesn't need to know about
+// them.
+type JobStore struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+// NewJobStore returns a JobStore that uses locker's database. BoltDB only
+// allows the database to be opened once so it has to be shared.
+func NewJobStore(locker *BoltLocker) (*JobStore, error) {
+	err := locker.db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists([]byte(jobsBucketName)); err != nil {
+			return errors.Wrapf(err, "creating %q bucket", jobsBucketName)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "starting BoltDB job store")
+	}
+	return &JobStore{db: locker.db, bucket: []byte(jobsBucketName)}, nil
+}
+
+// NextID returns a new job ID. IDs increase so sorting jobs by their ID sorts
+// them in the order they were created.
+func (j *JobStore) NextID() (uint64, error) {
+	var id uint64
+	err := j.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		id, err = tx.Bucket(j.bucket).NextSequence()
+		return err
+	})
+	return id, errors.Wrap(err, "DB transaction failed")
+}
+
+// Put stores job at id, replacing any job already stored there.
+func (j *JobStore) Put(id uint64, job []byte) error {
+	err := j.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(j.bucket).Put(jobKey(id), job)
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// Delete deletes the job at id. It's not an error if there's no job there.
+func (j *JobStore) Delete(id uint64) error {
+	err := j.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(j.bucket).Delete(jobKey(id))
+	})
+	return errors.Wrap(err, "DB transaction failed")
+}
+
+// List returns the stored jobs sorted by their ID.
+func (j *JobStore) List() ([][]byte, error) {
+	var jobs [][]byte
+	err := j.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(j.bucket).ForEach(func(_ []byte, v []byte) error {
+			// v is only valid during the transaction.
+			jobs = append(jobs, append([]byte(nil), v...))
+			return nil
+		})
+	})
+	return jobs, errors.Wrap(err, "DB transaction failed")
+}
+
+// jobKey returns the key for id. It's big endian so keys sort in ID order.
+func jobKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
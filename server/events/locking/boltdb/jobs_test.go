@@ -0,0 +1,44 @@
+package boltdb_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/locking/boltdb"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestJobStore(t *testing.T) {
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	locker, err := boltdb.New(tmp)
+	Ok(t, err)
+	store, err := boltdb.NewJobStore(locker)
+	Ok(t, err)
+
+	jobs, err := store.List()
+	Ok(t, err)
+	Equals(t, 0, len(jobs))
+
+	var ids []uint64
+	for i := 0; i < 3; i++ {
+		id, err := store.NextID()
+		Ok(t, err)
+		ids = append(ids, id)
+	}
+	Assert(t, ids[0] < ids[1] && ids[1] < ids[2], "expected increasing ids, got %v", ids)
+
+	// Jobs should be listed in ID order, not the order they were stored.
+	Ok(t, store.Put(ids[2], []byte("third")))
+	Ok(t, store.Put(ids[0], []byte("first")))
+	Ok(t, store.Put(ids[1], []byte("second")))
+	Ok(t, store.Put(ids[1], []byte("second updated")))
+	jobs, err = store.List()
+	Ok(t, err)
+	Equals(t, [][]byte{[]byte("first"), []byte("second updated"), []byte("third")}, jobs)
+
+	Ok(t, store.Delete(ids[0]))
+	Ok(t, store.Delete(ids[0]))
+	jobs, err = store.List()
+	Ok(t, err)
+	Equals(t, [][]byte{[]byte("second updated"), []byte("third")}, jobs)
+}
@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/runatlantis/atlantis/server/events/models"
@@ -31,7 +32,7 @@ type Backend interface {
 	Unlock(project models.Project, workspace string) (*models.ProjectLock, error)
 	List() ([]models.ProjectLock, error)
 	GetLock(project models.Project, workspace string) (*models.ProjectLock, error)
-	UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error)
+	UnlockByPull(repo models.Repo, pullNum int) ([]models.ProjectLock, error)
 }
 
 // TryLockResponse results from an attempted lock.
@@ -47,6 +48,9 @@ type TryLockResponse struct {
 // Client is used to perform locking actions.
 type Client struct {
 	backend Backend
+	// ttl is how long new locks are held for before they expire. If 0, locks
+	// never expire.
+	ttl time.Duration
 }
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_locker.go Locker
@@ -55,7 +59,7 @@ type Locker interface {
 	TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User) (TryLockResponse, error)
 	Unlock(key string) (*models.ProjectLock, error)
 	List() (map[string]models.ProjectLock, error)
-	UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error)
+	UnlockByPull(repo models.Repo, pullNum int) ([]models.ProjectLock, error)
 	GetLock(key string) (*models.ProjectLock, error)
 }
 
@@ -66,10 +70,38 @@ func NewClient(backend Backend) *Client {
 	}
 }
 
+// NewClientWithTTL returns a new locking client whose locks expire after ttl.
+// Expired locks are released by events.LockReaper.
+func NewClientWithTTL(backend Backend, ttl time.Duration) *Client {
+	return &Client{
+		backend: backend,
+		ttl:     ttl,
+	}
+}
+
 // keyRegex matches and captures {repoFullName}/{path}/{workspace} where path can have multiple /'s in it.
+// Keys in this format are from before keys included the VCS host.
 var keyRegex = regexp.MustCompile(`^(.*?\/.*?)\/(.*)\/(.*)$`)
 
-// TryLock attempts to acquire a lock to a project and workspace.
+// hostKeyRegex matches and captures
+// {vcsHostType}/{vcsHostname}/{repoFullName}/{path}/{workspace}.
+var hostKeyRegex = regexp.MustCompile(`^(Github|Gitlab|BitbucketCloud|BitbucketServer)\/([^/]+)\/(.*?\/.*?)\/(.*)\/(.*)$`)
+
+// vcsHostTypes maps from the names of VCS host types in keys to their type.
+var vcsHostTypes = map[string]models.VCSHostType{
+	models.Github.String():          models.Github,
+	models.Gitlab.String():          models.Gitlab,
+	models.BitbucketCloud.String():  models.BitbucketCloud,
+	models.BitbucketServer.String(): models.BitbucketServer,
+}
+
+// lockKeyRegex matches and captures {lockKey}/{workspace} for projects locked
+// on a models.Project.LockKey.
+var lockKeyRegex = regexp.MustCompile(`^(.*://.*)\/(.*)$`)
+
+// TryLock attempts to acquire a lock to a project and workspace. If the client
+// has a TTL and the lock is already held by pull, its expiry is extended so
+// re-planning keeps the lock for another TTL.
 func (c *Client) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User) (TryLockResponse, error) {
 	lock := models.ProjectLock{
 		Workspace: workspace,
@@ -78,6 +110,9 @@ func (c *Client) TryLock(p models.Project, workspace string, pull models.PullReq
 		User:      user,
 		Pull:      pull,
 	}
+	if c.ttl > 0 {
+		lock.ExpiresAt = lock.Time.Add(c.ttl)
+	}
 	lockAcquired, currLock, err := c.backend.TryLock(lock)
 	if err != nil {
 		return TryLockResponse{}, err
@@ -112,8 +147,8 @@ func (c *Client) List() (map[string]models.ProjectLock, error) {
 }
 
 // UnlockByPull deletes all locks associated with that pull request.
-func (c *Client) UnlockByPull(repoFullName string, pullNum int) ([]models.ProjectLock, error) {
-	return c.backend.UnlockByPull(repoFullName, pullNum)
+func (c *Client) UnlockByPull(repo models.Repo, pullNum int) ([]models.ProjectLock, error) {
+	return c.backend.UnlockByPull(repo, pullNum)
 }
 
 // GetLock attempts to get the lock stored at key. If successful,
@@ -135,14 +170,55 @@ func (c *Client) GetLock(key string) (*models.ProjectLock, error) {
 }
 
 func (c *Client) key(p models.Project, workspace string) string {
-	return fmt.Sprintf("%s/%s/%s", p.RepoFullName, p.Path, workspace)
+	if p.LockKey != "" {
+		return fmt.Sprintf("%s/%s", p.LockKey, workspace)
+	}
+	if p.VCSHost.Hostname == "" {
+		return fmt.Sprintf("%s/%s/%s", p.RepoFullName, p.Path, workspace)
+	}
+	return fmt.Sprintf("%s/%s/%s/%s/%s", p.VCSHost.Type, p.VCSHost.Hostname, p.RepoFullName, p.Path, workspace)
 }
 
 func (c *Client) lockKeyToProjectWorkspace(key string) (models.Project, string, error) {
+	if strings.Contains(key, "://") {
+		matches := lockKeyRegex.FindStringSubmatch(key)
+		if len(matches) != 3 {
+			return models.Project{}, "", errors.New("invalid key format")
+		}
+		return models.Project{LockKey: matches[1]}, matches[2], nil
+	}
+	if matches := hostKeyRegex.FindStringSubmatch(key); len(matches) == 6 {
+		return models.Project{
+			RepoFullName: matches[3],
+			Path:         matches[4],
+			VCSHost: models.VCSHost{
+				Type:     vcsHostTypes[matches[1]],
+				Hostname: matches[2],
+			},
+		}, matches[5], nil
+	}
 	matches := keyRegex.FindStringSubmatch(key)
 	if len(matches) != 4 {
 		return models.Project{}, "", errors.New("invalid key format")
 	}
 
-	return models.Project{RepoFullName: matches[1], Path: matches[2]}, matches[3], nil
+	return c.legacyProject(models.Project{RepoFullName: matches[1], Path: matches[2]}, matches[3])
+}
+
+// legacyProject finds the project locked under a key from before keys
+// included the VCS host, ex. from the lock URL in an old pull request comment.
+// Since the key doesn't say which host the repo is on, the first lock on a repo
+// with the same name, path and workspace is used. If there's none, project is
+// returned as is.
+func (c *Client) legacyProject(project models.Project, workspace string) (models.Project, string, error) {
+	locks, err := c.backend.List()
+	if err != nil {
+		return models.Project{}, "", err
+	}
+	for _, lock := range locks {
+		if lock.Project.LockKey == "" && lock.Project.RepoFullName == project.RepoFullName && lock.Project.Path == project.Path && lock.Workspace == workspace {
+			return lock.Project, workspace, nil
+		}
+	}
+	return project, workspace, nil
 }
@@ -22,6 +22,7 @@ import (
 
 	. "github.com/petergtz/pegomock"
 	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/locking/boltdb"
 	"github.com/runatlantis/atlantis/server/events/locking/mocks"
 	"github.com/runatlantis/atlantis/server/events/locking/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/events/models"
@@ -111,9 +112,10 @@ func TestList(t *testing.T) {
 func TestUnlockByPull(t *testing.T) {
 	RegisterMockTestingT(t)
 	backend := mocks.NewMockBackend()
-	When(backend.UnlockByPull("owner/repo", 1)).ThenReturn(nil, expectedErr)
+	repo := models.Repo{FullName: "owner/repo"}
+	When(backend.UnlockByPull(repo, 1)).ThenReturn(nil, expectedErr)
 	l := locking.NewClient(backend)
-	_, err := l.UnlockByPull("owner/repo", 1)
+	_, err := l.UnlockByPull(repo, 1)
 	Equals(t, expectedErr, err)
 }
 
@@ -144,3 +146,116 @@ func TestGetLock(t *testing.T) {
 	Ok(t, err)
 	Equals(t, &pl, lock)
 }
+
+func TestTryLock_TTL(t *testing.T) {
+	RegisterMockTestingT(t)
+	backend := mocks.NewMockBackend()
+	When(backend.TryLock(matchers.AnyModelsProjectLock())).ThenReturn(true, models.ProjectLock{}, nil)
+	l := locking.NewClientWithTTL(backend, time.Hour)
+	_, err := l.TryLock(project, workspace, pull, user)
+	Ok(t, err)
+	lock := backend.VerifyWasCalledOnce().TryLock(matchers.AnyModelsProjectLock()).GetCapturedArguments()
+	Equals(t, time.Hour, lock.ExpiresAt.Sub(lock.Time))
+}
+
+// Re-planning from the pull request holding the lock should extend its
+// expiry but planning from another pull request shouldn't.
+func TestTryLock_TTLExtendedBySamePull(t *testing.T) {
+	dataDir, cleanup := TempDir(t)
+	defer cleanup()
+	backend, err := boltdb.New(dataDir)
+	Ok(t, err)
+	l := locking.NewClientWithTTL(backend, time.Hour)
+	repo := models.Repo{FullName: "owner/repo"}
+	holder := models.PullRequest{Num: 1, BaseRepo: repo}
+
+	first, err := l.TryLock(project, workspace, holder, user)
+	Ok(t, err)
+	Assert(t, first.LockAcquired, "exp lock to be acquired")
+
+	second, err := l.TryLock(project, workspace, holder, user)
+	Ok(t, err)
+	Assert(t, !second.LockAcquired, "exp lock to already be held")
+	Assert(t, second.CurrLock.ExpiresAt.After(first.CurrLock.ExpiresAt), "exp expiry to be extended")
+	Assert(t, second.CurrLock.Time.Equal(first.CurrLock.Time), "exp creation time to be unchanged")
+
+	other, err := l.TryLock(project, workspace, models.PullRequest{Num: 2, BaseRepo: repo}, user)
+	Ok(t, err)
+	Assert(t, !other.LockAcquired, "exp lock to be held by the other pull")
+	Assert(t, other.CurrLock.ExpiresAt.Equal(second.CurrLock.ExpiresAt), "exp expiry not to be extended")
+
+	lock, err := l.GetLock(first.LockKey)
+	Ok(t, err)
+	Assert(t, lock.ExpiresAt.Equal(second.CurrLock.ExpiresAt), "exp extended expiry to be stored")
+}
+
+func TestTryLock_NoTTL(t *testing.T) {
+	RegisterMockTestingT(t)
+	backend := mocks.NewMockBackend()
+	When(backend.TryLock(matchers.AnyModelsProjectLock())).ThenReturn(true, models.ProjectLock{}, nil)
+	l := locking.NewClient(backend)
+	_, err := l.TryLock(project, workspace, pull, user)
+	Ok(t, err)
+	lock := backend.VerifyWasCalledOnce().TryLock(matchers.AnyModelsProjectLock()).GetCapturedArguments()
+	Assert(t, lock.ExpiresAt.IsZero(), "expected no expiry")
+}
+
+func TestTryLock_LockKey(t *testing.T) {
+	RegisterMockTestingT(t)
+	backend := mocks.NewMockBackend()
+	When(backend.TryLock(matchers.AnyModelsProjectLock())).ThenReturn(true, models.ProjectLock{}, nil)
+	l := locking.NewClient(backend)
+	r, err := l.TryLock(models.Project{RepoFullName: "owner/repo", Path: "path", LockKey: "s3://bucket/path/to/key"}, workspace, pull, user)
+	Ok(t, err)
+	Equals(t, "s3://bucket/path/to/key/workspace", r.LockKey)
+}
+
+func TestUnlock_LockKey(t *testing.T) {
+	RegisterMockTestingT(t)
+	backend := mocks.NewMockBackend()
+	l := locking.NewClient(backend)
+	_, err := l.Unlock("s3://bucket/path/to/key/workspace")
+	Ok(t, err)
+	backend.VerifyWasCalledOnce().Unlock(models.Project{LockKey: "s3://bucket/path/to/key"}, "workspace")
+}
+
+func TestTryLock_VCSHost(t *testing.T) {
+	RegisterMockTestingT(t)
+	backend := mocks.NewMockBackend()
+	When(backend.TryLock(matchers.AnyModelsProjectLock())).ThenReturn(true, models.ProjectLock{}, nil)
+	l := locking.NewClient(backend)
+	p := models.Project{RepoFullName: "owner/repo", Path: "path", VCSHost: models.VCSHost{Hostname: "gitlab.example.com", Type: models.Gitlab}}
+	r, err := l.TryLock(p, workspace, pull, user)
+	Ok(t, err)
+	Equals(t, "Gitlab/gitlab.example.com/owner/repo/path/workspace", r.LockKey)
+}
+
+func TestGetLock_VCSHost(t *testing.T) {
+	RegisterMockTestingT(t)
+	backend := mocks.NewMockBackend()
+	l := locking.NewClient(backend)
+	_, err := l.GetLock("BitbucketServer/bitbucket.example.com/owner/repo/path/to/dir/workspace")
+	Ok(t, err)
+	backend.VerifyWasCalledOnce().GetLock(models.Project{
+		RepoFullName: "owner/repo",
+		Path:         "path/to/dir",
+		VCSHost:      models.VCSHost{Hostname: "bitbucket.example.com", Type: models.BitbucketServer},
+	}, "workspace")
+}
+
+func TestGetLock_LegacyKey(t *testing.T) {
+	t.Log("keys from before keys included the VCS host should find the lock")
+	RegisterMockTestingT(t)
+	backend := mocks.NewMockBackend()
+	hostProject := models.Project{RepoFullName: "owner/repo", Path: "path", VCSHost: models.VCSHost{Hostname: "github.com", Type: models.Github}}
+	hostLock := models.ProjectLock{Project: hostProject, Workspace: workspace}
+	When(backend.List()).ThenReturn([]models.ProjectLock{
+		{Project: models.Project{RepoFullName: "owner/repo", Path: "other"}, Workspace: workspace},
+		hostLock,
+	}, nil)
+	When(backend.GetLock(hostProject, workspace)).ThenReturn(&hostLock, nil)
+	l := locking.NewClient(backend)
+	lock, err := l.GetLock("owner/repo/path/workspace")
+	Ok(t, err)
+	Equals(t, &hostLock, lock)
+}
@@ -16,11 +16,16 @@ package events
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
 
 	"github.com/Masterminds/sprig"
+	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/webhooks"
 )
 
 const (
@@ -37,6 +42,42 @@ type MarkdownRenderer struct {
 	// using supports the CommonMark markdown format.
 	// If we're not configured with a GitLab client, this will be false.
 	GitlabSupportsCommonMark bool
+	// templates are the templates for each VCS host with any overrides
+	// applied. If a host isn't in the map, defaultTemplates are used.
+	templates map[models.VCSHostType]*template.Template
+}
+
+// NewMarkdownRenderer returns a MarkdownRenderer. If templateOverridesDir is
+// set, the templates in it replace the default templates with the same name.
+// Templates named <name>.tmpl replace the template for every VCS host and
+// templates in a subdirectory named after a VCS host, ex. gitlab/<name>.tmpl,
+// only replace it for that host. Overrides are checked by rendering them with
+// example data so mistakes like unknown fields are found at startup.
+func NewMarkdownRenderer(gitlabSupportsCommonMark bool, templateOverridesDir string) (*MarkdownRenderer, error) {
+	m := &MarkdownRenderer{GitlabSupportsCommonMark: gitlabSupportsCommonMark}
+	if templateOverridesDir == "" {
+		return m, nil
+	}
+	overrides, hostOverrides, err := readTemplateOverrides(templateOverridesDir)
+	if err != nil {
+		return nil, err
+	}
+	common, err := parseTemplates(defaultTemplates, overrides)
+	if err != nil {
+		return nil, err
+	}
+	m.templates = make(map[models.VCSHostType]*template.Template)
+	for _, host := range vcsHosts {
+		tmpl, err := parseTemplates(common, hostOverrides[host])
+		if err != nil {
+			return nil, err
+		}
+		if err := validateTemplates(tmpl); err != nil {
+			return nil, errors.Wrapf(err, "validating %s templates", host)
+		}
+		m.templates[host] = tmpl
+	}
+	return m, nil
 }
 
 // CommonData is data that all responses have.
@@ -61,6 +102,12 @@ type FailureData struct {
 // ResultData is data about a successful response.
 type ResultData struct {
 	Results []projectResultTmplData
+	// NoChangesResults are the results of projects with no changes. They're
+	// also in Results.
+	NoChangesResults []projectResultTmplData
+	// Collapsible is true if the VCS host supports collapsing sections with
+	// <details>.
+	Collapsible bool
 	CommonData
 }
 
@@ -68,6 +115,31 @@ type projectResultTmplData struct {
 	Workspace  string
 	RepoRelDir string
 	Rendered   string
+	// Status is a word or two about the result, ex. Planned or Error.
+	Status string
+	// Changes are the resource counts from Terraform's summary, ex. "1 to
+	// add, 0 to change, 0 to destroy". It's empty if there's no summary or
+	// Terraform said there are no changes.
+	Changes string
+	// NoChanges is true if the project has nothing to change.
+	NoChanges bool
+}
+
+// projectErrTmplData is data about a project's error.
+type projectErrTmplData struct {
+	Command string
+	Error   string
+}
+
+// projectFailureTmplData is data about a project's failure.
+type projectFailureTmplData struct {
+	Command string
+	Failure string
+}
+
+// applySuccessTmplData is data about a project's successful apply.
+type applySuccessTmplData struct {
+	Output string
 }
 
 // Render formats the data into a markdown string.
@@ -76,17 +148,21 @@ func (m *MarkdownRenderer) Render(res CommandResult, cmdName CommandName, log st
 	commandStr := strings.Title(cmdName.String())
 	common := CommonData{commandStr, verbose, log}
 	if res.Error != nil {
-		return m.renderTemplate(unwrappedErrWithLogTmpl, ErrData{res.Error.Error(), common})
+		return m.renderTemplate(vcsHost, errWithLogTmpl, ErrData{res.Error.Error(), common})
 	}
 	if res.Failure != "" {
-		return m.renderTemplate(failureWithLogTmpl, FailureData{res.Failure, common})
+		return m.renderTemplate(vcsHost, failureWithLogTmpl, FailureData{res.Failure, common})
 	}
 	return m.renderProjectResults(res.ProjectResults, common, vcsHost)
 }
 
 func (m *MarkdownRenderer) renderProjectResults(results []ProjectResult, common CommonData, vcsHost models.VCSHostType) string {
 	var resultsTmplData []projectResultTmplData
+	var noChangesTmplData []projectResultTmplData
 	numPlanSuccesses := 0
+	// With more than one project, each project's output is collapsed so the
+	// comment can be skimmed.
+	collapseAll := len(results) > 1 && m.supportsCollapsing(vcsHost)
 
 	for _, result := range results {
 		resultData := projectResultTmplData{
@@ -95,45 +171,49 @@ func (m *MarkdownRenderer) renderProjectResults(results []ProjectResult, common
 		}
 		if result.Error != nil {
 			tmpl := unwrappedErrTmpl
-			if m.shouldUseWrappedTmpl(vcsHost, result.Error.Error()) {
+			if collapseAll || m.shouldUseWrappedTmpl(vcsHost, result.Error.Error()) {
 				tmpl = wrappedErrTmpl
 			}
-			resultData.Rendered = m.renderTemplate(tmpl, struct {
-				Command string
-				Error   string
-			}{
+			resultData.Rendered = m.renderTemplate(vcsHost, tmpl, projectErrTmplData{
 				Command: common.Command,
 				Error:   result.Error.Error(),
 			})
+			resultData.Status = "Error"
 		} else if result.Failure != "" {
-			resultData.Rendered = m.renderTemplate(failureTmpl, struct {
-				Command string
-				Failure string
-			}{
+			resultData.Rendered = m.renderTemplate(vcsHost, failureTmpl, projectFailureTmplData{
 				Command: common.Command,
 				Failure: result.Failure,
 			})
+			resultData.Status = "Failed"
 		} else if result.PlanSuccess != nil {
-			if m.shouldUseWrappedTmpl(vcsHost, result.PlanSuccess.TerraformOutput) {
-				resultData.Rendered = m.renderTemplate(planSuccessWrappedTmpl, *result.PlanSuccess)
+			if collapseAll || m.shouldUseWrappedTmpl(vcsHost, result.PlanSuccess.TerraformOutput) {
+				resultData.Rendered = m.renderTemplate(vcsHost, planSuccessWrappedTmpl, *result.PlanSuccess)
 			} else {
-				resultData.Rendered = m.renderTemplate(planSuccessUnwrappedTmpl, *result.PlanSuccess)
+				resultData.Rendered = m.renderTemplate(vcsHost, planSuccessUnwrappedTmpl, *result.PlanSuccess)
 			}
+			resultData.Changes, resultData.NoChanges = resourceChanges(result.PlanSuccess.TerraformOutput)
+			resultData.Status = "Planned"
 			numPlanSuccesses++
 		} else if result.ApplySuccess != "" {
-			if m.shouldUseWrappedTmpl(vcsHost, result.ApplySuccess) {
-				resultData.Rendered = m.renderTemplate(applyWrappedSuccessTmpl, struct{ Output string }{result.ApplySuccess})
+			resultData.Changes, resultData.NoChanges = resourceChanges(result.ApplySuccess)
+			resultData.Status = "Applied"
+			if collapseAll || m.shouldUseWrappedTmpl(vcsHost, result.ApplySuccess) {
+				resultData.Rendered = m.renderTemplate(vcsHost, applyWrappedSuccessTmpl, applySuccessTmplData{result.ApplySuccess})
 			} else {
-				resultData.Rendered = m.renderTemplate(applyUnwrappedSuccessTmpl, struct{ Output string }{result.ApplySuccess})
+				resultData.Rendered = m.renderTemplate(vcsHost, applyUnwrappedSuccessTmpl, applySuccessTmplData{result.ApplySuccess})
 			}
 
 		} else {
 			resultData.Rendered = "Found no template. This is a bug!"
 		}
+		if resultData.NoChanges {
+			resultData.Status = "No changes"
+			noChangesTmplData = append(noChangesTmplData, resultData)
+		}
 		resultsTmplData = append(resultsTmplData, resultData)
 	}
 
-	var tmpl *template.Template
+	var tmpl string
 	switch {
 	case len(resultsTmplData) == 1 && common.Command == planCommandTitle && numPlanSuccesses > 0:
 		tmpl = singleProjectPlanSuccessTmpl
@@ -148,7 +228,12 @@ func (m *MarkdownRenderer) renderProjectResults(results []ProjectResult, common
 	default:
 		return "no template matched–this is a bug"
 	}
-	return m.renderTemplate(tmpl, ResultData{resultsTmplData, common})
+	return m.renderTemplate(vcsHost, tmpl, ResultData{
+		Results:          resultsTmplData,
+		NoChangesResults: noChangesTmplData,
+		Collapsible:      m.supportsCollapsing(vcsHost),
+		CommonData:       common,
+	})
 }
 
 // shouldUseWrappedTmpl returns true if we should use the wrapped markdown
@@ -156,101 +241,297 @@ func (m *MarkdownRenderer) renderProjectResults(results []ProjectResult, common
 // load. Some VCS providers or versions of VCS providers don't support this
 // syntax.
 func (m *MarkdownRenderer) shouldUseWrappedTmpl(vcsHost models.VCSHostType, output string) bool {
-	// Bitbucket Cloud and Server don't support the folding markdown syntax.
-	if vcsHost == models.BitbucketServer || vcsHost == models.BitbucketCloud {
+	if !m.supportsCollapsing(vcsHost) {
 		return false
 	}
 
-	if vcsHost == models.Gitlab && !m.GitlabSupportsCommonMark {
+	return strings.Count(output, "\n") > maxUnwrappedLines
+}
+
+// supportsCollapsing returns true if vcsHost supports the folding markdown
+// syntax, <details><summary>.
+func (m *MarkdownRenderer) supportsCollapsing(vcsHost models.VCSHostType) bool {
+	// Bitbucket Cloud and Server don't support the folding markdown syntax.
+	if vcsHost == models.BitbucketServer || vcsHost == models.BitbucketCloud {
 		return false
 	}
+	return vcsHost != models.Gitlab || m.GitlabSupportsCommonMark
+}
 
-	return strings.Count(output, "\n") > maxUnwrappedLines
+// resourceChanges returns the resource counts in the summary of Terraform's
+// output, ex. "1 to add, 0 to change, 0 to destroy", and whether there's
+// nothing to change.
+func resourceChanges(output string) (string, bool) {
+	counts := webhooks.ResourceChanges(terraformSummary([]string{output}))
+	if counts == "No changes" {
+		return "", true
+	}
+	return counts, counts == "0 added, 0 changed, 0 destroyed"
 }
 
-func (m *MarkdownRenderer) renderTemplate(tmpl *template.Template, data interface{}) string {
+// renderTemplate renders the template called name for vcsHost.
+func (m *MarkdownRenderer) renderTemplate(vcsHost models.VCSHostType, name string, data interface{}) string {
+	tmpl, ok := m.templates[vcsHost]
+	if !ok {
+		tmpl = defaultTemplates
+	}
 	buf := &bytes.Buffer{}
-	if err := tmpl.Execute(buf, data); err != nil {
+	if err := tmpl.ExecuteTemplate(buf, name, data); err != nil {
 		return fmt.Sprintf("Failed to render template, this is a bug: %v", err)
 	}
 	return buf.String()
 }
 
-// todo: refactor to remove duplication #refactor
-var singleProjectApplyTmpl = template.Must(template.New("").Parse(
-	"{{$result := index .Results 0}}Ran {{.Command}} in dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n\n{{$result.Rendered}}\n" + logTmpl))
-var singleProjectPlanSuccessTmpl = template.Must(template.New("").Parse(
-	"{{$result := index .Results 0}}Ran {{.Command}} in dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n\n{{$result.Rendered}}\n" +
+// vcsHosts are the hosts that can have their own template overrides.
+var vcsHosts = []models.VCSHostType{models.Github, models.Gitlab, models.BitbucketCloud, models.BitbucketServer}
+
+// templateOverrideExt is the extension of template override files.
+const templateOverrideExt = ".tmpl"
+
+// readTemplateOverrides reads the override templates in dir. It returns the
+// templates for every host and the templates for each host, by name.
+func readTemplateOverrides(dir string) (map[string]string, map[models.VCSHostType]map[string]string, error) {
+	hostDirs := make(map[string]models.VCSHostType)
+	var hostDirNames []string
+	for _, host := range vcsHosts {
+		name := strings.ToLower(host.String())
+		hostDirs[name] = host
+		hostDirNames = append(hostDirNames, name)
+	}
+
+	overrides, err := readTemplateOverridesDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	hostOverrides := make(map[models.VCSHostType]map[string]string)
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "reading template overrides")
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		host, ok := hostDirs[entry.Name()]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown template overrides directory %q, expected one of %s", filepath.Join(dir, entry.Name()), strings.Join(hostDirNames, ", "))
+		}
+		hostOverrides[host], err = readTemplateOverridesDir(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return overrides, hostOverrides, nil
+}
+
+// readTemplateOverridesDir reads the templates in dir by name. Files that
+// aren't templates are ignored.
+func readTemplateOverridesDir(dir string) (map[string]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading template overrides")
+	}
+	overrides := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != templateOverrideExt {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), templateOverrideExt)
+		if _, ok := templateExampleData[name]; !ok {
+			return nil, fmt.Errorf("unknown template %q, expected one of %s", filepath.Join(dir, entry.Name()), strings.Join(templateNames(), ", "))
+		}
+		text, err := ioutil.ReadFile(filepath.Join(dir, entry.Name())) // nolint: gosec
+		if err != nil {
+			return nil, errors.Wrap(err, "reading template overrides")
+		}
+		overrides[name] = string(text)
+	}
+	return overrides, nil
+}
+
+// parseTemplates returns a copy of base with the templates in texts added or
+// replaced. If base is nil the templates are added to a new set.
+func parseTemplates(base *template.Template, texts map[string]string) (*template.Template, error) {
+	var set *template.Template
+	if base == nil {
+		set = template.New("").Funcs(sprig.TxtFuncMap())
+	} else {
+		var err error
+		if set, err = base.Clone(); err != nil {
+			return nil, err
+		}
+	}
+	for name, text := range texts {
+		if _, err := set.New(name).Parse(text); err != nil {
+			return nil, errors.Wrapf(err, "parsing template %q", name)
+		}
+	}
+	return set, nil
+}
+
+// validateTemplates renders each template in set with example data of the
+// type it's rendered with so that templates using fields that don't exist,
+// ex. {{.Output}} in a template rendered with ResultData, are caught.
+func validateTemplates(set *template.Template) error {
+	for _, name := range templateNames() {
+		if err := set.ExecuteTemplate(ioutil.Discard, name, templateExampleData[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// templateNames returns the names of the templates in order.
+func templateNames() []string {
+	var names []string
+	for name := range templateExampleData {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Template names. Override files are named after these.
+const (
+	singleProjectApplyTmpl            = "single_project_apply"
+	singleProjectPlanSuccessTmpl      = "single_project_plan_success"
+	singleProjectPlanUnsuccessfulTmpl = "single_project_plan_unsuccessful"
+	multiProjectPlanTmpl              = "multi_project_plan"
+	multiProjectApplyTmpl             = "multi_project_apply"
+	multiProjectSummaryTmpl           = "multi_project_summary"
+	multiProjectResultTmpl            = "multi_project_result"
+	multiProjectNoChangesTmpl         = "multi_project_no_changes"
+	planSuccessUnwrappedTmpl          = "plan_success_unwrapped"
+	planSuccessWrappedTmpl            = "plan_success_wrapped"
+	planNextStepsTmpl                 = "plan_next_steps"
+	applyUnwrappedSuccessTmpl         = "apply_success_unwrapped"
+	applyWrappedSuccessTmpl           = "apply_success_wrapped"
+	unwrappedErrTmpl                  = "error_unwrapped"
+	wrappedErrTmpl                    = "error_wrapped"
+	errWithLogTmpl                    = "error_with_log"
+	failureTmpl                       = "failure"
+	failureWithLogTmpl                = "failure_with_log"
+	logTmpl                           = "log"
+)
+
+// templateExampleData is example data of the type each template is rendered
+// with. It's used to validate override templates.
+var templateExampleData = func() map[string]interface{} {
+	common := CommonData{Command: planCommandTitle, Verbose: true, Log: "log"}
+	noChanges := projectResultTmplData{Workspace: "default", RepoRelDir: "network", Rendered: "output", Status: "No changes", NoChanges: true}
+	results := ResultData{
+		Results: []projectResultTmplData{
+			{Workspace: "default", RepoRelDir: ".", Rendered: "output", Status: "Planned", Changes: "1 to add, 0 to change, 0 to destroy"},
+			noChanges,
+		},
+		NoChangesResults: []projectResultTmplData{noChanges},
+		Collapsible:      true,
+		CommonData:       common,
+	}
+	planSuccess := PlanSuccess{}
+	return map[string]interface{}{
+		singleProjectApplyTmpl:            results,
+		singleProjectPlanSuccessTmpl:      results,
+		singleProjectPlanUnsuccessfulTmpl: results,
+		multiProjectPlanTmpl:              results,
+		multiProjectApplyTmpl:             results,
+		multiProjectSummaryTmpl:           results,
+		multiProjectResultTmpl:            results.Results[0],
+		multiProjectNoChangesTmpl:         results,
+		planSuccessUnwrappedTmpl:          planSuccess,
+		planSuccessWrappedTmpl:            planSuccess,
+		planNextStepsTmpl:                 planSuccess,
+		applyUnwrappedSuccessTmpl:         applySuccessTmplData{Output: "output"},
+		applyWrappedSuccessTmpl:           applySuccessTmplData{Output: "output"},
+		unwrappedErrTmpl:                  projectErrTmplData{Command: planCommandTitle, Error: "error"},
+		wrappedErrTmpl:                    projectErrTmplData{Command: planCommandTitle, Error: "error"},
+		errWithLogTmpl:                    ErrData{Error: "error", CommonData: common},
+		failureTmpl:                       projectFailureTmplData{Command: planCommandTitle, Failure: "failure"},
+		failureWithLogTmpl:                FailureData{Failure: "failure", CommonData: common},
+		logTmpl:                           common,
+	}
+}()
+
+// defaultTemplates are the templates responses are rendered with unless
+// they're overridden. Templates include each other with {{template "name" .}}.
+var defaultTemplates = template.Must(parseTemplates(nil, map[string]string{
+	// todo: refactor to remove duplication #refactor
+	singleProjectApplyTmpl: "{{$result := index .Results 0}}Ran {{.Command}} in dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n\n{{$result.Rendered}}\n" +
+		"{{template \"log\" .}}",
+	singleProjectPlanSuccessTmpl: "{{$result := index .Results 0}}Ran {{.Command}} in dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n\n{{$result.Rendered}}\n" +
 		"\n" +
 		"---\n" +
 		"* :fast_forward: To **apply** all unapplied plans from this pull request, comment:\n" +
-		"    * `atlantis apply`" + logTmpl))
-var singleProjectPlanUnsuccessfulTmpl = template.Must(template.New("").Parse(
-	"{{$result := index .Results 0}}Ran {{.Command}} in dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n\n" +
-		"{{$result.Rendered}}\n" + logTmpl))
-var multiProjectPlanTmpl = template.Must(template.New("").Funcs(sprig.TxtFuncMap()).Parse(
-	"Ran {{.Command}} for {{ len .Results }} projects:\n" +
-		"{{ range $result := .Results }}" +
-		"1. workspace: `{{$result.Workspace}}` dir: `{{$result.RepoRelDir}}`\n" +
-		"{{end}}\n" +
-		"{{ range $i, $result := .Results }}" +
-		"### {{add $i 1}}. workspace: `{{$result.Workspace}}` dir: `{{$result.RepoRelDir}}`\n" +
-		"{{$result.Rendered}}\n\n" +
-		"---\n{{end}}{{ if gt (len .Results) 0 }}* :fast_forward: To **apply** all unapplied plans from this pull request, comment:\n" +
+		"    * `atlantis apply`{{template \"log\" .}}",
+	singleProjectPlanUnsuccessfulTmpl: "{{$result := index .Results 0}}Ran {{.Command}} in dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`\n\n" +
+		"{{$result.Rendered}}\n{{template \"log\" .}}",
+	multiProjectPlanTmpl: "{{template \"multi_project_summary\" .}}" +
+		"{{ if gt (len .Results) 0 }}* :fast_forward: To **apply** all unapplied plans from this pull request, comment:\n" +
 		"    * `atlantis apply`{{end}}" +
-		logTmpl))
-var multiProjectApplyTmpl = template.Must(template.New("").Funcs(sprig.TxtFuncMap()).Parse(
-	"Ran {{.Command}} for {{ len .Results }} projects:\n" +
-		"{{ range $result := .Results }}" +
-		"1. workspace: `{{$result.Workspace}}` dir: `{{$result.RepoRelDir}}`\n" +
-		"{{end}}\n" +
-		"{{ range $i, $result := .Results }}" +
-		"### {{add $i 1}}. workspace: `{{$result.Workspace}}` dir: `{{$result.RepoRelDir}}`\n" +
-		"{{$result.Rendered}}\n\n" +
-		"---\n{{end}}" +
-		logTmpl))
-var planSuccessUnwrappedTmpl = template.Must(template.New("").Parse(
-	"```diff\n" +
+		"{{template \"log\" .}}",
+	multiProjectApplyTmpl: "{{template \"multi_project_summary\" .}}" +
+		"{{template \"log\" .}}",
+	// multi_project_summary is a table of the projects followed by their
+	// results.
+	multiProjectSummaryTmpl: "Ran {{.Command}} for {{ len .Results }} projects:\n" +
+		"{{ if .Results }}\n" +
+		"| Dir | Workspace | Status | Changes |\n" +
+		"|-----|-----------|--------|---------|\n" +
+		"{{ range .Results }}" +
+		"| `{{.RepoRelDir}}` | `{{.Workspace}}` | {{.Status}} | {{.Changes}} |\n" +
+		"{{end}}{{end}}\n" +
+		"{{ range .Results }}{{ if not .NoChanges }}{{template \"multi_project_result\" .}}{{end}}{{end}}" +
+		"{{template \"multi_project_no_changes\" .}}",
+	multiProjectResultTmpl: "### dir: `{{.RepoRelDir}}` workspace: `{{.Workspace}}`\n" +
+		"{{.Rendered}}\n\n" +
+		"---\n",
+	// multi_project_no_changes hides the projects with no changes behind a
+	// single line.
+	multiProjectNoChangesTmpl: "{{ with $projects := .NoChangesResults }}" +
+		"{{ if $.Collapsible }}" +
+		"<details><summary>{{ len $projects }} {{ plural \"project has\" \"projects have\" (len $projects) }} no changes</summary>\n\n" +
+		"{{ range $projects }}{{template \"multi_project_result\" .}}{{end}}" +
+		"</details>\n\n" +
+		"{{ else }}" +
+		"{{ len $projects }} {{ plural \"project has\" \"projects have\" (len $projects) }} no changes: " +
+		"{{ range $i, $result := $projects }}{{ if $i }}, {{end}}dir: `{{$result.RepoRelDir}}` workspace: `{{$result.Workspace}}`{{end}}\n\n" +
+		"---\n" +
+		"{{end}}{{end}}",
+	planSuccessUnwrappedTmpl: "```diff\n" +
 		"{{.TerraformOutput}}\n" +
-		"```\n\n" + planNextSteps))
-var planSuccessWrappedTmpl = template.Must(template.New("").Parse(
-	"<details><summary>Show Output</summary>\n\n" +
+		"```\n\n{{template \"plan_next_steps\" .}}",
+	planSuccessWrappedTmpl: "<details><summary>Show Output</summary>\n\n" +
 		"```diff\n" +
 		"{{.TerraformOutput}}\n" +
 		"```\n\n" +
-		planNextSteps + "\n" +
-		"</details>"))
-
-// planNextSteps are instructions appended after successful plans as to what
-// to do next.
-var planNextSteps = "* :arrow_forward: To **apply** this plan, comment:\n" +
-	"    * `{{.ApplyCmd}}`\n" +
-	"* :put_litter_in_its_place: To **delete** this plan click [here]({{.LockURL}})\n" +
-	"* :repeat: To **plan** this project again, comment:\n" +
-	"    * `{{.RePlanCmd}}`"
-var applyUnwrappedSuccessTmpl = template.Must(template.New("").Parse(
-	"```diff\n" +
+		"{{template \"plan_next_steps\" .}}\n" +
+		"</details>",
+	// plan_next_steps are instructions appended after successful plans as to
+	// what to do next.
+	planNextStepsTmpl: "* :arrow_forward: To **apply** this plan, comment:\n" +
+		"    * `{{.ApplyCmd}}`\n" +
+		"* :put_litter_in_its_place: To **delete** this plan click [here]({{.LockURL}})\n" +
+		"* :repeat: To **plan** this project again, comment:\n" +
+		"    * `{{.RePlanCmd}}`",
+	applyUnwrappedSuccessTmpl: "```diff\n" +
 		"{{.Output}}\n" +
-		"```"))
-var applyWrappedSuccessTmpl = template.Must(template.New("").Parse(
-	"<details><summary>Show Output</summary>\n\n" +
+		"```",
+	applyWrappedSuccessTmpl: "<details><summary>Show Output</summary>\n\n" +
 		"```diff\n" +
 		"{{.Output}}\n" +
 		"```\n" +
-		"</details>"))
-var unwrappedErrTmplText = "**{{.Command}} Error**\n" +
-	"```\n" +
-	"{{.Error}}\n" +
-	"```"
-var wrappedErrTmplText = "**{{.Command}} Error**\n" +
-	"<details><summary>Show Output</summary>\n\n" +
-	"```\n" +
-	"{{.Error}}\n" +
-	"```\n</details>"
-var unwrappedErrTmpl = template.Must(template.New("").Parse(unwrappedErrTmplText))
-var unwrappedErrWithLogTmpl = template.Must(template.New("").Parse(unwrappedErrTmplText + logTmpl))
-var wrappedErrTmpl = template.Must(template.New("").Parse(wrappedErrTmplText))
-var failureTmplText = "**{{.Command}} Failed**: {{.Failure}}"
-var failureTmpl = template.Must(template.New("").Parse(failureTmplText))
-var failureWithLogTmpl = template.Must(template.New("").Parse(failureTmplText + logTmpl))
-var logTmpl = "{{if .Verbose}}\n<details><summary>Log</summary>\n  <p>\n\n```\n{{.Log}}```\n</p></details>{{end}}\n"
+		"</details>",
+	unwrappedErrTmpl: "**{{.Command}} Error**\n" +
+		"```\n" +
+		"{{.Error}}\n" +
+		"```",
+	wrappedErrTmpl: "**{{.Command}} Error**\n" +
+		"<details><summary>Show Output</summary>\n\n" +
+		"```\n" +
+		"{{.Error}}\n" +
+		"```\n</details>",
+	errWithLogTmpl:     "{{template \"error_unwrapped\" .}}{{template \"log\" .}}",
+	failureTmpl:        "**{{.Command}} Failed**: {{.Failure}}",
+	failureWithLogTmpl: "{{template \"failure\" .}}{{template \"log\" .}}",
+	logTmpl:            "{{if .Verbose}}\n<details><summary>Log</summary>\n  <p>\n\n```\n{{.Log}}```\n</p></details>{{end}}\n",
+}))
@@ -16,6 +16,9 @@ package events_test
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -210,10 +213,15 @@ $$$
 			},
 			models.Github,
 			`Ran Plan for 2 projects:
-1. workspace: $workspace$ dir: $path$
-1. workspace: $workspace$ dir: $path2$
 
-### 1. workspace: $workspace$ dir: $path$
+| Dir | Workspace | Status | Changes |
+|-----|-----------|--------|---------|
+| $path$ | $workspace$ | Planned |  |
+| $path2$ | $workspace$ | Planned |  |
+
+### dir: $path$ workspace: $workspace$
+<details><summary>Show Output</summary>
+
 $$$diff
 terraform-output
 $$$
@@ -223,9 +231,12 @@ $$$
 * :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
 * :repeat: To **plan** this project again, comment:
     * $atlantis plan -d path -w workspace$
+</details>
 
 ---
-### 2. workspace: $workspace$ dir: $path2$
+### dir: $path2$ workspace: $workspace$
+<details><summary>Show Output</summary>
+
 $$$diff
 terraform-output2
 $$$
@@ -235,6 +246,7 @@ $$$
 * :put_litter_in_its_place: To **delete** this plan click [here](lock-url2)
 * :repeat: To **plan** this project again, comment:
     * $atlantis plan -d path2 -w workspace$
+</details>
 
 ---
 * :fast_forward: To **apply** all unapplied plans from this pull request, comment:
@@ -258,19 +270,28 @@ $$$
 			},
 			models.Github,
 			`Ran Apply for 2 projects:
-1. workspace: $workspace$ dir: $path$
-1. workspace: $workspace$ dir: $path2$
 
-### 1. workspace: $workspace$ dir: $path$
+| Dir | Workspace | Status | Changes |
+|-----|-----------|--------|---------|
+| $path$ | $workspace$ | Applied |  |
+| $path2$ | $workspace$ | Applied |  |
+
+### dir: $path$ workspace: $workspace$
+<details><summary>Show Output</summary>
+
 $$$diff
 success
 $$$
+</details>
 
 ---
-### 2. workspace: $workspace$ dir: $path2$
+### dir: $path2$ workspace: $workspace$
+<details><summary>Show Output</summary>
+
 $$$diff
 success2
 $$$
+</details>
 
 ---
 
@@ -340,11 +361,16 @@ $$$
 			},
 			models.Github,
 			`Ran Plan for 3 projects:
-1. workspace: $workspace$ dir: $path$
-1. workspace: $workspace$ dir: $path2$
-1. workspace: $workspace$ dir: $path3$
 
-### 1. workspace: $workspace$ dir: $path$
+| Dir | Workspace | Status | Changes |
+|-----|-----------|--------|---------|
+| $path$ | $workspace$ | Planned |  |
+| $path2$ | $workspace$ | Failed |  |
+| $path3$ | $workspace$ | Error |  |
+
+### dir: $path$ workspace: $workspace$
+<details><summary>Show Output</summary>
+
 $$$diff
 terraform-output
 $$$
@@ -354,17 +380,21 @@ $$$
 * :put_litter_in_its_place: To **delete** this plan click [here](lock-url)
 * :repeat: To **plan** this project again, comment:
     * $atlantis plan -d path -w workspace$
+</details>
 
 ---
-### 2. workspace: $workspace$ dir: $path2$
+### dir: $path2$ workspace: $workspace$
 **Plan Failed**: failure
 
 ---
-### 3. workspace: $workspace$ dir: $path3$
+### dir: $path3$ workspace: $workspace$
 **Plan Error**
+<details><summary>Show Output</summary>
+
 $$$
 error
 $$$
+</details>
 
 ---
 * :fast_forward: To **apply** all unapplied plans from this pull request, comment:
@@ -393,25 +423,34 @@ $$$
 			},
 			models.Github,
 			`Ran Apply for 3 projects:
-1. workspace: $workspace$ dir: $path$
-1. workspace: $workspace$ dir: $path2$
-1. workspace: $workspace$ dir: $path3$
 
-### 1. workspace: $workspace$ dir: $path$
+| Dir | Workspace | Status | Changes |
+|-----|-----------|--------|---------|
+| $path$ | $workspace$ | Applied |  |
+| $path2$ | $workspace$ | Failed |  |
+| $path3$ | $workspace$ | Error |  |
+
+### dir: $path$ workspace: $workspace$
+<details><summary>Show Output</summary>
+
 $$$diff
 success
 $$$
+</details>
 
 ---
-### 2. workspace: $workspace$ dir: $path2$
+### dir: $path2$ workspace: $workspace$
 **Apply Failed**: failure
 
 ---
-### 3. workspace: $workspace$ dir: $path3$
+### dir: $path3$ workspace: $workspace$
 **Apply Error**
+<details><summary>Show Output</summary>
+
 $$$
 error
 $$$
+</details>
 
 ---
 
@@ -439,25 +478,34 @@ $$$
 			},
 			models.Github,
 			`Ran Apply for 3 projects:
-1. workspace: $workspace$ dir: $path$
-1. workspace: $workspace$ dir: $path2$
-1. workspace: $workspace$ dir: $path3$
 
-### 1. workspace: $workspace$ dir: $path$
+| Dir | Workspace | Status | Changes |
+|-----|-----------|--------|---------|
+| $path$ | $workspace$ | Applied |  |
+| $path2$ | $workspace$ | Failed |  |
+| $path3$ | $workspace$ | Error |  |
+
+### dir: $path$ workspace: $workspace$
+<details><summary>Show Output</summary>
+
 $$$diff
 success
 $$$
+</details>
 
 ---
-### 2. workspace: $workspace$ dir: $path2$
+### dir: $path2$ workspace: $workspace$
 **Apply Failed**: failure
 
 ---
-### 3. workspace: $workspace$ dir: $path3$
+### dir: $path3$ workspace: $workspace$
 **Apply Error**
+<details><summary>Show Output</summary>
+
 $$$
 error
 $$$
+</details>
 
 ---
 
@@ -783,10 +831,13 @@ func TestRenderProjectResults_MultiProjectApplyWrapped(t *testing.T) {
 		},
 	}, events.ApplyCommand, "log", false, models.Github)
 	exp := `Ran Apply for 2 projects:
-1. workspace: $staging$ dir: $.$
-1. workspace: $production$ dir: $.$
 
-### 1. workspace: $staging$ dir: $.$
+| Dir | Workspace | Status | Changes |
+|-----|-----------|--------|---------|
+| $.$ | $staging$ | Applied |  |
+| $.$ | $production$ | Applied |  |
+
+### dir: $.$ workspace: $staging$
 <details><summary>Show Output</summary>
 
 $$$diff
@@ -795,7 +846,7 @@ $$$
 </details>
 
 ---
-### 2. workspace: $production$ dir: $.$
+### dir: $.$ workspace: $production$
 <details><summary>Show Output</summary>
 
 $$$diff
@@ -838,10 +889,13 @@ func TestRenderProjectResults_MultiProjectPlanWrapped(t *testing.T) {
 		},
 	}, events.PlanCommand, "log", false, models.Github)
 	exp := `Ran Plan for 2 projects:
-1. workspace: $staging$ dir: $.$
-1. workspace: $production$ dir: $.$
 
-### 1. workspace: $staging$ dir: $.$
+| Dir | Workspace | Status | Changes |
+|-----|-----------|--------|---------|
+| $.$ | $staging$ | Planned |  |
+| $.$ | $production$ | Planned |  |
+
+### dir: $.$ workspace: $staging$
 <details><summary>Show Output</summary>
 
 $$$diff
@@ -856,7 +910,7 @@ $$$
 </details>
 
 ---
-### 2. workspace: $production$ dir: $.$
+### dir: $.$ workspace: $production$
 <details><summary>Show Output</summary>
 
 $$$diff
@@ -877,3 +931,286 @@ $$$
 	expWithBackticks := strings.Replace(exp, "$", "`", -1)
 	Equals(t, expWithBackticks, rendered)
 }
+
+func TestRenderProjectResults_MultiProjectNoChanges(t *testing.T) {
+	results := []events.ProjectResult{
+		{
+			RepoRelDir: "app",
+			Workspace:  "default",
+			PlanSuccess: &events.PlanSuccess{
+				TerraformOutput: "+ null_resource.app\nPlan: 1 to add, 0 to change, 0 to destroy.",
+				LockURL:         "app-lock-url",
+				ApplyCmd:        "app-apply-cmd",
+				RePlanCmd:       "app-replan-cmd",
+			},
+		},
+		{
+			RepoRelDir: "network",
+			Workspace:  "default",
+			PlanSuccess: &events.PlanSuccess{
+				TerraformOutput: "No changes. Infrastructure is up-to-date.",
+				LockURL:         "network-lock-url",
+				ApplyCmd:        "network-apply-cmd",
+				RePlanCmd:       "network-replan-cmd",
+			},
+		},
+		{
+			RepoRelDir: "dns",
+			Workspace:  "default",
+			PlanSuccess: &events.PlanSuccess{
+				TerraformOutput: "No changes. Your infrastructure matches the configuration.",
+				LockURL:         "dns-lock-url",
+				ApplyCmd:        "dns-apply-cmd",
+				RePlanCmd:       "dns-replan-cmd",
+			},
+		},
+	}
+	table := `Ran Plan for 3 projects:
+
+| Dir | Workspace | Status | Changes |
+|-----|-----------|--------|---------|
+| $app$ | $default$ | Planned | 1 to add, 0 to change, 0 to destroy |
+| $network$ | $default$ | No changes |  |
+| $dns$ | $default$ | No changes |  |
+
+`
+	applyAll := `* :fast_forward: To **apply** all unapplied plans from this pull request, comment:
+    * $atlantis apply$
+`
+
+	t.Log("hosts that support <details> should collapse the projects with no changes")
+	mr := events.MarkdownRenderer{}
+	rendered := mr.Render(events.CommandResult{ProjectResults: results}, events.PlanCommand, "log", false, models.Github)
+	exp := table + `### dir: $app$ workspace: $default$
+<details><summary>Show Output</summary>
+
+$$$diff
++ null_resource.app
+Plan: 1 to add, 0 to change, 0 to destroy.
+$$$
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $app-apply-cmd$
+* :put_litter_in_its_place: To **delete** this plan click [here](app-lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $app-replan-cmd$
+</details>
+
+---
+<details><summary>2 projects have no changes</summary>
+
+### dir: $network$ workspace: $default$
+<details><summary>Show Output</summary>
+
+$$$diff
+No changes. Infrastructure is up-to-date.
+$$$
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $network-apply-cmd$
+* :put_litter_in_its_place: To **delete** this plan click [here](network-lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $network-replan-cmd$
+</details>
+
+---
+### dir: $dns$ workspace: $default$
+<details><summary>Show Output</summary>
+
+$$$diff
+No changes. Your infrastructure matches the configuration.
+$$$
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $dns-apply-cmd$
+* :put_litter_in_its_place: To **delete** this plan click [here](dns-lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $dns-replan-cmd$
+</details>
+
+---
+</details>
+
+` + applyAll
+	Equals(t, strings.Replace(exp, "$", "`", -1), rendered)
+
+	t.Log("hosts that don't support <details> should list the projects with no changes on one line")
+	for _, host := range []models.VCSHostType{models.Gitlab, models.BitbucketCloud, models.BitbucketServer} {
+		rendered = mr.Render(events.CommandResult{ProjectResults: results}, events.PlanCommand, "log", false, host)
+		exp = table + `### dir: $app$ workspace: $default$
+$$$diff
++ null_resource.app
+Plan: 1 to add, 0 to change, 0 to destroy.
+$$$
+
+* :arrow_forward: To **apply** this plan, comment:
+    * $app-apply-cmd$
+* :put_litter_in_its_place: To **delete** this plan click [here](app-lock-url)
+* :repeat: To **plan** this project again, comment:
+    * $app-replan-cmd$
+
+---
+2 projects have no changes: dir: $network$ workspace: $default$, dir: $dns$ workspace: $default$
+
+---
+` + applyAll
+		Equals(t, strings.Replace(exp, "$", "`", -1), rendered)
+	}
+}
+
+func TestRenderProjectResults_MultiProjectApplyNoChanges(t *testing.T) {
+	mr := events.MarkdownRenderer{GitlabSupportsCommonMark: true}
+	rendered := mr.Render(events.CommandResult{
+		ProjectResults: []events.ProjectResult{
+			{
+				RepoRelDir:   "app",
+				Workspace:    "default",
+				ApplySuccess: "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.",
+			},
+			{
+				RepoRelDir:   "network",
+				Workspace:    "default",
+				ApplySuccess: "Apply complete! Resources: 0 added, 0 changed, 0 destroyed.",
+			},
+		},
+	}, events.ApplyCommand, "log", false, models.Gitlab)
+	exp := `Ran Apply for 2 projects:
+
+| Dir | Workspace | Status | Changes |
+|-----|-----------|--------|---------|
+| $app$ | $default$ | Applied | 1 added, 0 changed, 0 destroyed |
+| $network$ | $default$ | No changes | 0 added, 0 changed, 0 destroyed |
+
+### dir: $app$ workspace: $default$
+<details><summary>Show Output</summary>
+
+$$$diff
+Apply complete! Resources: 1 added, 0 changed, 0 destroyed.
+$$$
+</details>
+
+---
+<details><summary>1 project has no changes</summary>
+
+### dir: $network$ workspace: $default$
+<details><summary>Show Output</summary>
+
+$$$diff
+Apply complete! Resources: 0 added, 0 changed, 0 destroyed.
+$$$
+</details>
+
+---
+</details>
+
+
+`
+	Equals(t, strings.Replace(exp, "$", "`", -1), rendered)
+}
+
+// writeTemplateOverrides writes files, by path, to a new directory.
+func writeTemplateOverrides(t *testing.T, files map[string]string) (string, func()) {
+	dir, cleanup := TempDir(t)
+	for path, contents := range files {
+		Ok(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, path)), 0700))
+		Ok(t, ioutil.WriteFile(filepath.Join(dir, path), []byte(contents), 0600))
+	}
+	return dir, cleanup
+}
+
+func TestNewMarkdownRenderer_TemplateOverrides(t *testing.T) {
+	dir, cleanup := writeTemplateOverrides(t, map[string]string{
+		"error_with_log.tmpl":         "**{{.Command}} Error**: {{.Error}}\nSee the [runbook](https://runbook).{{template \"log\" .}}",
+		"failure.tmpl":                "**{{.Command}} Failed** :x: {{.Failure}}",
+		"gitlab/failure.tmpl":         "**{{.Command}} Failed** on GitLab: {{.Failure}}",
+		"bitbucketcloud/README.md":    "not a template",
+		"apply_success_unwrapped.txt": "not a template",
+	})
+	defer cleanup()
+	r, err := events.NewMarkdownRenderer(false, dir)
+	Ok(t, err)
+
+	t.Log("overrides should be used for every VCS host")
+	for _, host := range []models.VCSHostType{models.Github, models.Gitlab, models.BitbucketCloud, models.BitbucketServer} {
+		s := r.Render(events.CommandResult{Error: errors.New("err")}, events.PlanCommand, "log", true, host)
+		Equals(t, "**Plan Error**: err\nSee the [runbook](https://runbook).\n<details><summary>Log</summary>\n  <p>\n\n```\nlog```\n</p></details>\n", s)
+	}
+
+	t.Log("templates included by the defaults should be overridden")
+	s := r.Render(events.CommandResult{ProjectResults: []events.ProjectResult{{
+		Workspace:  "default",
+		RepoRelDir: ".",
+		Failure:    "failure",
+	}}}, events.PlanCommand, "", false, models.Github)
+	Equals(t, "Ran Plan in dir: `.` workspace: `default`\n\n**Plan Failed** :x: failure\n\n", s)
+
+	t.Log("host overrides should only be used for that host")
+	s = r.Render(events.CommandResult{ProjectResults: []events.ProjectResult{{
+		Workspace:  "default",
+		RepoRelDir: ".",
+		Failure:    "failure",
+	}}}, events.PlanCommand, "", false, models.Gitlab)
+	Equals(t, "Ran Plan in dir: `.` workspace: `default`\n\n**Plan Failed** on GitLab: failure\n\n", s)
+
+	t.Log("templates that aren't overridden should be the defaults")
+	s = r.Render(events.CommandResult{ProjectResults: []events.ProjectResult{{
+		Workspace:    "default",
+		RepoRelDir:   ".",
+		ApplySuccess: "success",
+	}}}, events.ApplyCommand, "", false, models.Github)
+	Equals(t, "Ran Apply in dir: `.` workspace: `default`\n\n```diff\nsuccess\n```\n\n", s)
+}
+
+func TestNewMarkdownRenderer_NoOverrides(t *testing.T) {
+	r, err := events.NewMarkdownRenderer(true, "")
+	Ok(t, err)
+	s := r.Render(events.CommandResult{Failure: "failure"}, events.ApplyCommand, "", false, models.Gitlab)
+	Equals(t, "**Apply Failed**: failure\n", s)
+}
+
+func TestNewMarkdownRenderer_InvalidOverrides(t *testing.T) {
+	cases := []struct {
+		Description string
+		Files       map[string]string
+		ExpErr      string
+	}{
+		{
+			"unknown field",
+			map[string]string{"single_project_apply.tmpl": "{{.Output}}"},
+			`validating Github templates: template: single_project_apply:1:2: executing "single_project_apply" at <.Output>: can't evaluate field Output in type events.ResultData`,
+		},
+		{
+			"unknown field in host override",
+			map[string]string{"bitbucketserver/failure_with_log.tmpl": "{{.Error}}"},
+			`validating BitbucketServer templates: template: failure_with_log:1:2: executing "failure_with_log" at <.Error>: can't evaluate field Error in type events.FailureData`,
+		},
+		{
+			"parse error",
+			map[string]string{"log.tmpl": "{{if .Verbose}}"},
+			`parsing template "log": template: log:1: unexpected EOF`,
+		},
+		{
+			"unknown template",
+			map[string]string{"plan.tmpl": ""},
+			`unknown template "DIR/plan.tmpl", expected one of apply_success_unwrapped, apply_success_wrapped, error_unwrapped, error_with_log, error_wrapped, failure, failure_with_log, log, multi_project_apply, multi_project_no_changes, multi_project_plan, multi_project_result, multi_project_summary, plan_next_steps, plan_success_unwrapped, plan_success_wrapped, single_project_apply, single_project_plan_success, single_project_plan_unsuccessful`,
+		},
+		{
+			"unknown host",
+			map[string]string{"github.com/log.tmpl": ""},
+			`unknown template overrides directory "DIR/github.com", expected one of github, gitlab, bitbucketcloud, bitbucketserver`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.Description, func(t *testing.T) {
+			dir, cleanup := writeTemplateOverrides(t, c.Files)
+			defer cleanup()
+			_, err := events.NewMarkdownRenderer(false, dir)
+			ErrEquals(t, strings.Replace(c.ExpErr, "DIR", dir, -1), err)
+		})
+	}
+}
+
+func TestNewMarkdownRenderer_MissingDir(t *testing.T) {
+	_, err := events.NewMarkdownRenderer(false, "/does/not/exist")
+	ErrEquals(t, "reading template overrides: open /does/not/exist: no such file or directory", err)
+}
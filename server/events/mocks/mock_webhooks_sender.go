@@ -31,6 +31,18 @@ func (mock *MockWebhooksSender) Send(log *logging.SimpleLogger, res webhooks.App
 	return ret0
 }
 
+func (mock *MockWebhooksSender) SendPlan(log *logging.SimpleLogger, res webhooks.PlanResult) error {
+	params := []pegomock.Param{log, res}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("SendPlan", params, []reflect.Type{reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(error)
+		}
+	}
+	return ret0
+}
+
 func (mock *MockWebhooksSender) VerifyWasCalledOnce() *VerifierWebhooksSender {
 	return &VerifierWebhooksSender{mock, pegomock.Times(1), nil}
 }
@@ -79,3 +91,34 @@ func (c *WebhooksSender_Send_OngoingVerification) GetAllCapturedArguments() (_pa
 	}
 	return
 }
+
+func (verifier *VerifierWebhooksSender) SendPlan(log *logging.SimpleLogger, res webhooks.PlanResult) *WebhooksSender_SendPlan_OngoingVerification {
+	params := []pegomock.Param{log, res}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "SendPlan", params)
+	return &WebhooksSender_SendPlan_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type WebhooksSender_SendPlan_OngoingVerification struct {
+	mock              *MockWebhooksSender
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *WebhooksSender_SendPlan_OngoingVerification) GetCapturedArguments() (*logging.SimpleLogger, webhooks.PlanResult) {
+	log, res := c.GetAllCapturedArguments()
+	return log[len(log)-1], res[len(res)-1]
+}
+
+func (c *WebhooksSender_SendPlan_OngoingVerification) GetAllCapturedArguments() (_param0 []*logging.SimpleLogger, _param1 []webhooks.PlanResult) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]*logging.SimpleLogger, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(*logging.SimpleLogger)
+		}
+		_param1 = make([]webhooks.PlanResult, len(params[1]))
+		for u, param := range params[1] {
+			_param1[u] = param.(webhooks.PlanResult)
+		}
+	}
+	return
+}
@@ -17,6 +17,7 @@
 package models
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	paths "path"
@@ -120,6 +121,13 @@ func NewRepo(vcsHostType VCSHostType, repoFullName string, cloneURL string, vcsU
 	}, nil
 }
 
+// ID returns a string that identifies the repo across VCS hosts, ex.
+// "Github/github.com/runatlantis/atlantis". Use it instead of FullName to key
+// data for repos that may be on different hosts.
+func (r Repo) ID() string {
+	return fmt.Sprintf("%s/%s/%s", r.VCSHost.Type, r.VCSHost.Hostname, r.FullName)
+}
+
 // PullRequest is a VCS pull request.
 // GitLab calls these Merge Requests.
 type PullRequest struct {
@@ -135,6 +143,9 @@ type PullRequest struct {
 	URL string
 	// Branch is the name of the head branch (not the base).
 	Branch string
+	// Title is the title of the pull request. It can be empty, ex. for
+	// pull requests from Bitbucket webhooks that don't include it.
+	Title string
 	// Author is the username of the pull request author.
 	Author string
 	// State will be one of Open or Closed.
@@ -196,6 +207,14 @@ type ProjectLock struct {
 	Workspace string
 	// Time is the time at which the lock was first created.
 	Time time.Time
+	// ExpiresAt is when the lock expires. It's zero if the lock never
+	// expires.
+	ExpiresAt time.Time
+}
+
+// Expired returns true if the lock has an expiry that has passed.
+func (l ProjectLock) Expired(now time.Time) bool {
+	return !l.ExpiresAt.IsZero() && now.After(l.ExpiresAt)
 }
 
 // Project represents a Terraform project. Since there may be multiple
@@ -211,6 +230,15 @@ type Project struct {
 	// out how this is saved in boltdb vs. its usage everywhere else so we don't
 	// break existing dbs.
 	Path string
+	// LockKey, if set, is what the project is locked on instead of its repo
+	// and path, ex. "s3://bucket/key" for a project whose Terraform state is
+	// in S3. Projects with the same LockKey can't be locked at the same time.
+	// It always contains "://" so it can't be mistaken for a repo name.
+	LockKey string
+	// VCSHost is where the project's repo is hosted. It's part of the
+	// project's lock so repos with the same name on different hosts don't
+	// share locks. It's empty for locks created by old versions of Atlantis.
+	VCSHost VCSHost
 }
 
 func (p Project) String() string {
@@ -301,6 +329,11 @@ type ProjectCommandContext struct {
 	// ApplyCmd is the command that users should run to apply this plan. If
 	// this is an apply then this will be empty.
 	ApplyCmd string
+	// Context is cancelled when the command should stop running, for example
+	// because it was cancelled via a comment or its step timed out. Commands
+	// started by steps should be interrupted when it's done.
+	// If nil, the command can't be cancelled.
+	Context context.Context
 }
 
 // SplitRepoFullName splits a repo full name up into its owner and repo name
@@ -140,6 +140,16 @@ func TestNewRepo_HTTPSAuth(t *testing.T) {
 	}, repo)
 }
 
+func TestRepo_ID(t *testing.T) {
+	Equals(t, "Gitlab/gitlab.example.com/group/subgroup/repo", (models.Repo{
+		FullName: "group/subgroup/repo",
+		VCSHost: models.VCSHost{
+			Hostname: "gitlab.example.com",
+			Type:     models.Gitlab,
+		},
+	}).ID())
+}
+
 func TestProject_String(t *testing.T) {
 	Equals(t, "repofullname=owner/repo path=my/path", (models.Project{
 		RepoFullName: "owner/repo",
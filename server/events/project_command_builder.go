@@ -1,6 +1,7 @@
 package events
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/hashicorp/go-version"
@@ -56,7 +57,7 @@ type DefaultProjectCommandBuilder struct {
 // TFCommandRunner runs Terraform commands.
 type TFCommandRunner interface {
 	// RunCommandWithVersion runs a Terraform command using the version v.
-	RunCommandWithVersion(log *logging.SimpleLogger, path string, args []string, v *version.Version, workspace string) (string, error)
+	RunCommandWithVersion(ctx context.Context, log *logging.SimpleLogger, path string, args []string, v *version.Version, workspace string) (string, error)
 }
 
 // BuildAutoplanCommands builds project commands that will run plan on
@@ -81,7 +82,7 @@ func (p *DefaultProjectCommandBuilder) BuildAutoplanCommands(ctx *CommandContext
 func (p *DefaultProjectCommandBuilder) buildPlanAllCommands(ctx *CommandContext, commentFlags []string, verbose bool) ([]models.ProjectCommandContext, error) {
 	// Need to lock the workspace we're about to clone to.
 	workspace := DefaultWorkspace
-	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo.FullName, ctx.Pull.Num, workspace)
+	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo, ctx.Pull.Num, workspace)
 	if err != nil {
 		ctx.Log.Warn("workspace was locked")
 		return nil, err
@@ -186,7 +187,7 @@ func (p *DefaultProjectCommandBuilder) buildProjectPlanCommand(ctx *CommandConte
 
 	var pcc models.ProjectCommandContext
 	ctx.Log.Debug("building plan command")
-	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo.FullName, ctx.Pull.Num, workspace)
+	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo, ctx.Pull.Num, workspace)
 	if err != nil {
 		return pcc, err
 	}
@@ -222,7 +223,7 @@ func (p *DefaultProjectCommandBuilder) BuildPlanCommands(ctx *CommandContext, cm
 
 func (p *DefaultProjectCommandBuilder) buildApplyAllCommands(ctx *CommandContext, commentCmd *CommentCommand) ([]models.ProjectCommandContext, error) {
 	// lock all dirs in this pull request
-	unlockFn, err := p.WorkingDirLocker.TryLockPull(ctx.BaseRepo.FullName, ctx.Pull.Num)
+	unlockFn, err := p.WorkingDirLocker.TryLockPull(ctx.BaseRepo, ctx.Pull.Num)
 	if err != nil {
 		return nil, err
 	}
@@ -270,7 +271,7 @@ func (p *DefaultProjectCommandBuilder) buildProjectApplyCommand(ctx *CommandCont
 	}
 
 	var projCtx models.ProjectCommandContext
-	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo.FullName, ctx.Pull.Num, workspace)
+	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo, ctx.Pull.Num, workspace)
 	if err != nil {
 		return projCtx, err
 	}
@@ -14,14 +14,18 @@
 package events
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/terraform"
 	"github.com/runatlantis/atlantis/server/events/webhooks"
 	"github.com/runatlantis/atlantis/server/events/yaml/raw"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
@@ -45,12 +49,23 @@ type StepRunner interface {
 	Run(ctx models.ProjectCommandContext, extraArgs []string, path string) (string, error)
 }
 
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_env_step_runner.go EnvStepRunner
+
+// EnvStepRunner runs env steps.
+type EnvStepRunner interface {
+	// Run returns the value of the env var set by the step. If command is
+	// set, it's run and its output is the value. Otherwise value is.
+	Run(ctx models.ProjectCommandContext, command []string, value string, path string) (string, error)
+}
+
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_webhooks_sender.go WebhooksSender
 
 // WebhooksSender sends webhook.
 type WebhooksSender interface {
 	// Send sends the webhook.
 	Send(log *logging.SimpleLogger, res webhooks.ApplyResult) error
+	// SendPlan sends the plan webhook.
+	SendPlan(log *logging.SimpleLogger, res webhooks.PlanResult) error
 }
 
 // PlanSuccess is the result of a successful plan.
@@ -84,11 +99,25 @@ type DefaultProjectCommandRunner struct {
 	PlanStepRunner          StepRunner
 	ApplyStepRunner         StepRunner
 	RunStepRunner           StepRunner
+	EnvStepRunner           EnvStepRunner
 	PullApprovedChecker     runtime.PullApprovedChecker
 	WorkingDir              WorkingDir
 	Webhooks                WebhooksSender
 	WorkingDirLocker        WorkingDirLocker
 	RequireApprovalOverride bool
+	// OutputStore is optional. If set, the output of each step is streamed
+	// to it as the step runs.
+	OutputStore *ProjectOutputStore
+	// CommandAuthorizer is optional. If set, it's checked before running plan
+	// or apply.
+	CommandAuthorizer CommandAuthorizer
+	// LockKeyStrategy is what projects without a lock_key in atlantis.yaml
+	// are locked on. One of DirLockKeyStrategy, the default, or
+	// BackendLockKeyStrategy.
+	LockKeyStrategy string
+	// OutputURLGenerator is optional. If set, webhooks link to the output
+	// of the pull request's commands.
+	OutputURLGenerator PullOutputURLGenerator
 }
 
 // Plan runs terraform plan for the project described by ctx.
@@ -100,6 +129,7 @@ func (p *DefaultProjectCommandRunner) Plan(ctx models.ProjectCommandContext) Pro
 		Failure:     failure,
 		RepoRelDir:  ctx.RepoRelDir,
 		Workspace:   ctx.Workspace,
+		ProjectName: projectName(ctx),
 	}
 }
 
@@ -112,37 +142,45 @@ func (p *DefaultProjectCommandRunner) Apply(ctx models.ProjectCommandContext) Pr
 		ApplySuccess: applyOut,
 		RepoRelDir:   ctx.RepoRelDir,
 		Workspace:    ctx.Workspace,
+		ProjectName:  projectName(ctx),
 	}
 }
 
 func (p *DefaultProjectCommandRunner) doPlan(ctx models.ProjectCommandContext) (*PlanSuccess, string, error) {
-	// Acquire Atlantis lock for this repo/dir/workspace.
-	lockAttempt, err := p.Locker.TryLock(ctx.Log, ctx.Pull, ctx.User, ctx.Workspace, models.NewProject(ctx.BaseRepo.FullName, ctx.RepoRelDir))
-	if err != nil {
-		return nil, "", errors.Wrap(err, "acquiring lock")
+	if failure, err := p.authorize(ctx, PlanCommand); failure != "" || err != nil {
+		return nil, failure, err
 	}
-	if !lockAttempt.LockAcquired {
-		return nil, lockAttempt.LockFailureReason, nil
-	}
-	ctx.Log.Debug("acquired lock for project")
 
 	// Acquire internal lock for the directory we're going to operate in.
-	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace)
+	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo, ctx.Pull.Num, ctx.Workspace)
 	if err != nil {
 		return nil, "", err
 	}
 	defer unlockFn()
 
 	// Clone is idempotent so okay to run even if the repo was already cloned.
-	repoDir, cloneErr := p.WorkingDir.Clone(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, ctx.Workspace)
-	if cloneErr != nil {
-		if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
-			ctx.Log.Err("error unlocking state after plan error: %v", unlockErr)
-		}
-		return nil, "", cloneErr
+	// We clone before locking since the project's backend config might be
+	// needed to know what to lock.
+	repoDir, err := p.WorkingDir.Clone(ctx.Log, ctx.BaseRepo, ctx.HeadRepo, ctx.Pull, ctx.Workspace)
+	if err != nil {
+		return nil, "", err
 	}
 	projAbsPath := filepath.Join(repoDir, ctx.RepoRelDir)
 
+	// Acquire Atlantis lock for this project and workspace.
+	project, err := p.lockProject(ctx, projAbsPath)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "determining lock key")
+	}
+	lockAttempt, err := p.Locker.TryLock(ctx.Log, ctx.Pull, ctx.User, ctx.Workspace, project)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "acquiring lock")
+	}
+	if !lockAttempt.LockAcquired {
+		return nil, lockAttempt.LockFailureReason, nil
+	}
+	ctx.Log.Debug("acquired lock for project")
+
 	// Use default stage unless another workflow is defined in config
 	stage := p.defaultPlanStage()
 	if ctx.ProjectConfig != nil && ctx.ProjectConfig.Workflow != nil {
@@ -153,7 +191,21 @@ func (p *DefaultProjectCommandRunner) doPlan(ctx models.ProjectCommandContext) (
 			stage = *configuredStage
 		}
 	}
-	outputs, err := p.runSteps(stage.Steps, ctx, projAbsPath)
+	outputs, err := p.runSteps(stage.Steps, ctx, projAbsPath, PlanCommand)
+	planResult := webhooks.PlanResult{
+		Workspace:   ctx.Workspace,
+		User:        ctx.User,
+		Repo:        ctx.BaseRepo,
+		Pull:        ctx.Pull,
+		RepoRelDir:  ctx.RepoRelDir,
+		ProjectName: projectName(ctx),
+		Success:     err == nil,
+		OutputURL:   p.outputURL(ctx),
+	}
+	if err == nil {
+		planResult.Summary = terraformSummary(outputs)
+	}
+	p.Webhooks.SendPlan(ctx.Log, planResult) // nolint: errcheck
 	if err != nil {
 		if unlockErr := lockAttempt.UnlockFn(); unlockErr != nil {
 			ctx.Log.Err("error unlocking state after plan error: %v", unlockErr)
@@ -169,21 +221,76 @@ func (p *DefaultProjectCommandRunner) doPlan(ctx models.ProjectCommandContext) (
 	}, "", nil
 }
 
-func (p *DefaultProjectCommandRunner) runSteps(steps []valid.Step, ctx models.ProjectCommandContext, absPath string) ([]string, error) {
+// lockProject returns the project to lock for ctx. It's locked on its lock_key
+// from atlantis.yaml if set. Otherwise, if LockKeyStrategy is
+// BackendLockKeyStrategy, it's locked on its Terraform state backend if that
+// can be determined from the .tf files in projAbsPath. Otherwise it's locked
+// on its repo and dir.
+func (p *DefaultProjectCommandRunner) lockProject(ctx models.ProjectCommandContext, projAbsPath string) (models.Project, error) {
+	project := models.NewProject(ctx.BaseRepo.FullName, ctx.RepoRelDir)
+	project.VCSHost = ctx.BaseRepo.VCSHost
+	if ctx.ProjectConfig != nil && ctx.ProjectConfig.LockKey != "" {
+		project.LockKey = overrideLockKey(ctx.ProjectConfig.LockKey)
+		return project, nil
+	}
+	if p.LockKeyStrategy != BackendLockKeyStrategy {
+		return project, nil
+	}
+	lockKey, err := backendLockKey(projAbsPath)
+	if err != nil {
+		return project, err
+	}
+	if lockKey == "" {
+		ctx.Log.Debug("couldn't determine the project's backend so locking on its dir")
+	}
+	project.LockKey = lockKey
+	return project, nil
+}
+
+func (p *DefaultProjectCommandRunner) runSteps(steps []valid.Step, ctx models.ProjectCommandContext, absPath string, cmdName CommandName) ([]string, error) {
+	var timeout time.Duration
+	if ctx.ProjectConfig != nil {
+		timeout = ctx.ProjectConfig.StepTimeout
+	}
+	parentCtx := ctx.Context
+	if parentCtx == nil {
+		parentCtx = context.Background()
+	}
+	if p.OutputStore != nil {
+		buf := p.OutputStore.Start(ctx, cmdName)
+		defer buf.Close()
+		parentCtx = terraform.WithOutputWriter(parentCtx, buf)
+		ctx.Context = parentCtx
+	}
+
+	// env holds the env vars that describe the pull request and project, the
+	// env vars from the project's config and those from the env steps that
+	// have run so far. They're exported to the steps after them.
+	env := runtime.StepEnv(ctx, absPath)
+	if ctx.ProjectConfig != nil {
+		for name, value := range ctx.ProjectConfig.Env {
+			env[name] = value
+		}
+	}
+
 	var outputs []string
 	for _, step := range steps {
-		var out string
-		var err error
-		switch step.StepName {
-		case "init":
-			out, err = p.InitStepRunner.Run(ctx, step.ExtraArgs, absPath)
-		case "plan":
-			out, err = p.PlanStepRunner.Run(ctx, step.ExtraArgs, absPath)
-		case "apply":
-			out, err = p.ApplyStepRunner.Run(ctx, step.ExtraArgs, absPath)
-		case "run":
-			out, err = p.RunStepRunner.Run(ctx, step.RunCommand, absPath)
+		stepCtx := ctx
+		stepCtx.Context = terraform.WithEnv(parentCtx, env)
+		cancel := func() {}
+		if timeout > 0 {
+			stepCtx.Context, cancel = context.WithTimeout(stepCtx.Context, timeout)
+		}
+		out, err := p.runStep(step, stepCtx, absPath, env)
+		if err != nil && stepCtx.Context != nil {
+			switch stepCtx.Context.Err() {
+			case context.DeadlineExceeded:
+				err = fmt.Errorf("%s step timed out after %s: %s", step.StepName, timeout, err)
+			case context.Canceled:
+				err = fmt.Errorf("%s step was cancelled: %s", step.StepName, err)
+			}
 		}
+		cancel()
 
 		if out != "" {
 			outputs = append(outputs, out)
@@ -195,7 +302,38 @@ func (p *DefaultProjectCommandRunner) runSteps(steps []valid.Step, ctx models.Pr
 	return outputs, nil
 }
 
+// runStep runs step. Its extra_args are rendered with env. If it's an env
+// step, the env var it sets is added to env.
+func (p *DefaultProjectCommandRunner) runStep(step valid.Step, ctx models.ProjectCommandContext, absPath string, env map[string]string) (string, error) {
+	extraArgs, err := runtime.RenderExtraArgs(step.ExtraArgs, env)
+	if err != nil {
+		return "", err
+	}
+	switch step.StepName {
+	case "init":
+		return p.InitStepRunner.Run(ctx, extraArgs, absPath)
+	case "plan":
+		return p.PlanStepRunner.Run(ctx, extraArgs, absPath)
+	case "apply":
+		return p.ApplyStepRunner.Run(ctx, extraArgs, absPath)
+	case "run":
+		return p.RunStepRunner.Run(ctx, step.RunCommand, absPath)
+	case "env":
+		value, err := p.EnvStepRunner.Run(ctx, step.RunCommand, step.EnvVarValue, absPath)
+		if err != nil {
+			return "", err
+		}
+		env[step.EnvVarName] = value
+		return "", nil
+	}
+	return "", nil
+}
+
 func (p *DefaultProjectCommandRunner) doApply(ctx models.ProjectCommandContext) (applyOut string, failure string, err error) {
+	if failure, err = p.authorize(ctx, ApplyCommand); failure != "" || err != nil {
+		return "", failure, err
+	}
+
 	repoDir, err := p.WorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -227,7 +365,7 @@ func (p *DefaultProjectCommandRunner) doApply(ctx models.ProjectCommandContext)
 		}
 	}
 	// Acquire internal lock for the directory we're going to operate in.
-	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo.FullName, ctx.Pull.Num, ctx.Workspace)
+	unlockFn, err := p.WorkingDirLocker.TryLock(ctx.BaseRepo, ctx.Pull.Num, ctx.Workspace)
 	if err != nil {
 		return "", "", err
 	}
@@ -241,20 +379,75 @@ func (p *DefaultProjectCommandRunner) doApply(ctx models.ProjectCommandContext)
 			stage = *configuredStage
 		}
 	}
-	outputs, err := p.runSteps(stage.Steps, ctx, absPath)
-	p.Webhooks.Send(ctx.Log, webhooks.ApplyResult{ // nolint: errcheck
-		Workspace: ctx.Workspace,
-		User:      ctx.User,
-		Repo:      ctx.BaseRepo,
-		Pull:      ctx.Pull,
-		Success:   err == nil,
-	})
+	outputs, err := p.runSteps(stage.Steps, ctx, absPath, ApplyCommand)
+	applyResult := webhooks.ApplyResult{
+		Workspace:   ctx.Workspace,
+		User:        ctx.User,
+		Repo:        ctx.BaseRepo,
+		Pull:        ctx.Pull,
+		RepoRelDir:  ctx.RepoRelDir,
+		ProjectName: projectName(ctx),
+		Success:     err == nil,
+		OutputURL:   p.outputURL(ctx),
+	}
+	if err == nil {
+		applyResult.Summary = terraformSummary(outputs)
+	}
+	p.Webhooks.Send(ctx.Log, applyResult) // nolint: errcheck
 	if err != nil {
 		return "", "", fmt.Errorf("%s\n%s", err, strings.Join(outputs, "\n"))
 	}
 	return strings.Join(outputs, "\n"), "", nil
 }
 
+// summaryRegex matches the lines Terraform summarizes plans and applies with.
+var summaryRegex = regexp.MustCompile(`(?m)^\s*((Plan: \d+ to add.*)|(No changes\..*)|(Apply complete!.*))$`)
+
+// terraformSummary returns the last summary line in the output of a plan or
+// apply's steps, ex. "Plan: 1 to add, 0 to change, 0 to destroy.", or an
+// empty string if there isn't one.
+func terraformSummary(outputs []string) string {
+	matches := summaryRegex.FindAllStringSubmatch(strings.Join(outputs, "\n"), -1)
+	if len(matches) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(matches[len(matches)-1][1])
+}
+
+// projectName returns the name of ctx's project or an empty string if it
+// doesn't have one.
+func projectName(ctx models.ProjectCommandContext) string {
+	if ctx.ProjectConfig == nil {
+		return ""
+	}
+	return ctx.ProjectConfig.GetName()
+}
+
+// outputURL returns the URL of the output of ctx's pull request or an empty
+// string if there's no OutputURLGenerator.
+func (p *DefaultProjectCommandRunner) outputURL(ctx models.ProjectCommandContext) string {
+	if p.OutputURLGenerator == nil {
+		return ""
+	}
+	return p.OutputURLGenerator.GeneratePullOutputURL(ctx.BaseRepo, ctx.Pull.Num)
+}
+
+// authorize returns a failure if ctx.User isn't allowed to run cmdName on the
+// project.
+func (p *DefaultProjectCommandRunner) authorize(ctx models.ProjectCommandContext, cmdName CommandName) (string, error) {
+	if p.CommandAuthorizer == nil {
+		return "", nil
+	}
+	failure, err := p.CommandAuthorizer.Authorize(ctx, cmdName)
+	if err != nil {
+		return "", errors.Wrap(err, "checking authorization")
+	}
+	if failure != "" {
+		ctx.Log.Info("%s isn't authorized to run %s", ctx.User.Username, cmdName.String())
+	}
+	return failure, nil
+}
+
 func (p DefaultProjectCommandRunner) defaultPlanStage() valid.Stage {
 	return valid.Stage{
 		Steps: []valid.Step{
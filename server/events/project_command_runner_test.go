@@ -14,16 +14,25 @@
 package events_test
 
 import (
+	"context"
+	"io/ioutil"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/go-version"
 	. "github.com/petergtz/pegomock"
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/mocks"
 	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
 	mocks2 "github.com/runatlantis/atlantis/server/events/runtime/mocks"
+	"github.com/runatlantis/atlantis/server/events/terraform"
+	"github.com/runatlantis/atlantis/server/events/webhooks"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
 	. "github.com/runatlantis/atlantis/testing"
@@ -142,7 +151,7 @@ func TestDefaultProjectCommandRunner_Plan(t *testing.T) {
 				RunStepRunner:       mockRun,
 				PullApprovedChecker: nil,
 				WorkingDir:          mockWorkingDir,
-				Webhooks:            nil,
+				Webhooks:            mocks.NewMockWebhooksSender(),
 				WorkingDirLocker:    events.NewDefaultWorkingDirLocker(),
 			}
 
@@ -172,10 +181,10 @@ func TestDefaultProjectCommandRunner_Plan(t *testing.T) {
 				GlobalConfig:  c.globalCfg,
 				RepoRelDir:    ".",
 			}
-			When(mockInit.Run(ctx, nil, repoDir)).ThenReturn("init", nil)
-			When(mockPlan.Run(ctx, nil, repoDir)).ThenReturn("plan", nil)
-			When(mockApply.Run(ctx, nil, repoDir)).ThenReturn("apply", nil)
-			When(mockRun.Run(ctx, nil, repoDir)).ThenReturn("run", nil)
+			When(mockInit.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), EqString(repoDir))).ThenReturn("init", nil)
+			When(mockPlan.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), EqString(repoDir))).ThenReturn("plan", nil)
+			When(mockApply.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), EqString(repoDir))).ThenReturn("apply", nil)
+			When(mockRun.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), EqString(repoDir))).ThenReturn("run", nil)
 
 			res := runner.Plan(ctx)
 
@@ -186,23 +195,392 @@ func TestDefaultProjectCommandRunner_Plan(t *testing.T) {
 			for _, step := range c.expSteps {
 				switch step {
 				case "init":
-					mockInit.VerifyWasCalledOnce().Run(ctx, nil, repoDir)
+					mockInit.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), EqString(repoDir))
 				case "plan":
-					mockPlan.VerifyWasCalledOnce().Run(ctx, nil, repoDir)
+					mockPlan.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), EqString(repoDir))
 				case "apply":
-					mockApply.VerifyWasCalledOnce().Run(ctx, nil, repoDir)
+					mockApply.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), EqString(repoDir))
 				case "run":
-					mockRun.VerifyWasCalledOnce().Run(ctx, nil, repoDir)
+					mockRun.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), EqString(repoDir))
 				}
 			}
 		})
 	}
 }
 
+// blockingStepRunner is a StepRunner that blocks until its context is done.
+type blockingStepRunner struct{}
+
+func (b blockingStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string) (string, error) {
+	<-ctx.Context.Done()
+	return "partial output", ctx.Context.Err()
+}
+
+func TestDefaultProjectCommandRunner_PlanStepTimeout(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	mockPlan := mocks.NewMockStepRunner()
+	runner := events.DefaultProjectCommandRunner{
+		Locker:           mockLocker,
+		LockURLGenerator: mockURLGenerator{},
+		InitStepRunner:   blockingStepRunner{},
+		PlanStepRunner:   mockPlan,
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		Webhooks:         mocks.NewMockWebhooksSender(),
+	}
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn("/tmp/mydir", nil)
+	When(mockLocker.TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+	)).ThenReturn(&events.TryLockResponse{
+		LockAcquired: true,
+		LockKey:      "lock-key",
+		UnlockFn:     func() error { return nil },
+	}, nil)
+
+	res := runner.Plan(models.ProjectCommandContext{
+		Log: logging.NewNoopLogger(),
+		ProjectConfig: &valid.Project{
+			Dir:         ".",
+			Workspace:   "default",
+			StepTimeout: 10 * time.Millisecond,
+		},
+		Workspace:  "default",
+		RepoRelDir: ".",
+	})
+	ErrEquals(t, "init step timed out after 10ms: context deadline exceeded\npartial output", res.Error)
+	mockPlan.VerifyWasCalled(Never()).Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString())
+}
+
+func TestDefaultProjectCommandRunner_PlanEnv(t *testing.T) {
+	t.Log("the project's env and env steps should be exported to later steps")
+	RegisterMockTestingT(t)
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	runStepRunner := &runtime.RunStepRunner{DefaultTFVersion: version.Must(version.NewVersion("0.11.0"))}
+	runner := events.DefaultProjectCommandRunner{
+		Locker:           mockLocker,
+		LockURLGenerator: mockURLGenerator{},
+		RunStepRunner:    runStepRunner,
+		EnvStepRunner:    &runtime.EnvStepRunner{RunStepRunner: runStepRunner},
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		Webhooks:         mocks.NewMockWebhooksSender(),
+	}
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn(repoDir, nil)
+	When(mockLocker.TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+	)).ThenReturn(&events.TryLockResponse{
+		LockAcquired: true,
+		LockKey:      "lock-key",
+	}, nil)
+
+	res := runner.Plan(models.ProjectCommandContext{
+		Log: logging.NewNoopLogger(),
+		ProjectConfig: &valid.Project{
+			Dir:       ".",
+			Workspace: "default",
+			Workflow:  String("myworkflow"),
+			Env:       map[string]string{"PROJECT": "project", "OVERRIDDEN": "project"},
+		},
+		GlobalConfig: &valid.Config{
+			Workflows: map[string]valid.Workflow{
+				"myworkflow": {
+					Plan: &valid.Stage{
+						Steps: []valid.Step{
+							{StepName: "run", RunCommand: []string{"echo", "before=$STATIC"}},
+							{StepName: "env", EnvVarName: "STATIC", EnvVarValue: "static"},
+							{StepName: "env", EnvVarName: "OVERRIDDEN", RunCommand: []string{"echo", "$STATIC-command"}},
+							{StepName: "run", RunCommand: []string{"echo", "$PROJECT $STATIC $OVERRIDDEN"}},
+						},
+					},
+				},
+			},
+		},
+		Workspace:  "default",
+		RepoRelDir: ".",
+	})
+	Ok(t, res.Error)
+	Equals(t, "before=\n\nproject static static-command\n", res.PlanSuccess.TerraformOutput)
+}
+
+func TestDefaultProjectCommandRunner_PlanExtraArgsTemplate(t *testing.T) {
+	t.Log("extra_args should be rendered with the project's env")
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	mockPlan := mocks.NewMockStepRunner()
+	runner := events.DefaultProjectCommandRunner{
+		Locker:           mockLocker,
+		LockURLGenerator: mockURLGenerator{},
+		PlanStepRunner:   mockPlan,
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		Webhooks:         mocks.NewMockWebhooksSender(),
+	}
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn("/tmp/mydir", nil)
+	When(mockLocker.TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+	)).ThenReturn(&events.TryLockResponse{
+		LockAcquired: true,
+		LockKey:      "lock-key",
+		UnlockFn:     func() error { return nil },
+	}, nil)
+
+	res := runner.Plan(models.ProjectCommandContext{
+		Log: logging.NewNoopLogger(),
+		ProjectConfig: &valid.Project{
+			Dir:       "project",
+			Workspace: "staging",
+			Workflow:  String("myworkflow"),
+			Env:       map[string]string{"STAGE": "prod"},
+		},
+		GlobalConfig: &valid.Config{
+			Workflows: map[string]valid.Workflow{
+				"myworkflow": {
+					Plan: &valid.Stage{
+						Steps: []valid.Step{
+							{StepName: "plan", ExtraArgs: []string{"-var-file={{.WORKSPACE}}.tfvars", "-var", "pull={{.PULL_NUM}}", "-var", "stage={{.STAGE}}"}},
+						},
+					},
+				},
+			},
+		},
+		Pull:       models.PullRequest{Num: 2},
+		Workspace:  "staging",
+		RepoRelDir: "project",
+	})
+	Ok(t, res.Error)
+	_, extraArgs, path := mockPlan.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString()).GetCapturedArguments()
+	Equals(t, []string{"-var-file='staging'.tfvars", "-var", "pull='2'", "-var", "stage='prod'"}, extraArgs)
+	Equals(t, "/tmp/mydir/project", path)
+
+	t.Log("unknown keys should error")
+	res = runner.Plan(models.ProjectCommandContext{
+		Log: logging.NewNoopLogger(),
+		ProjectConfig: &valid.Project{
+			Dir:       "project",
+			Workspace: "staging",
+			Workflow:  String("myworkflow"),
+		},
+		GlobalConfig: &valid.Config{
+			Workflows: map[string]valid.Workflow{
+				"myworkflow": {
+					Plan: &valid.Stage{
+						Steps: []valid.Step{
+							{StepName: "plan", ExtraArgs: []string{"{{.UNKNOWN}}"}},
+						},
+					},
+				},
+			},
+		},
+		Workspace:  "staging",
+		RepoRelDir: "project",
+	})
+	ErrContains(t, "map has no entry for key \"UNKNOWN\"", res.Error)
+}
+
+func TestDefaultProjectCommandRunner_PlanWebhook(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	mockInit := mocks.NewMockStepRunner()
+	mockPlan := mocks.NewMockStepRunner()
+	mockSender := mocks.NewMockWebhooksSender()
+	runner := events.DefaultProjectCommandRunner{
+		Locker:             mockLocker,
+		LockURLGenerator:   mockURLGenerator{},
+		InitStepRunner:     mockInit,
+		PlanStepRunner:     mockPlan,
+		WorkingDir:         mockWorkingDir,
+		WorkingDirLocker:   events.NewDefaultWorkingDirLocker(),
+		Webhooks:           mockSender,
+		OutputURLGenerator: mockOutputURLGenerator{},
+	}
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn("/tmp/mydir", nil)
+	When(mockLocker.TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+	)).ThenReturn(&events.TryLockResponse{
+		LockAcquired: true,
+		LockKey:      "lock-key",
+	}, nil)
+	When(mockPlan.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), AnyString())).
+		ThenReturn("An execution plan has been generated...\n\nPlan: 1 to add, 0 to change, 0 to destroy.\n", nil)
+
+	name := "myproject"
+	ctx := models.ProjectCommandContext{
+		Log:           logging.NewNoopLogger(),
+		BaseRepo:      models.Repo{FullName: "owner/repo"},
+		Pull:          models.PullRequest{Num: 1},
+		User:          models.User{Username: "bob"},
+		ProjectConfig: &valid.Project{Dir: "dir", Workspace: "default", Name: &name},
+		Workspace:     "default",
+		RepoRelDir:    "dir",
+	}
+	res := runner.Plan(ctx)
+	Ok(t, res.Error)
+	mockSender.VerifyWasCalledOnce().SendPlan(ctx.Log, webhooks.PlanResult{
+		Workspace:   "default",
+		Repo:        models.Repo{FullName: "owner/repo"},
+		Pull:        models.PullRequest{Num: 1},
+		User:        models.User{Username: "bob"},
+		RepoRelDir:  "dir",
+		ProjectName: "myproject",
+		Success:     true,
+		Summary:     "Plan: 1 to add, 0 to change, 0 to destroy.",
+		OutputURL:   "https://atlantis/output/owner/repo/1",
+	})
+}
+
+func TestDefaultProjectCommandRunner_PlanCancelled(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	runner := events.DefaultProjectCommandRunner{
+		Locker:           mockLocker,
+		LockURLGenerator: mockURLGenerator{},
+		InitStepRunner:   blockingStepRunner{},
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		Webhooks:         mocks.NewMockWebhooksSender(),
+	}
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn("/tmp/mydir", nil)
+	When(mockLocker.TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+	)).ThenReturn(&events.TryLockResponse{
+		LockAcquired: true,
+		LockKey:      "lock-key",
+		UnlockFn:     func() error { return nil },
+	}, nil)
+
+	cmdCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res := runner.Plan(models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(),
+		Workspace:  "default",
+		RepoRelDir: ".",
+		Context:    cmdCtx,
+	})
+	ErrEquals(t, "init step was cancelled: context canceled\npartial output", res.Error)
+}
+
+// echoStepRunner is a StepRunner that runs echo so its output is streamed.
+type echoStepRunner struct{}
+
+func (e echoStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string) (string, error) {
+	out, err := terraform.RunInterruptibleCmd(ctx.Context, exec.Command("sh", "-c", "echo streamed"), time.Second)
+	return string(out), err
+}
+
+func TestDefaultProjectCommandRunner_PlanStreamsOutput(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	store := events.NewProjectOutputStore(10, nil)
+	runner := events.DefaultProjectCommandRunner{
+		Locker:           mockLocker,
+		LockURLGenerator: mockURLGenerator{},
+		InitStepRunner:   echoStepRunner{},
+		PlanStepRunner:   echoStepRunner{},
+		WorkingDir:       mockWorkingDir,
+		WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+		OutputStore:      store,
+		Webhooks:         mocks.NewMockWebhooksSender(),
+	}
+	When(mockWorkingDir.Clone(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsRepo(),
+		matchers.AnyModelsPullRequest(),
+		AnyString(),
+	)).ThenReturn("/tmp/mydir", nil)
+	When(mockLocker.TryLock(
+		matchers.AnyPtrToLoggingSimpleLogger(),
+		matchers.AnyModelsPullRequest(),
+		matchers.AnyModelsUser(),
+		AnyString(),
+		matchers.AnyModelsProject(),
+	)).ThenReturn(&events.TryLockResponse{
+		LockAcquired: true,
+		LockKey:      "lock-key",
+		UnlockFn:     func() error { return nil },
+	}, nil)
+
+	res := runner.Plan(models.ProjectCommandContext{
+		Log:        logging.NewNoopLogger(),
+		BaseRepo:   models.Repo{FullName: "owner/repo"},
+		Pull:       models.PullRequest{Num: 1},
+		Workspace:  "default",
+		RepoRelDir: ".",
+	})
+	Ok(t, res.Error)
+
+	outputs, _ := store.ListForPull(models.Repo{FullName: "owner/repo"}, 1)
+	Equals(t, 1, len(outputs))
+	Equals(t, events.PlanCommand, outputs[0].Command)
+	lines, _, closed := outputs[0].Buffer.Tail(0)
+	Equals(t, []string{"streamed", "streamed"}, lines)
+	Equals(t, true, closed)
+}
+
 func TestDefaultProjectCommandRunner_ApplyNotCloned(t *testing.T) {
 	mockWorkingDir := mocks.NewMockWorkingDir()
 	runner := &events.DefaultProjectCommandRunner{
 		WorkingDir: mockWorkingDir,
+		Webhooks:   mocks.NewMockWebhooksSender(),
 	}
 	ctx := models.ProjectCommandContext{}
 	When(mockWorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)).ThenReturn("", os.ErrNotExist)
@@ -220,6 +598,7 @@ func TestDefaultProjectCommandRunner_ApplyNotApproved(t *testing.T) {
 		PullApprovedChecker:     mockApproved,
 		WorkingDirLocker:        events.NewDefaultWorkingDirLocker(),
 		RequireApprovalOverride: true,
+		Webhooks:                mocks.NewMockWebhooksSender(),
 	}
 	ctx := models.ProjectCommandContext{}
 	When(mockWorkingDir.GetWorkingDir(ctx.BaseRepo, ctx.Pull, ctx.Workspace)).ThenReturn("/tmp/mydir", nil)
@@ -229,6 +608,28 @@ func TestDefaultProjectCommandRunner_ApplyNotApproved(t *testing.T) {
 	Equals(t, "Pull request must be approved before running apply.", res.Failure)
 }
 
+func TestDefaultProjectCommandRunner_NotAuthorized(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockWorkingDir := mocks.NewMockWorkingDir()
+	mockLocker := mocks.NewMockProjectLocker()
+	mockAuthorizer := mocks.NewMockCommandAuthorizer()
+	runner := &events.DefaultProjectCommandRunner{
+		Locker:            mockLocker,
+		WorkingDir:        mockWorkingDir,
+		WorkingDirLocker:  events.NewDefaultWorkingDirLocker(),
+		CommandAuthorizer: mockAuthorizer,
+		Webhooks:          mocks.NewMockWebhooksSender(),
+	}
+	ctx := models.ProjectCommandContext{Log: logging.NewNoopLogger()}
+	When(mockAuthorizer.Authorize(ctx, events.PlanCommand)).ThenReturn("not allowed to plan", nil)
+	When(mockAuthorizer.Authorize(ctx, events.ApplyCommand)).ThenReturn("not allowed to apply", nil)
+
+	Equals(t, "not allowed to plan", runner.Plan(ctx).Failure)
+	Equals(t, "not allowed to apply", runner.Apply(ctx).Failure)
+	mockLocker.VerifyWasCalled(Never()).TryLock(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyModelsPullRequest(), matchers.AnyModelsUser(), AnyString(), matchers.AnyModelsProject())
+	mockWorkingDir.VerifyWasCalled(Never()).GetWorkingDir(matchers.AnyModelsRepo(), matchers.AnyModelsPullRequest(), AnyString())
+}
+
 func TestDefaultProjectCommandRunner_Apply(t *testing.T) {
 	cases := []struct {
 		description string
@@ -380,10 +781,10 @@ func TestDefaultProjectCommandRunner_Apply(t *testing.T) {
 				GlobalConfig:  c.globalCfg,
 				RepoRelDir:    ".",
 			}
-			When(mockInit.Run(ctx, nil, repoDir)).ThenReturn("init", nil)
-			When(mockPlan.Run(ctx, nil, repoDir)).ThenReturn("plan", nil)
-			When(mockApply.Run(ctx, nil, repoDir)).ThenReturn("apply", nil)
-			When(mockRun.Run(ctx, nil, repoDir)).ThenReturn("run", nil)
+			When(mockInit.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), EqString(repoDir))).ThenReturn("init", nil)
+			When(mockPlan.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), EqString(repoDir))).ThenReturn("plan", nil)
+			When(mockApply.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), EqString(repoDir))).ThenReturn("apply", nil)
+			When(mockRun.Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), EqString(repoDir))).ThenReturn("run", nil)
 			When(mockApproved.PullIsApproved(ctx.BaseRepo, ctx.Pull)).ThenReturn(true, nil)
 
 			res := runner.Apply(ctx)
@@ -394,13 +795,13 @@ func TestDefaultProjectCommandRunner_Apply(t *testing.T) {
 				case "approved":
 					mockApproved.VerifyWasCalledOnce().PullIsApproved(ctx.BaseRepo, ctx.Pull)
 				case "init":
-					mockInit.VerifyWasCalledOnce().Run(ctx, nil, repoDir)
+					mockInit.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), EqString(repoDir))
 				case "plan":
-					mockPlan.VerifyWasCalledOnce().Run(ctx, nil, repoDir)
+					mockPlan.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), EqString(repoDir))
 				case "apply":
-					mockApply.VerifyWasCalledOnce().Run(ctx, nil, repoDir)
+					mockApply.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), EqString(repoDir))
 				case "run":
-					mockRun.VerifyWasCalledOnce().Run(ctx, nil, repoDir)
+					mockRun.VerifyWasCalledOnce().Run(matchers.AnyModelsProjectCommandContext(), AnyStringSlice(), EqString(repoDir))
 				}
 			}
 		})
@@ -412,3 +813,121 @@ type mockURLGenerator struct{}
 func (m mockURLGenerator) GenerateLockURL(lockID string) string {
 	return "https://" + lockID
 }
+
+func TestDefaultProjectCommandRunner_PlanLockKey(t *testing.T) {
+	cases := []struct {
+		description string
+		strategy    string
+		tf          string
+		lockKey     string
+		expLockKey  string
+	}{
+		{
+			description: "dir strategy",
+			strategy:    events.DirLockKeyStrategy,
+			tf:          `terraform { backend "s3" { bucket = "bucket" key = "prod.tfstate" } }`,
+		},
+		{
+			description: "s3 backend",
+			strategy:    events.BackendLockKeyStrategy,
+			tf:          `terraform { backend "s3" { bucket = "bucket" key = "env/prod.tfstate" region = "us-east-1" } }`,
+			expLockKey:  "s3://bucket/env/prod.tfstate",
+		},
+		{
+			description: "gcs backend without prefix",
+			strategy:    events.BackendLockKeyStrategy,
+			tf:          `terraform { backend "gcs" { bucket = "bucket" } }`,
+			expLockKey:  "gcs://bucket",
+		},
+		{
+			description: "remote backend",
+			strategy:    events.BackendLockKeyStrategy,
+			tf:          `terraform { backend "remote" { organization = "org" workspaces { prefix = "network-" } } }`,
+			expLockKey:  "remote://app.terraform.io/org/network-",
+		},
+		{
+			description: "partial backend config",
+			strategy:    events.BackendLockKeyStrategy,
+			tf:          `terraform { backend "s3" { bucket = "bucket" } }`,
+		},
+		{
+			description: "interpolated backend config",
+			strategy:    events.BackendLockKeyStrategy,
+			tf:          `terraform { backend "s3" { bucket = "bucket" key = "${var.key}" } }`,
+		},
+		{
+			description: "unsupported backend",
+			strategy:    events.BackendLockKeyStrategy,
+			tf:          `terraform { backend "local" { path = "terraform.tfstate" } }`,
+		},
+		{
+			description: "no backend",
+			strategy:    events.BackendLockKeyStrategy,
+			tf:          `resource "null_resource" "null" {}`,
+		},
+		{
+			description: "lock_key override",
+			strategy:    events.BackendLockKeyStrategy,
+			tf:          `terraform { backend "s3" { bucket = "bucket" key = "prod.tfstate" } }`,
+			lockKey:     "network",
+			expLockKey:  "lock_key://network",
+		},
+		{
+			description: "lock_key override with backend identity",
+			strategy:    events.DirLockKeyStrategy,
+			lockKey:     "s3://bucket/prod.tfstate",
+			expLockKey:  "s3://bucket/prod.tfstate",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmpDir, cleanup := TempDir(t)
+			defer cleanup()
+			Ok(t, ioutil.WriteFile(filepath.Join(tmpDir, "main.tf"), []byte(c.tf), 0600))
+			mockWorkingDir := mocks.NewMockWorkingDir()
+			mockLocker := mocks.NewMockProjectLocker()
+			runner := events.DefaultProjectCommandRunner{
+				Locker:           mockLocker,
+				LockURLGenerator: mockURLGenerator{},
+				InitStepRunner:   mocks.NewMockStepRunner(),
+				PlanStepRunner:   mocks.NewMockStepRunner(),
+				WorkingDir:       mockWorkingDir,
+				WorkingDirLocker: events.NewDefaultWorkingDirLocker(),
+				LockKeyStrategy:  c.strategy,
+				Webhooks:         mocks.NewMockWebhooksSender(),
+			}
+			When(mockWorkingDir.Clone(
+				matchers.AnyPtrToLoggingSimpleLogger(),
+				matchers.AnyModelsRepo(),
+				matchers.AnyModelsRepo(),
+				matchers.AnyModelsPullRequest(),
+				AnyString(),
+			)).ThenReturn(tmpDir, nil)
+			When(mockLocker.TryLock(
+				matchers.AnyPtrToLoggingSimpleLogger(),
+				matchers.AnyModelsPullRequest(),
+				matchers.AnyModelsUser(),
+				AnyString(),
+				matchers.AnyModelsProject(),
+			)).ThenReturn(&events.TryLockResponse{LockAcquired: false, LockFailureReason: "locked"}, nil)
+
+			res := runner.Plan(models.ProjectCommandContext{
+				Log:           logging.NewNoopLogger(),
+				BaseRepo:      models.Repo{FullName: "owner/repo"},
+				ProjectConfig: &valid.Project{Dir: ".", Workspace: "default", LockKey: c.lockKey},
+				Workspace:     "default",
+				RepoRelDir:    ".",
+			})
+			Equals(t, "locked", res.Failure)
+			_, _, _, _, project := mockLocker.VerifyWasCalledOnce().TryLock(
+				matchers.AnyPtrToLoggingSimpleLogger(),
+				matchers.AnyModelsPullRequest(),
+				matchers.AnyModelsUser(),
+				AnyString(),
+				matchers.AnyModelsProject(),
+			).GetCapturedArguments()
+			Equals(t, models.Project{RepoFullName: "owner/repo", Path: ".", LockKey: c.expLockKey}, project)
+		})
+	}
+}
@@ -15,6 +15,7 @@ package events
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/runatlantis/atlantis/server/events/locking"
 	"github.com/runatlantis/atlantis/server/events/models"
@@ -38,6 +39,9 @@ type ProjectLocker interface {
 // DefaultProjectLocker implements ProjectLocker.
 type DefaultProjectLocker struct {
 	Locker locking.Locker
+	// Queue, if set, queues pull requests that are blocked by another pull
+	// request's lock so they're re-planned when it's released.
+	Queue *LockQueue
 }
 
 // TryLockResponse is the result of trying to lock a project.
@@ -61,11 +65,34 @@ func (p *DefaultProjectLocker) TryLock(log *logging.SimpleLogger, pull models.Pu
 	if err != nil {
 		return nil, err
 	}
-	if !lockAttempt.LockAcquired && lockAttempt.CurrLock.Pull.Num != pull.Num {
+	// Lock keys can be shared across repos so the lock is only ours if it's
+	// held by this pull request in this repo.
+	currPull := lockAttempt.CurrLock.Pull
+	sameRepo := currPull.BaseRepo.FullName == pull.BaseRepo.FullName && currPull.BaseRepo.VCSHost == pull.BaseRepo.VCSHost
+	if !lockAttempt.LockAcquired && (!sameRepo || currPull.Num != pull.Num) {
+		lockingPull := fmt.Sprintf("#%d", currPull.Num)
+		if !sameRepo {
+			lockingPull = fmt.Sprintf("%s#%d", currPull.BaseRepo.FullName, currPull.Num)
+		}
 		failureMsg := fmt.Sprintf(
-			"This project is currently locked by an unapplied plan from pull #%d. To continue, delete the lock from #%d or apply that plan and merge the pull request.\n\nOnce the lock is released, comment `atlantis plan` here to re-plan.",
-			lockAttempt.CurrLock.Pull.Num,
-			lockAttempt.CurrLock.Pull.Num)
+			"This project is currently locked by an unapplied plan from pull %s. To continue, delete the lock from %s or apply that plan and merge the pull request.",
+			lockingPull,
+			lockingPull)
+		if !lockAttempt.CurrLock.ExpiresAt.IsZero() {
+			failureMsg += fmt.Sprintf(" The lock expires at %s.", lockAttempt.CurrLock.ExpiresAt.Format(time.RFC1123))
+		}
+		if p.Queue == nil {
+			failureMsg += "\n\nOnce the lock is released, comment `atlantis plan` here to re-plan."
+		} else {
+			position := p.Queue.Enqueue(project, workspace, pull, user)
+			log.Info("queued for lock held by pull #%d at position %d", lockAttempt.CurrLock.Pull.Num, position)
+			failureMsg += fmt.Sprintf("\n\nThis pull request is #%d in the queue for this lock.", position)
+			if p.Queue.canReplan(pull.BaseRepo) {
+				failureMsg += " When it's this pull request's turn, it will be re-planned automatically."
+			} else {
+				failureMsg += " When it's this pull request's turn, you'll be notified here so you can re-plan."
+			}
+		}
 		return &TryLockResponse{
 			LockAcquired:      false,
 			LockFailureReason: failureMsg,
@@ -15,11 +15,13 @@ package events_test
 
 import (
 	"testing"
+	"time"
 
 	. "github.com/petergtz/pegomock"
 	"github.com/runatlantis/atlantis/server/events"
 	"github.com/runatlantis/atlantis/server/events/locking"
 	"github.com/runatlantis/atlantis/server/events/locking/mocks"
+	emocks "github.com/runatlantis/atlantis/server/events/mocks"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/logging"
 	. "github.com/runatlantis/atlantis/testing"
@@ -92,6 +94,51 @@ func TestDefaultProjectLocker_TryLockWhenLockedSamePull(t *testing.T) {
 	mockLocker.VerifyWasCalledOnce().Unlock(lockKey)
 }
 
+// Lock keys are shared across repos so a lock held by the same pull number in
+// another repo must not be treated as ours.
+func TestDefaultProjectLocker_TryLockWhenLockedSamePullNumOtherRepo(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockLocker := mocks.NewMockLocker()
+	locker := events.DefaultProjectLocker{
+		Locker: mockLocker,
+	}
+	expProject := models.Project{LockKey: "network"}
+	expWorkspace := "default"
+	expPull := models.PullRequest{
+		Num: 5,
+		BaseRepo: models.Repo{
+			FullName: "owner/repo-b",
+			VCSHost:  models.VCSHost{Hostname: "github.com", Type: models.Github},
+		},
+	}
+	expUser := models.User{}
+
+	lockingPull := models.PullRequest{
+		Num: 5,
+		BaseRepo: models.Repo{
+			FullName: "owner/repo-a",
+			VCSHost:  models.VCSHost{Hostname: "github.com", Type: models.Github},
+		},
+	}
+	When(mockLocker.TryLock(expProject, expWorkspace, expPull, expUser)).ThenReturn(
+		locking.TryLockResponse{
+			LockAcquired: false,
+			CurrLock: models.ProjectLock{
+				Pull: lockingPull,
+			},
+			LockKey: "network/default",
+		},
+		nil,
+	)
+	res, err := locker.TryLock(logging.NewNoopLogger(), expPull, expUser, expWorkspace, expProject)
+	Ok(t, err)
+	Equals(t, &events.TryLockResponse{
+		LockAcquired:      false,
+		LockFailureReason: "This project is currently locked by an unapplied plan from pull owner/repo-a#5. To continue, delete the lock from owner/repo-a#5 or apply that plan and merge the pull request.\n\nOnce the lock is released, comment `atlantis plan` here to re-plan.",
+	}, res)
+	mockLocker.VerifyWasCalled(Never()).Unlock(AnyString())
+}
+
 func TestDefaultProjectLocker_TryLockUnlocked(t *testing.T) {
 	RegisterMockTestingT(t)
 	mockLocker := mocks.NewMockLocker()
@@ -127,3 +174,37 @@ func TestDefaultProjectLocker_TryLockUnlocked(t *testing.T) {
 	Ok(t, err)
 	mockLocker.VerifyWasCalledOnce().Unlock(lockKey)
 }
+
+func TestDefaultProjectLocker_TryLockWhenLockedQueues(t *testing.T) {
+	RegisterMockTestingT(t)
+	mockLocker := mocks.NewMockLocker()
+	queue := &events.LockQueue{CommandRunner: emocks.NewMockAPICommandRunner()}
+	locker := events.DefaultProjectLocker{
+		Locker: mockLocker,
+		Queue:  queue,
+	}
+	expProject := models.NewProject("owner/repo", "prod")
+	expPull := models.PullRequest{Num: 3, BaseRepo: models.Repo{VCSHost: models.VCSHost{Type: models.Github}}}
+	expUser := models.User{Username: "bob"}
+	expires := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	When(mockLocker.TryLock(expProject, "default", expPull, expUser)).ThenReturn(
+		locking.TryLockResponse{
+			LockAcquired: false,
+			CurrLock: models.ProjectLock{
+				Pull:      models.PullRequest{Num: 2},
+				ExpiresAt: expires,
+			},
+		},
+		nil,
+	)
+	queue.Enqueue(expProject, "default", models.PullRequest{Num: 4}, models.User{})
+	res, err := locker.TryLock(logging.NewNoopLogger(), expPull, expUser, "default", expProject)
+	Ok(t, err)
+	Equals(t, &events.TryLockResponse{
+		LockAcquired:      false,
+		LockFailureReason: "This project is currently locked by an unapplied plan from pull #2. To continue, delete the lock from #2 or apply that plan and merge the pull request. The lock expires at Mon, 01 Oct 2018 12:00:00 UTC.\n\nThis pull request is #2 in the queue for this lock. When it's this pull request's turn, it will be re-planned automatically.",
+	}, res)
+	queued := queue.List(expProject, "default")
+	Equals(t, 2, len(queued))
+	Equals(t, expUser, queued[1].User)
+}
@@ -0,0 +1,239 @@
+package events
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// DefaultOutputBufferLines is the number of lines of output kept in memory for
+// each project run.
+const DefaultOutputBufferLines = 10000
+
+// OutputBuffer is an in-memory ring buffer holding the most recent lines of
+// output from a project run. It implements io.Writer.
+type OutputBuffer struct {
+	mutex sync.Mutex
+	// lines holds the most recent lines. Once it's full, line n (counting from
+	// 0 across all the lines ever written) is stored at lines[n%maxLines].
+	lines    []string
+	maxLines int
+	// total is the number of lines ever written.
+	total int
+	// partial holds output that hasn't been terminated by a newline yet.
+	partial []byte
+	closed  bool
+	// notify is called whenever lines are added or the buffer is closed.
+	notify func()
+	// redactor, if set, redacts each line before it's stored.
+	redactor *LineRedactor
+}
+
+// NewOutputBuffer returns an OutputBuffer that keeps the last maxLines lines.
+// notify is optional and is called whenever lines are added or the buffer is
+// closed.
+func NewOutputBuffer(maxLines int, notify func()) *OutputBuffer {
+	if maxLines <= 0 {
+		maxLines = DefaultOutputBufferLines
+	}
+	if notify == nil {
+		notify = func() {}
+	}
+	return &OutputBuffer{
+		maxLines: maxLines,
+		notify:   notify,
+	}
+}
+
+// Write adds p to the buffer. Output is stored line by line so a line is only
+// readable once its newline has been written.
+func (b *OutputBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	added := false
+	for _, c := range p {
+		if c == '\n' {
+			b.addLine(string(b.partial))
+			b.partial = b.partial[:0]
+			added = true
+			continue
+		}
+		b.partial = append(b.partial, c)
+	}
+	b.mutex.Unlock()
+
+	if added {
+		b.notify()
+	}
+	return len(p), nil
+}
+
+// Close marks the buffer as complete. Any output not terminated by a newline
+// is added as the last line.
+func (b *OutputBuffer) Close() {
+	b.mutex.Lock()
+	if b.closed {
+		b.mutex.Unlock()
+		return
+	}
+	if len(b.partial) > 0 {
+		b.addLine(string(b.partial))
+		b.partial = nil
+	}
+	b.closed = true
+	b.mutex.Unlock()
+	b.notify()
+}
+
+// Closed returns true once the buffer has been closed, ie. the command is
+// complete.
+func (b *OutputBuffer) Closed() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.closed
+}
+
+// Tail returns the lines written starting at line offset, counting from 0
+// across all the lines ever written. It also returns the offset to pass in to
+// read the next lines and whether the buffer is closed. If the lines at offset
+// have already been dropped from the buffer, reading starts at the oldest line
+// still stored.
+func (b *OutputBuffer) Tail(offset int) (lines []string, next int, closed bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	oldest := b.total - len(b.lines)
+	if offset < oldest {
+		offset = oldest
+	}
+	for i := offset; i < b.total; i++ {
+		lines = append(lines, b.lines[i%b.maxLines])
+	}
+	return lines, b.total, b.closed
+}
+
+// addLine must be called while holding the mutex.
+func (b *OutputBuffer) addLine(line string) {
+	if b.redactor != nil {
+		line = b.redactor.Redact(line)
+	}
+	if len(b.lines) < b.maxLines {
+		b.lines = append(b.lines, line)
+	} else {
+		b.lines[b.total%b.maxLines] = line
+	}
+	b.total++
+}
+
+// ProjectOutput is the output of the latest command run for a project.
+type ProjectOutput struct {
+	// ID uniquely identifies this run of the command.
+	ID          int
+	RepoRelDir  string
+	Workspace   string
+	ProjectName string
+	Command     CommandName
+	StartTime   time.Time
+	Buffer      *OutputBuffer
+}
+
+// ProjectOutputStore keeps the output of the latest command run for each
+// project so it can be streamed while the command is still running.
+type ProjectOutputStore struct {
+	mutex    sync.Mutex
+	maxLines int
+	redactor *Redactor
+	lastID   int
+	// outputs maps from pull key to project key to the project's output.
+	outputs map[string]map[string]*ProjectOutput
+	// changed is closed and replaced every time any output changes so that
+	// readers can wait for new output.
+	changed chan struct{}
+}
+
+// NewProjectOutputStore returns a ProjectOutputStore that keeps the last
+// maxLines lines of output for each project. The output is redacted with
+// redactor, which can be nil.
+func NewProjectOutputStore(maxLines int, redactor *Redactor) *ProjectOutputStore {
+	return &ProjectOutputStore{
+		maxLines: maxLines,
+		redactor: redactor,
+		outputs:  make(map[string]map[string]*ProjectOutput),
+		changed:  make(chan struct{}),
+	}
+}
+
+// Start creates a new buffer for the output of running cmdName for the project
+// described by ctx, replacing the output of any previous runs. The returned
+// buffer must be closed once the command is complete.
+func (s *ProjectOutputStore) Start(ctx models.ProjectCommandContext, cmdName CommandName) *OutputBuffer {
+	var projectName string
+	if ctx.ProjectConfig != nil {
+		projectName = ctx.ProjectConfig.GetName()
+	}
+
+	s.mutex.Lock()
+	s.lastID++
+	output := &ProjectOutput{
+		ID:          s.lastID,
+		RepoRelDir:  ctx.RepoRelDir,
+		Workspace:   ctx.Workspace,
+		ProjectName: projectName,
+		Command:     cmdName,
+		StartTime:   time.Now(),
+		Buffer:      NewOutputBuffer(s.maxLines, s.notify),
+	}
+	output.Buffer.redactor = s.redactor.NewLineRedactor()
+	pullKey := s.pullKey(ctx.BaseRepo, ctx.Pull.Num)
+	if s.outputs[pullKey] == nil {
+		s.outputs[pullKey] = make(map[string]*ProjectOutput)
+	}
+	s.outputs[pullKey][fmt.Sprintf("%s/%s/%s", ctx.RepoRelDir, ctx.Workspace, projectName)] = output
+	s.mutex.Unlock()
+
+	s.notify()
+	return output.Buffer
+}
+
+// ListForPull returns the outputs of the projects in pull request pullNum of
+// repo, sorted by directory, workspace and project name. It also
+// returns a channel that's closed when any output changes.
+func (s *ProjectOutputStore) ListForPull(repo models.Repo, pullNum int) ([]*ProjectOutput, <-chan struct{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var outputs []*ProjectOutput
+	for _, o := range s.outputs[s.pullKey(repo, pullNum)] {
+		outputs = append(outputs, o)
+	}
+	sort.Slice(outputs, func(i, j int) bool {
+		if outputs[i].RepoRelDir != outputs[j].RepoRelDir {
+			return outputs[i].RepoRelDir < outputs[j].RepoRelDir
+		}
+		if outputs[i].Workspace != outputs[j].Workspace {
+			return outputs[i].Workspace < outputs[j].Workspace
+		}
+		return outputs[i].ProjectName < outputs[j].ProjectName
+	})
+	return outputs, s.changed
+}
+
+// DeleteForPull deletes the outputs of all the projects in pull request
+// pullNum of repo.
+func (s *ProjectOutputStore) DeleteForPull(repo models.Repo, pullNum int) {
+	s.mutex.Lock()
+	delete(s.outputs, s.pullKey(repo, pullNum))
+	s.mutex.Unlock()
+	s.notify()
+}
+
+func (s *ProjectOutputStore) notify() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+func (s *ProjectOutputStore) pullKey(repo models.Repo, pullNum int) string {
+	return fmt.Sprintf("%s/%d", repo.ID(), pullNum)
+}
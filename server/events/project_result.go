@@ -19,8 +19,10 @@ import (
 
 // ProjectResult is the result of executing a plan/apply for a specific project.
 type ProjectResult struct {
-	RepoRelDir   string
-	Workspace    string
+	RepoRelDir string
+	Workspace  string
+	// ProjectName is the name of the project or empty if it doesn't have one.
+	ProjectName  string
 	Error        error
 	Failure      string
 	PlanSuccess  *PlanSuccess
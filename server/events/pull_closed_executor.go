@@ -41,6 +41,9 @@ type PullClosedExecutor struct {
 	Locker     locking.Locker
 	VCSClient  vcs.ClientProxy
 	WorkingDir WorkingDir
+	// OutputStore is optional. If set, the live output of the pull's
+	// commands is deleted.
+	OutputStore *ProjectOutputStore
 }
 
 type templatedProject struct {
@@ -58,11 +61,14 @@ func (p *PullClosedExecutor) CleanUpPull(repo models.Repo, pull models.PullReque
 	if err := p.WorkingDir.Delete(repo, pull); err != nil {
 		return errors.Wrap(err, "cleaning workspace")
 	}
+	if p.OutputStore != nil {
+		p.OutputStore.DeleteForPull(repo, pull.Num)
+	}
 
 	// Finally, delete locks. We do this last because when someone
 	// unlocks a project, right now we don't actually delete the plan
 	// so we might have plans laying around but no locks.
-	locks, err := p.Locker.UnlockByPull(repo.FullName, pull.Num)
+	locks, err := p.Locker.UnlockByPull(repo, pull.Num)
 	if err != nil {
 		return errors.Wrap(err, "cleaning up locks")
 	}
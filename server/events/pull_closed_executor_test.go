@@ -51,7 +51,7 @@ func TestCleanUpPullUnlockErr(t *testing.T) {
 		WorkingDir: w,
 	}
 	err := errors.New("err")
-	When(l.UnlockByPull(fixtures.GithubRepo.FullName, fixtures.Pull.Num)).ThenReturn(nil, err)
+	When(l.UnlockByPull(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(nil, err)
 	actualErr := pce.CleanUpPull(fixtures.GithubRepo, fixtures.Pull)
 	Equals(t, "cleaning up locks: err", actualErr.Error())
 }
@@ -67,7 +67,7 @@ func TestCleanUpPullNoLocks(t *testing.T) {
 		VCSClient:  cp,
 		WorkingDir: w,
 	}
-	When(l.UnlockByPull(fixtures.GithubRepo.FullName, fixtures.Pull.Num)).ThenReturn(nil, nil)
+	When(l.UnlockByPull(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(nil, nil)
 	err := pce.CleanUpPull(fixtures.GithubRepo, fixtures.Pull)
 	Ok(t, err)
 	cp.VerifyWasCalled(Never()).CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString())
@@ -148,7 +148,7 @@ func TestCleanUpPullComments(t *testing.T) {
 			WorkingDir: w,
 		}
 		t.Log("testing: " + c.Description)
-		When(l.UnlockByPull(fixtures.GithubRepo.FullName, fixtures.Pull.Num)).ThenReturn(c.Locks, nil)
+		When(l.UnlockByPull(fixtures.GithubRepo, fixtures.Pull.Num)).ThenReturn(c.Locks, nil)
 		err := pce.CleanUpPull(fixtures.GithubRepo, fixtures.Pull)
 		Ok(t, err)
 		_, _, comment := cp.VerifyWasCalledOnce().CreateComment(matchers.AnyModelsRepo(), AnyInt(), AnyString()).GetCapturedArguments()
@@ -0,0 +1,155 @@
+package events
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RedactedText replaces anything the Redactor matches.
+const RedactedText = "[REDACTED]"
+
+// builtinRedactPatterns match secrets that commonly end up in Terraform and
+// run step output. If a pattern has a capture group only the first group is
+// redacted so the surrounding text still makes sense.
+var builtinRedactPatterns = []string{
+	// AWS access key ids.
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	// AWS secret keys and session tokens, ex. from running env.
+	`(?i)aws_(?:secret_access_key|session_token)["']?\s*[=:]\s*["']?([A-Za-z0-9/+=]{16,})`,
+	// PEM encoded private keys.
+	`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`,
+	// GitHub tokens.
+	`\bgh[pousr]_[A-Za-z0-9]{36,}\b`,
+}
+
+// privateKeyBeginRegex and privateKeyEndRegex match the first and last lines
+// of PEM encoded private keys so they can be redacted from streamed output,
+// which is redacted line by line.
+var (
+	privateKeyBeginRegex = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)
+	privateKeyEndRegex   = regexp.MustCompile(`-----END [A-Z ]*PRIVATE KEY-----`)
+)
+
+// Redactor removes secrets from command output before it's commented back
+// to the pull request, streamed or returned by the API. A nil Redactor
+// doesn't redact anything.
+type Redactor struct {
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor returns a Redactor that redacts the built-in patterns, the
+// regexes in patterns and the current values of the environment variables
+// named in envVars. Env vars that aren't set are ignored.
+func NewRedactor(patterns []string, envVars []string) (*Redactor, error) {
+	r := &Redactor{}
+	for _, p := range builtinRedactPatterns {
+		r.patterns = append(r.patterns, regexp.MustCompile(p))
+	}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing redact pattern %q", p)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	var values []string
+	for _, name := range envVars {
+		if v := os.Getenv(name); v != "" {
+			values = append(values, regexp.QuoteMeta(v))
+		}
+	}
+	if len(values) > 0 {
+		// Match the longest values first so a value that contains another
+		// isn't only partially redacted.
+		sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+		r.patterns = append(r.patterns, regexp.MustCompile(strings.Join(values, "|")))
+	}
+	return r, nil
+}
+
+// Redact returns s with all the secrets replaced by RedactedText.
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	for _, re := range r.patterns {
+		if re.NumSubexp() == 0 {
+			s = re.ReplaceAllLiteralString(s, RedactedText)
+			continue
+		}
+		s = re.ReplaceAllStringFunc(s, func(match string) string {
+			loc := re.FindStringSubmatchIndex(match)
+			if loc[2] < 0 {
+				return RedactedText
+			}
+			return match[:loc[2]] + RedactedText + match[loc[3]:]
+		})
+	}
+	return s
+}
+
+// RedactResult returns a copy of res with the secrets in its errors,
+// failures and output redacted.
+func (r *Redactor) RedactResult(res CommandResult) CommandResult {
+	if r == nil {
+		return res
+	}
+	redacted := CommandResult{
+		Error:   r.redactErr(res.Error),
+		Failure: r.Redact(res.Failure),
+	}
+	for _, p := range res.ProjectResults {
+		p.Error = r.redactErr(p.Error)
+		p.Failure = r.Redact(p.Failure)
+		p.ApplySuccess = r.Redact(p.ApplySuccess)
+		if p.PlanSuccess != nil {
+			planSuccess := *p.PlanSuccess
+			planSuccess.TerraformOutput = r.Redact(planSuccess.TerraformOutput)
+			p.PlanSuccess = &planSuccess
+		}
+		redacted.ProjectResults = append(redacted.ProjectResults, p)
+	}
+	return redacted
+}
+
+func (r *Redactor) redactErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if msg := err.Error(); r.Redact(msg) != msg {
+		return errors.New(r.Redact(msg))
+	}
+	return err
+}
+
+// LineRedactor redacts output one line at a time as it's streamed. Use
+// Redactor.NewLineRedactor to create one.
+type LineRedactor struct {
+	redactor *Redactor
+	// inPrivateKey is true while redacting the lines of a private key since
+	// they can't be matched on their own.
+	inPrivateKey bool
+}
+
+// NewLineRedactor returns a LineRedactor for a single stream of output.
+func (r *Redactor) NewLineRedactor() *LineRedactor {
+	return &LineRedactor{redactor: r}
+}
+
+// Redact returns line with all the secrets replaced by RedactedText. Lines
+// from the start of a private key to its end are replaced entirely.
+func (l *LineRedactor) Redact(line string) string {
+	if l.redactor == nil {
+		return line
+	}
+	if l.inPrivateKey || privateKeyBeginRegex.MatchString(line) {
+		l.inPrivateKey = !privateKeyEndRegex.MatchString(line)
+		return RedactedText
+	}
+	return l.redactor.Redact(line)
+}
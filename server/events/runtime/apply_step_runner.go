@@ -5,7 +5,6 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/hashicorp/go-version"
 	"github.com/runatlantis/atlantis/server/events/models"
 )
 
@@ -24,11 +23,20 @@ func (a *ApplyStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []stri
 	// NOTE: we need to quote the plan path because Bitbucket Server can
 	// have spaces in its repo owner names which is part of the path.
 	tfApplyCmd := append(append(append([]string{"apply", "-input=false", "-no-color"}, extraArgs...), ctx.CommentArgs...), fmt.Sprintf("%q", planPath))
-	var tfVersion *version.Version
-	if ctx.ProjectConfig != nil && ctx.ProjectConfig.TerraformVersion != nil {
-		tfVersion = ctx.ProjectConfig.TerraformVersion
+	// Apply with the version the plan was created with. Version constraints
+	// are only resolved again for plans that didn't record their version. A
+	// nil default version means the client's default is used.
+	tfVersion, err := readPlanVersion(planPath)
+	if err != nil {
+		return "", err
 	}
-	out, tfErr := a.TerraformExecutor.RunCommandWithVersion(ctx.Log, path, tfApplyCmd, tfVersion, ctx.Workspace)
+	if tfVersion == nil {
+		tfVersion, err = getTFVersion(ctx, a.TerraformExecutor, nil, path)
+		if err != nil {
+			return "", err
+		}
+	}
+	out, tfErr := a.TerraformExecutor.RunCommandWithVersion(ctx.Context, ctx.Log, path, tfApplyCmd, tfVersion, ctx.Workspace)
 
 	// If the apply was successful, delete the plan.
 	if tfErr == nil {
@@ -36,6 +44,9 @@ func (a *ApplyStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []stri
 		if err := os.Remove(planPath); err != nil {
 			ctx.Log.Warn("failed to delete planfile after successful apply: %s", err)
 		}
+		if err := os.Remove(planPath + planVersionSuffix); err != nil && !os.IsNotExist(err) {
+			ctx.Log.Warn("failed to delete planfile's terraform version after successful apply: %s", err)
+		}
 	}
 	return out, tfErr
 }
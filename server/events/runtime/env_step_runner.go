@@ -0,0 +1,31 @@
+package runtime
+
+import (
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/terraform"
+)
+
+// EnvStepRunner runs env steps, which set an env var for the steps after
+// them.
+type EnvStepRunner struct {
+	RunStepRunner *RunStepRunner
+}
+
+// Run returns the value of the env var. If command is set, the value is its
+// output with the surrounding whitespace trimmed. Otherwise it's value.
+func (r *EnvStepRunner) Run(ctx models.ProjectCommandContext, command []string, value string, path string) (string, error) {
+	if len(command) == 0 {
+		return value, nil
+	}
+	// The value could be a secret so it isn't streamed to the output page.
+	if ctx.Context != nil {
+		ctx.Context = terraform.WithOutputWriter(ctx.Context, nil)
+	}
+	out, err := r.RunStepRunner.Run(ctx, command, path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
@@ -0,0 +1,46 @@
+package runtime_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/terraform"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestEnvStepRunner_Run(t *testing.T) {
+	defaultVersion, _ := version.NewVersion("0.11.0")
+	r := runtime.EnvStepRunner{
+		RunStepRunner: &runtime.RunStepRunner{
+			DefaultTFVersion: defaultVersion,
+		},
+	}
+	var streamed bytes.Buffer
+	ctx := models.ProjectCommandContext{
+		Log:       logging.NewNoopLogger(),
+		Workspace: "default",
+		Context:   terraform.WithOutputWriter(context.Background(), &streamed),
+	}
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+
+	t.Log("a static value should be returned as is")
+	value, err := r.Run(ctx, nil, " value ", tmpDir)
+	Ok(t, err)
+	Equals(t, " value ", value)
+
+	t.Log("a command's output should be trimmed and not streamed")
+	value, err = r.Run(ctx, []string{"echo", "  $WORKSPACE  "}, "", tmpDir)
+	Ok(t, err)
+	Equals(t, "default", value)
+	Equals(t, "", streamed.String())
+
+	t.Log("command errors should be returned")
+	_, err = r.Run(ctx, []string{"exit", "1"}, "", tmpDir)
+	ErrContains(t, "exit status 1", err)
+}
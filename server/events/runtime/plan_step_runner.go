@@ -22,12 +22,15 @@ var (
 type PlanStepRunner struct {
 	TerraformExecutor TerraformExec
 	DefaultTFVersion  *version.Version
+	// DisableTFVars stops the atlantis_* variables from being passed to plan
+	// with -var. Terraform 0.12 errors on variables that aren't declared.
+	DisableTFVars bool
 }
 
 func (p *PlanStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []string, path string) (string, error) {
-	tfVersion := p.DefaultTFVersion
-	if ctx.ProjectConfig != nil && ctx.ProjectConfig.TerraformVersion != nil {
-		tfVersion = ctx.ProjectConfig.TerraformVersion
+	tfVersion, err := getTFVersion(ctx, p.TerraformExecutor, p.DefaultTFVersion, path)
+	if err != nil {
+		return "", err
 	}
 
 	// We only need to switch workspaces in version 0.9.*. In older versions,
@@ -37,10 +40,15 @@ func (p *PlanStepRunner) Run(ctx models.ProjectCommandContext, extraArgs []strin
 	}
 
 	planCmd := p.buildPlanCmd(ctx, extraArgs, path)
-	output, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Log, filepath.Clean(path), planCmd, tfVersion, ctx.Workspace)
+	output, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Context, ctx.Log, filepath.Clean(path), planCmd, tfVersion, ctx.Workspace)
 	if err != nil {
 		return "", err
 	}
+	planPath := filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectConfig))
+	// If the version can't be recorded, apply resolves it again.
+	if err := writePlanVersion(planPath, tfVersion); err != nil {
+		ctx.Log.Warn("%s", err)
+	}
 	return p.fmtPlanOutput(output), nil
 }
 
@@ -69,7 +77,7 @@ func (p *PlanStepRunner) switchWorkspace(ctx models.ProjectCommandContext, path
 	// already in the right workspace then no need to switch. This will save us
 	// about ten seconds. This command is only available in > 0.10.
 	if !runningZeroPointNine {
-		workspaceShowOutput, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Log, path, []string{workspaceCmd, "show"}, tfVersion, ctx.Workspace)
+		workspaceShowOutput, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Context, ctx.Log, path, []string{workspaceCmd, "show"}, tfVersion, ctx.Workspace)
 		if err != nil {
 			return err
 		}
@@ -84,11 +92,11 @@ func (p *PlanStepRunner) switchWorkspace(ctx models.ProjectCommandContext, path
 	// To do this we can either select and catch the error or use list and then
 	// look for the workspace. Both commands take the same amount of time so
 	// that's why we're running select here.
-	_, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Log, path, []string{workspaceCmd, "select", "-no-color", ctx.Workspace}, tfVersion, ctx.Workspace)
+	_, err := p.TerraformExecutor.RunCommandWithVersion(ctx.Context, ctx.Log, path, []string{workspaceCmd, "select", "-no-color", ctx.Workspace}, tfVersion, ctx.Workspace)
 	if err != nil {
 		// If terraform workspace select fails we run terraform workspace
 		// new to create a new workspace automatically.
-		_, err = p.TerraformExecutor.RunCommandWithVersion(ctx.Log, path, []string{workspaceCmd, "new", "-no-color", ctx.Workspace}, tfVersion, ctx.Workspace)
+		_, err = p.TerraformExecutor.RunCommandWithVersion(ctx.Context, ctx.Log, path, []string{workspaceCmd, "new", "-no-color", ctx.Workspace}, tfVersion, ctx.Workspace)
 		return err
 	}
 	return nil
@@ -126,6 +134,9 @@ func (p *PlanStepRunner) buildPlanCmd(ctx models.ProjectCommandContext, extraArg
 // session name in AWS which will identify in CloudTrail the source of
 // Atlantis API calls.
 func (p *PlanStepRunner) tfVars(ctx models.ProjectCommandContext) []string {
+	if p.DisableTFVars {
+		return nil
+	}
 	// NOTE: not using maps and looping here because we need to keep the
 	// ordering for testing purposes.
 	// NOTE: quoting the values because in Bitbucket the owner can have
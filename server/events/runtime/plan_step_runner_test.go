@@ -15,6 +15,7 @@ import (
 	"github.com/runatlantis/atlantis/server/events/runtime"
 	"github.com/runatlantis/atlantis/server/events/terraform/mocks"
 	matchers2 "github.com/runatlantis/atlantis/server/events/terraform/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
 	. "github.com/runatlantis/atlantis/testing"
@@ -33,7 +34,7 @@ func TestRun_NoWorkspaceIn08(t *testing.T) {
 		TerraformExecutor: terraform,
 	}
 
-	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+	When(terraform.RunCommandWithVersion(matchers2.AnyContextContext(), matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
 		ThenReturn("output", nil)
 	output, err := s.Run(models.ProjectCommandContext{
 		Log:         logger,
@@ -54,6 +55,7 @@ func TestRun_NoWorkspaceIn08(t *testing.T) {
 
 	Equals(t, "output", output)
 	terraform.VerifyWasCalledOnce().RunCommandWithVersion(
+		nil,
 		logger,
 		"/path",
 		[]string{"plan",
@@ -80,7 +82,7 @@ func TestRun_NoWorkspaceIn08(t *testing.T) {
 		workspace)
 
 	// Verify that no env or workspace commands were run
-	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(logger,
+	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(nil, logger,
 		"/path",
 		[]string{"env",
 			"select",
@@ -88,7 +90,7 @@ func TestRun_NoWorkspaceIn08(t *testing.T) {
 			"workspace"},
 		tfVersion,
 		workspace)
-	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(logger,
+	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(nil, logger,
 		"/path",
 		[]string{"workspace",
 			"select",
@@ -98,6 +100,53 @@ func TestRun_NoWorkspaceIn08(t *testing.T) {
 		workspace)
 }
 
+func TestRun_DisableTFVars(t *testing.T) {
+	t.Log("the atlantis_* variables shouldn't be passed if they're disabled")
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+
+	tfVersion, _ := version.NewVersion("0.8")
+	logger := logging.NewNoopLogger()
+	s := runtime.PlanStepRunner{
+		DefaultTFVersion:  tfVersion,
+		TerraformExecutor: terraform,
+		DisableTFVars:     true,
+	}
+
+	When(terraform.RunCommandWithVersion(matchers2.AnyContextContext(), matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("output", nil)
+	_, err := s.Run(models.ProjectCommandContext{
+		Log:        logger,
+		Workspace:  "default",
+		RepoRelDir: ".",
+		User:       models.User{Username: "username"},
+		Pull: models.PullRequest{
+			Num: 2,
+		},
+		BaseRepo: models.Repo{
+			FullName: "owner/repo",
+			Owner:    "owner",
+			Name:     "repo",
+		},
+	}, []string{"extra", "args"}, "/path")
+	Ok(t, err)
+
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(
+		nil,
+		logger,
+		"/path",
+		[]string{"plan",
+			"-input=false",
+			"-refresh",
+			"-no-color",
+			"-out",
+			"\"/path/default.tfplan\"",
+			"extra",
+			"args"},
+		tfVersion,
+		"default")
+}
+
 func TestRun_ErrWorkspaceIn08(t *testing.T) {
 	// If they attempt to use a workspace other than default in 0.8
 	// we should error.
@@ -112,7 +161,7 @@ func TestRun_ErrWorkspaceIn08(t *testing.T) {
 		DefaultTFVersion:  tfVersion,
 	}
 
-	When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+	When(terraform.RunCommandWithVersion(matchers2.AnyContextContext(), matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
 		ThenReturn("output", nil)
 	_, err := s.Run(models.ProjectCommandContext{
 		Log:        logger,
@@ -160,7 +209,7 @@ func TestRun_SwitchesWorkspace(t *testing.T) {
 				DefaultTFVersion:  tfVersion,
 			}
 
-			When(terraform.RunCommandWithVersion(matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+			When(terraform.RunCommandWithVersion(matchers2.AnyContextContext(), matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
 				ThenReturn("output", nil)
 			output, err := s.Run(models.ProjectCommandContext{
 				Log:         logger,
@@ -181,7 +230,7 @@ func TestRun_SwitchesWorkspace(t *testing.T) {
 
 			Equals(t, "output", output)
 			// Verify that env select was called as well as plan.
-			terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger,
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(nil, logger,
 				"/path",
 				[]string{c.expWorkspaceCmd,
 					"select",
@@ -189,7 +238,7 @@ func TestRun_SwitchesWorkspace(t *testing.T) {
 					"workspace"},
 				tfVersion,
 				"workspace")
-			terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger,
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(nil, logger,
 				"/path",
 				[]string{"plan",
 					"-input=false",
@@ -255,10 +304,10 @@ func TestRun_CreatesWorkspace(t *testing.T) {
 
 			// Ensure that we actually try to switch workspaces by making the
 			// output of `workspace show` to be a different name.
-			When(terraform.RunCommandWithVersion(logger, "/path", []string{"workspace", "show"}, tfVersion, "workspace")).ThenReturn("diffworkspace\n", nil)
+			When(terraform.RunCommandWithVersion(nil, logger, "/path", []string{"workspace", "show"}, tfVersion, "workspace")).ThenReturn("diffworkspace\n", nil)
 
 			expWorkspaceArgs := []string{c.expWorkspaceCommand, "select", "-no-color", "workspace"}
-			When(terraform.RunCommandWithVersion(logger, "/path", expWorkspaceArgs, tfVersion, "workspace")).ThenReturn("", errors.New("workspace does not exist"))
+			When(terraform.RunCommandWithVersion(nil, logger, "/path", expWorkspaceArgs, tfVersion, "workspace")).ThenReturn("", errors.New("workspace does not exist"))
 
 			expPlanArgs := []string{"plan",
 				"-input=false",
@@ -280,7 +329,7 @@ func TestRun_CreatesWorkspace(t *testing.T) {
 				"args",
 				"comment",
 				"args"}
-			When(terraform.RunCommandWithVersion(logger, "/path", expPlanArgs, tfVersion, "workspace")).ThenReturn("output", nil)
+			When(terraform.RunCommandWithVersion(nil, logger, "/path", expPlanArgs, tfVersion, "workspace")).ThenReturn("output", nil)
 
 			output, err := s.Run(models.ProjectCommandContext{
 				Log:         logger,
@@ -301,8 +350,8 @@ func TestRun_CreatesWorkspace(t *testing.T) {
 
 			Equals(t, "output", output)
 			// Verify that env select was called as well as plan.
-			terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, "/path", expWorkspaceArgs, tfVersion, "workspace")
-			terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, "/path", expPlanArgs, tfVersion, "workspace")
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(nil, logger, "/path", expWorkspaceArgs, tfVersion, "workspace")
+			terraform.VerifyWasCalledOnce().RunCommandWithVersion(nil, logger, "/path", expPlanArgs, tfVersion, "workspace")
 		})
 	}
 }
@@ -318,7 +367,7 @@ func TestRun_NoWorkspaceSwitchIfNotNecessary(t *testing.T) {
 		TerraformExecutor: terraform,
 		DefaultTFVersion:  tfVersion,
 	}
-	When(terraform.RunCommandWithVersion(logger, "/path", []string{"workspace", "show"}, tfVersion, "workspace")).ThenReturn("workspace\n", nil)
+	When(terraform.RunCommandWithVersion(nil, logger, "/path", []string{"workspace", "show"}, tfVersion, "workspace")).ThenReturn("workspace\n", nil)
 
 	expPlanArgs := []string{"plan",
 		"-input=false",
@@ -340,7 +389,7 @@ func TestRun_NoWorkspaceSwitchIfNotNecessary(t *testing.T) {
 		"args",
 		"comment",
 		"args"}
-	When(terraform.RunCommandWithVersion(logger, "/path", expPlanArgs, tfVersion, "workspace")).ThenReturn("output", nil)
+	When(terraform.RunCommandWithVersion(nil, logger, "/path", expPlanArgs, tfVersion, "workspace")).ThenReturn("output", nil)
 
 	output, err := s.Run(models.ProjectCommandContext{
 		Log:         logger,
@@ -360,10 +409,10 @@ func TestRun_NoWorkspaceSwitchIfNotNecessary(t *testing.T) {
 	Ok(t, err)
 
 	Equals(t, "output", output)
-	terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, "/path", expPlanArgs, tfVersion, "workspace")
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(nil, logger, "/path", expPlanArgs, tfVersion, "workspace")
 
 	// Verify that workspace select was never called.
-	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(logger, "/path", []string{"workspace", "select", "-no-color", "workspace"}, tfVersion, "workspace")
+	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(nil, logger, "/path", []string{"workspace", "select", "-no-color", "workspace"}, tfVersion, "workspace")
 }
 
 func TestRun_AddsEnvVarFile(t *testing.T) {
@@ -411,7 +460,7 @@ func TestRun_AddsEnvVarFile(t *testing.T) {
 		"-var-file",
 		envVarsFile,
 	}
-	When(terraform.RunCommandWithVersion(logger, tmpDir, expPlanArgs, tfVersion, "workspace")).ThenReturn("output", nil)
+	When(terraform.RunCommandWithVersion(nil, logger, tmpDir, expPlanArgs, tfVersion, "workspace")).ThenReturn("output", nil)
 
 	output, err := s.Run(models.ProjectCommandContext{
 		Log:         logger,
@@ -431,9 +480,61 @@ func TestRun_AddsEnvVarFile(t *testing.T) {
 	Ok(t, err)
 
 	// Verify that env select was never called since we're in version >= 0.10
-	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(logger, tmpDir, []string{"env", "select", "-no-color", "workspace"}, tfVersion, "workspace")
-	terraform.VerifyWasCalledOnce().RunCommandWithVersion(logger, tmpDir, expPlanArgs, tfVersion, "workspace")
+	terraform.VerifyWasCalled(Never()).RunCommandWithVersion(nil, logger, tmpDir, []string{"env", "select", "-no-color", "workspace"}, tfVersion, "workspace")
+	terraform.VerifyWasCalledOnce().RunCommandWithVersion(nil, logger, tmpDir, expPlanArgs, tfVersion, "workspace")
 	Equals(t, "output", output)
+
+	t.Log("the version should be recorded next to the planfile so it's used for apply")
+	recorded, err := ioutil.ReadFile(filepath.Join(tmpDir, "workspace.tfplan.tfversion"))
+	Ok(t, err)
+	Equals(t, "0.10.0", string(recorded))
+}
+
+func TestRun_DiscoveredProjectsUseEnvVarFile(t *testing.T) {
+	// Autodiscover gives a project a workspace for each env/{workspace}.tfvars
+	// file so planning it should pass that file without a custom workflow.
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	Ok(t, os.MkdirAll(filepath.Join(repoDir, "network", "env"), 0700))
+	Ok(t, ioutil.WriteFile(filepath.Join(repoDir, "network", "main.tf"), []byte("provider \"aws\" {}\n"), 0600))
+	Ok(t, ioutil.WriteFile(filepath.Join(repoDir, "network", "env", "staging.tfvars"), nil, 0600))
+	projects, err := raw.Autodiscover{}.Discover(repoDir)
+	Ok(t, err)
+	Equals(t, 1, len(projects))
+	Equals(t, []string{"staging"}, projects[0].Workspaces)
+
+	tfVersion, _ := version.NewVersion("0.10.0")
+	logger := logging.NewNoopLogger()
+	s := runtime.PlanStepRunner{
+		TerraformExecutor: terraform,
+		DefaultTFVersion:  tfVersion,
+	}
+	When(terraform.RunCommandWithVersion(matchers2.AnyContextContext(), matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString())).
+		ThenReturn("output", nil)
+	projectDir := filepath.Join(repoDir, *projects[0].Dir)
+	_, err = s.Run(models.ProjectCommandContext{
+		Log:        logger,
+		Workspace:  projects[0].Workspaces[0],
+		RepoRelDir: *projects[0].Dir,
+		User:       models.User{Username: "username"},
+		Pull: models.PullRequest{
+			Num: 2,
+		},
+		BaseRepo: models.Repo{
+			FullName: "owner/repo",
+			Owner:    "owner",
+			Name:     "repo",
+		},
+	}, nil, projectDir)
+	Ok(t, err)
+
+	// The last command is the plan, after the workspace is selected.
+	_, _, _, args, _, _ := terraform.VerifyWasCalled(AtLeast(1)).RunCommandWithVersion(matchers2.AnyContextContext(), matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString()).GetCapturedArguments()
+	Equals(t, "plan", args[0])
+	Equals(t, []string{"-var-file", filepath.Join(projectDir, "env", "staging.tfvars")}, args[len(args)-2:])
 }
 
 func TestRun_UsesDiffPathForProject(t *testing.T) {
@@ -447,7 +548,7 @@ func TestRun_UsesDiffPathForProject(t *testing.T) {
 		TerraformExecutor: terraform,
 		DefaultTFVersion:  tfVersion,
 	}
-	When(terraform.RunCommandWithVersion(logger, "/path", []string{"workspace", "show"}, tfVersion, "workspace")).ThenReturn("workspace\n", nil)
+	When(terraform.RunCommandWithVersion(nil, logger, "/path", []string{"workspace", "show"}, tfVersion, "workspace")).ThenReturn("workspace\n", nil)
 
 	expPlanArgs := []string{"plan",
 		"-input=false",
@@ -470,7 +571,7 @@ func TestRun_UsesDiffPathForProject(t *testing.T) {
 		"comment",
 		"args",
 	}
-	When(terraform.RunCommandWithVersion(logger, "/path", expPlanArgs, tfVersion, "default")).ThenReturn("output", nil)
+	When(terraform.RunCommandWithVersion(nil, logger, "/path", expPlanArgs, tfVersion, "default")).ThenReturn("output", nil)
 
 	projectName := "projectname"
 	output, err := s.Run(models.ProjectCommandContext{
@@ -531,6 +632,7 @@ Terraform will perform the following actions:
 		DefaultTFVersion:  tfVersion,
 	}
 	When(terraform.RunCommandWithVersion(
+		matchers2.AnyContextContext(),
 		matchers.AnyPtrToLoggingSimpleLogger(),
 		AnyString(),
 		AnyStringSlice(),
@@ -4,12 +4,12 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 
 	"github.com/hashicorp/go-version"
 	"github.com/pkg/errors"
 	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/terraform"
 )
 
 // RunStepRunner runs custom commands.
@@ -29,26 +29,15 @@ func (r *RunStepRunner) Run(ctx models.ProjectCommandContext, command []string,
 		tfVersion = ctx.ProjectConfig.TerraformVersion.String()
 	}
 	baseEnvVars := os.Environ()
-	customEnvVars := map[string]string{
-		"WORKSPACE":                  ctx.Workspace,
-		"ATLANTIS_TERRAFORM_VERSION": tfVersion,
-		"DIR":                        path,
-		"PLANFILE":                   filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectConfig)),
-		"BASE_REPO_NAME":             ctx.BaseRepo.Name,
-		"BASE_REPO_OWNER":            ctx.BaseRepo.Owner,
-		"HEAD_REPO_NAME":             ctx.HeadRepo.Name,
-		"HEAD_REPO_OWNER":            ctx.HeadRepo.Owner,
-		"HEAD_BRANCH_NAME":           ctx.Pull.Branch,
-		"PULL_NUM":                   fmt.Sprintf("%d", ctx.Pull.Num),
-		"PULL_AUTHOR":                ctx.Pull.Author,
-	}
+	customEnvVars := StepEnv(ctx, path)
+	customEnvVars["ATLANTIS_TERRAFORM_VERSION"] = tfVersion
 
 	finalEnvVars := baseEnvVars
 	for key, val := range customEnvVars {
 		finalEnvVars = append(finalEnvVars, fmt.Sprintf("%s=%s", key, val))
 	}
 	cmd.Env = finalEnvVars
-	out, err := cmd.CombinedOutput()
+	out, err := terraform.RunInterruptibleCmd(ctx.Context, cmd, terraform.DefaultInterruptGracePeriod)
 
 	commandStr := strings.Join(command, " ")
 	if err != nil {
@@ -36,12 +36,16 @@ func TestRunStepRunner_Run(t *testing.T) {
 		},
 		{
 			Command: "echo workspace=$WORKSPACE version=$ATLANTIS_TERRAFORM_VERSION dir=$DIR planfile=$PLANFILE",
-			ExpOut:  "workspace=myworkspace version=0.11.0 dir=$DIR planfile=$DIR/myworkspace.tfplan\n",
+			ExpOut:  "workspace=myworkspace version=0.11.0 dir=$DIR planfile=$DIR/myproject-myworkspace.tfplan\n",
 		},
 		{
 			Command: "echo base_repo_name=$BASE_REPO_NAME base_repo_owner=$BASE_REPO_OWNER head_repo_name=$HEAD_REPO_NAME head_repo_owner=$HEAD_REPO_OWNER head_branch_name=$HEAD_BRANCH_NAME pull_num=$PULL_NUM pull_author=$PULL_AUTHOR",
 			ExpOut:  "base_repo_name=basename base_repo_owner=baseowner head_repo_name=headname head_repo_owner=headowner head_branch_name=add-feat pull_num=2 pull_author=acme\n",
 		},
+		{
+			Command: "echo head_commit=$HEAD_COMMIT pull_url=$PULL_URL user_name=$USER_NAME project_name=$PROJECT_NAME repo_rel_dir=$REPO_REL_DIR",
+			ExpOut:  "head_commit=abc123 pull_url=https://github.com/baseowner/basename/pull/2 user_name=commenter project_name=myproject repo_rel_dir=mydir\n",
+		},
 	}
 
 	projVersion, err := version.NewVersion("v0.11.0")
@@ -50,6 +54,7 @@ func TestRunStepRunner_Run(t *testing.T) {
 	r := runtime.RunStepRunner{
 		DefaultTFVersion: defaultVersion,
 	}
+	projectName := "myproject"
 	ctx := models.ProjectCommandContext{
 		BaseRepo: models.Repo{
 			Name:  "basename",
@@ -60,10 +65,13 @@ func TestRunStepRunner_Run(t *testing.T) {
 			Owner: "headowner",
 		},
 		Pull: models.PullRequest{
-			Num:    2,
-			Branch: "add-feat",
-			Author: "acme",
+			Num:        2,
+			Branch:     "add-feat",
+			Author:     "acme",
+			HeadCommit: "abc123",
+			URL:        "https://github.com/baseowner/basename/pull/2",
 		},
+		User:       models.User{Username: "commenter"},
 		Log:        logging.NewNoopLogger(),
 		Workspace:  "myworkspace",
 		RepoRelDir: "mydir",
@@ -71,6 +79,7 @@ func TestRunStepRunner_Run(t *testing.T) {
 			TerraformVersion: projVersion,
 			Workspace:        "myworkspace",
 			Dir:              "mydir",
+			Name:             &projectName,
 		},
 	}
 	for _, c := range cases {
@@ -3,6 +3,7 @@
 package runtime
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 
@@ -12,7 +13,10 @@ import (
 )
 
 type TerraformExec interface {
-	RunCommandWithVersion(log *logging.SimpleLogger, path string, args []string, v *version.Version, workspace string) (string, error)
+	RunCommandWithVersion(ctx context.Context, log *logging.SimpleLogger, path string, args []string, v *version.Version, workspace string) (string, error)
+	// ResolveConstraint returns the newest version of terraform that
+	// satisfies constraints.
+	ResolveConstraint(log *logging.SimpleLogger, constraints version.Constraints) (*version.Version, error)
 }
 
 // MustConstraint returns a constraint. It panics on error.
@@ -0,0 +1,105 @@
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/terraform"
+)
+
+// StepEnv returns the env vars that describe the pull request and project
+// that ctx is for. path is the absolute path to the project. They're set for
+// every step and are the data extra_args are rendered with.
+func StepEnv(ctx models.ProjectCommandContext, path string) map[string]string {
+	var projectName string
+	if ctx.ProjectConfig != nil {
+		projectName = ctx.ProjectConfig.GetName()
+	}
+	return map[string]string{
+		"WORKSPACE":        ctx.Workspace,
+		"DIR":              path,
+		"REPO_REL_DIR":     ctx.RepoRelDir,
+		"PROJECT_NAME":     projectName,
+		"PLANFILE":         filepath.Join(path, GetPlanFilename(ctx.Workspace, ctx.ProjectConfig)),
+		"BASE_REPO_NAME":   ctx.BaseRepo.Name,
+		"BASE_REPO_OWNER":  ctx.BaseRepo.Owner,
+		"HEAD_REPO_NAME":   ctx.HeadRepo.Name,
+		"HEAD_REPO_OWNER":  ctx.HeadRepo.Owner,
+		"HEAD_BRANCH_NAME": ctx.Pull.Branch,
+		"HEAD_COMMIT":      ctx.Pull.HeadCommit,
+		"PULL_NUM":         fmt.Sprintf("%d", ctx.Pull.Num),
+		"PULL_URL":         ctx.Pull.URL,
+		"PULL_AUTHOR":      ctx.Pull.Author,
+		"USER_NAME":        ctx.User.Username,
+	}
+}
+
+// RenderExtraArgs renders each of args as a Go template with env as its data,
+// ex. -var-file={{.WORKSPACE}}.tfvars. It errors if an arg uses a key that
+// isn't in env.
+// The args are run through sh -c and values like HEAD_BRANCH_NAME are
+// controlled by whoever opened the pull request so the output of each action
+// is shell quoted, ex. -var-file='staging'.tfvars. Conditions like
+// {{if eq .WORKSPACE "prod"}} see the raw values.
+func RenderExtraArgs(args []string, env map[string]string) ([]string, error) {
+	var rendered []string
+	for _, arg := range args {
+		tmpl, err := template.New("extra_args").
+			Option("missingkey=error").
+			Funcs(template.FuncMap{shellQuoteFunc: shellQuote}).
+			Parse(arg)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing extra_args %q", arg)
+		}
+		quoteActions(tmpl.Tree.Root)
+		buf := &bytes.Buffer{}
+		if err := tmpl.Execute(buf, env); err != nil {
+			return nil, errors.Wrapf(err, "rendering extra_args %q", arg)
+		}
+		rendered = append(rendered, buf.String())
+	}
+	return rendered, nil
+}
+
+// shellQuoteFunc is the name of the template func that quotes the output of
+// actions. It's added to every action by quoteActions so extra_args don't
+// need to call it.
+const shellQuoteFunc = "_shell_quote"
+
+func shellQuote(v interface{}) string {
+	return terraform.ShellQuote(fmt.Sprint(v))
+}
+
+// quoteActions pipes the output of every action in list, including the ones
+// nested in if, range and with, to shellQuoteFunc.
+func quoteActions(list *parse.ListNode) {
+	if list == nil {
+		return
+	}
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.ActionNode:
+			// Actions that declare variables don't output anything.
+			if len(n.Pipe.Decl) == 0 {
+				n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+					NodeType: parse.NodeCommand,
+					Args:     []parse.Node{parse.NewIdentifier(shellQuoteFunc)},
+				})
+			}
+		case *parse.IfNode:
+			quoteActions(n.List)
+			quoteActions(n.ElseList)
+		case *parse.RangeNode:
+			quoteActions(n.List)
+			quoteActions(n.ElseList)
+		case *parse.WithNode:
+			quoteActions(n.List)
+			quoteActions(n.ElseList)
+		}
+	}
+}
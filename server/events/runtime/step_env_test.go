@@ -0,0 +1,94 @@
+package runtime_test
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestRenderExtraArgs(t *testing.T) {
+	env := map[string]string{
+		"WORKSPACE":        "staging",
+		"PULL_NUM":         "2",
+		"HEAD_BRANCH_NAME": "a;touch pwned`id`$(id)|cat's",
+	}
+	cases := []struct {
+		description string
+		args        []string
+		exp         []string
+		expErr      string
+	}{
+		{
+			description: "no args",
+		},
+		{
+			description: "no templates",
+			args:        []string{"-var", "a=b"},
+			exp:         []string{"-var", "a=b"},
+		},
+		{
+			description: "templates",
+			args:        []string{"-var-file={{.WORKSPACE}}.tfvars", "-var", "pull={{.PULL_NUM}}"},
+			exp:         []string{"-var-file='staging'.tfvars", "-var", "pull='2'"},
+		},
+		{
+			description: "hostile values are shell quoted",
+			args:        []string{"-var", "branch={{.HEAD_BRANCH_NAME}}"},
+			exp:         []string{"-var", `branch='a;touch pwned` + "`id`" + `$(id)|cat'\''s'`},
+		},
+		{
+			description: "conditions see raw values",
+			args:        []string{`{{if eq .WORKSPACE "staging"}}-var-file=staging.tfvars{{else}}-var-file={{.WORKSPACE}}.tfvars{{end}}`},
+			exp:         []string{"-var-file=staging.tfvars"},
+		},
+		{
+			description: "actions in conditions are quoted",
+			args:        []string{`{{if ne .WORKSPACE "prod"}}-var=branch={{.HEAD_BRANCH_NAME}}{{end}}`},
+			exp:         []string{`-var=branch='a;touch pwned` + "`id`" + `$(id)|cat'\''s'`},
+		},
+		{
+			description: "pipelines are quoted once",
+			args:        []string{`{{$ws := .WORKSPACE}}-var=ws={{$ws | printf "%s-1"}}`},
+			exp:         []string{"-var=ws='staging-1'"},
+		},
+		{
+			description: "missing key",
+			args:        []string{"-var-file={{.MISSING}}.tfvars"},
+			expErr:      "rendering extra_args \"-var-file={{.MISSING}}.tfvars\": template: extra_args:1:12: executing \"extra_args\" at <.MISSING>: map has no entry for key \"MISSING\"",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			act, err := runtime.RenderExtraArgs(c.args, env)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.exp, act)
+		})
+	}
+}
+
+// The rendered args must come out of sh unchanged, no matter what's in the
+// values.
+func TestRenderExtraArgs_ShellSafe(t *testing.T) {
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+	branch := "a;touch pwned`touch pwned2`$(touch pwned3)|cat's \"x\" \\"
+	act, err := runtime.RenderExtraArgs([]string{"branch={{.HEAD_BRANCH_NAME}}"}, map[string]string{"HEAD_BRANCH_NAME": branch})
+	Ok(t, err)
+
+	cmd := exec.Command("sh", "-c", "printf %s "+strings.Join(act, " ")) // nolint: gosec
+	cmd.Dir = tmpDir
+	out, err := cmd.CombinedOutput()
+	Ok(t, err)
+	Equals(t, "branch="+branch, string(out))
+	files, err := ioutil.ReadDir(tmpDir)
+	Ok(t, err)
+	Equals(t, 0, len(files))
+}
@@ -0,0 +1,129 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/hcl/ast"
+	"github.com/hashicorp/hcl/hcl/parser"
+	"github.com/hashicorp/hcl/hcl/token"
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// planVersionSuffix is appended to a planfile's path to get the path of the
+// file recording the version of terraform that created the plan.
+const planVersionSuffix = ".tfversion"
+
+// getTFVersion returns the version of terraform to run for the project in
+// path. An exact terraform_version in the project's config is used as is.
+// Otherwise a version constraint from the project's config, or from the
+// required_version setting in the project's Terraform code, is resolved to the
+// newest matching version. If there's no constraint, defaultVersion is used.
+func getTFVersion(ctx models.ProjectCommandContext, tf TerraformExec, defaultVersion *version.Version, path string) (*version.Version, error) {
+	if ctx.ProjectConfig != nil && ctx.ProjectConfig.TerraformVersion != nil {
+		return ctx.ProjectConfig.TerraformVersion, nil
+	}
+
+	var constraints version.Constraints
+	if ctx.ProjectConfig != nil && ctx.ProjectConfig.TerraformVersionConstraint != nil {
+		constraints = ctx.ProjectConfig.TerraformVersionConstraint
+	} else {
+		var err error
+		constraints, err = requiredVersion(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if constraints == nil {
+		return defaultVersion, nil
+	}
+	v, err := tf.ResolveConstraint(ctx.Log, constraints)
+	if err != nil {
+		return nil, errors.Wrap(err, "resolving terraform version")
+	}
+	return v, nil
+}
+
+// requiredVersion returns the constraints from the required_version settings
+// in the terraform blocks of the .tf files in dir. It returns nil if there are
+// none. Files that can't be parsed are skipped since terraform itself will
+// report the error.
+func requiredVersion(dir string) (version.Constraints, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	// Sort so the constraints are always combined in the same order.
+	sort.Strings(files)
+
+	var constraints version.Constraints
+	for _, file := range files {
+		contents, err := ioutil.ReadFile(file) // nolint: gosec
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading %s", file)
+		}
+		hclFile, err := parser.Parse(contents)
+		if err != nil {
+			continue
+		}
+		list, ok := hclFile.Node.(*ast.ObjectList)
+		if !ok {
+			continue
+		}
+		for _, block := range list.Filter("terraform").Items {
+			obj, ok := block.Val.(*ast.ObjectType)
+			if !ok {
+				continue
+			}
+			for _, setting := range obj.List.Filter("required_version").Items {
+				lit, ok := setting.Val.(*ast.LiteralType)
+				if !ok || lit.Token.Type != token.STRING {
+					continue
+				}
+				constraintStr, ok := lit.Token.Value().(string)
+				if !ok {
+					continue
+				}
+				c, err := version.NewConstraint(strings.TrimSpace(constraintStr))
+				if err != nil {
+					return nil, errors.Wrapf(err, "parsing required_version %q in %s", constraintStr, filepath.Base(file))
+				}
+				// Terraform requires all the constraints to be satisfied.
+				constraints = append(constraints, c...)
+			}
+		}
+	}
+	return constraints, nil
+}
+
+// writePlanVersion records that the plan at planPath was created with v so
+// it's applied with the same version, even if a newer version that matches
+// the project's constraints is released in between. Nothing is recorded if v
+// is nil since terraform's default version is used.
+func writePlanVersion(planPath string, v *version.Version) error {
+	if v == nil {
+		return nil
+	}
+	err := ioutil.WriteFile(planPath+planVersionSuffix, []byte(v.String()), 0600)
+	return errors.Wrap(err, "recording terraform version of plan")
+}
+
+// readPlanVersion returns the version of terraform recorded by
+// writePlanVersion for the plan at planPath, or nil if none was recorded, ex.
+// for plans created before versions were recorded.
+func readPlanVersion(planPath string) (*version.Version, error) {
+	contents, err := ioutil.ReadFile(planPath + planVersionSuffix) // nolint: gosec
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "reading terraform version of plan")
+	}
+	v, err := version.NewVersion(strings.TrimSpace(string(contents)))
+	return v, errors.Wrap(err, "parsing terraform version of plan")
+}
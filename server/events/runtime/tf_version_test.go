@@ -0,0 +1,133 @@
+package runtime_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/runtime"
+	"github.com/runatlantis/atlantis/server/events/terraform/mocks"
+	matchers2 "github.com/runatlantis/atlantis/server/events/terraform/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestRun_TFVersion(t *testing.T) {
+	defaultVersion, _ := version.NewVersion("0.11.0")
+	exactVersion, _ := version.NewVersion("0.11.3")
+	resolvedVersion, _ := version.NewVersion("0.11.7")
+	cases := []struct {
+		description string
+		files       map[string]string
+		projCfg     *valid.Project
+		// expConstraint is the constraint we expect to be resolved. If empty,
+		// we expect no constraint to be resolved.
+		expConstraint string
+		expVersion    *version.Version
+		expErr        string
+	}{
+		{
+			description: "no config or required_version",
+			files:       map[string]string{"main.tf": `resource "null_resource" "a" {}`},
+			expVersion:  defaultVersion,
+		},
+		{
+			description: "exact version in config",
+			files:       map[string]string{"main.tf": `terraform { required_version = "~> 0.10.0" }`},
+			projCfg:     &valid.Project{TerraformVersion: exactVersion},
+			expVersion:  exactVersion,
+		},
+		{
+			description:   "constraint in config",
+			files:         map[string]string{"main.tf": `terraform { required_version = "~> 0.10.0" }`},
+			projCfg:       &valid.Project{TerraformVersionConstraint: runtime.MustConstraint("~> 0.11.0")},
+			expConstraint: "~> 0.11.0",
+			expVersion:    resolvedVersion,
+		},
+		{
+			description:   "required_version",
+			files:         map[string]string{"versions.tf": `terraform { required_version = "~> 0.11.0" }`},
+			projCfg:       &valid.Project{},
+			expConstraint: "~> 0.11.0",
+			expVersion:    resolvedVersion,
+		},
+		{
+			description: "required_version in multiple blocks and files",
+			files: map[string]string{
+				"a.tf": `terraform {
+  required_version = ">= 0.11.0"
+  backend "s3" {}
+}`,
+				"b.tf": `terraform { required_version = "< 0.12" }`,
+				// Files that aren't valid HCL should be skipped.
+				"c.tf": `terraform {`,
+				// Only .tf files should be parsed.
+				"d.tf.json": `{"terraform": {"required_version": "0.9.0"}}`,
+			},
+			expConstraint: ">= 0.11.0,< 0.12",
+			expVersion:    resolvedVersion,
+		},
+		{
+			description: "invalid required_version",
+			files:       map[string]string{"main.tf": `terraform { required_version = "latest" }`},
+			expErr:      "parsing required_version \"latest\" in main.tf: Malformed constraint: latest",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			RegisterMockTestingT(t)
+			tmpDir, cleanup := TempDir(t)
+			defer cleanup()
+			for name, contents := range c.files {
+				Ok(t, ioutil.WriteFile(filepath.Join(tmpDir, name), []byte(contents), 0600))
+			}
+
+			terraform := mocks.NewMockClient()
+			When(terraform.ResolveConstraint(matchers.AnyPtrToLoggingSimpleLogger(), matchers2.AnyGoVersionConstraints())).ThenReturn(resolvedVersion, nil)
+			runner := runtime.InitStepRunner{
+				TerraformExecutor: terraform,
+				DefaultTFVersion:  defaultVersion,
+			}
+			_, err := runner.Run(models.ProjectCommandContext{
+				Workspace:     "default",
+				RepoRelDir:    ".",
+				ProjectConfig: c.projCfg,
+			}, nil, tmpDir)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+
+			if c.expConstraint == "" {
+				terraform.VerifyWasCalled(Never()).ResolveConstraint(matchers.AnyPtrToLoggingSimpleLogger(), matchers2.AnyGoVersionConstraints())
+			} else {
+				_, constraints := terraform.VerifyWasCalledOnce().ResolveConstraint(matchers.AnyPtrToLoggingSimpleLogger(), matchers2.AnyGoVersionConstraints()).GetCapturedArguments()
+				Equals(t, c.expConstraint, constraints.String())
+			}
+			_, _, _, _, usedVersion, _ := terraform.VerifyWasCalledOnce().RunCommandWithVersion(matchers2.AnyContextContext(), matchers.AnyPtrToLoggingSimpleLogger(), AnyString(), AnyStringSlice(), matchers2.AnyPtrToGoVersionVersion(), AnyString()).GetCapturedArguments()
+			Equals(t, c.expVersion, usedVersion)
+		})
+	}
+}
+
+func TestRun_TFVersionResolveErr(t *testing.T) {
+	RegisterMockTestingT(t)
+	terraform := mocks.NewMockClient()
+	When(terraform.ResolveConstraint(matchers.AnyPtrToLoggingSimpleLogger(), matchers2.AnyGoVersionConstraints())).ThenReturn(nil, errors.New("no version"))
+	runner := runtime.PlanStepRunner{
+		TerraformExecutor: terraform,
+	}
+	_, err := runner.Run(models.ProjectCommandContext{
+		Workspace:     "default",
+		RepoRelDir:    ".",
+		ProjectConfig: &valid.Project{TerraformVersionConstraint: runtime.MustConstraint("~> 0.11.0")},
+	}, nil, "/path")
+	ErrEquals(t, "resolving terraform version: no version", err)
+}
@@ -0,0 +1,165 @@
+package terraform
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+)
+
+// DefaultDownloadURL is the base URL of the mirror that terraform releases
+// are downloaded from by default.
+const DefaultDownloadURL = "https://releases.hashicorp.com"
+
+// versionLinkRegex matches links to the directories of versions in a
+// mirror's index page, ex. href="/terraform/0.11.7/" or href="0.11.7/".
+var versionLinkRegex = regexp.MustCompile(`href="(?:[^"]*/)?(\d+\.\d+\.\d+[^"/]*)/?"`)
+
+// ReleaseDownloader downloads terraform from a mirror of
+// releases.hashicorp.com, ie. a server that has the same directory layout.
+// Downloads are verified against the release's SHA256SUMS file.
+type ReleaseDownloader struct {
+	// BaseURL is the URL of the mirror, ex. https://releases.hashicorp.com.
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// Download downloads version v of terraform for the current OS and
+// architecture and writes the executable to dest. dest is only created once
+// the download has been verified so it will never hold a partial download.
+func (r *ReleaseDownloader) Download(v *version.Version, dest string) error {
+	zipName := fmt.Sprintf("terraform_%s_%s_%s.zip", v.String(), runtime.GOOS, runtime.GOARCH)
+	sums, err := r.get(r.releaseURL(v, fmt.Sprintf("terraform_%s_SHA256SUMS", v.String())))
+	if err != nil {
+		return errors.Wrap(err, "downloading checksums")
+	}
+	expSum, err := findChecksum(sums, zipName)
+	if err != nil {
+		return err
+	}
+	zipBytes, err := r.get(r.releaseURL(v, zipName))
+	if err != nil {
+		return errors.Wrapf(err, "downloading %s", zipName)
+	}
+	actSum := sha256.Sum256(zipBytes)
+	if hex.EncodeToString(actSum[:]) != expSum {
+		return fmt.Errorf("checksum of %s was %x, expected %s", zipName, actSum, expSum)
+	}
+
+	exe, err := extractTerraform(zipBytes)
+	if err != nil {
+		return errors.Wrapf(err, "extracting %s", zipName)
+	}
+	// Write to a temp file in the same directory and rename it so that dest
+	// appears atomically, even to other processes sharing the directory.
+	if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(dest), filepath.Base(dest)+".download")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // nolint: errcheck
+	if _, err := tmp.Write(exe); err != nil {
+		tmp.Close() // nolint: errcheck
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0700); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+// ListVersions returns the versions of terraform the mirror has. It parses
+// the links in the mirror's terraform/ index page.
+func (r *ReleaseDownloader) ListVersions() ([]*version.Version, error) {
+	index, err := r.get(fmt.Sprintf("%s/terraform/", strings.TrimSuffix(r.BaseURL, "/")))
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var versions []*version.Version
+	for _, match := range versionLinkRegex.FindAllStringSubmatch(string(index), -1) {
+		v, err := version.NewVersion(match[1])
+		if err != nil || seen[v.String()] {
+			continue
+		}
+		seen[v.String()] = true
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+func (r *ReleaseDownloader) releaseURL(v *version.Version, filename string) string {
+	return fmt.Sprintf("%s/terraform/%s/%s", strings.TrimSuffix(r.BaseURL, "/"), v.String(), filename)
+}
+
+func (r *ReleaseDownloader) get(url string) ([]byte, error) {
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// findChecksum returns the checksum of filename from the contents of a
+// SHA256SUMS file.
+func findChecksum(sums []byte, filename string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[1] == filename {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s in SHA256SUMS", filename)
+}
+
+// extractTerraform returns the contents of the terraform executable in the
+// release zip.
+func extractTerraform(zipBytes []byte) ([]byte, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(zipBytes), int64(len(zipBytes)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zipReader.File {
+		if f.Name != "terraform" && f.Name != "terraform.exe" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close() // nolint: errcheck
+		var buf bytes.Buffer
+		if _, err := io.Copy(&buf, rc); err != nil { // nolint: gosec
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, errors.New("no terraform executable in zip")
+}
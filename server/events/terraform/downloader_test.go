@@ -0,0 +1,202 @@
+package terraform_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/runatlantis/atlantis/server/events/terraform"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestReleaseDownloader_Download(t *testing.T) {
+	mirror := newReleaseMirror(t, "0.11.8", "#!/bin/sh\necho downloaded\n")
+	defer mirror.Close()
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+
+	d := terraform.ReleaseDownloader{BaseURL: mirror.URL}
+	dest := filepath.Join(tmpDir, "terraform0.11.8")
+	err := d.Download(version.Must(version.NewVersion("0.11.8")), dest)
+	Ok(t, err)
+
+	contents, err := ioutil.ReadFile(dest)
+	Ok(t, err)
+	Equals(t, "#!/bin/sh\necho downloaded\n", string(contents))
+	info, err := os.Stat(dest)
+	Ok(t, err)
+	Equals(t, os.FileMode(0700), info.Mode().Perm())
+
+	// Only the executable should be left in the directory.
+	files, err := ioutil.ReadDir(tmpDir)
+	Ok(t, err)
+	Equals(t, 1, len(files))
+}
+
+func TestReleaseDownloader_DownloadErrors(t *testing.T) {
+	zipName := fmt.Sprintf("terraform_0.11.8_%s_%s.zip", runtime.GOOS, runtime.GOARCH)
+	cases := []struct {
+		description string
+		sums        string
+		zipBytes    []byte
+		expErr      string
+	}{
+		{
+			description: "checksum mismatch",
+			sums:        fmt.Sprintf("%x  %s\n", sha256.Sum256([]byte("other")), zipName),
+			zipBytes:    releaseZip(t, "exe"),
+			expErr:      fmt.Sprintf("checksum of %s was %x, expected %x", zipName, sha256.Sum256(releaseZip(t, "exe")), sha256.Sum256([]byte("other"))),
+		},
+		{
+			description: "no checksum for our platform",
+			sums:        "abc  terraform_0.11.8_plan9_386.zip\n",
+			zipBytes:    releaseZip(t, "exe"),
+			expErr:      fmt.Sprintf("no checksum for %s in SHA256SUMS", zipName),
+		},
+		{
+			description: "zip missing",
+			sums:        fmt.Sprintf("%x  %s\n", sha256.Sum256(nil), zipName),
+			zipBytes:    nil,
+			expErr:      fmt.Sprintf("downloading %s: GET {url}/terraform/0.11.8/%s returned status 404", zipName, zipName),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/terraform/0.11.8/terraform_0.11.8_SHA256SUMS":
+					w.Write([]byte(c.sums)) // nolint: errcheck
+				case "/terraform/0.11.8/" + zipName:
+					if c.zipBytes == nil {
+						http.NotFound(w, r)
+						return
+					}
+					w.Write(c.zipBytes) // nolint: errcheck
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+			tmpDir, cleanup := TempDir(t)
+			defer cleanup()
+
+			d := terraform.ReleaseDownloader{BaseURL: server.URL}
+			dest := filepath.Join(tmpDir, "terraform0.11.8")
+			err := d.Download(version.Must(version.NewVersion("0.11.8")), dest)
+			ErrEquals(t, strings.Replace(c.expErr, "{url}", server.URL, -1), err)
+			_, err = os.Stat(dest)
+			Assert(t, os.IsNotExist(err), "exp executable to not exist")
+		})
+	}
+}
+
+func TestRunCommandWithVersion_DownloadsMissingVersion(t *testing.T) {
+	mirror := newReleaseMirror(t, "0.11.8", "#!/bin/sh\necho \"downloaded $@\"\n")
+	defer mirror.Close()
+	dataDir, cleanup := TempDir(t)
+	defer cleanup()
+	defer fakeDefaultTerraform(t, "0.11.7")()
+
+	client, err := terraform.NewClient(dataDir, mirror.URL)
+	Ok(t, err)
+	Equals(t, "0.11.7", client.Version().String())
+
+	// Run concurrently to check the version is only downloaded once.
+	v := version.Must(version.NewVersion("0.11.8"))
+	var wg sync.WaitGroup
+	outputs := make([]string, 5)
+	errs := make([]error, 5)
+	for i := range outputs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			outputs[i], errs[i] = client.RunCommandWithVersion(context.Background(), logging.NewNoopLogger(), dataDir, []string{"plan"}, v, "default")
+		}(i)
+	}
+	wg.Wait()
+	for i := range outputs {
+		Ok(t, errs[i])
+		Equals(t, "downloaded plan\n", outputs[i])
+	}
+	Equals(t, 1, mirror.zipDownloads())
+
+	_, err = os.Stat(filepath.Join(dataDir, "bin", "terraform0.11.8"))
+	Ok(t, err)
+}
+
+type releaseMirror struct {
+	*httptest.Server
+	mutex     sync.Mutex
+	downloads int
+}
+
+func (m *releaseMirror) zipDownloads() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.downloads
+}
+
+// newReleaseMirror returns a server with the same layout as
+// releases.hashicorp.com that serves version v of terraform for the current
+// platform, with exe as the contents of the executable.
+func newReleaseMirror(t *testing.T, v string, exe string) *releaseMirror {
+	zipName := fmt.Sprintf("terraform_%s_%s_%s.zip", v, runtime.GOOS, runtime.GOARCH)
+	zipBytes := releaseZip(t, exe)
+	sums := fmt.Sprintf("%x  terraform_%s_plan9_386.zip\n%x  %s\n", sha256.Sum256(nil), v, sha256.Sum256(zipBytes), zipName)
+	m := &releaseMirror{}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case fmt.Sprintf("/terraform/%s/terraform_%s_SHA256SUMS", v, v):
+			w.Write([]byte(sums)) // nolint: errcheck
+		case fmt.Sprintf("/terraform/%s/%s", v, zipName):
+			m.mutex.Lock()
+			m.downloads++
+			m.mutex.Unlock()
+			w.Write(zipBytes) // nolint: errcheck
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return m
+}
+
+// releaseZip returns a zip containing a terraform executable with contents
+// exe.
+func releaseZip(t *testing.T, exe string) []byte {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create("terraform")
+	Ok(t, err)
+	_, err = f.Write([]byte(exe))
+	Ok(t, err)
+	Ok(t, w.Close())
+	return buf.Bytes()
+}
+
+// fakeDefaultTerraform puts a terraform executable that reports version v in
+// $PATH. The returned function restores $PATH.
+func fakeDefaultTerraform(t *testing.T, v string) func() {
+	binDir, cleanup := TempDir(t)
+	err := ioutil.WriteFile(filepath.Join(binDir, "terraform"), []byte(fmt.Sprintf("#!/bin/sh\necho 'Terraform v%s'\n", v)), 0700) // nolint: gosec
+	Ok(t, err)
+	origPath := os.Getenv("PATH")
+	os.Setenv("PATH", binDir+string(os.PathListSeparator)+origPath) // nolint: errcheck
+	return func() {
+		os.Setenv("PATH", origPath) // nolint: errcheck
+		cleanup()
+	}
+}
@@ -0,0 +1,106 @@
+package terraform
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// DefaultInterruptGracePeriod is how long we wait for a command to exit after
+// sending it SIGINT before we send SIGKILL.
+const DefaultInterruptGracePeriod = 30 * time.Second
+
+type outputWriterKey struct{}
+
+// WithOutputWriter returns a copy of ctx that causes commands run with
+// RunInterruptibleCmd to also write their output to w as it's produced. This
+// is used to stream output while a command is still running.
+func WithOutputWriter(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, outputWriterKey{}, w)
+}
+
+type envKey struct{}
+
+// WithEnv returns a copy of ctx that causes commands run with
+// RunInterruptibleCmd to also have the env vars in env. They're added after
+// cmd's env so they take precedence. This is used to export the env vars set
+// by env steps and atlantis.yaml to later steps.
+func WithEnv(ctx context.Context, env map[string]string) context.Context {
+	return context.WithValue(ctx, envKey{}, env)
+}
+
+// RunInterruptibleCmd runs cmd and returns its combined stdout and stderr.
+// If ctx is cancelled (or times out) before cmd exits, cmd's process group is
+// sent SIGINT so that Terraform can stop gracefully and release any state
+// locks it's holding. If it still hasn't exited after gracePeriod, it's sent
+// SIGKILL. When cmd is interrupted, the error returned is ctx.Err().
+// If ctx was created with WithOutputWriter, the output is also written to that
+// writer as it's produced. If ctx was created with WithEnv, those env vars are
+// added to cmd's env.
+func RunInterruptibleCmd(ctx context.Context, cmd *exec.Cmd, gracePeriod time.Duration) ([]byte, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if env, ok := ctx.Value(envKey{}).(map[string]string); ok && len(env) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		// Sort so the env is the same every time.
+		var names []string
+		for name := range env {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", name, env[name]))
+		}
+	}
+
+	var out bytes.Buffer
+	var w io.Writer = &out
+	if streamTo, ok := ctx.Value(outputWriterKey{}).(io.Writer); ok && streamTo != nil {
+		w = io.MultiWriter(&out, streamTo)
+	}
+	// Stdout and Stderr are set to the same writer so exec only uses one
+	// goroutine to write to it.
+	cmd.Stdout = w
+	cmd.Stderr = w
+	// Run in a new process group so that we can signal the commands being run
+	// by 'sh -c' and not just the shell itself.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	waitErr := make(chan error, 1)
+	go func() {
+		waitErr <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-waitErr:
+		return out.Bytes(), err
+	case <-ctx.Done():
+	}
+
+	// A negative pid signals the whole process group.
+	pgid := -cmd.Process.Pid
+	syscall.Kill(pgid, syscall.SIGINT) // nolint: errcheck
+	select {
+	case <-waitErr:
+	case <-time.After(gracePeriod):
+		syscall.Kill(pgid, syscall.SIGKILL) // nolint: errcheck
+		<-waitErr
+	}
+	return out.Bytes(), ctx.Err()
+}
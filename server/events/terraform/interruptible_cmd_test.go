@@ -0,0 +1,78 @@
+package terraform_test
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/terraform"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestRunInterruptibleCmd_Success(t *testing.T) {
+	out, err := terraform.RunInterruptibleCmd(context.Background(), exec.Command("sh", "-c", "echo out; echo err >&2"), time.Second)
+	Ok(t, err)
+	Equals(t, "out\nerr\n", string(out))
+}
+
+func TestRunInterruptibleCmd_NilContext(t *testing.T) {
+	out, err := terraform.RunInterruptibleCmd(nil, exec.Command("sh", "-c", "echo hi"), time.Second) // nolint: staticcheck
+	Ok(t, err)
+	Equals(t, "hi\n", string(out))
+}
+
+func TestRunInterruptibleCmd_AlreadyCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := terraform.RunInterruptibleCmd(ctx, exec.Command("sh", "-c", "echo hi"), time.Second)
+	Assert(t, err == context.Canceled, "expected cancelled error, got %v", err)
+}
+
+func TestRunInterruptibleCmd_SendsInterrupt(t *testing.T) {
+	t.Log("the command should be sent SIGINT so it can clean up")
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	cmd := exec.Command("sh", "-c", `trap 'echo interrupted; exit 1' INT; echo started; while true; do sleep 0.05; done`)
+	out, err := terraform.RunInterruptibleCmd(ctx, cmd, 5*time.Second)
+	Assert(t, err == context.DeadlineExceeded, "expected deadline exceeded error, got %v", err)
+	Assert(t, strings.Contains(string(out), "interrupted"), "expected output to contain interrupted, got %q", string(out))
+}
+
+func TestRunInterruptibleCmd_KillsAfterGracePeriod(t *testing.T) {
+	t.Log("if the command ignores SIGINT it should be killed after the grace period")
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	cmd := exec.Command("sh", "-c", `trap '' INT; while true; do sleep 0.05; done`)
+	start := time.Now()
+	_, err := terraform.RunInterruptibleCmd(ctx, cmd, 200*time.Millisecond)
+	Assert(t, err == context.DeadlineExceeded, "expected deadline exceeded error, got %v", err)
+	Assert(t, time.Since(start) < 5*time.Second, "command should have been killed")
+}
+
+func TestRunInterruptibleCmd_OutputWriter(t *testing.T) {
+	t.Log("output should also be written to the writer in the context")
+	var streamed bytes.Buffer
+	ctx := terraform.WithOutputWriter(context.Background(), &streamed)
+	out, err := terraform.RunInterruptibleCmd(ctx, exec.Command("sh", "-c", "echo out; echo err >&2"), time.Second)
+	Ok(t, err)
+	Equals(t, "out\nerr\n", string(out))
+	Equals(t, "out\nerr\n", streamed.String())
+}
+
+func TestRunInterruptibleCmd_Env(t *testing.T) {
+	t.Log("env vars in the context should be added and override the command's env")
+	ctx := terraform.WithEnv(context.Background(), map[string]string{"FOO": "override", "BAR": "bar"})
+	cmd := exec.Command("sh", "-c", `echo "$FOO $BAR $BAZ"`)
+	cmd.Env = []string{"FOO=foo", "BAZ=baz"}
+	out, err := terraform.RunInterruptibleCmd(ctx, cmd, time.Second)
+	Ok(t, err)
+	Equals(t, "override bar baz\n", string(out))
+
+	t.Log("if the command has no env, the process env should be kept")
+	out, err = terraform.RunInterruptibleCmd(ctx, exec.Command("sh", "-c", `echo "$FOO $PATH"`), time.Second)
+	Ok(t, err)
+	Assert(t, strings.HasPrefix(string(out), "override /"), "expected PATH to be kept, got %q", string(out))
+}
@@ -0,0 +1,20 @@
+package matchers
+
+import (
+	"reflect"
+
+	context "context"
+	"github.com/petergtz/pegomock"
+)
+
+func AnyContextContext() context.Context {
+	pegomock.RegisterMatcher(pegomock.NewAnyMatcher(reflect.TypeOf((*(context.Context))(nil)).Elem()))
+	var nullValue context.Context
+	return nullValue
+}
+
+func EqContextContext(value context.Context) context.Context {
+	pegomock.RegisterMatcher(&pegomock.EqMatcher{Value: value})
+	var nullValue context.Context
+	return nullValue
+}
@@ -0,0 +1,20 @@
+package matchers
+
+import (
+	"reflect"
+
+	go_version "github.com/hashicorp/go-version"
+	"github.com/petergtz/pegomock"
+)
+
+func AnyGoVersionConstraints() go_version.Constraints {
+	pegomock.RegisterMatcher(pegomock.NewAnyMatcher(reflect.TypeOf((*(go_version.Constraints))(nil)).Elem()))
+	var nullValue go_version.Constraints
+	return nullValue
+}
+
+func EqGoVersionConstraints(value go_version.Constraints) go_version.Constraints {
+	pegomock.RegisterMatcher(&pegomock.EqMatcher{Value: value})
+	var nullValue go_version.Constraints
+	return nullValue
+}
@@ -15,12 +15,17 @@
 package terraform
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/go-version"
 	"github.com/pkg/errors"
@@ -31,20 +36,56 @@ import (
 
 type Client interface {
 	Version() *version.Version
-	RunCommandWithVersion(log *logging.SimpleLogger, path string, args []string, v *version.Version, workspace string) (string, error)
+	RunCommandWithVersion(ctx context.Context, log *logging.SimpleLogger, path string, args []string, v *version.Version, workspace string) (string, error)
+	ResolveConstraint(log *logging.SimpleLogger, constraints version.Constraints) (*version.Version, error)
 }
 
 type DefaultClient struct {
 	defaultVersion          *version.Version
 	terraformPluginCacheDir string
+	// interruptGracePeriod is how long we give terraform to exit after
+	// interrupting it before we kill it.
+	interruptGracePeriod time.Duration
+	// binDir is the directory that downloaded terraform versions are stored
+	// in.
+	binDir     string
+	downloader *ReleaseDownloader
+	// versionLocks maps from version to a lock that's held while checking for
+	// and downloading that version so it's only downloaded once.
+	versionLocks      map[string]*sync.Mutex
+	versionLocksMutex sync.Mutex
+	// downloadableVersions caches the versions available to download. It's
+	// refreshed after downloadableVersionsTTL.
+	downloadableVersions      []*version.Version
+	downloadableVersionsTime  time.Time
+	downloadableVersionsMutex sync.Mutex
 }
 
 const terraformPluginCacheDirName = "plugin-cache"
 
+// binDirName is the name of the directory in the data dir that downloaded
+// terraform versions are stored in.
+const binDirName = "bin"
+
+// downloadableVersionsTTL is how long the list of versions available to
+// download is cached for.
+const downloadableVersionsTTL = time.Hour
+
+// versionedExeRegex matches the names of versioned terraform executables, ex.
+// terraform0.11.7.
+var versionedExeRegex = regexp.MustCompile(`^terraform(\d+\.\d+\.\d+.*)$`)
+
+// downloadTimeout is how long we wait for a terraform download to complete.
+const downloadTimeout = 5 * time.Minute
+
 // zeroPointNine constrains the version to be 0.9.*
 var versionRegex = regexp.MustCompile("Terraform v(.*)\n")
 
-func NewClient(dataDir string) (*DefaultClient, error) {
+// NewClient returns a client that runs the terraform executable in $PATH by
+// default. Other versions are run from $PATH if they exist there as
+// terraform{version}, ex. terraform0.11.7, otherwise they're downloaded from
+// the releases mirror at downloadURL and cached in dataDir.
+func NewClient(dataDir string, downloadURL string) (*DefaultClient, error) {
 	_, err := exec.LookPath("terraform")
 	if err != nil {
 		return nil, errors.New("terraform not found in $PATH. \n\nDownload terraform from https://www.terraform.io/downloads.html")
@@ -71,9 +112,21 @@ func NewClient(dataDir string) (*DefaultClient, error) {
 		return nil, errors.Wrapf(err, "unable to create terraform plugin cache directory at %q", terraformPluginCacheDirName)
 	}
 
+	binDir := filepath.Join(dataDir, binDirName)
+	if err := os.MkdirAll(binDir, 0700); err != nil {
+		return nil, errors.Wrapf(err, "unable to create terraform bin directory at %q", binDir)
+	}
+
 	return &DefaultClient{
 		defaultVersion:          v,
 		terraformPluginCacheDir: cacheDir,
+		interruptGracePeriod:    DefaultInterruptGracePeriod,
+		binDir:                  binDir,
+		downloader: &ReleaseDownloader{
+			BaseURL:    downloadURL,
+			HTTPClient: &http.Client{Timeout: downloadTimeout},
+		},
+		versionLocks: make(map[string]*sync.Mutex),
 	}, nil
 }
 
@@ -87,12 +140,18 @@ func (c *DefaultClient) Version() *version.Version {
 // If v is nil, will use the default version.
 // Workspace is the terraform workspace to run in. We won't switch workspaces
 // but will set the TERRAFORM_WORKSPACE environment variable.
-func (c *DefaultClient) RunCommandWithVersion(log *logging.SimpleLogger, path string, args []string, v *version.Version, workspace string) (string, error) {
+// If ctx is cancelled while terraform is running, terraform is interrupted
+// so it can release its state locks before exiting.
+func (c *DefaultClient) RunCommandWithVersion(ctx context.Context, log *logging.SimpleLogger, path string, args []string, v *version.Version, workspace string) (string, error) {
 	tfExecutable := "terraform"
 	tfVersionStr := c.defaultVersion.String()
 	// if version is the same as the default, don't need to prepend the version name to the executable
 	if v != nil && !v.Equal(c.defaultVersion) {
-		tfExecutable = fmt.Sprintf("%s%s", tfExecutable, v.String())
+		var err error
+		tfExecutable, err = c.ensureVersion(log, v)
+		if err != nil {
+			return "", errors.Wrapf(err, "getting terraform %s", v.String())
+		}
 		tfVersionStr = v.String()
 	}
 
@@ -111,15 +170,16 @@ func (c *DefaultClient) RunCommandWithVersion(log *logging.SimpleLogger, path st
 	// preserved and any vars that users purposely exec'd Atlantis with.
 	envVars = append(envVars, os.Environ()...)
 
-	// append terraform executable name with args
-	tfCmd := fmt.Sprintf("%s %s", tfExecutable, strings.Join(args, " "))
+	// append terraform executable name with args. The executable is quoted
+	// because downloaded versions are in the data dir, which can have spaces.
+	tfCmd := fmt.Sprintf("%s %s", ShellQuote(tfExecutable), strings.Join(args, " "))
 
 	// We use 'sh -c' so that if extra_args have been specified with env vars,
 	// ex. -var-file=$WORKSPACE.tfvars, then they get substituted.
 	terraformCmd := exec.Command("sh", "-c", tfCmd) // #nosec
 	terraformCmd.Dir = path
 	terraformCmd.Env = envVars
-	out, err := terraformCmd.CombinedOutput()
+	out, err := RunInterruptibleCmd(ctx, terraformCmd, c.interruptGracePeriod)
 	commandStr := strings.Join(terraformCmd.Args, " ")
 	if err != nil {
 		err = fmt.Errorf("%s: running %q in %q", err, commandStr, path)
@@ -130,6 +190,116 @@ func (c *DefaultClient) RunCommandWithVersion(log *logging.SimpleLogger, path st
 	return string(out), nil
 }
 
+// ensureVersion returns the executable to run for version v of terraform. If
+// terraform{version} isn't in $PATH, v is downloaded into our bin dir unless
+// it's already there.
+// ShellQuote returns s in single quotes so sh doesn't interpret any of it.
+// Single quotes in s are closed, escaped and reopened.
+func ShellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func (c *DefaultClient) ensureVersion(log *logging.SimpleLogger, v *version.Version) (string, error) {
+	exeName := fmt.Sprintf("terraform%s", v.String())
+	if _, err := exec.LookPath(exeName); err == nil {
+		return exeName, nil
+	}
+
+	lock := c.versionLock(v)
+	lock.Lock()
+	defer lock.Unlock()
+
+	exePath := filepath.Join(c.binDir, exeName)
+	if _, err := os.Stat(exePath); err == nil {
+		return exePath, nil
+	}
+	log.Info("%s not found in $PATH or %q, downloading it", exeName, c.binDir)
+	if err := c.downloader.Download(v, exePath); err != nil {
+		return "", err
+	}
+	log.Info("downloaded terraform %s to %q", v.String(), exePath)
+	return exePath, nil
+}
+
+func (c *DefaultClient) versionLock(v *version.Version) *sync.Mutex {
+	c.versionLocksMutex.Lock()
+	defer c.versionLocksMutex.Unlock()
+	lock, ok := c.versionLocks[v.String()]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.versionLocks[v.String()] = lock
+	}
+	return lock
+}
+
+// ResolveConstraint returns the newest version of terraform that satisfies
+// constraints out of the default version, the versions installed in $PATH or
+// our bin dir and the versions that can be downloaded. Pre-releases are never
+// chosen.
+func (c *DefaultClient) ResolveConstraint(log *logging.SimpleLogger, constraints version.Constraints) (*version.Version, error) {
+	candidates := append([]*version.Version{c.defaultVersion}, c.installedVersions()...)
+	downloadable, err := c.listDownloadableVersions()
+	if err != nil {
+		log.Warn("unable to list terraform versions available to download, only considering installed versions: %s", err)
+	}
+	candidates = append(candidates, downloadable...)
+
+	var newest *version.Version
+	for _, v := range candidates {
+		if v.Prerelease() != "" || !constraints.Check(v) {
+			continue
+		}
+		if newest == nil || v.GreaterThan(newest) {
+			newest = v
+		}
+	}
+	if newest == nil {
+		return nil, fmt.Errorf("no installed or downloadable version of terraform satisfies %q", constraints.String())
+	}
+	log.Debug("resolved terraform version constraint %q to %s", constraints.String(), newest)
+	return newest, nil
+}
+
+// installedVersions returns the versions of the terraform{version}
+// executables in $PATH and our bin dir.
+func (c *DefaultClient) installedVersions() []*version.Version {
+	var versions []*version.Version
+	dirs := append(filepath.SplitList(os.Getenv("PATH")), c.binDir)
+	for _, dir := range dirs {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, f := range files {
+			match := versionedExeRegex.FindStringSubmatch(f.Name())
+			if match == nil || f.IsDir() {
+				continue
+			}
+			if v, err := version.NewVersion(match[1]); err == nil {
+				versions = append(versions, v)
+			}
+		}
+	}
+	return versions
+}
+
+// listDownloadableVersions returns the versions that can be downloaded,
+// caching them for downloadableVersionsTTL.
+func (c *DefaultClient) listDownloadableVersions() ([]*version.Version, error) {
+	c.downloadableVersionsMutex.Lock()
+	defer c.downloadableVersionsMutex.Unlock()
+	if c.downloadableVersions != nil && time.Since(c.downloadableVersionsTime) < downloadableVersionsTTL {
+		return c.downloadableVersions, nil
+	}
+	versions, err := c.downloader.ListVersions()
+	if err != nil {
+		return nil, err
+	}
+	c.downloadableVersions = versions
+	c.downloadableVersionsTime = time.Now()
+	return versions, nil
+}
+
 // MustConstraint will parse one or more constraints from the given
 // constraint string. The string must be a comma-separated list of
 // constraints. It panics if there is an error.
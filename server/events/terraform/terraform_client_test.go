@@ -14,10 +14,16 @@
 package terraform_test
 
 import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/hashicorp/go-version"
 	"github.com/runatlantis/atlantis/server/events/terraform"
+	"github.com/runatlantis/atlantis/server/logging"
 	. "github.com/runatlantis/atlantis/testing"
 )
 
@@ -39,3 +45,114 @@ func TestMustConstraint(t *testing.T) {
 	Ok(t, err)
 	Equals(t, expectedConstraint.String(), c.String())
 }
+
+func TestResolveConstraint(t *testing.T) {
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/terraform/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`<ul>
+<li><a href="../">../</a></li>
+<li><a href="/terraform/0.12.0-beta1/">terraform_0.12.0-beta1</a></li>
+<li><a href="/terraform/0.11.8/">terraform_0.11.8</a></li>
+<li><a href="/terraform/0.10.8/">terraform_0.10.8</a></li>
+<li><a href="0.9.11/">0.9.11/</a></li>
+</ul>`)) // nolint: errcheck
+	}))
+	defer mirror.Close()
+	dataDir, cleanup := TempDir(t)
+	defer cleanup()
+	defer fakeDefaultTerraform(t, "0.11.7")()
+	// Put terraform0.11.9 in our bin dir so it's installed.
+	Ok(t, os.MkdirAll(filepath.Join(dataDir, "bin"), 0700))
+	Ok(t, ioutil.WriteFile(filepath.Join(dataDir, "bin", "terraform0.11.9"), nil, 0700))
+
+	client, err := terraform.NewClient(dataDir, mirror.URL)
+	Ok(t, err)
+
+	cases := []struct {
+		constraint string
+		expVersion string
+		expErr     string
+	}{
+		{
+			// The installed version is newest.
+			"~> 0.11.0",
+			"0.11.9",
+			"",
+		},
+		{
+			// Only the default version matches.
+			"= 0.11.7",
+			"0.11.7",
+			"",
+		},
+		{
+			// Only downloadable versions match.
+			"< 0.11",
+			"0.10.8",
+			"",
+		},
+		{
+			"~> 0.9.0",
+			"0.9.11",
+			"",
+		},
+		{
+			// Pre-releases should never be chosen.
+			">= 0.12.0-a",
+			"",
+			"no installed or downloadable version of terraform satisfies \">= 0.12.0-a\"",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.constraint, func(t *testing.T) {
+			v, err := client.ResolveConstraint(logging.NewNoopLogger(), terraform.MustConstraint(c.constraint))
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.expVersion, v.String())
+		})
+	}
+}
+
+func TestResolveConstraint_MirrorDown(t *testing.T) {
+	t.Log("if the mirror can't be reached, only installed versions should be used")
+	mirror := httptest.NewServer(http.NotFoundHandler())
+	defer mirror.Close()
+	dataDir, cleanup := TempDir(t)
+	defer cleanup()
+	defer fakeDefaultTerraform(t, "0.11.7")()
+
+	client, err := terraform.NewClient(dataDir, mirror.URL)
+	Ok(t, err)
+	v, err := client.ResolveConstraint(logging.NewNoopLogger(), terraform.MustConstraint(">= 0.11"))
+	Ok(t, err)
+	Equals(t, "0.11.7", v.String())
+}
+
+func TestRunCommandWithVersion_DataDirWithSpaces(t *testing.T) {
+	t.Log("downloaded versions should run even if the data dir has spaces")
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	dataDir := filepath.Join(tmp, "data dir")
+	defer fakeDefaultTerraform(t, "0.11.7")()
+	Ok(t, os.MkdirAll(filepath.Join(dataDir, "bin"), 0700))
+	Ok(t, ioutil.WriteFile(filepath.Join(dataDir, "bin", "terraform0.11.9"), []byte("#!/bin/sh\necho \"ran $@\"\n"), 0700)) // nolint: gosec
+
+	client, err := terraform.NewClient(dataDir, "")
+	Ok(t, err)
+	v, err := version.NewVersion("0.11.9")
+	Ok(t, err)
+	out, err := client.RunCommandWithVersion(nil, logging.NewNoopLogger(), tmp, []string{"plan"}, v, "default")
+	Ok(t, err)
+	Equals(t, "ran plan\n", out)
+}
+
+func TestShellQuote(t *testing.T) {
+	Equals(t, "'/data dir/terraform'", terraform.ShellQuote("/data dir/terraform"))
+	Equals(t, `'it'\''s'`, terraform.ShellQuote("it's"))
+}
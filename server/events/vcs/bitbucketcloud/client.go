@@ -124,8 +124,14 @@ func (b *Client) PullIsApproved(repo models.Repo, pull models.PullRequest) (bool
 	return false, nil
 }
 
-// UpdateStatus updates the status of a commit.
-func (b *Client) UpdateStatus(repo models.Repo, pull models.PullRequest, status models.CommitStatus, description string) error {
+// IsTeamMember isn't supported on Bitbucket Cloud so it always errors.
+func (b *Client) IsTeamMember(repo models.Repo, user models.User, team string) (bool, error) {
+	return false, errors.New("team membership lookups aren't supported on Bitbucket Cloud")
+}
+
+// UpdateStatus updates the status of a commit. The status links to url if
+// it's set, otherwise to Atlantis itself.
+func (b *Client) UpdateStatus(repo models.Repo, pull models.PullRequest, status models.CommitStatus, description string, url string) error {
 	bbState := "FAILED"
 	switch status {
 	case models.PendingCommitStatus:
@@ -136,9 +142,12 @@ func (b *Client) UpdateStatus(repo models.Repo, pull models.PullRequest, status
 		bbState = "FAILED"
 	}
 
+	if url == "" {
+		url = b.AtlantisURL
+	}
 	bodyBytes, err := json.Marshal(map[string]string{
 		"key":         "atlantis",
-		"url":         b.AtlantisURL,
+		"url":         url,
 		"state":       bbState,
 		"description": description,
 	})
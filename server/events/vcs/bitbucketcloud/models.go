@@ -46,6 +46,7 @@ type Repository struct {
 }
 type PullRequest struct {
 	ID           *int          `json:"id,omitempty" validate:"required"`
+	Title        *string       `json:"title,omitempty"`
 	Source       *Source       `json:"source,omitempty" validate:"required"`
 	Participants []Participant `json:"participants,omitempty" validate:"required"`
 	Links        *Links        `json:"links,omitempty" validate:"required"`
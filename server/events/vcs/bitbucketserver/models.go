@@ -23,6 +23,7 @@ type CommonEventData struct {
 
 type PullRequest struct {
 	ID        *int    `json:"id,omitempty" validate:"required"`
+	Title     *string `json:"title,omitempty"`
 	FromRef   *Ref    `json:"fromRef,omitempty" validate:"required"`
 	ToRef     *Ref    `json:"toRef,omitempty" validate:"required"`
 	State     *string `json:"state,omitempty" validate:"required"`
@@ -24,5 +24,8 @@ type Client interface {
 	GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error)
 	CreateComment(repo models.Repo, pullNum int, comment string) error
 	PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error)
-	UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, description string) error
+	UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, description string, url string) error
+	// IsTeamMember returns true if user is a member of team, ex. a GitHub
+	// team or GitLab group.
+	IsTeamMember(repo models.Repo, user models.User, team string) (bool, error)
 }
@@ -16,6 +16,7 @@ package vcs
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 
@@ -127,9 +128,10 @@ func (g *GithubClient) GetPullRequest(repo models.Repo, num int) (*github.PullRe
 	return pull, err
 }
 
-// UpdateStatus updates the status badge on the pull request.
+// UpdateStatus updates the status badge on the pull request. If url is set,
+// the status will link to it.
 // See https://github.com/blog/1227-commit-status-api.
-func (g *GithubClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, description string) error {
+func (g *GithubClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, description string, url string) error {
 	const statusContext = "Atlantis"
 	ghState := "error"
 	switch state {
@@ -144,6 +146,53 @@ func (g *GithubClient) UpdateStatus(repo models.Repo, pull models.PullRequest, s
 		State:       github.String(ghState),
 		Description: github.String(description),
 		Context:     github.String(statusContext)}
+	if url != "" {
+		status.TargetURL = github.String(url)
+	}
 	_, _, err := g.client.Repositories.CreateStatus(g.ctx, repo.Owner, repo.Name, pull.HeadCommit, status)
 	return err
 }
+
+// IsTeamMember returns true if user is an active member of team. team is in
+// the format {org}/{team slug}, ex. runatlantis/maintainers.
+func (g *GithubClient) IsTeamMember(repo models.Repo, user models.User, team string) (bool, error) {
+	slash := strings.Index(team, "/")
+	if slash <= 0 || slash == len(team)-1 {
+		return false, fmt.Errorf("team %q is not in the format {org}/{team slug}", team)
+	}
+	org, slug := team[:slash], team[slash+1:]
+
+	teamID := 0
+	nextPage := 0
+	for teamID == 0 {
+		opts := github.ListOptions{
+			PerPage: 100,
+			Page:    nextPage,
+		}
+		teams, resp, err := g.client.Organizations.ListTeams(g.ctx, org, &opts)
+		if err != nil {
+			return false, errors.Wrapf(err, "listing teams of %q", org)
+		}
+		for _, t := range teams {
+			if strings.EqualFold(t.GetSlug(), slug) {
+				teamID = t.GetID()
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		nextPage = resp.NextPage
+	}
+	if teamID == 0 {
+		return false, fmt.Errorf("team %q not found", team)
+	}
+
+	membership, resp, err := g.client.Organizations.GetTeamMembership(g.ctx, teamID, user.Username)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "getting membership of %q in team %q", user.Username, team)
+	}
+	return membership.GetState() == "active", nil
+}
@@ -132,7 +132,7 @@ func TestGithubClient_UpdateStatus(t *testing.T) {
 				},
 			}, models.PullRequest{
 				Num: 1,
-			}, c.status, "description")
+			}, c.status, "description", "")
 			Ok(t, err)
 		})
 	}
@@ -147,3 +147,53 @@ func disableSSLVerification() func() {
 		http.DefaultTransport.(*http.Transport).TLSClientConfig = orig
 	}
 }
+
+func TestGithubClient_IsTeamMember(t *testing.T) {
+	testServer := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v3/orgs/owner/teams?per_page=100":
+				w.Write([]byte(`[{"id": 1, "slug": "devs"}, {"id": 2, "slug": "infra"}]`)) // nolint: errcheck
+			case "/api/v3/teams/2/memberships/alice":
+				w.Write([]byte(`{"state": "active", "role": "member"}`)) // nolint: errcheck
+			case "/api/v3/teams/2/memberships/bob":
+				w.Write([]byte(`{"state": "pending", "role": "member"}`)) // nolint: errcheck
+			case "/api/v3/teams/2/memberships/carol":
+				http.Error(w, `{"message": "Not Found"}`, http.StatusNotFound)
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+
+	testServerURL, err := url.Parse(testServer.URL)
+	Ok(t, err)
+	client, err := vcs.NewGithubClient(testServerURL.Host, "user", "pass")
+	Ok(t, err)
+	defer disableSSLVerification()()
+	repo := models.Repo{FullName: "owner/repo", Owner: "owner", Name: "repo"}
+
+	cases := []struct {
+		user      string
+		team      string
+		expMember bool
+		expErr    string
+	}{
+		{"alice", "owner/infra", true, ""},
+		{"bob", "owner/infra", false, ""},
+		{"carol", "owner/infra", false, ""},
+		{"alice", "owner/missing", false, `team "owner/missing" not found`},
+		{"alice", "infra", false, `team "infra" is not in the format {org}/{team slug}`},
+	}
+	for _, c := range cases {
+		t.Run(c.user+" "+c.team, func(t *testing.T) {
+			member, err := client.IsTeamMember(repo, models.User{Username: c.user}, c.team)
+			if c.expErr != "" {
+				ErrEquals(t, c.expErr, err)
+				return
+			}
+			Ok(t, err)
+			Equals(t, c.expMember, member)
+		})
+	}
+}
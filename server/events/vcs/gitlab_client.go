@@ -139,8 +139,9 @@ func (g *GitlabClient) PullIsApproved(repo models.Repo, pull models.PullRequest)
 	return true, nil
 }
 
-// UpdateStatus updates the build status of a commit.
-func (g *GitlabClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, description string) error {
+// UpdateStatus updates the build status of a commit. If url is set, the
+// status will link to it.
+func (g *GitlabClient) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, description string, url string) error {
 	const statusContext = "Atlantis"
 
 	gitlabState := gitlab.Failed
@@ -152,11 +153,15 @@ func (g *GitlabClient) UpdateStatus(repo models.Repo, pull models.PullRequest, s
 	case models.SuccessCommitStatus:
 		gitlabState = gitlab.Success
 	}
-	_, _, err := g.Client.Commits.SetCommitStatus(repo.FullName, pull.HeadCommit, &gitlab.SetCommitStatusOptions{
+	opts := &gitlab.SetCommitStatusOptions{
 		State:       gitlabState,
 		Context:     gitlab.String(statusContext),
 		Description: gitlab.String(description),
-	})
+	}
+	if url != "" {
+		opts.TargetURL = gitlab.String(url)
+	}
+	_, _, err := g.Client.Commits.SetCommitStatus(repo.FullName, pull.HeadCommit, opts)
 	return err
 }
 
@@ -165,6 +170,34 @@ func (g *GitlabClient) GetMergeRequest(repoFullName string, pullNum int) (*gitla
 	return mr, err
 }
 
+// IsTeamMember returns true if user is a direct member of the group team. team
+// is the group's full path, ex. owner/infra.
+func (g *GitlabClient) IsTeamMember(repo models.Repo, user models.User, team string) (bool, error) {
+	const maxPerPage = 100
+	nextPage := 1
+	for {
+		opts := gitlab.ListGroupMembersOptions{
+			ListOptions: gitlab.ListOptions{
+				Page:    nextPage,
+				PerPage: maxPerPage,
+			},
+		}
+		members, resp, err := g.Client.Groups.ListGroupMembers(team, &opts)
+		if err != nil {
+			return false, errors.Wrapf(err, "listing members of group %q", team)
+		}
+		for _, m := range members {
+			if strings.EqualFold(m.Username, user.Username) {
+				return m.State == "active", nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return false, nil
+		}
+		nextPage = resp.NextPage
+	}
+}
+
 // GetVersion returns the version of the Gitlab server this client is using.
 func (g *GitlabClient) GetVersion() (*version.Version, error) {
 	req, err := g.Client.NewRequest("GET", "/version", nil, nil)
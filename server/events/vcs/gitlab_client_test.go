@@ -1,9 +1,13 @@
 package vcs
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/hashicorp/go-version"
+	"github.com/lkysow/go-gitlab"
+	"github.com/runatlantis/atlantis/server/events/models"
 	. "github.com/runatlantis/atlantis/testing"
 )
 
@@ -79,3 +83,30 @@ func TestGitlabClient_SupportsCommonMark(t *testing.T) {
 		})
 	}
 }
+
+func TestGitlabClient_IsTeamMember(t *testing.T) {
+	testServer := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.RequestURI {
+			case "/api/v4/groups/owner%2Finfra/members?page=1&per_page=100":
+				w.Header().Set("X-Next-Page", "2")
+				w.Write([]byte(`[{"id": 1, "username": "alice", "state": "active"}]`)) // nolint: errcheck
+			case "/api/v4/groups/owner%2Finfra/members?page=2&per_page=100":
+				w.Write([]byte(`[{"id": 2, "username": "bob", "state": "blocked"}]`)) // nolint: errcheck
+			default:
+				t.Errorf("got unexpected request at %q", r.RequestURI)
+				http.Error(w, "not found", http.StatusNotFound)
+			}
+		}))
+	defer testServer.Close()
+	client := &GitlabClient{Client: gitlab.NewClient(nil, "token")}
+	Ok(t, client.Client.SetBaseURL(testServer.URL+"/api/v4/"))
+
+	for user, expMember := range map[string]bool{"alice": true, "bob": false, "carol": false} {
+		t.Run(user, func(t *testing.T) {
+			member, err := client.IsTeamMember(models.Repo{}, models.User{Username: user}, "owner/infra")
+			Ok(t, err)
+			Equals(t, expMember, member)
+		})
+	}
+}
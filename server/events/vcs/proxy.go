@@ -25,7 +25,8 @@ type ClientProxy interface {
 	GetModifiedFiles(repo models.Repo, pull models.PullRequest) ([]string, error)
 	CreateComment(repo models.Repo, pullNum int, comment string) error
 	PullIsApproved(repo models.Repo, pull models.PullRequest) (bool, error)
-	UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, description string) error
+	UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, description string, url string) error
+	IsTeamMember(repo models.Repo, user models.User, team string) (bool, error)
 }
 
 // DefaultClientProxy proxies calls to the correct VCS client depending on which
@@ -71,6 +72,10 @@ func (d *DefaultClientProxy) PullIsApproved(repo models.Repo, pull models.PullRe
 	return d.clients[repo.VCSHost.Type].PullIsApproved(repo, pull)
 }
 
-func (d *DefaultClientProxy) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, description string) error {
-	return d.clients[repo.VCSHost.Type].UpdateStatus(repo, pull, state, description)
+func (d *DefaultClientProxy) UpdateStatus(repo models.Repo, pull models.PullRequest, state models.CommitStatus, description string, url string) error {
+	return d.clients[repo.VCSHost.Type].UpdateStatus(repo, pull, state, description, url)
+}
+
+func (d *DefaultClientProxy) IsTeamMember(repo models.Repo, user models.User, team string) (bool, error) {
+	return d.clients[repo.VCSHost.Type].IsTeamMember(repo, user, team)
 }
@@ -0,0 +1,209 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// DefaultSMTPTimeout is how long sending an email through the SMTP relay can
+// take by default, including connecting to it.
+const DefaultSMTPTimeout = 10 * time.Second
+
+// SMTPClient sends emails through an SMTP relay.
+type SMTPClient interface {
+	// IsConfigured returns true if there's a relay to send through.
+	IsConfigured() bool
+	SendMail(to []string, subject string, body string) error
+}
+
+// DefaultSMTPClient sends emails through the relay at Host:Port. If the relay
+// supports STARTTLS it's always used.
+type DefaultSMTPClient struct {
+	Host string
+	Port int
+	// Username and Password are used to authenticate with PLAIN auth if
+	// Username is set. Go's smtp package refuses to send them unencrypted
+	// unless the relay is on localhost.
+	Username string
+	Password string
+	// From is the address emails are sent from.
+	From string
+	// TLS is true if the connection should use TLS from the start, usually
+	// on port 465, instead of STARTTLS.
+	TLS bool
+	// TLSConfig is optional. It defaults to verifying the relay's
+	// certificate against Host.
+	TLSConfig *tls.Config
+	// Timeout is how long sending an email can take so a relay that stops
+	// responding doesn't block the command that triggered it. It defaults to
+	// DefaultSMTPTimeout.
+	Timeout time.Duration
+}
+
+func (d *DefaultSMTPClient) IsConfigured() bool {
+	return d.Host != ""
+}
+
+// SendMail sends a plain text email.
+func (d *DefaultSMTPClient) SendMail(to []string, subject string, body string) error {
+	addr := net.JoinHostPort(d.Host, strconv.Itoa(d.Port))
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = DefaultSMTPTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	var conn net.Conn
+	var err error
+	if d.TLS {
+		conn, err = tls.DialWithDialer(&net.Dialer{Deadline: deadline}, "tcp", addr, d.tlsConfig())
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, timeout)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "connecting to smtp relay %s", addr)
+	}
+	// The deadline covers the whole conversation with the relay, including
+	// after STARTTLS since that wraps conn.
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close() // nolint: errcheck
+		return errors.Wrapf(err, "connecting to smtp relay %s", addr)
+	}
+	client, err := smtp.NewClient(conn, d.Host)
+	if err != nil {
+		conn.Close() // nolint: errcheck
+		return errors.Wrapf(err, "connecting to smtp relay %s", addr)
+	}
+	defer client.Close() // nolint: errcheck
+
+	if !d.TLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(d.tlsConfig()); err != nil {
+				return errors.Wrap(err, "starting tls")
+			}
+		}
+	}
+	if d.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", d.Username, d.Password, d.Host)); err != nil {
+			return errors.Wrap(err, "authenticating with smtp relay")
+		}
+	}
+	if err := client.Mail(d.From); err != nil {
+		return errors.Wrap(err, "sending email")
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return errors.Wrapf(err, "sending email to %s", addr)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return errors.Wrap(err, "sending email")
+	}
+	if _, err := w.Write(d.message(to, subject, body)); err != nil {
+		return errors.Wrap(err, "sending email")
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrap(err, "sending email")
+	}
+	return client.Quit()
+}
+
+func (d *DefaultSMTPClient) tlsConfig() *tls.Config {
+	if d.TLSConfig != nil {
+		return d.TLSConfig
+	}
+	return &tls.Config{ServerName: d.Host}
+}
+
+// message returns the email with its headers.
+func (d *DefaultSMTPClient) message(to []string, subject string, body string) []byte {
+	var buf bytes.Buffer
+	header := func(key string, value string) {
+		// Newlines in values would start new headers.
+		value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value) // nolint: errcheck
+	}
+	header("From", d.From)
+	header("To", strings.Join(to, ", "))
+	header("Subject", subject)
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=UTF-8")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.Replace(body, "\n", "\r\n", -1))
+	return buf.Bytes()
+}
+
+// EmailWebhook sends emails through an SMTP relay.
+type EmailWebhook struct {
+	Client SMTPClient
+	To     []string
+	Filter Filter
+}
+
+// Send emails the apply webhook if the apply matches the filter.
+func (e *EmailWebhook) Send(log *logging.SimpleLogger, result ApplyResult) error {
+	if !e.Filter.Matches(result.Workspace, result.Repo.FullName, result.Pull.Branch, result.ProjectName, result.Success) {
+		return nil
+	}
+	return e.sendCommand(applyMessage(result))
+}
+
+// SendPlan emails the plan webhook if the plan matches the filter.
+func (e *EmailWebhook) SendPlan(log *logging.SimpleLogger, result PlanResult) error {
+	if !e.Filter.Matches(result.Workspace, result.Repo.FullName, result.Pull.Branch, result.ProjectName, result.Success) {
+		return nil
+	}
+	return e.sendCommand(planMessage(result))
+}
+
+// SendDrift emails the drift webhook if the result matches the filter.
+func (e *EmailWebhook) SendDrift(log *logging.SimpleLogger, result DriftResult) error {
+	if !e.Filter.Matches(result.Workspace, result.Repo.FullName, result.Branch, result.ProjectName, result.Success) {
+		return nil
+	}
+	headline := driftHeadline(result)
+	lines := []string{
+		headline,
+		"",
+		"Directory: " + result.RepoRelDir,
+		"Workspace: " + result.Workspace,
+	}
+	if result.ProjectName != "" {
+		lines = append(lines, "Project: "+result.ProjectName)
+	}
+	return e.Client.SendMail(e.To, "[Atlantis] "+headline, strings.Join(lines, "\n")+"\n")
+}
+
+func (e *EmailWebhook) sendCommand(msg commandMessage) error {
+	subject := fmt.Sprintf("[Atlantis] %s in %s", msg.headline(), msg.Workspace)
+	lines := []string{msg.headline()}
+	if msg.Pull.Title != "" {
+		lines = append(lines, msg.Pull.Title)
+	}
+	lines = append(lines,
+		"",
+		"Pull request: "+msg.Pull.URL,
+		"Workspace: "+msg.Workspace,
+		"User: "+msg.User.Username,
+	)
+	if msg.ProjectName != "" {
+		lines = append(lines, "Project: "+msg.ProjectName)
+	}
+	if changes := ResourceChanges(msg.Summary); changes != "" {
+		lines = append(lines, "Resources: "+changes)
+	}
+	if msg.OutputURL != "" {
+		lines = append(lines, "Output: "+msg.OutputURL)
+	}
+	return e.Client.SendMail(e.To, subject, strings.Join(lines, "\n")+"\n")
+}
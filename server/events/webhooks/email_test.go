@@ -0,0 +1,300 @@
+package webhooks_test
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/webhooks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// smtpServer is a stand-in for an SMTP relay. It accepts PLAIN auth with
+// Username and Password and records the emails sent through it.
+type smtpServer struct {
+	Username string
+	Password string
+
+	listener net.Listener
+	mutex    sync.Mutex
+	emails   []smtpEmail
+}
+
+type smtpEmail struct {
+	From string
+	To   []string
+	Msg  *mail.Message
+	Body string
+}
+
+// newSMTPServer starts an smtpServer on localhost. If tlsConfig is set the
+// connections use TLS from the start.
+func newSMTPServer(t *testing.T, tlsConfig *tls.Config) *smtpServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Ok(t, err)
+	if tlsConfig != nil {
+		l = tls.NewListener(l, tlsConfig)
+	}
+	s := &smtpServer{Username: "atlantis", Password: "password", listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// client returns a DefaultSMTPClient for the server.
+func (s *smtpServer) client() *webhooks.DefaultSMTPClient {
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return &webhooks.DefaultSMTPClient{
+		Host:     "127.0.0.1",
+		Port:     addr.Port,
+		Username: s.Username,
+		Password: s.Password,
+		From:     "atlantis@example.com",
+	}
+}
+
+func (s *smtpServer) Close() {
+	s.listener.Close() // nolint: errcheck
+}
+
+func (s *smtpServer) Emails() []smtpEmail {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.emails
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	c := textproto.NewConn(conn)
+	defer c.Close()                     // nolint: errcheck
+	c.PrintfLine("220 localhost ESMTP") // nolint: errcheck
+	var email smtpEmail
+	authenticated := false
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case cmd == "EHLO" || cmd == "HELO":
+			c.PrintfLine("250-localhost")  // nolint: errcheck
+			c.PrintfLine("250 AUTH PLAIN") // nolint: errcheck
+		case strings.HasPrefix(line, "AUTH PLAIN "):
+			creds, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(line, "AUTH PLAIN "))
+			if string(creds) != "\x00"+s.Username+"\x00"+s.Password {
+				c.PrintfLine("535 5.7.8 Authentication failed") // nolint: errcheck
+				continue
+			}
+			authenticated = true
+			c.PrintfLine("235 2.7.0 Authentication successful") // nolint: errcheck
+		case strings.HasPrefix(line, "MAIL FROM:"):
+			if !authenticated {
+				c.PrintfLine("530 5.7.0 Authentication required") // nolint: errcheck
+				continue
+			}
+			email = smtpEmail{From: strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")}
+			c.PrintfLine("250 OK") // nolint: errcheck
+		case strings.HasPrefix(line, "RCPT TO:"):
+			email.To = append(email.To, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+			c.PrintfLine("250 OK") // nolint: errcheck
+		case cmd == "DATA":
+			c.PrintfLine("354 End data with <CR><LF>.<CR><LF>") // nolint: errcheck
+			data, err := c.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg, err := mail.ReadMessage(bytes.NewReader(data))
+			if err != nil {
+				c.PrintfLine("554 %s", err) // nolint: errcheck
+				continue
+			}
+			body, _ := ioutil.ReadAll(msg.Body)
+			email.Msg = msg
+			email.Body = string(body)
+			s.mutex.Lock()
+			s.emails = append(s.emails, email)
+			s.mutex.Unlock()
+			c.PrintfLine("250 OK") // nolint: errcheck
+		case cmd == "QUIT":
+			c.PrintfLine("221 Bye") // nolint: errcheck
+			return
+		default:
+			c.PrintfLine("502 Command not implemented") // nolint: errcheck
+		}
+	}
+}
+
+func TestDefaultSMTPClient_SendMail(t *testing.T) {
+	server := newSMTPServer(t, nil)
+	defer server.Close()
+
+	err := server.client().SendMail([]string{"a@example.com", "b@example.com"}, "Subject\r\nBcc: evil@example.com", "line 1\nline 2\n")
+	Ok(t, err)
+	emails := server.Emails()
+	Equals(t, 1, len(emails))
+	Equals(t, "atlantis@example.com", emails[0].From)
+	Equals(t, []string{"a@example.com", "b@example.com"}, emails[0].To)
+	Equals(t, "a@example.com, b@example.com", emails[0].Msg.Header.Get("To"))
+	Equals(t, "Subject  Bcc: evil@example.com", emails[0].Msg.Header.Get("Subject"))
+	Equals(t, "", emails[0].Msg.Header.Get("Bcc"))
+	Equals(t, "text/plain; charset=UTF-8", emails[0].Msg.Header.Get("Content-Type"))
+	_, err = emails[0].Msg.Header.Date()
+	Ok(t, err)
+	// The stand-in's dot reader turns the CRLFs back into LFs.
+	Equals(t, "line 1\nline 2\n", emails[0].Body)
+}
+
+func TestDefaultSMTPClient_SendMailTLS(t *testing.T) {
+	// Borrow httptest's certificate, which is valid for 127.0.0.1.
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	clientTLS := ts.Client().Transport.(*http.Transport).TLSClientConfig
+	server := newSMTPServer(t, ts.TLS)
+	defer server.Close()
+	ts.Close()
+
+	client := server.client()
+	client.TLS = true
+	client.TLSConfig = clientTLS
+	Ok(t, client.SendMail([]string{"a@example.com"}, "subject", "body\n"))
+	Equals(t, 1, len(server.Emails()))
+
+	t.Log("the relay's certificate should be verified")
+	client.TLSConfig = nil
+	err := client.SendMail([]string{"a@example.com"}, "subject", "body\n")
+	Assert(t, err != nil, "expected an error")
+	Assert(t, strings.Contains(err.Error(), "certificate"), "expected a certificate error, got %s", err)
+}
+
+func TestDefaultSMTPClient_SendMailBadAuth(t *testing.T) {
+	server := newSMTPServer(t, nil)
+	defer server.Close()
+
+	client := server.client()
+	client.Password = "wrong"
+	err := client.SendMail([]string{"a@example.com"}, "subject", "body\n")
+	ErrEquals(t, "authenticating with smtp relay: 535 \"5.7.8 Authentication failed\"", err)
+	Equals(t, 0, len(server.Emails()))
+}
+
+func TestDefaultSMTPClient_SendMailTimeout(t *testing.T) {
+	t.Log("a relay that stops responding shouldn't block sending forever")
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	Ok(t, err)
+	defer l.Close() // nolint: errcheck
+	go func() {
+		// Accept the connection but never send the greeting.
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close() // nolint: errcheck
+			time.Sleep(5 * time.Second)
+		}
+	}()
+	host, port, err := net.SplitHostPort(l.Addr().String())
+	Ok(t, err)
+	portNum, err := strconv.Atoi(port)
+	Ok(t, err)
+	client := &webhooks.DefaultSMTPClient{
+		Host:    host,
+		Port:    portNum,
+		From:    "atlantis@example.com",
+		Timeout: 50 * time.Millisecond,
+	}
+
+	start := time.Now()
+	err = client.SendMail([]string{"a@example.com"}, "subject", "body\n")
+	Assert(t, err != nil, "expected an error")
+	Assert(t, strings.Contains(err.Error(), "timeout"), "expected a timeout error, got %s", err)
+	Assert(t, time.Since(start) < 2*time.Second, "expected SendMail to time out, took %s", time.Since(start))
+}
+
+func TestEmailWebhook_Send(t *testing.T) {
+	server := newSMTPServer(t, nil)
+	defer server.Close()
+	email := &webhooks.EmailWebhook{Client: server.client(), To: []string{"compliance@example.com"}}
+
+	err := email.Send(logging.NewNoopLogger(), webhooks.ApplyResult{
+		Workspace: "production",
+		Repo:      models.Repo{FullName: "runatlantis/atlantis"},
+		Pull: models.PullRequest{
+			Num:   1,
+			URL:   "https://github.com/runatlantis/atlantis/pull/1",
+			Title: "Add a bucket",
+		},
+		User:        models.User{Username: "lkysow"},
+		ProjectName: "storage",
+		Success:     true,
+		Summary:     "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.",
+		OutputURL:   "https://atlantis/output?pull=1",
+	})
+	Ok(t, err)
+	emails := server.Emails()
+	Equals(t, 1, len(emails))
+	Equals(t, []string{"compliance@example.com"}, emails[0].To)
+	Equals(t, "[Atlantis] Apply succeeded for runatlantis/atlantis#1 in production", emails[0].Msg.Header.Get("Subject"))
+	Equals(t, "Apply succeeded for runatlantis/atlantis#1\n"+
+		"Add a bucket\n"+
+		"\n"+
+		"Pull request: https://github.com/runatlantis/atlantis/pull/1\n"+
+		"Workspace: production\n"+
+		"User: lkysow\n"+
+		"Project: storage\n"+
+		"Resources: 1 added, 0 changed, 0 destroyed\n"+
+		"Output: https://atlantis/output?pull=1\n", emails[0].Body)
+}
+
+func TestEmailWebhook_SendDrift(t *testing.T) {
+	server := newSMTPServer(t, nil)
+	defer server.Close()
+	email := &webhooks.EmailWebhook{Client: server.client(), To: []string{"ops@example.com"}}
+
+	err := email.SendDrift(logging.NewNoopLogger(), webhooks.DriftResult{
+		Workspace:  "default",
+		Repo:       models.Repo{FullName: "runatlantis/atlantis"},
+		Branch:     "master",
+		RepoRelDir: "network",
+		Success:    false,
+	})
+	Ok(t, err)
+	emails := server.Emails()
+	Equals(t, 1, len(emails))
+	Equals(t, "[Atlantis] Drift detection plan failed in runatlantis/atlantis on branch master", emails[0].Msg.Header.Get("Subject"))
+	body := bufio.NewScanner(strings.NewReader(emails[0].Body))
+	body.Scan()
+	Equals(t, "Drift detection plan failed in runatlantis/atlantis on branch master", body.Text())
+}
+
+func TestEmailWebhook_Filter(t *testing.T) {
+	server := newSMTPServer(t, nil)
+	defer server.Close()
+	filter, err := webhooks.NewFilter(webhooks.Config{WorkspaceRegex: "^production$", Only: webhooks.OnlySuccess})
+	Ok(t, err)
+	email := &webhooks.EmailWebhook{Client: server.client(), To: []string{"compliance@example.com"}, Filter: filter}
+
+	log := logging.NewNoopLogger()
+	Ok(t, email.Send(log, webhooks.ApplyResult{Workspace: "staging", Success: true}))
+	Ok(t, email.Send(log, webhooks.ApplyResult{Workspace: "production", Success: false}))
+	Equals(t, 0, len(server.Emails()))
+	Ok(t, email.Send(log, webhooks.ApplyResult{Workspace: "production", Success: true}))
+	Equals(t, 1, len(server.Emails()))
+}
@@ -0,0 +1,86 @@
+package webhooks
+
+import (
+	"fmt"
+	"regexp"
+)
+
+const (
+	// OnlySuccess configures a webhook to only be sent for commands that
+	// succeeded.
+	OnlySuccess = "success"
+	// OnlyFailure configures a webhook to only be sent for commands that
+	// failed.
+	OnlyFailure = "failure"
+)
+
+// Filter decides which events a webhook is sent for. Nil regexes and empty
+// strings match everything.
+type Filter struct {
+	WorkspaceRegex *regexp.Regexp
+	// RepoRegex is matched against the repo's full name, ex.
+	// runatlantis/atlantis.
+	RepoRegex *regexp.Regexp
+	// BranchRegex is matched against the head branch of the pull request or
+	// the branch that was checked for drift.
+	BranchRegex *regexp.Regexp
+	// ProjectName is the name of the project from atlantis.yaml.
+	ProjectName string
+	// Only is OnlySuccess, OnlyFailure or empty to send the webhook whether
+	// the command succeeded or not.
+	Only string
+}
+
+// NewFilter compiles the filters in c.
+func NewFilter(c Config) (Filter, error) {
+	var f Filter
+	var err error
+	if f.WorkspaceRegex, err = regexp.Compile(c.WorkspaceRegex); err != nil {
+		return f, err
+	}
+	if f.RepoRegex, err = compileOptional(c.RepoRegex); err != nil {
+		return f, err
+	}
+	if f.BranchRegex, err = compileOptional(c.BranchRegex); err != nil {
+		return f, err
+	}
+	switch c.Only {
+	case "", OnlySuccess, OnlyFailure:
+	default:
+		return f, fmt.Errorf("\"only: %s\" not supported. Only \"only: %s\" and \"only: %s\" are supported", c.Only, OnlySuccess, OnlyFailure)
+	}
+	f.ProjectName = c.ProjectName
+	f.Only = c.Only
+	return f, nil
+}
+
+// Matches returns true if a webhook with this filter should be sent for an
+// event with these attributes.
+func (f Filter) Matches(workspace string, repoFullName string, branch string, projectName string, success bool) bool {
+	if f.WorkspaceRegex != nil && !f.WorkspaceRegex.MatchString(workspace) {
+		return false
+	}
+	if f.RepoRegex != nil && !f.RepoRegex.MatchString(repoFullName) {
+		return false
+	}
+	if f.BranchRegex != nil && !f.BranchRegex.MatchString(branch) {
+		return false
+	}
+	if f.ProjectName != "" && f.ProjectName != projectName {
+		return false
+	}
+	switch f.Only {
+	case OnlySuccess:
+		return success
+	case OnlyFailure:
+		return !success
+	}
+	return true
+}
+
+func compileOptional(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return regexp.Compile(expr)
+}
@@ -0,0 +1,146 @@
+package webhooks_test
+
+import (
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/webhooks"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestNewFilter_Errors(t *testing.T) {
+	cases := []struct {
+		config webhooks.Config
+		expErr string
+	}{
+		{
+			webhooks.Config{WorkspaceRegex: "("},
+			"error parsing regexp: missing closing ): `(`",
+		},
+		{
+			webhooks.Config{RepoRegex: "("},
+			"error parsing regexp: missing closing ): `(`",
+		},
+		{
+			webhooks.Config{BranchRegex: "("},
+			"error parsing regexp: missing closing ): `(`",
+		},
+		{
+			webhooks.Config{Only: "sometimes"},
+			"\"only: sometimes\" not supported. Only \"only: success\" and \"only: failure\" are supported",
+		},
+	}
+	for _, c := range cases {
+		_, err := webhooks.NewFilter(c.config)
+		ErrEquals(t, c.expErr, err)
+	}
+}
+
+func TestFilter_Matches(t *testing.T) {
+	type event struct {
+		workspace string
+		repo      string
+		branch    string
+		project   string
+		success   bool
+	}
+	defaultEvent := event{"production", "runatlantis/atlantis", "main", "network", true}
+	cases := []struct {
+		description string
+		config      webhooks.Config
+		event       event
+		exp         bool
+	}{
+		{
+			"no filters",
+			webhooks.Config{},
+			defaultEvent,
+			true,
+		},
+		{
+			"workspace doesn't match",
+			webhooks.Config{WorkspaceRegex: "^staging$"},
+			defaultEvent,
+			false,
+		},
+		{
+			"repo matches",
+			webhooks.Config{RepoRegex: "^runatlantis/"},
+			defaultEvent,
+			true,
+		},
+		{
+			"repo doesn't match",
+			webhooks.Config{RepoRegex: "^lkysow/"},
+			defaultEvent,
+			false,
+		},
+		{
+			"branch matches",
+			webhooks.Config{BranchRegex: "^(main|master)$"},
+			defaultEvent,
+			true,
+		},
+		{
+			"branch doesn't match",
+			webhooks.Config{BranchRegex: "^release/"},
+			defaultEvent,
+			false,
+		},
+		{
+			"project matches",
+			webhooks.Config{ProjectName: "network"},
+			defaultEvent,
+			true,
+		},
+		{
+			"project doesn't match",
+			webhooks.Config{ProjectName: "database"},
+			defaultEvent,
+			false,
+		},
+		{
+			"project filter doesn't match events without a project",
+			webhooks.Config{ProjectName: "network"},
+			event{"production", "runatlantis/atlantis", "main", "", true},
+			false,
+		},
+		{
+			"only success",
+			webhooks.Config{Only: webhooks.OnlySuccess},
+			defaultEvent,
+			true,
+		},
+		{
+			"only failure",
+			webhooks.Config{Only: webhooks.OnlyFailure},
+			defaultEvent,
+			false,
+		},
+		{
+			"only failure with a failure",
+			webhooks.Config{Only: webhooks.OnlyFailure},
+			event{"production", "runatlantis/atlantis", "main", "network", false},
+			true,
+		},
+		{
+			"all filters match",
+			webhooks.Config{
+				WorkspaceRegex: "prod.*",
+				RepoRegex:      "atlantis",
+				BranchRegex:    "main",
+				ProjectName:    "network",
+				Only:           webhooks.OnlySuccess,
+			},
+			defaultEvent,
+			true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			filter, err := webhooks.NewFilter(c.config)
+			Ok(t, err)
+			e := c.event
+			Equals(t, c.exp, filter.Matches(e.workspace, e.repo, e.branch, e.project, e.success))
+		})
+	}
+}
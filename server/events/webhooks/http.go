@@ -0,0 +1,291 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// HTTPPayloadVersion is the version of the JSON documents POSTed by http
+// webhooks. It's increased when fields are changed or removed but not when
+// they're added.
+const HTTPPayloadVersion = 1
+
+// HTTPSignatureHeader is the header http webhooks are signed in if they have
+// a secret. It's "sha256=" followed by the hex-encoded HMAC-SHA256 of the
+// body using the secret as the key.
+const HTTPSignatureHeader = "X-Atlantis-Signature"
+
+// HTTPEventHeader is the header that holds the event of http webhooks, ex.
+// apply.
+const HTTPEventHeader = "X-Atlantis-Event"
+
+// httpMaxAttempts is how many times a webhook is POSTed before giving up.
+const httpMaxAttempts = 5
+
+// HTTPPayload is the JSON document POSTed by http webhooks.
+type HTTPPayload struct {
+	Version int      `json:"version"`
+	Event   string   `json:"event"`
+	Repo    HTTPRepo `json:"repo"`
+	// Pull is nil for drift events since they aren't for a pull request.
+	Pull *HTTPPull `json:"pull,omitempty"`
+	// Branch is only set for drift events.
+	Branch    string      `json:"branch,omitempty"`
+	User      string      `json:"user,omitempty"`
+	Project   HTTPProject `json:"project"`
+	Workspace string      `json:"workspace"`
+	// Command is the command that caused the event, ex. plan for lock
+	// events. It's empty for unlock events.
+	Command string `json:"command,omitempty"`
+	Success bool   `json:"success"`
+	// Drifted is only set for drift events.
+	Drifted bool   `json:"drifted,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	// OutputURL links to the output of the command in the Atlantis UI.
+	OutputURL string `json:"output_url,omitempty"`
+}
+
+// HTTPRepo is the repo in an HTTPPayload.
+type HTTPRepo struct {
+	FullName string `json:"full_name"`
+	Owner    string `json:"owner"`
+	Name     string `json:"name"`
+	// VCS is the type of VCS host, ex. Github.
+	VCS      string `json:"vcs"`
+	Hostname string `json:"hostname"`
+}
+
+// HTTPPull is the pull request in an HTTPPayload.
+type HTTPPull struct {
+	Num        int    `json:"num"`
+	URL        string `json:"url"`
+	Author     string `json:"author"`
+	Branch     string `json:"branch"`
+	Title      string `json:"title,omitempty"`
+	HeadCommit string `json:"head_commit"`
+}
+
+// HTTPProject is the project in an HTTPPayload.
+type HTTPProject struct {
+	Name string `json:"name,omitempty"`
+	Dir  string `json:"dir"`
+}
+
+// HTTPWebhook POSTs JSON documents describing events to a URL. Failed
+// requests are retried with exponential backoff in the background.
+type HTTPWebhook struct {
+	Client *http.Client
+	URL    string
+	Secret []byte
+	Filter Filter
+	// Backoff is how long to wait before retrying a failed request. It's
+	// doubled after each retry.
+	Backoff time.Duration
+
+	// wg tracks the requests being sent so tests can wait for them.
+	wg sync.WaitGroup
+}
+
+// NewHTTP returns an HTTPWebhook that POSTs to rawURL and signs requests with
+// secret if it's not empty.
+func NewHTTP(filter Filter, rawURL string, secret string, client *http.Client) (*HTTPWebhook, error) {
+	if err := validateURL(rawURL); err != nil {
+		return nil, err
+	}
+	return &HTTPWebhook{
+		Client:  client,
+		URL:     rawURL,
+		Secret:  []byte(secret),
+		Filter:  filter,
+		Backoff: time.Second,
+	}, nil
+}
+
+// validateURL returns an error if rawURL can't be POSTed to.
+func validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook url %q must be an absolute http or https url", rawURL)
+	}
+	return nil
+}
+
+// Send POSTs the apply webhook if the apply matches the filter.
+func (h *HTTPWebhook) Send(log *logging.SimpleLogger, result ApplyResult) error {
+	if !h.Filter.Matches(result.Workspace, result.Repo.FullName, result.Pull.Branch, result.ProjectName, result.Success) {
+		return nil
+	}
+	return h.post(log, HTTPPayload{
+		Event:     ApplyEvent,
+		Repo:      httpRepo(result.Repo),
+		Pull:      httpPull(result.Pull),
+		User:      result.User.Username,
+		Project:   HTTPProject{Name: result.ProjectName, Dir: result.RepoRelDir},
+		Workspace: result.Workspace,
+		Command:   "apply",
+		Success:   result.Success,
+		Summary:   result.Summary,
+		OutputURL: result.OutputURL,
+	})
+}
+
+// SendPlan POSTs the plan webhook if the plan matches the filter.
+func (h *HTTPWebhook) SendPlan(log *logging.SimpleLogger, result PlanResult) error {
+	if !h.Filter.Matches(result.Workspace, result.Repo.FullName, result.Pull.Branch, result.ProjectName, result.Success) {
+		return nil
+	}
+	return h.post(log, HTTPPayload{
+		Event:     PlanEvent,
+		Repo:      httpRepo(result.Repo),
+		Pull:      httpPull(result.Pull),
+		User:      result.User.Username,
+		Project:   HTTPProject{Name: result.ProjectName, Dir: result.RepoRelDir},
+		Workspace: result.Workspace,
+		Command:   "plan",
+		Success:   result.Success,
+		Summary:   result.Summary,
+		OutputURL: result.OutputURL,
+	})
+}
+
+// SendLock POSTs the lock or unlock webhook if the lock matches the filter.
+// Locks don't have a project name so they never match a filter with one.
+func (h *HTTPWebhook) SendLock(log *logging.SimpleLogger, result LockResult) error {
+	if !h.Filter.Matches(result.Workspace, result.Repo.FullName, result.Pull.Branch, "", true) {
+		return nil
+	}
+	payload := HTTPPayload{
+		Event:     UnlockEvent,
+		Repo:      httpRepo(result.Repo),
+		Pull:      httpPull(result.Pull),
+		User:      result.User.Username,
+		Project:   HTTPProject{Dir: result.RepoRelDir},
+		Workspace: result.Workspace,
+		Success:   true,
+	}
+	if result.Locked {
+		payload.Event = LockEvent
+		payload.Command = "plan"
+	}
+	return h.post(log, payload)
+}
+
+// SendDrift POSTs the drift webhook if the result matches the filter.
+func (h *HTTPWebhook) SendDrift(log *logging.SimpleLogger, result DriftResult) error {
+	if !h.Filter.Matches(result.Workspace, result.Repo.FullName, result.Branch, result.ProjectName, result.Success) {
+		return nil
+	}
+	return h.post(log, HTTPPayload{
+		Event:     DriftEvent,
+		Repo:      httpRepo(result.Repo),
+		Branch:    result.Branch,
+		Project:   HTTPProject{Name: result.ProjectName, Dir: result.RepoRelDir},
+		Workspace: result.Workspace,
+		Command:   "plan",
+		Success:   result.Success,
+		Drifted:   result.Drifted,
+	})
+}
+
+// Wait waits for the webhooks being sent to be delivered or to fail.
+func (h *HTTPWebhook) Wait() {
+	h.wg.Wait()
+}
+
+// post POSTs payload in the background so commands don't wait for retries.
+func (h *HTTPWebhook) post(log *logging.SimpleLogger, payload HTTPPayload) error {
+	payload.Version = HTTPPayloadVersion
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "serializing webhook")
+	}
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		if err := h.postWithRetries(payload.Event, body); err != nil {
+			log.Warn("error sending %s webhook to %s: %s", payload.Event, h.URL, err)
+		}
+	}()
+	return nil
+}
+
+func (h *HTTPWebhook) postWithRetries(event string, body []byte) error {
+	backoff := h.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := h.postOnce(event, body)
+		if err == nil || !retry || attempt == httpMaxAttempts {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// postOnce POSTs body and returns whether it should be retried if it failed.
+// Requests that failed because of the network, rate limiting or a server
+// error are retried.
+func (h *HTTPWebhook) postOnce(event string, body []byte) (bool, error) {
+	req, err := http.NewRequest("POST", h.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HTTPEventHeader, event)
+	if len(h.Secret) > 0 {
+		req.Header.Set(HTTPSignatureHeader, "sha256="+Sign(h.Secret, body))
+	}
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()            // nolint: errcheck
+	io.Copy(ioutil.Discard, resp.Body) // nolint: errcheck
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("got response %d", resp.StatusCode)
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of body using secret as the key.
+// Receivers of http webhooks can use it to check HTTPSignatureHeader.
+func Sign(secret []byte, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body) // nolint: errcheck
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func httpRepo(repo models.Repo) HTTPRepo {
+	return HTTPRepo{
+		FullName: repo.FullName,
+		Owner:    repo.Owner,
+		Name:     repo.Name,
+		VCS:      repo.VCSHost.Type.String(),
+		Hostname: repo.VCSHost.Hostname,
+	}
+}
+
+func httpPull(pull models.PullRequest) *HTTPPull {
+	return &HTTPPull{
+		Num:        pull.Num,
+		URL:        pull.URL,
+		Author:     pull.Author,
+		Branch:     pull.Branch,
+		Title:      pull.Title,
+		HeadCommit: pull.HeadCommit,
+	}
+}
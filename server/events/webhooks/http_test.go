@@ -0,0 +1,200 @@
+package webhooks_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/webhooks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// webhookServer records the requests it gets and responds with the next of
+// its statuses, or 200 once they're used up.
+type webhookServer struct {
+	mutex    sync.Mutex
+	statuses []int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.bodies = append(s.bodies, body)
+	s.headers = append(s.headers, r.Header)
+	status := http.StatusOK
+	if len(s.statuses) > 0 {
+		status = s.statuses[0]
+		s.statuses = s.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func newTestHTTPWebhook(t *testing.T, ws *webhookServer, secret string) (*webhooks.HTTPWebhook, func()) {
+	server := httptest.NewServer(ws)
+	webhook, err := webhooks.NewHTTP(webhooks.Filter{}, server.URL, secret, server.Client())
+	Ok(t, err)
+	webhook.Backoff = time.Millisecond
+	return webhook, server.Close
+}
+
+func TestNewHTTP_InvalidURL(t *testing.T) {
+	for _, u := range []string{"", "example.com/hook", "ftp://example.com/hook", "https://"} {
+		t.Run(u, func(t *testing.T) {
+			_, err := webhooks.NewHTTP(webhooks.Filter{}, u, "", http.DefaultClient)
+			ErrEquals(t, "webhook url \""+u+"\" must be an absolute http or https url", err)
+		})
+	}
+}
+
+func TestHTTPWebhook_Send(t *testing.T) {
+	ws := &webhookServer{}
+	webhook, cleanup := newTestHTTPWebhook(t, ws, "secret")
+	defer cleanup()
+
+	err := webhook.Send(logging.NewNoopLogger(), webhooks.ApplyResult{
+		Workspace: "production",
+		Repo: models.Repo{
+			FullName: "runatlantis/atlantis",
+			Owner:    "runatlantis",
+			Name:     "atlantis",
+			VCSHost:  models.VCSHost{Type: models.Github, Hostname: "github.com"},
+		},
+		Pull: models.PullRequest{
+			Num:        1,
+			URL:        "https://github.com/runatlantis/atlantis/pull/1",
+			Author:     "lkysow",
+			Branch:     "branch",
+			HeadCommit: "abc123",
+		},
+		User:        models.User{Username: "bob"},
+		RepoRelDir:  "dir",
+		ProjectName: "myproject",
+		Success:     true,
+		Summary:     "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.",
+	})
+	Ok(t, err)
+	webhook.Wait()
+
+	Equals(t, 1, len(ws.bodies))
+	Equals(t, `{"version":1,"event":"apply","repo":{"full_name":"runatlantis/atlantis","owner":"runatlantis","name":"atlantis","vcs":"Github","hostname":"github.com"},`+
+		`"pull":{"num":1,"url":"https://github.com/runatlantis/atlantis/pull/1","author":"lkysow","branch":"branch","head_commit":"abc123"},`+
+		`"user":"bob","project":{"name":"myproject","dir":"dir"},"workspace":"production","command":"apply","success":true,`+
+		`"summary":"Apply complete! Resources: 1 added, 0 changed, 0 destroyed."}`, string(ws.bodies[0]))
+	Equals(t, "application/json", ws.headers[0].Get("Content-Type"))
+	Equals(t, "apply", ws.headers[0].Get(webhooks.HTTPEventHeader))
+	Equals(t, "sha256="+webhooks.Sign([]byte("secret"), ws.bodies[0]), ws.headers[0].Get(webhooks.HTTPSignatureHeader))
+}
+
+func TestHTTPWebhook_NoSecret(t *testing.T) {
+	ws := &webhookServer{}
+	webhook, cleanup := newTestHTTPWebhook(t, ws, "")
+	defer cleanup()
+
+	Ok(t, webhook.SendPlan(logging.NewNoopLogger(), webhooks.PlanResult{Workspace: "default"}))
+	webhook.Wait()
+	Equals(t, 1, len(ws.bodies))
+	Equals(t, "", ws.headers[0].Get(webhooks.HTTPSignatureHeader))
+}
+
+func TestHTTPWebhook_Events(t *testing.T) {
+	ws := &webhookServer{}
+	webhook, cleanup := newTestHTTPWebhook(t, ws, "")
+	defer cleanup()
+	log := logging.NewNoopLogger()
+
+	Ok(t, webhook.SendPlan(log, webhooks.PlanResult{Workspace: "default", Success: false}))
+	webhook.Wait()
+	Ok(t, webhook.SendLock(log, webhooks.LockResult{Workspace: "default", Locked: true}))
+	webhook.Wait()
+	Ok(t, webhook.SendLock(log, webhooks.LockResult{Workspace: "default"}))
+	webhook.Wait()
+	Ok(t, webhook.SendDrift(log, webhooks.DriftResult{Workspace: "default", Branch: "master", Drifted: true, Success: true}))
+	webhook.Wait()
+
+	var payloads []webhooks.HTTPPayload
+	for _, body := range ws.bodies {
+		var payload webhooks.HTTPPayload
+		Ok(t, json.Unmarshal(body, &payload))
+		payloads = append(payloads, payload)
+	}
+	Equals(t, 4, len(payloads))
+	Equals(t, "plan", payloads[0].Event)
+	Equals(t, "plan", payloads[0].Command)
+	Equals(t, false, payloads[0].Success)
+	Equals(t, "lock", payloads[1].Event)
+	Equals(t, "plan", payloads[1].Command)
+	Equals(t, "unlock", payloads[2].Event)
+	Equals(t, "", payloads[2].Command)
+	Equals(t, "drift", payloads[3].Event)
+	Equals(t, "master", payloads[3].Branch)
+	Equals(t, true, payloads[3].Drifted)
+	Assert(t, payloads[3].Pull == nil, "expected drift events not to have a pull request")
+	for _, payload := range payloads {
+		Equals(t, webhooks.HTTPPayloadVersion, payload.Version)
+	}
+}
+
+func TestHTTPWebhook_WorkspaceRegex(t *testing.T) {
+	ws := &webhookServer{}
+	webhook, cleanup := newTestHTTPWebhook(t, ws, "")
+	defer cleanup()
+	webhook.Filter.WorkspaceRegex = regexp.MustCompile("^production$")
+
+	Ok(t, webhook.Send(logging.NewNoopLogger(), webhooks.ApplyResult{Workspace: "staging"}))
+	Ok(t, webhook.SendLock(logging.NewNoopLogger(), webhooks.LockResult{Workspace: "staging", Locked: true}))
+	webhook.Wait()
+	Equals(t, 0, len(ws.bodies))
+}
+
+func TestHTTPWebhook_Retries(t *testing.T) {
+	cases := []struct {
+		description string
+		statuses    []int
+		expRequests int
+	}{
+		{
+			"server errors are retried",
+			[]int{http.StatusInternalServerError, http.StatusBadGateway},
+			3,
+		},
+		{
+			"rate limits are retried",
+			[]int{http.StatusTooManyRequests},
+			2,
+		},
+		{
+			"client errors aren't retried",
+			[]int{http.StatusBadRequest},
+			1,
+		},
+		{
+			"gives up after 5 attempts",
+			[]int{500, 500, 500, 500, 500, 500, 500},
+			5,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			ws := &webhookServer{statuses: c.statuses}
+			webhook, cleanup := newTestHTTPWebhook(t, ws, "secret")
+			defer cleanup()
+
+			Ok(t, webhook.Send(logging.NewNoopLogger(), webhooks.ApplyResult{Workspace: "default"}))
+			webhook.Wait()
+			Equals(t, c.expRequests, len(ws.bodies))
+			for _, body := range ws.bodies {
+				Equals(t, string(ws.bodies[0]), string(body))
+			}
+		})
+	}
+}
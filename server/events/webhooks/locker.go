@@ -0,0 +1,60 @@
+package webhooks
+
+import (
+	"github.com/runatlantis/atlantis/server/events/locking"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// Locker returns a locking.Locker that locks and unlocks with locker and
+// sends lock and unlock webhooks for the locks it acquires and releases.
+func (w *MultiWebhookSender) Locker(locker locking.Locker, log *logging.SimpleLogger) locking.Locker {
+	return &webhookLocker{Locker: locker, sender: w, log: log}
+}
+
+// webhookLocker is returned by MultiWebhookSender.Locker.
+type webhookLocker struct {
+	locking.Locker
+	sender *MultiWebhookSender
+	log    *logging.SimpleLogger
+}
+
+func (l *webhookLocker) TryLock(p models.Project, workspace string, pull models.PullRequest, user models.User) (locking.TryLockResponse, error) {
+	resp, err := l.Locker.TryLock(p, workspace, pull, user)
+	// LockAcquired is only true if the lock is new, not if the pull request
+	// already held it.
+	if err == nil && resp.LockAcquired {
+		l.send(resp.CurrLock, true)
+	}
+	return resp, err
+}
+
+func (l *webhookLocker) Unlock(key string) (*models.ProjectLock, error) {
+	lock, err := l.Locker.Unlock(key)
+	if err == nil && lock != nil {
+		l.send(*lock, false)
+	}
+	return lock, err
+}
+
+func (l *webhookLocker) UnlockByPull(repo models.Repo, pullNum int) ([]models.ProjectLock, error) {
+	locks, err := l.Locker.UnlockByPull(repo, pullNum)
+	if err != nil {
+		return locks, err
+	}
+	for _, lock := range locks {
+		l.send(lock, false)
+	}
+	return locks, nil
+}
+
+func (l *webhookLocker) send(lock models.ProjectLock, locked bool) {
+	l.sender.SendLock(l.log, LockResult{ // nolint: errcheck
+		Workspace:  lock.Workspace,
+		Repo:       lock.Pull.BaseRepo,
+		Pull:       lock.Pull,
+		User:       lock.User,
+		RepoRelDir: lock.Project.Path,
+		Locked:     locked,
+	})
+}
@@ -0,0 +1,112 @@
+package webhooks_test
+
+import (
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server/events/locking"
+	lockmocks "github.com/runatlantis/atlantis/server/events/locking/mocks"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/webhooks"
+	"github.com/runatlantis/atlantis/server/events/webhooks/mocks"
+	"github.com/runatlantis/atlantis/server/events/webhooks/mocks/matchers"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+var lockerProject = models.NewProject("owner/repo", "path")
+var lockerPull = models.PullRequest{
+	Num:      1,
+	BaseRepo: models.Repo{FullName: "owner/repo"},
+}
+var lockerUser = models.User{Username: "bob"}
+var lockerLock = models.ProjectLock{
+	Project:   lockerProject,
+	Workspace: "default",
+	Pull:      lockerPull,
+	User:      lockerUser,
+}
+
+func newTestWebhookLocker() (locking.Locker, *lockmocks.MockLocker, *mocks.MockLockSender, *mocks.MockLockSender) {
+	lockSender := mocks.NewMockLockSender()
+	unlockSender := mocks.NewMockLockSender()
+	sender := &webhooks.MultiWebhookSender{
+		LockWebhooks:   []webhooks.LockSender{lockSender},
+		UnlockWebhooks: []webhooks.LockSender{unlockSender},
+	}
+	backend := lockmocks.NewMockLocker()
+	return sender.Locker(backend, logging.NewNoopLogger()), backend, lockSender, unlockSender
+}
+
+func TestWebhookLocker_TryLock(t *testing.T) {
+	RegisterMockTestingT(t)
+	locker, backend, lockSender, unlockSender := newTestWebhookLocker()
+	When(backend.TryLock(lockerProject, "default", lockerPull, lockerUser)).
+		ThenReturn(locking.TryLockResponse{LockAcquired: true, CurrLock: lockerLock}, nil)
+
+	resp, err := locker.TryLock(lockerProject, "default", lockerPull, lockerUser)
+	Ok(t, err)
+	Equals(t, true, resp.LockAcquired)
+	lockSender.VerifyWasCalledOnce().SendLock(matchers.AnyPtrToLoggingSimpleLogger(), matchers.EqWebhooksLockResult(webhooks.LockResult{
+		Workspace:  "default",
+		Repo:       lockerPull.BaseRepo,
+		Pull:       lockerPull,
+		User:       lockerUser,
+		RepoRelDir: "path",
+		Locked:     true,
+	}))
+	unlockSender.VerifyWasCalled(Never()).SendLock(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyWebhooksLockResult())
+}
+
+func TestWebhookLocker_TryLockNotAcquired(t *testing.T) {
+	t.Log("Lock webhooks shouldn't be sent if another pull request has the lock")
+	RegisterMockTestingT(t)
+	locker, backend, lockSender, _ := newTestWebhookLocker()
+	When(backend.TryLock(lockerProject, "default", lockerPull, lockerUser)).
+		ThenReturn(locking.TryLockResponse{LockAcquired: false, CurrLock: lockerLock}, nil)
+
+	_, err := locker.TryLock(lockerProject, "default", lockerPull, lockerUser)
+	Ok(t, err)
+	lockSender.VerifyWasCalled(Never()).SendLock(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyWebhooksLockResult())
+}
+
+func TestWebhookLocker_Unlock(t *testing.T) {
+	RegisterMockTestingT(t)
+	locker, backend, lockSender, unlockSender := newTestWebhookLocker()
+	When(backend.Unlock("key")).ThenReturn(&lockerLock, nil)
+
+	lock, err := locker.Unlock("key")
+	Ok(t, err)
+	Equals(t, &lockerLock, lock)
+	unlockSender.VerifyWasCalledOnce().SendLock(matchers.AnyPtrToLoggingSimpleLogger(), matchers.EqWebhooksLockResult(webhooks.LockResult{
+		Workspace:  "default",
+		Repo:       lockerPull.BaseRepo,
+		Pull:       lockerPull,
+		User:       lockerUser,
+		RepoRelDir: "path",
+	}))
+	lockSender.VerifyWasCalled(Never()).SendLock(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyWebhooksLockResult())
+}
+
+func TestWebhookLocker_UnlockNoLock(t *testing.T) {
+	RegisterMockTestingT(t)
+	locker, backend, _, unlockSender := newTestWebhookLocker()
+	When(backend.Unlock("key")).ThenReturn(nil, nil)
+
+	_, err := locker.Unlock("key")
+	Ok(t, err)
+	unlockSender.VerifyWasCalled(Never()).SendLock(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyWebhooksLockResult())
+}
+
+func TestWebhookLocker_UnlockByPull(t *testing.T) {
+	RegisterMockTestingT(t)
+	locker, backend, _, unlockSender := newTestWebhookLocker()
+	otherLock := lockerLock
+	otherLock.Workspace = "staging"
+	When(backend.UnlockByPull(lockerPull.BaseRepo, 1)).ThenReturn([]models.ProjectLock{lockerLock, otherLock}, nil)
+
+	locks, err := locker.UnlockByPull(lockerPull.BaseRepo, 1)
+	Ok(t, err)
+	Equals(t, 2, len(locks))
+	unlockSender.VerifyWasCalled(Times(2)).SendLock(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyWebhooksLockResult())
+}
@@ -0,0 +1,89 @@
+package webhooks
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+)
+
+// commandMessage is what's common to the plan and apply messages sent to
+// chat and email webhooks.
+type commandMessage struct {
+	// Command is the capitalized name of the command, ex. Apply.
+	Command     string
+	Workspace   string
+	Repo        models.Repo
+	Pull        models.PullRequest
+	User        models.User
+	ProjectName string
+	Success     bool
+	Summary     string
+	OutputURL   string
+}
+
+func applyMessage(result ApplyResult) commandMessage {
+	return commandMessage{
+		Command:     "Apply",
+		Workspace:   result.Workspace,
+		Repo:        result.Repo,
+		Pull:        result.Pull,
+		User:        result.User,
+		ProjectName: result.ProjectName,
+		Success:     result.Success,
+		Summary:     result.Summary,
+		OutputURL:   result.OutputURL,
+	}
+}
+
+func planMessage(result PlanResult) commandMessage {
+	return commandMessage{
+		Command:     "Plan",
+		Workspace:   result.Workspace,
+		Repo:        result.Repo,
+		Pull:        result.Pull,
+		User:        result.User,
+		ProjectName: result.ProjectName,
+		Success:     result.Success,
+		Summary:     result.Summary,
+		OutputURL:   result.OutputURL,
+	}
+}
+
+// successWord returns "succeeded" or "failed".
+func (m commandMessage) successWord() string {
+	if m.Success {
+		return "succeeded"
+	}
+	return "failed"
+}
+
+// headline returns a one line description of the message, ex. "Apply
+// succeeded for runatlantis/atlantis#1".
+func (m commandMessage) headline() string {
+	return fmt.Sprintf("%s %s for %s#%d", m.Command, m.successWord(), m.Repo.FullName, m.Pull.Num)
+}
+
+// driftHeadline returns a one line description of a drift result.
+func driftHeadline(result DriftResult) string {
+	// Drift and failed plans both need someone to look at them.
+	if !result.Success {
+		return fmt.Sprintf("Drift detection plan failed in %s on branch %s", result.Repo.FullName, result.Branch)
+	}
+	return fmt.Sprintf("Drift detected in %s on branch %s", result.Repo.FullName, result.Branch)
+}
+
+// resourceCountsRegex matches the resource counts in Terraform's plan and
+// apply summaries.
+var resourceCountsRegex = regexp.MustCompile(`\d+ to add, \d+ to change, \d+ to destroy|\d+ added, \d+ changed, \d+ destroyed`)
+
+// ResourceChanges returns the resource counts in summary, ex. "1 to add, 0 to
+// change, 0 to destroy", "No changes" or an empty string if summary doesn't
+// have any.
+func ResourceChanges(summary string) string {
+	if strings.HasPrefix(summary, "No changes") {
+		return "No changes"
+	}
+	return resourceCountsRegex.FindString(summary)
+}
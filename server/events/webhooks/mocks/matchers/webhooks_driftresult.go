@@ -0,0 +1,20 @@
+package matchers
+
+import (
+	"reflect"
+
+	"github.com/petergtz/pegomock"
+	webhooks "github.com/runatlantis/atlantis/server/events/webhooks"
+)
+
+func AnyWebhooksDriftResult() webhooks.DriftResult {
+	pegomock.RegisterMatcher(pegomock.NewAnyMatcher(reflect.TypeOf((*(webhooks.DriftResult))(nil)).Elem()))
+	var nullValue webhooks.DriftResult
+	return nullValue
+}
+
+func EqWebhooksDriftResult(value webhooks.DriftResult) webhooks.DriftResult {
+	pegomock.RegisterMatcher(&pegomock.EqMatcher{Value: value})
+	var nullValue webhooks.DriftResult
+	return nullValue
+}
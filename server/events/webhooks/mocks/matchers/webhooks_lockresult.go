@@ -0,0 +1,20 @@
+package matchers
+
+import (
+	"reflect"
+
+	"github.com/petergtz/pegomock"
+	webhooks "github.com/runatlantis/atlantis/server/events/webhooks"
+)
+
+func AnyWebhooksLockResult() webhooks.LockResult {
+	pegomock.RegisterMatcher(pegomock.NewAnyMatcher(reflect.TypeOf((*(webhooks.LockResult))(nil)).Elem()))
+	var nullValue webhooks.LockResult
+	return nullValue
+}
+
+func EqWebhooksLockResult(value webhooks.LockResult) webhooks.LockResult {
+	pegomock.RegisterMatcher(&pegomock.EqMatcher{Value: value})
+	var nullValue webhooks.LockResult
+	return nullValue
+}
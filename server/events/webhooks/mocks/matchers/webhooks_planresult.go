@@ -0,0 +1,20 @@
+package matchers
+
+import (
+	"reflect"
+
+	"github.com/petergtz/pegomock"
+	webhooks "github.com/runatlantis/atlantis/server/events/webhooks"
+)
+
+func AnyWebhooksPlanResult() webhooks.PlanResult {
+	pegomock.RegisterMatcher(pegomock.NewAnyMatcher(reflect.TypeOf((*(webhooks.PlanResult))(nil)).Elem()))
+	var nullValue webhooks.PlanResult
+	return nullValue
+}
+
+func EqWebhooksPlanResult(value webhooks.PlanResult) webhooks.PlanResult {
+	pegomock.RegisterMatcher(&pegomock.EqMatcher{Value: value})
+	var nullValue webhooks.PlanResult
+	return nullValue
+}
@@ -70,6 +70,30 @@ func (mock *MockSlackClient) PostMessage(channel string, applyResult webhooks.Ap
 	return ret0
 }
 
+func (mock *MockSlackClient) PostDriftMessage(channel string, driftResult webhooks.DriftResult) error {
+	params := []pegomock.Param{channel, driftResult}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("PostDriftMessage", params, []reflect.Type{reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(error)
+		}
+	}
+	return ret0
+}
+
+func (mock *MockSlackClient) PostPlanMessage(channel string, planResult webhooks.PlanResult) error {
+	params := []pegomock.Param{channel, planResult}
+	result := pegomock.GetGenericMockFrom(mock).Invoke("PostPlanMessage", params, []reflect.Type{reflect.TypeOf((*error)(nil)).Elem()})
+	var ret0 error
+	if len(result) != 0 {
+		if result[0] != nil {
+			ret0 = result[0].(error)
+		}
+	}
+	return ret0
+}
+
 func (mock *MockSlackClient) VerifyWasCalledOnce() *VerifierSlackClient {
 	return &VerifierSlackClient{mock, pegomock.Times(1), nil}
 }
@@ -179,3 +203,65 @@ func (c *SlackClient_PostMessage_OngoingVerification) GetAllCapturedArguments()
 	}
 	return
 }
+
+func (verifier *VerifierSlackClient) PostDriftMessage(channel string, driftResult webhooks.DriftResult) *SlackClient_PostDriftMessage_OngoingVerification {
+	params := []pegomock.Param{channel, driftResult}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "PostDriftMessage", params)
+	return &SlackClient_PostDriftMessage_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type SlackClient_PostDriftMessage_OngoingVerification struct {
+	mock              *MockSlackClient
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *SlackClient_PostDriftMessage_OngoingVerification) GetCapturedArguments() (string, webhooks.DriftResult) {
+	channel, driftResult := c.GetAllCapturedArguments()
+	return channel[len(channel)-1], driftResult[len(driftResult)-1]
+}
+
+func (c *SlackClient_PostDriftMessage_OngoingVerification) GetAllCapturedArguments() (_param0 []string, _param1 []webhooks.DriftResult) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]string, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(string)
+		}
+		_param1 = make([]webhooks.DriftResult, len(params[1]))
+		for u, param := range params[1] {
+			_param1[u] = param.(webhooks.DriftResult)
+		}
+	}
+	return
+}
+
+func (verifier *VerifierSlackClient) PostPlanMessage(channel string, planResult webhooks.PlanResult) *SlackClient_PostPlanMessage_OngoingVerification {
+	params := []pegomock.Param{channel, planResult}
+	methodInvocations := pegomock.GetGenericMockFrom(verifier.mock).Verify(verifier.inOrderContext, verifier.invocationCountMatcher, "PostPlanMessage", params)
+	return &SlackClient_PostPlanMessage_OngoingVerification{mock: verifier.mock, methodInvocations: methodInvocations}
+}
+
+type SlackClient_PostPlanMessage_OngoingVerification struct {
+	mock              *MockSlackClient
+	methodInvocations []pegomock.MethodInvocation
+}
+
+func (c *SlackClient_PostPlanMessage_OngoingVerification) GetCapturedArguments() (string, webhooks.PlanResult) {
+	channel, planResult := c.GetAllCapturedArguments()
+	return channel[len(channel)-1], planResult[len(planResult)-1]
+}
+
+func (c *SlackClient_PostPlanMessage_OngoingVerification) GetAllCapturedArguments() (_param0 []string, _param1 []webhooks.PlanResult) {
+	params := pegomock.GetGenericMockFrom(c.mock).GetInvocationParams(c.methodInvocations)
+	if len(params) > 0 {
+		_param0 = make([]string, len(params[0]))
+		for u, param := range params[0] {
+			_param0[u] = param.(string)
+		}
+		_param1 = make([]webhooks.PlanResult, len(params[1]))
+		for u, param := range params[1] {
+			_param1[u] = param.(webhooks.PlanResult)
+		}
+	}
+	return
+}
@@ -0,0 +1,195 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+const (
+	// adaptiveCardContentType is the content type of adaptive cards in
+	// Microsoft Teams messages.
+	adaptiveCardContentType = "application/vnd.microsoft.card.adaptive"
+	adaptiveCardSchema      = "http://adaptivecards.io/schemas/adaptive-card.json"
+	adaptiveCardVersion     = "1.2"
+)
+
+// MSTeamsMessage is the JSON document POSTed to Microsoft Teams incoming
+// webhooks.
+type MSTeamsMessage struct {
+	Type        string              `json:"type"`
+	Attachments []MSTeamsAttachment `json:"attachments"`
+}
+
+// MSTeamsAttachment is an attachment of an MSTeamsMessage.
+type MSTeamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	Content     AdaptiveCard `json:"content"`
+}
+
+// AdaptiveCard is a card that Microsoft Teams displays.
+// See https://adaptivecards.io/explorer/.
+type AdaptiveCard struct {
+	Schema  string                `json:"$schema"`
+	Type    string                `json:"type"`
+	Version string                `json:"version"`
+	Body    []AdaptiveCardElement `json:"body"`
+	Actions []AdaptiveCardAction  `json:"actions,omitempty"`
+}
+
+// AdaptiveCardElement is either a TextBlock or a FactSet.
+type AdaptiveCardElement struct {
+	Type   string             `json:"type"`
+	Text   string             `json:"text,omitempty"`
+	Size   string             `json:"size,omitempty"`
+	Weight string             `json:"weight,omitempty"`
+	Color  string             `json:"color,omitempty"`
+	Wrap   bool               `json:"wrap,omitempty"`
+	Facts  []AdaptiveCardFact `json:"facts,omitempty"`
+}
+
+// AdaptiveCardFact is a row of a FactSet.
+type AdaptiveCardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// AdaptiveCardAction is a button that opens a URL.
+type AdaptiveCardAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// MSTeamsWebhook posts adaptive cards to a Microsoft Teams incoming webhook.
+type MSTeamsWebhook struct {
+	Client *http.Client
+	URL    string
+	Filter Filter
+}
+
+// NewMSTeams returns an MSTeamsWebhook that posts to the incoming webhook at
+// rawURL.
+func NewMSTeams(filter Filter, rawURL string, client *http.Client) (*MSTeamsWebhook, error) {
+	if err := validateURL(rawURL); err != nil {
+		return nil, err
+	}
+	return &MSTeamsWebhook{
+		Client: client,
+		URL:    rawURL,
+		Filter: filter,
+	}, nil
+}
+
+// Send posts the apply webhook to Microsoft Teams if the apply matches the
+// filter.
+func (m *MSTeamsWebhook) Send(log *logging.SimpleLogger, result ApplyResult) error {
+	if !m.Filter.Matches(result.Workspace, result.Repo.FullName, result.Pull.Branch, result.ProjectName, result.Success) {
+		return nil
+	}
+	return m.post(commandCard(applyMessage(result)))
+}
+
+// SendPlan posts the plan webhook to Microsoft Teams if the plan matches the
+// filter.
+func (m *MSTeamsWebhook) SendPlan(log *logging.SimpleLogger, result PlanResult) error {
+	if !m.Filter.Matches(result.Workspace, result.Repo.FullName, result.Pull.Branch, result.ProjectName, result.Success) {
+		return nil
+	}
+	return m.post(commandCard(planMessage(result)))
+}
+
+// SendDrift posts the drift webhook to Microsoft Teams if the result matches
+// the filter.
+func (m *MSTeamsWebhook) SendDrift(log *logging.SimpleLogger, result DriftResult) error {
+	if !m.Filter.Matches(result.Workspace, result.Repo.FullName, result.Branch, result.ProjectName, result.Success) {
+		return nil
+	}
+	facts := []AdaptiveCardFact{
+		{Title: "Directory", Value: result.RepoRelDir},
+		{Title: "Workspace", Value: result.Workspace},
+	}
+	if result.ProjectName != "" {
+		facts = append(facts, AdaptiveCardFact{Title: "Project", Value: result.ProjectName})
+	}
+	return m.post(AdaptiveCard{
+		Body: []AdaptiveCardElement{
+			headlineElement(driftHeadline(result), false),
+			{Type: "FactSet", Facts: facts},
+		},
+	})
+}
+
+func (m *MSTeamsWebhook) post(card AdaptiveCard) error {
+	card.Schema = adaptiveCardSchema
+	card.Type = "AdaptiveCard"
+	card.Version = adaptiveCardVersion
+	body, err := json.Marshal(MSTeamsMessage{
+		Type:        "message",
+		Attachments: []MSTeamsAttachment{{ContentType: adaptiveCardContentType, Content: card}},
+	})
+	if err != nil {
+		return errors.Wrap(err, "serializing Microsoft Teams message")
+	}
+	resp, err := m.Client.Post(m.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "posting to Microsoft Teams")
+	}
+	defer resp.Body.Close()            // nolint: errcheck
+	io.Copy(ioutil.Discard, resp.Body) // nolint: errcheck
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("posting to Microsoft Teams: got response %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// commandCard returns the card for a plan or apply.
+func commandCard(msg commandMessage) AdaptiveCard {
+	body := []AdaptiveCardElement{headlineElement(msg.headline(), msg.Success)}
+	if msg.Pull.Title != "" {
+		body = append(body, AdaptiveCardElement{Type: "TextBlock", Text: msg.Pull.Title, Wrap: true})
+	}
+	facts := []AdaptiveCardFact{
+		{Title: "Workspace", Value: msg.Workspace},
+		{Title: "User", Value: msg.User.Username},
+	}
+	if msg.ProjectName != "" {
+		facts = append(facts, AdaptiveCardFact{Title: "Project", Value: msg.ProjectName})
+	}
+	if changes := ResourceChanges(msg.Summary); changes != "" {
+		facts = append(facts, AdaptiveCardFact{Title: "Resources", Value: changes})
+	}
+	body = append(body, AdaptiveCardElement{Type: "FactSet", Facts: facts})
+
+	var actions []AdaptiveCardAction
+	if msg.Pull.URL != "" {
+		actions = append(actions, AdaptiveCardAction{Type: "Action.OpenUrl", Title: "View pull request", URL: msg.Pull.URL})
+	}
+	if msg.OutputURL != "" {
+		actions = append(actions, AdaptiveCardAction{Type: "Action.OpenUrl", Title: "View output", URL: msg.OutputURL})
+	}
+	return AdaptiveCard{Body: body, Actions: actions}
+}
+
+// headlineElement returns a bold TextBlock coloured by whether it's about a
+// success.
+func headlineElement(text string, success bool) AdaptiveCardElement {
+	color := "Attention"
+	if success {
+		color = "Good"
+	}
+	return AdaptiveCardElement{
+		Type:   "TextBlock",
+		Text:   text,
+		Size:   "Medium",
+		Weight: "Bolder",
+		Color:  color,
+		Wrap:   true,
+	}
+}
@@ -0,0 +1,150 @@
+package webhooks_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/events/webhooks"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+// newTeamsServer returns a stand-in for a Microsoft Teams incoming webhook
+// that decodes the messages posted to it.
+func newTeamsServer(t *testing.T, status int) (*httptest.Server, *[]webhooks.MSTeamsMessage) {
+	var messages []webhooks.MSTeamsMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Equals(t, "application/json", r.Header.Get("Content-Type"))
+		var msg webhooks.MSTeamsMessage
+		Ok(t, json.NewDecoder(r.Body).Decode(&msg))
+		messages = append(messages, msg)
+		w.WriteHeader(status)
+	}))
+	return server, &messages
+}
+
+func TestMSTeamsWebhook_Send(t *testing.T) {
+	server, messages := newTeamsServer(t, http.StatusOK)
+	defer server.Close()
+	teams, err := webhooks.NewMSTeams(webhooks.Filter{}, server.URL, server.Client())
+	Ok(t, err)
+
+	err = teams.Send(logging.NewNoopLogger(), webhooks.ApplyResult{
+		Workspace: "production",
+		Repo:      models.Repo{FullName: "runatlantis/atlantis"},
+		Pull: models.PullRequest{
+			Num:   1,
+			URL:   "https://github.com/runatlantis/atlantis/pull/1",
+			Title: "Add a bucket",
+		},
+		User:        models.User{Username: "lkysow"},
+		ProjectName: "storage",
+		Success:     true,
+		Summary:     "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.",
+		OutputURL:   "https://atlantis/output?pull=1",
+	})
+	Ok(t, err)
+	Equals(t, []webhooks.MSTeamsMessage{{
+		Type: "message",
+		Attachments: []webhooks.MSTeamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: webhooks.AdaptiveCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.2",
+				Body: []webhooks.AdaptiveCardElement{
+					{
+						Type:   "TextBlock",
+						Text:   "Apply succeeded for runatlantis/atlantis#1",
+						Size:   "Medium",
+						Weight: "Bolder",
+						Color:  "Good",
+						Wrap:   true,
+					},
+					{
+						Type: "TextBlock",
+						Text: "Add a bucket",
+						Wrap: true,
+					},
+					{
+						Type: "FactSet",
+						Facts: []webhooks.AdaptiveCardFact{
+							{Title: "Workspace", Value: "production"},
+							{Title: "User", Value: "lkysow"},
+							{Title: "Project", Value: "storage"},
+							{Title: "Resources", Value: "1 added, 0 changed, 0 destroyed"},
+						},
+					},
+				},
+				Actions: []webhooks.AdaptiveCardAction{
+					{Type: "Action.OpenUrl", Title: "View pull request", URL: "https://github.com/runatlantis/atlantis/pull/1"},
+					{Type: "Action.OpenUrl", Title: "View output", URL: "https://atlantis/output?pull=1"},
+				},
+			},
+		}},
+	}}, *messages)
+}
+
+func TestMSTeamsWebhook_SendPlanAndDrift(t *testing.T) {
+	server, messages := newTeamsServer(t, http.StatusOK)
+	defer server.Close()
+	teams, err := webhooks.NewMSTeams(webhooks.Filter{}, server.URL, server.Client())
+	Ok(t, err)
+	log := logging.NewNoopLogger()
+
+	Ok(t, teams.SendPlan(log, webhooks.PlanResult{
+		Workspace: "default",
+		Repo:      models.Repo{FullName: "runatlantis/atlantis"},
+		Pull:      models.PullRequest{Num: 2},
+		Success:   false,
+	}))
+	Ok(t, teams.SendDrift(log, webhooks.DriftResult{
+		Workspace:  "default",
+		Repo:       models.Repo{FullName: "runatlantis/atlantis"},
+		Branch:     "master",
+		RepoRelDir: "network",
+		Drifted:    true,
+		Success:    true,
+	}))
+
+	Equals(t, 2, len(*messages))
+	plan := (*messages)[0].Attachments[0].Content
+	Equals(t, "Plan failed for runatlantis/atlantis#2", plan.Body[0].Text)
+	Equals(t, "Attention", plan.Body[0].Color)
+	Equals(t, 0, len(plan.Actions))
+	drift := (*messages)[1].Attachments[0].Content
+	Equals(t, "Drift detected in runatlantis/atlantis on branch master", drift.Body[0].Text)
+	Equals(t, []webhooks.AdaptiveCardFact{
+		{Title: "Directory", Value: "network"},
+		{Title: "Workspace", Value: "default"},
+	}, drift.Body[1].Facts)
+}
+
+func TestMSTeamsWebhook_Filter(t *testing.T) {
+	server, messages := newTeamsServer(t, http.StatusOK)
+	defer server.Close()
+	teams, err := webhooks.NewMSTeams(webhooks.Filter{WorkspaceRegex: regexp.MustCompile("^production$")}, server.URL, server.Client())
+	Ok(t, err)
+
+	Ok(t, teams.Send(logging.NewNoopLogger(), webhooks.ApplyResult{Workspace: "staging"}))
+	Equals(t, 0, len(*messages))
+}
+
+func TestMSTeamsWebhook_Error(t *testing.T) {
+	server, _ := newTeamsServer(t, http.StatusBadRequest)
+	defer server.Close()
+	teams, err := webhooks.NewMSTeams(webhooks.Filter{}, server.URL, server.Client())
+	Ok(t, err)
+
+	err = teams.Send(logging.NewNoopLogger(), webhooks.ApplyResult{Workspace: "default"})
+	ErrEquals(t, "posting to Microsoft Teams: got response 400", err)
+}
+
+func TestNewMSTeams_InvalidURL(t *testing.T) {
+	_, err := webhooks.NewMSTeams(webhooks.Filter{}, "outlook.office.com/webhook", http.DefaultClient)
+	ErrEquals(t, "webhook url \"outlook.office.com/webhook\" must be an absolute http or https url", err)
+}
@@ -14,8 +14,6 @@
 package webhooks
 
 import (
-	"regexp"
-
 	"fmt"
 
 	"github.com/pkg/errors"
@@ -24,12 +22,12 @@ import (
 
 // SlackWebhook sends webhooks to Slack.
 type SlackWebhook struct {
-	Client         SlackClient
-	WorkspaceRegex *regexp.Regexp
-	Channel        string
+	Client  SlackClient
+	Filter  Filter
+	Channel string
 }
 
-func NewSlack(r *regexp.Regexp, channel string, client SlackClient) (*SlackWebhook, error) {
+func NewSlack(filter Filter, channel string, client SlackClient) (*SlackWebhook, error) {
 	if err := client.AuthTest(); err != nil {
 		return nil, fmt.Errorf("testing slack authentication: %s. Verify your slack-token is valid", err)
 	}
@@ -43,16 +41,33 @@ func NewSlack(r *regexp.Regexp, channel string, client SlackClient) (*SlackWebho
 	}
 
 	return &SlackWebhook{
-		Client:         client,
-		WorkspaceRegex: r,
-		Channel:        channel,
+		Client:  client,
+		Filter:  filter,
+		Channel: channel,
 	}, nil
 }
 
-// Send sends the webhook to Slack if the workspace matches the regex.
+// Send sends the webhook to Slack if the apply matches the filter.
 func (s *SlackWebhook) Send(log *logging.SimpleLogger, applyResult ApplyResult) error {
-	if !s.WorkspaceRegex.MatchString(applyResult.Workspace) {
+	if !s.Filter.Matches(applyResult.Workspace, applyResult.Repo.FullName, applyResult.Pull.Branch, applyResult.ProjectName, applyResult.Success) {
 		return nil
 	}
 	return s.Client.PostMessage(s.Channel, applyResult)
 }
+
+// SendPlan sends the plan webhook to Slack if the plan matches the filter.
+func (s *SlackWebhook) SendPlan(log *logging.SimpleLogger, planResult PlanResult) error {
+	if !s.Filter.Matches(planResult.Workspace, planResult.Repo.FullName, planResult.Pull.Branch, planResult.ProjectName, planResult.Success) {
+		return nil
+	}
+	return s.Client.PostPlanMessage(s.Channel, planResult)
+}
+
+// SendDrift sends the drift webhook to Slack if the result matches the
+// filter.
+func (s *SlackWebhook) SendDrift(log *logging.SimpleLogger, driftResult DriftResult) error {
+	if !s.Filter.Matches(driftResult.Workspace, driftResult.Repo.FullName, driftResult.Branch, driftResult.ProjectName, driftResult.Success) {
+		return nil
+	}
+	return s.Client.PostDriftMessage(s.Channel, driftResult)
+}
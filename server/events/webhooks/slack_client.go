@@ -32,6 +32,8 @@ type SlackClient interface {
 	TokenIsSet() bool
 	ChannelExists(channelName string) (bool, error)
 	PostMessage(channel string, applyResult ApplyResult) error
+	PostPlanMessage(channel string, planResult PlanResult) error
+	PostDriftMessage(channel string, driftResult DriftResult) error
 }
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_underlying_slack_client.go UnderlyingSlackClient
@@ -86,33 +88,107 @@ func (d *DefaultSlackClient) PostMessage(channel string, applyResult ApplyResult
 	return err
 }
 
+func (d *DefaultSlackClient) PostPlanMessage(channel string, planResult PlanResult) error {
+	params := slack.NewPostMessageParameters()
+	params.Attachments = d.createPlanAttachments(planResult)
+	params.EscapeText = false
+	_, _, err := d.Slack.PostMessage(channel, "", params)
+	return err
+}
+
+func (d *DefaultSlackClient) PostDriftMessage(channel string, driftResult DriftResult) error {
+	params := slack.NewPostMessageParameters()
+	params.Attachments = d.createDriftAttachments(driftResult)
+	params.EscapeText = false
+	_, _, err := d.Slack.PostMessage(channel, "", params)
+	return err
+}
+
 func (d *DefaultSlackClient) createAttachments(applyResult ApplyResult) []slack.Attachment {
-	var colour string
-	var successWord string
-	if applyResult.Success {
+	return d.createCommandAttachments(applyMessage(applyResult))
+}
+
+func (d *DefaultSlackClient) createPlanAttachments(planResult PlanResult) []slack.Attachment {
+	return d.createCommandAttachments(planMessage(planResult))
+}
+
+func (d *DefaultSlackClient) createCommandAttachments(msg commandMessage) []slack.Attachment {
+	colour := slackFailureColour
+	if msg.Success {
 		colour = slackSuccessColour
-		successWord = "succeeded"
-	} else {
-		colour = slackFailureColour
-		successWord = "failed"
 	}
 
-	text := fmt.Sprintf("Apply %s for <%s|%s>", successWord, applyResult.Pull.URL, applyResult.Repo.FullName)
+	text := fmt.Sprintf("%s %s for <%s|%s>", msg.Command, msg.successWord(), msg.Pull.URL, msg.Repo.FullName)
+	fields := []slack.AttachmentField{
+		{
+			Title: "Workspace",
+			Value: msg.Workspace,
+			Short: true,
+		},
+		{
+			Title: "User",
+			Value: msg.User.Username,
+			Short: true,
+		},
+	}
+	if msg.ProjectName != "" {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Project",
+			Value: msg.ProjectName,
+			Short: true,
+		})
+	}
+	if changes := ResourceChanges(msg.Summary); changes != "" {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Resources",
+			Value: changes,
+			Short: true,
+		})
+	}
+	if msg.OutputURL != "" {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Output",
+			Value: fmt.Sprintf("<%s|View in Atlantis>", msg.OutputURL),
+			Short: true,
+		})
+	}
 	attachment := slack.Attachment{
-		Color: colour,
-		Text:  text,
-		Fields: []slack.AttachmentField{
-			{
-				Title: "Workspace",
-				Value: applyResult.Workspace,
-				Short: true,
-			},
-			{
-				Title: "User",
-				Value: applyResult.User.Username,
-				Short: true,
-			},
+		Color:  colour,
+		Text:   text,
+		Fields: fields,
+	}
+	if msg.Pull.Title != "" {
+		attachment.Title = fmt.Sprintf("#%d %s", msg.Pull.Num, msg.Pull.Title)
+		attachment.TitleLink = msg.Pull.URL
+	}
+	return []slack.Attachment{attachment}
+}
+
+func (d *DefaultSlackClient) createDriftAttachments(driftResult DriftResult) []slack.Attachment {
+	text := driftHeadline(driftResult)
+	fields := []slack.AttachmentField{
+		{
+			Title: "Directory",
+			Value: driftResult.RepoRelDir,
+			Short: true,
+		},
+		{
+			Title: "Workspace",
+			Value: driftResult.Workspace,
+			Short: true,
 		},
 	}
+	if driftResult.ProjectName != "" {
+		fields = append(fields, slack.AttachmentField{
+			Title: "Project",
+			Value: driftResult.ProjectName,
+			Short: true,
+		})
+	}
+	attachment := slack.Attachment{
+		Color:  slackFailureColour,
+		Text:   text,
+		Fields: fields,
+	}
 	return []slack.Attachment{attachment}
 }
@@ -16,12 +16,17 @@ package webhooks_test
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/nlopes/slack"
 	"github.com/runatlantis/atlantis/server/events/models"
 	"github.com/runatlantis/atlantis/server/events/webhooks"
 	"github.com/runatlantis/atlantis/server/events/webhooks/mocks"
+	"github.com/runatlantis/atlantis/server/logging"
 
 	. "github.com/petergtz/pegomock"
 	. "github.com/runatlantis/atlantis/testing"
@@ -182,3 +187,222 @@ func setup(t *testing.T) {
 		Success: true,
 	}
 }
+
+func TestPostDriftMessage_Success(t *testing.T) {
+	t.Log("When drift is detected, a message should be posted about it")
+	setup(t)
+	driftResult := webhooks.DriftResult{
+		Workspace:  "production",
+		Repo:       result.Repo,
+		Branch:     "master",
+		RepoRelDir: "project1",
+		Drifted:    true,
+		Success:    true,
+	}
+
+	expParams := slack.NewPostMessageParameters()
+	expParams.Attachments = []slack.Attachment{{
+		Color: "danger",
+		Text:  "Drift detected in runatlantis/atlantis on branch master",
+		Fields: []slack.AttachmentField{
+			{
+				Title: "Directory",
+				Value: "project1",
+				Short: true,
+			},
+			{
+				Title: "Workspace",
+				Value: "production",
+				Short: true,
+			},
+		},
+	}}
+	expParams.AsUser = false
+	expParams.EscapeText = false
+
+	channel := "somechannel"
+	err := client.PostDriftMessage(channel, driftResult)
+	Ok(t, err)
+	underlying.VerifyWasCalledOnce().PostMessage(channel, "", expParams)
+
+	t.Log("When the plan fails, the message should say so")
+	driftResult.Success = false
+	driftResult.ProjectName = "myproject"
+	expParams.Attachments[0].Text = "Drift detection plan failed in runatlantis/atlantis on branch master"
+	expParams.Attachments[0].Fields = append(expParams.Attachments[0].Fields, slack.AttachmentField{
+		Title: "Project",
+		Value: "myproject",
+		Short: true,
+	})
+	err = client.PostDriftMessage(channel, driftResult)
+	Ok(t, err)
+	underlying.VerifyWasCalledOnce().PostMessage(channel, "", expParams)
+}
+
+// slackAPI is an httptest stand-in for the Slack API. It records the
+// attachments of the messages posted to it.
+type slackAPI struct {
+	mutex       sync.Mutex
+	channels    []string
+	attachments map[string][][]slack.Attachment
+}
+
+// newSlackAPI starts a slackAPI with channels and points the slack package
+// at it until the returned func is called.
+func newSlackAPI(channels ...string) (*slackAPI, func()) {
+	api := &slackAPI{channels: channels, attachments: make(map[string][][]slack.Attachment)}
+	server := httptest.NewServer(api)
+	prevAPI := slack.SLACK_API
+	slack.SLACK_API = server.URL + "/"
+	return api, func() {
+		slack.SLACK_API = prevAPI
+		server.Close()
+	}
+}
+
+func (s *slackAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/auth.test":
+		fmt.Fprint(w, `{"ok":true}`) // nolint: errcheck
+	case "/channels.list":
+		var channels []map[string]string
+		for _, c := range s.channels {
+			channels = append(channels, map[string]string{"name": c})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "channels": channels}) // nolint: errcheck
+	case "/chat.postMessage":
+		var attachments []slack.Attachment
+		if err := json.Unmarshal([]byte(r.FormValue("attachments")), &attachments); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		channel := r.FormValue("channel")
+		s.attachments[channel] = append(s.attachments[channel], attachments)
+		fmt.Fprintf(w, `{"ok":true,"channel":%q,"ts":"1"}`, channel) // nolint: errcheck
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *slackAPI) posted(channel string) [][]slack.Attachment {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.attachments[channel]
+}
+
+func TestPostPlanMessage_SlackAPI(t *testing.T) {
+	t.Log("Plan messages should include the pull request title, resource counts and a link to the output")
+	api, cleanup := newSlackAPI()
+	defer cleanup()
+
+	c := webhooks.NewSlackClient("sometoken")
+	err := c.PostPlanMessage("somechannel", webhooks.PlanResult{
+		Workspace: "production",
+		Repo:      models.Repo{FullName: "runatlantis/atlantis"},
+		Pull: models.PullRequest{
+			Num:   1,
+			URL:   "url",
+			Title: "Add a bucket",
+		},
+		User:        models.User{Username: "lkysow"},
+		ProjectName: "storage",
+		Success:     true,
+		Summary:     "Plan: 1 to add, 2 to change, 3 to destroy.",
+		OutputURL:   "https://atlantis/output?pull=1",
+	})
+	Ok(t, err)
+	Equals(t, [][]slack.Attachment{{{
+		Color:     "good",
+		Title:     "#1 Add a bucket",
+		TitleLink: "url",
+		Text:      "Plan succeeded for <url|runatlantis/atlantis>",
+		Fields: []slack.AttachmentField{
+			{Title: "Workspace", Value: "production", Short: true},
+			{Title: "User", Value: "lkysow", Short: true},
+			{Title: "Project", Value: "storage", Short: true},
+			{Title: "Resources", Value: "1 to add, 2 to change, 3 to destroy", Short: true},
+			{Title: "Output", Value: "<https://atlantis/output?pull=1|View in Atlantis>", Short: true},
+		},
+	}}}, api.posted("somechannel"))
+}
+
+func TestPostMessage_SlackAPIResourceCounts(t *testing.T) {
+	cases := []struct {
+		summary string
+		exp     string
+	}{
+		{"Apply complete! Resources: 1 added, 0 changed, 2 destroyed.", "1 added, 0 changed, 2 destroyed"},
+		{"No changes. Infrastructure is up-to-date.", "No changes"},
+	}
+	for _, c := range cases {
+		t.Run(c.summary, func(t *testing.T) {
+			api, cleanup := newSlackAPI()
+			defer cleanup()
+
+			err := webhooks.NewSlackClient("sometoken").PostMessage("somechannel", webhooks.ApplyResult{
+				Workspace: "default",
+				Repo:      models.Repo{FullName: "runatlantis/atlantis"},
+				Pull:      models.PullRequest{Num: 1, URL: "url"},
+				User:      models.User{Username: "lkysow"},
+				Success:   true,
+				Summary:   c.summary,
+			})
+			Ok(t, err)
+			posted := api.posted("somechannel")
+			Equals(t, 1, len(posted))
+			Equals(t, "Apply succeeded for <url|runatlantis/atlantis>", posted[0][0].Text)
+			Equals(t, slack.AttachmentField{Title: "Resources", Value: c.exp, Short: true}, posted[0][0].Fields[2])
+		})
+	}
+}
+
+func TestNewMultiWebhookSender_SlackAPI(t *testing.T) {
+	t.Log("Slack webhooks should only post the events that match their filters")
+	api, cleanup := newSlackAPI("plans", "failures")
+	defer cleanup()
+
+	sender, err := webhooks.NewMultiWebhookSender([]webhooks.Config{
+		{
+			Event:       webhooks.PlanEvent,
+			Kind:        webhooks.SlackKind,
+			Channel:     "plans",
+			RepoRegex:   "^runatlantis/",
+			BranchRegex: "^feature/",
+		},
+		{
+			Event:   webhooks.ApplyEvent,
+			Kind:    webhooks.SlackKind,
+			Channel: "failures",
+			Only:    webhooks.OnlyFailure,
+		},
+	}, webhooks.NewSlackClient("sometoken"), nil)
+	Ok(t, err)
+	Equals(t, 1, len(sender.PlanWebhooks))
+	Equals(t, 1, len(sender.Webhooks))
+
+	log := logging.NewNoopLogger()
+	repo := models.Repo{FullName: "runatlantis/atlantis"}
+	Ok(t, sender.SendPlan(log, webhooks.PlanResult{Repo: repo, Pull: models.PullRequest{Branch: "feature/a"}, Success: true}))
+	Ok(t, sender.SendPlan(log, webhooks.PlanResult{Repo: repo, Pull: models.PullRequest{Branch: "main"}, Success: true}))
+	Ok(t, sender.SendPlan(log, webhooks.PlanResult{Repo: models.Repo{FullName: "lkysow/atlantis"}, Pull: models.PullRequest{Branch: "feature/b"}, Success: true}))
+	Ok(t, sender.Send(log, webhooks.ApplyResult{Repo: repo, Success: true}))
+	Ok(t, sender.Send(log, webhooks.ApplyResult{Repo: repo, Success: false}))
+
+	Equals(t, 1, len(api.posted("plans")))
+	failures := api.posted("failures")
+	Equals(t, 1, len(failures))
+	Equals(t, "danger", failures[0][0].Color)
+}
+
+func TestNewMultiWebhookSender_SlackAPIMissingChannel(t *testing.T) {
+	_, cleanup := newSlackAPI("otherchannel")
+	defer cleanup()
+
+	_, err := webhooks.NewMultiWebhookSender([]webhooks.Config{
+		{Event: webhooks.ApplyEvent, Kind: webhooks.SlackKind, Channel: "somechannel"},
+	}, webhooks.NewSlackClient("sometoken"), nil)
+	ErrEquals(t, "slack channel \"somechannel\" doesn't exist", err)
+}
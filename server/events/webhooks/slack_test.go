@@ -33,9 +33,9 @@ func TestSend_PostMessage(t *testing.T) {
 
 	channel := "somechannel"
 	hook := webhooks.SlackWebhook{
-		Client:         client,
-		WorkspaceRegex: regex,
-		Channel:        channel,
+		Client:  client,
+		Filter:  webhooks.Filter{WorkspaceRegex: regex},
+		Channel: channel,
 	}
 	result := webhooks.ApplyResult{
 		Workspace: "production",
@@ -55,9 +55,9 @@ func TestSend_NoopSuccess(t *testing.T) {
 
 	channel := "somechannel"
 	hook := webhooks.SlackWebhook{
-		Client:         client,
-		WorkspaceRegex: regex,
-		Channel:        channel,
+		Client:  client,
+		Filter:  webhooks.Filter{WorkspaceRegex: regex},
+		Channel: channel,
 	}
 	result := webhooks.ApplyResult{
 		Workspace: "production",
@@ -66,3 +66,29 @@ func TestSend_NoopSuccess(t *testing.T) {
 	Ok(t, err)
 	client.VerifyWasCalled(Never()).PostMessage(channel, result)
 }
+
+func TestSendDrift_PostDriftMessage(t *testing.T) {
+	t.Log("Sending a drift hook with a matching regex should call PostDriftMessage")
+	RegisterMockTestingT(t)
+	client := mocks.NewMockSlackClient()
+	regex, err := regexp.Compile("prod.*")
+	Ok(t, err)
+
+	channel := "somechannel"
+	hook := webhooks.SlackWebhook{
+		Client:  client,
+		Filter:  webhooks.Filter{WorkspaceRegex: regex},
+		Channel: channel,
+	}
+	result := webhooks.DriftResult{
+		Workspace: "production",
+		Drifted:   true,
+	}
+	_ = hook.SendDrift(logging.NewNoopLogger(), result)
+	client.VerifyWasCalledOnce().PostDriftMessage(channel, result)
+
+	result.Workspace = "staging"
+	err = hook.SendDrift(logging.NewNoopLogger(), result)
+	Ok(t, err)
+	client.VerifyWasCalled(Never()).PostDriftMessage(channel, result)
+}
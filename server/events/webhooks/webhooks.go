@@ -15,7 +15,8 @@ package webhooks
 
 import (
 	"fmt"
-	"regexp"
+	"net/http"
+	"time"
 
 	"errors"
 
@@ -24,7 +25,26 @@ import (
 )
 
 const SlackKind = "slack"
+const HTTPKind = "http"
+const MSTeamsKind = "msteams"
+const EmailKind = "email"
 const ApplyEvent = "apply"
+const DriftEvent = "drift"
+const PlanEvent = "plan"
+const LockEvent = "lock"
+const UnlockEvent = "unlock"
+
+// messageEvents are the events that can be sent to Slack, Microsoft Teams and
+// email. All events can be sent with HTTP webhooks.
+var messageEvents = map[string]bool{ApplyEvent: true, PlanEvent: true, DriftEvent: true}
+
+// messageWebhook is implemented by the webhooks that send messages for
+// messageEvents.
+type messageWebhook interface {
+	Sender
+	PlanSender
+	DriftSender
+}
 
 //go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_sender.go Sender
 
@@ -34,39 +54,146 @@ type Sender interface {
 	Send(log *logging.SimpleLogger, applyResult ApplyResult) error
 }
 
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_drift_sender.go DriftSender
+
+// DriftSender sends webhooks when drift is detected.
+type DriftSender interface {
+	// SendDrift sends the webhook (if the implementation thinks it should).
+	SendDrift(log *logging.SimpleLogger, driftResult DriftResult) error
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_plan_sender.go PlanSender
+
+// PlanSender sends webhooks when projects are planned.
+type PlanSender interface {
+	// SendPlan sends the webhook (if the implementation thinks it should).
+	SendPlan(log *logging.SimpleLogger, planResult PlanResult) error
+}
+
+//go:generate pegomock generate -m --use-experimental-model-gen --package mocks -o mocks/mock_lock_sender.go LockSender
+
+// LockSender sends webhooks when projects are locked or unlocked.
+type LockSender interface {
+	// SendLock sends the webhook (if the implementation thinks it should).
+	SendLock(log *logging.SimpleLogger, lockResult LockResult) error
+}
+
 // ApplyResult is the result of a terraform apply.
 type ApplyResult struct {
+	Workspace   string
+	Repo        models.Repo
+	Pull        models.PullRequest
+	User        models.User
+	RepoRelDir  string
+	ProjectName string
+	Success     bool
+	// Summary is Terraform's summary of the apply, ex. "Apply complete!
+	// Resources: 1 added, 0 changed, 0 destroyed.". It's empty if the apply
+	// failed.
+	Summary string
+	// OutputURL links to the output of the commands running on the pull
+	// request in the Atlantis UI. It's empty if it's not known.
+	OutputURL string
+}
+
+// PlanResult is the result of a terraform plan.
+type PlanResult struct {
+	Workspace   string
+	Repo        models.Repo
+	Pull        models.PullRequest
+	User        models.User
+	RepoRelDir  string
+	ProjectName string
+	Success     bool
+	// Summary is Terraform's summary of the plan, ex. "Plan: 1 to add, 0 to
+	// change, 0 to destroy.". It's empty if the plan failed.
+	Summary string
+	// OutputURL links to the output of the commands running on the pull
+	// request in the Atlantis UI. It's empty if it's not known.
+	OutputURL string
+}
+
+// LockResult is sent when a project is locked or unlocked.
+type LockResult struct {
 	Workspace string
 	Repo      models.Repo
 	Pull      models.PullRequest
-	User      models.User
-	Success   bool
+	// User is the user who holds the lock.
+	User       models.User
+	RepoRelDir string
+	// Locked is true if the project was locked and false if it was unlocked.
+	Locked bool
+}
+
+// DriftResult is the result of checking a project for drift. Drift is when
+// running plan on the repo's default branch shows changes.
+type DriftResult struct {
+	Workspace   string
+	Repo        models.Repo
+	Branch      string
+	RepoRelDir  string
+	ProjectName string
+	// Drifted is true if the plan showed changes.
+	Drifted bool
+	// Success is false if the plan failed.
+	Success bool
 }
 
 // MultiWebhookSender sends multiple webhooks for each one it's configured for.
 type MultiWebhookSender struct {
+	// Webhooks are sent for apply events.
 	Webhooks []Sender
+	// DriftWebhooks are sent for drift events.
+	DriftWebhooks []DriftSender
+	// PlanWebhooks are sent for plan events.
+	PlanWebhooks []PlanSender
+	// LockWebhooks are sent for lock events.
+	LockWebhooks []LockSender
+	// UnlockWebhooks are sent for unlock events.
+	UnlockWebhooks []LockSender
 }
 
 type Config struct {
 	Event          string
 	WorkspaceRegex string
-	Kind           string
-	Channel        string
+	// RepoRegex, BranchRegex, ProjectName and Only are optional filters.
+	// See Filter.
+	RepoRegex   string
+	BranchRegex string
+	ProjectName string
+	Only        string
+	Kind        string
+	Channel     string
+	// URL is where http webhooks are POSTed.
+	URL string
+	// Secret is used to sign http webhooks. If empty, they aren't signed.
+	Secret string
+	// To are the addresses email webhooks are sent to.
+	To []string
 }
 
-func NewMultiWebhookSender(configs []Config, client SlackClient) (*MultiWebhookSender, error) {
-	var webhooks []Sender
+// NewMultiWebhookSender returns a sender for configs. client is used for
+// slack webhooks and smtpClient for email webhooks. Either can be nil if
+// there are no webhooks of that kind.
+func NewMultiWebhookSender(configs []Config, client SlackClient, smtpClient SMTPClient) (*MultiWebhookSender, error) {
+	sender := &MultiWebhookSender{}
+	httpClient := &http.Client{Timeout: 10 * time.Second}
 	for _, c := range configs {
-		r, err := regexp.Compile(c.WorkspaceRegex)
+		filter, err := NewFilter(c)
 		if err != nil {
 			return nil, err
 		}
 		if c.Kind == "" || c.Event == "" {
 			return nil, errors.New("must specify \"kind\" and \"event\" keys for webhooks")
 		}
-		if c.Event != ApplyEvent {
-			return nil, fmt.Errorf("\"event: %s\" not supported. Only \"event: %s\" is supported right now", c.Event, ApplyEvent)
+		switch c.Event {
+		case ApplyEvent, DriftEvent, PlanEvent, LockEvent, UnlockEvent:
+		default:
+			return nil, fmt.Errorf("\"event: %s\" not supported. Only \"event: %s\", \"event: %s\", \"event: %s\", \"event: %s\" and \"event: %s\" are supported right now",
+				c.Event, ApplyEvent, PlanEvent, LockEvent, UnlockEvent, DriftEvent)
+		}
+		if c.Kind != HTTPKind && !messageEvents[c.Event] {
+			return nil, fmt.Errorf("\"event: %s\" not supported for webhooks of \"kind: %s\". Only \"event: %s\", \"event: %s\" and \"event: %s\" are supported", c.Event, c.Kind, ApplyEvent, PlanEvent, DriftEvent)
 		}
 		switch c.Kind {
 		case SlackKind:
@@ -76,26 +203,113 @@ func NewMultiWebhookSender(configs []Config, client SlackClient) (*MultiWebhookS
 			if c.Channel == "" {
 				return nil, errors.New("must specify \"channel\" if using a webhook of \"kind: slack\"")
 			}
-			slack, err := NewSlack(r, c.Channel, client)
+			slack, err := NewSlack(filter, c.Channel, client)
 			if err != nil {
 				return nil, err
 			}
-			webhooks = append(webhooks, slack)
+			sender.addMessage(c.Event, slack)
+		case MSTeamsKind:
+			if c.URL == "" {
+				return nil, errors.New("must specify \"url\" if using a webhook of \"kind: msteams\"")
+			}
+			teams, err := NewMSTeams(filter, c.URL, httpClient)
+			if err != nil {
+				return nil, err
+			}
+			sender.addMessage(c.Event, teams)
+		case EmailKind:
+			if smtpClient == nil || !smtpClient.IsConfigured() {
+				return nil, errors.New("must specify top-level \"smtp\" if using a webhook of \"kind: email\"")
+			}
+			if len(c.To) == 0 {
+				return nil, errors.New("must specify \"to\" if using a webhook of \"kind: email\"")
+			}
+			sender.addMessage(c.Event, &EmailWebhook{Client: smtpClient, To: c.To, Filter: filter})
+		case HTTPKind:
+			if c.URL == "" {
+				return nil, errors.New("must specify \"url\" if using a webhook of \"kind: http\"")
+			}
+			webhook, err := NewHTTP(filter, c.URL, c.Secret, httpClient)
+			if err != nil {
+				return nil, err
+			}
+			sender.add(c.Event, webhook)
 		default:
-			return nil, fmt.Errorf("\"kind: %s\" not supported. Only \"kind: %s\" is supported right now", c.Kind, SlackKind)
+			return nil, fmt.Errorf("\"kind: %s\" not supported. Only \"kind: %s\", \"kind: %s\", \"kind: %s\" and \"kind: %s\" are supported right now", c.Kind, SlackKind, MSTeamsKind, EmailKind, HTTPKind)
 		}
 	}
+	return sender, nil
+}
 
-	return &MultiWebhookSender{
-		Webhooks: webhooks,
-	}, nil
+// addMessage adds webhook to the webhooks for event, which must be one of
+// messageEvents.
+func (w *MultiWebhookSender) addMessage(event string, webhook messageWebhook) {
+	switch event {
+	case ApplyEvent:
+		w.Webhooks = append(w.Webhooks, webhook)
+	case PlanEvent:
+		w.PlanWebhooks = append(w.PlanWebhooks, webhook)
+	case DriftEvent:
+		w.DriftWebhooks = append(w.DriftWebhooks, webhook)
+	}
+}
+
+// add adds webhook to the webhooks for event.
+func (w *MultiWebhookSender) add(event string, webhook *HTTPWebhook) {
+	switch event {
+	case ApplyEvent:
+		w.Webhooks = append(w.Webhooks, webhook)
+	case DriftEvent:
+		w.DriftWebhooks = append(w.DriftWebhooks, webhook)
+	case PlanEvent:
+		w.PlanWebhooks = append(w.PlanWebhooks, webhook)
+	case LockEvent:
+		w.LockWebhooks = append(w.LockWebhooks, webhook)
+	case UnlockEvent:
+		w.UnlockWebhooks = append(w.UnlockWebhooks, webhook)
+	}
 }
 
 // Send sends the webhook using its Webhooks.
 func (w *MultiWebhookSender) Send(log *logging.SimpleLogger, result ApplyResult) error {
 	for _, w := range w.Webhooks {
 		if err := w.Send(log, result); err != nil {
-			log.Warn("error sending slack webhook: %s", err)
+			log.Warn("error sending webhook: %s", err)
+		}
+	}
+	return nil
+}
+
+// SendDrift sends the drift webhook using its DriftWebhooks.
+func (w *MultiWebhookSender) SendDrift(log *logging.SimpleLogger, result DriftResult) error {
+	for _, w := range w.DriftWebhooks {
+		if err := w.SendDrift(log, result); err != nil {
+			log.Warn("error sending webhook: %s", err)
+		}
+	}
+	return nil
+}
+
+// SendPlan sends the plan webhook using its PlanWebhooks.
+func (w *MultiWebhookSender) SendPlan(log *logging.SimpleLogger, result PlanResult) error {
+	for _, w := range w.PlanWebhooks {
+		if err := w.SendPlan(log, result); err != nil {
+			log.Warn("error sending webhook: %s", err)
+		}
+	}
+	return nil
+}
+
+// SendLock sends the lock webhook using its LockWebhooks if result is for a
+// lock or its UnlockWebhooks if it's for an unlock.
+func (w *MultiWebhookSender) SendLock(log *logging.SimpleLogger, result LockResult) error {
+	webhooks := w.UnlockWebhooks
+	if result.Locked {
+		webhooks = w.LockWebhooks
+	}
+	for _, w := range webhooks {
+		if err := w.SendLock(log, result); err != nil {
+			log.Warn("error sending webhook: %s", err)
 		}
 	}
 	return nil
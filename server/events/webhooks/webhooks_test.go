@@ -20,6 +20,7 @@ import (
 	. "github.com/petergtz/pegomock"
 	"github.com/runatlantis/atlantis/server/events/webhooks"
 	"github.com/runatlantis/atlantis/server/events/webhooks/mocks"
+	"github.com/runatlantis/atlantis/server/events/webhooks/mocks/matchers"
 	"github.com/runatlantis/atlantis/server/logging"
 	. "github.com/runatlantis/atlantis/testing"
 )
@@ -51,7 +52,7 @@ func TestNewWebhooksManager_InvalidRegex(t *testing.T) {
 	invalidRegex := "("
 	configs := validConfigs()
 	configs[0].WorkspaceRegex = invalidRegex
-	_, err := webhooks.NewMultiWebhookSender(configs, client)
+	_, err := webhooks.NewMultiWebhookSender(configs, client, nil)
 	Assert(t, err != nil, "expected error")
 	Assert(t, strings.Contains(err.Error(), "error parsing regexp"), "expected regex error")
 }
@@ -62,7 +63,7 @@ func TestNewWebhooksManager_NoEvent(t *testing.T) {
 	client := mocks.NewMockSlackClient()
 	configs := validConfigs()
 	configs[0].Event = ""
-	_, err := webhooks.NewMultiWebhookSender(configs, client)
+	_, err := webhooks.NewMultiWebhookSender(configs, client, nil)
 	Assert(t, err != nil, "expected error")
 	Equals(t, "must specify \"kind\" and \"event\" keys for webhooks", err.Error())
 }
@@ -76,9 +77,9 @@ func TestNewWebhooksManager_UnsupportedEvent(t *testing.T) {
 	unsupportedEvent := "badevent"
 	configs := validConfigs()
 	configs[0].Event = unsupportedEvent
-	_, err := webhooks.NewMultiWebhookSender(configs, client)
+	_, err := webhooks.NewMultiWebhookSender(configs, client, nil)
 	Assert(t, err != nil, "expected error")
-	Equals(t, "\"event: badevent\" not supported. Only \"event: apply\" is supported right now", err.Error())
+	Equals(t, "\"event: badevent\" not supported. Only \"event: apply\", \"event: plan\", \"event: lock\", \"event: unlock\" and \"event: drift\" are supported right now", err.Error())
 }
 
 func TestNewWebhooksManager_NoKind(t *testing.T) {
@@ -87,7 +88,7 @@ func TestNewWebhooksManager_NoKind(t *testing.T) {
 	client := mocks.NewMockSlackClient()
 	configs := validConfigs()
 	configs[0].Kind = ""
-	_, err := webhooks.NewMultiWebhookSender(configs, client)
+	_, err := webhooks.NewMultiWebhookSender(configs, client, nil)
 	Assert(t, err != nil, "expected error")
 	Equals(t, "must specify \"kind\" and \"event\" keys for webhooks", err.Error())
 }
@@ -101,9 +102,9 @@ func TestNewWebhooksManager_UnsupportedKind(t *testing.T) {
 	unsupportedKind := "badkind"
 	configs := validConfigs()
 	configs[0].Kind = unsupportedKind
-	_, err := webhooks.NewMultiWebhookSender(configs, client)
+	_, err := webhooks.NewMultiWebhookSender(configs, client, nil)
 	Assert(t, err != nil, "expected error")
-	Equals(t, "\"kind: badkind\" not supported. Only \"kind: slack\" is supported right now", err.Error())
+	Equals(t, "\"kind: badkind\" not supported. Only \"kind: slack\", \"kind: msteams\", \"kind: email\" and \"kind: http\" are supported right now", err.Error())
 }
 
 func TestNewWebhooksManager_NoConfigSuccess(t *testing.T) {
@@ -111,13 +112,13 @@ func TestNewWebhooksManager_NoConfigSuccess(t *testing.T) {
 	t.Log("passing any client should succeed")
 	var emptyConfigs []webhooks.Config
 	emptyToken := ""
-	m, err := webhooks.NewMultiWebhookSender(emptyConfigs, webhooks.NewSlackClient(emptyToken))
+	m, err := webhooks.NewMultiWebhookSender(emptyConfigs, webhooks.NewSlackClient(emptyToken), nil)
 	Ok(t, err)
 	Assert(t, m != nil, "manager shouldn't be nil")
 	Equals(t, 0, len(m.Webhooks))
 
 	t.Log("passing nil client hould succeed")
-	m, err = webhooks.NewMultiWebhookSender(emptyConfigs, nil)
+	m, err = webhooks.NewMultiWebhookSender(emptyConfigs, nil, nil)
 	Ok(t, err)
 	Assert(t, m != nil, "manager shouldn't be nil")
 	Equals(t, 0, len(m.Webhooks))
@@ -130,7 +131,7 @@ func TestNewWebhooksManager_SingleConfigSuccess(t *testing.T) {
 	When(client.ChannelExists(validChannel)).ThenReturn(true, nil)
 
 	configs := validConfigs()
-	m, err := webhooks.NewMultiWebhookSender(configs, client)
+	m, err := webhooks.NewMultiWebhookSender(configs, client, nil)
 	Ok(t, err)
 	Assert(t, m != nil, "manager shouldn't be nil")
 	Equals(t, 1, len(m.Webhooks))
@@ -148,12 +149,49 @@ func TestNewWebhooksManager_MultipleConfigSuccess(t *testing.T) {
 	for i := 0; i < nConfigs; i++ {
 		configs = append(configs, validConfig)
 	}
-	m, err := webhooks.NewMultiWebhookSender(configs, client)
+	m, err := webhooks.NewMultiWebhookSender(configs, client, nil)
 	Ok(t, err)
 	Assert(t, m != nil, "manager shouldn't be nil")
 	Equals(t, nConfigs, len(m.Webhooks))
 }
 
+func TestNewWebhooksManager_DriftConfig(t *testing.T) {
+	t.Log("Drift webhooks should only be sent for drift events")
+	RegisterMockTestingT(t)
+	client := mocks.NewMockSlackClient()
+	When(client.TokenIsSet()).ThenReturn(true)
+	When(client.ChannelExists(validChannel)).ThenReturn(true, nil)
+
+	driftConfig := validConfig
+	driftConfig.Event = webhooks.DriftEvent
+	m, err := webhooks.NewMultiWebhookSender([]webhooks.Config{validConfig, driftConfig, driftConfig}, client, nil)
+	Ok(t, err)
+	Equals(t, 1, len(m.Webhooks))
+	Equals(t, 2, len(m.DriftWebhooks))
+}
+
+func TestSendDrift_MultipleSuccess(t *testing.T) {
+	t.Log("Sending multiple drift webhooks should succeed")
+	RegisterMockTestingT(t)
+	senders := []*mocks.MockDriftSender{
+		mocks.NewMockDriftSender(),
+		mocks.NewMockDriftSender(),
+	}
+	applySender := mocks.NewMockSender()
+	manager := webhooks.MultiWebhookSender{
+		Webhooks:      []webhooks.Sender{applySender},
+		DriftWebhooks: []webhooks.DriftSender{senders[0], senders[1]},
+	}
+	logger := logging.NewNoopLogger()
+	result := webhooks.DriftResult{Drifted: true}
+	err := manager.SendDrift(logger, result)
+	Ok(t, err)
+	for _, s := range senders {
+		s.VerifyWasCalledOnce().SendDrift(logger, result)
+	}
+	applySender.VerifyWasCalled(Never()).Send(matchers.AnyPtrToLoggingSimpleLogger(), matchers.AnyWebhooksApplyResult())
+}
+
 func TestSend_SingleSuccess(t *testing.T) {
 	t.Log("Sending one webhook should succeed")
 	RegisterMockTestingT(t)
@@ -186,3 +224,132 @@ func TestSend_MultipleSuccess(t *testing.T) {
 		s.VerifyWasCalledOnce().Send(logger, result)
 	}
 }
+
+func TestNewWebhooksManager_SlackUnsupportedEvent(t *testing.T) {
+	t.Log("Slack webhooks should only be supported for apply, plan and drift events")
+	RegisterMockTestingT(t)
+	client := mocks.NewMockSlackClient()
+	When(client.TokenIsSet()).ThenReturn(true)
+	When(client.ChannelExists(validChannel)).ThenReturn(true, nil)
+
+	configs := validConfigs()
+	configs[0].Event = webhooks.LockEvent
+	_, err := webhooks.NewMultiWebhookSender(configs, client, nil)
+	ErrEquals(t, "\"event: lock\" not supported for webhooks of \"kind: slack\". Only \"event: apply\", \"event: plan\" and \"event: drift\" are supported", err)
+}
+
+func TestNewWebhooksManager_HTTPNoURL(t *testing.T) {
+	t.Log("When the url key is not specified for an http webhook, an error is returned")
+	configs := []webhooks.Config{{Event: webhooks.ApplyEvent, WorkspaceRegex: validRegex, Kind: webhooks.HTTPKind}}
+	_, err := webhooks.NewMultiWebhookSender(configs, nil, nil)
+	ErrEquals(t, "must specify \"url\" if using a webhook of \"kind: http\"", err)
+}
+
+func TestNewWebhooksManager_HTTPInvalidURL(t *testing.T) {
+	configs := []webhooks.Config{{Event: webhooks.ApplyEvent, WorkspaceRegex: validRegex, Kind: webhooks.HTTPKind, URL: "example.com"}}
+	_, err := webhooks.NewMultiWebhookSender(configs, nil, nil)
+	ErrEquals(t, "webhook url \"example.com\" must be an absolute http or https url", err)
+}
+
+func TestNewWebhooksManager_HTTPConfig(t *testing.T) {
+	t.Log("http webhooks should be sent for the event they're configured for")
+	var configs []webhooks.Config
+	for _, event := range []string{webhooks.ApplyEvent, webhooks.PlanEvent, webhooks.PlanEvent, webhooks.LockEvent, webhooks.UnlockEvent, webhooks.DriftEvent} {
+		configs = append(configs, webhooks.Config{
+			Event:          event,
+			WorkspaceRegex: validRegex,
+			Kind:           webhooks.HTTPKind,
+			URL:            "https://example.com/hook",
+			Secret:         "secret",
+		})
+	}
+	m, err := webhooks.NewMultiWebhookSender(configs, nil, nil)
+	Ok(t, err)
+	Equals(t, 1, len(m.Webhooks))
+	Equals(t, 2, len(m.PlanWebhooks))
+	Equals(t, 1, len(m.LockWebhooks))
+	Equals(t, 1, len(m.UnlockWebhooks))
+	Equals(t, 1, len(m.DriftWebhooks))
+}
+
+func TestSendLock_Routing(t *testing.T) {
+	t.Log("Lock results should go to the lock webhooks and unlock results to the unlock webhooks")
+	RegisterMockTestingT(t)
+	lockSender := mocks.NewMockLockSender()
+	unlockSender := mocks.NewMockLockSender()
+	manager := webhooks.MultiWebhookSender{
+		LockWebhooks:   []webhooks.LockSender{lockSender},
+		UnlockWebhooks: []webhooks.LockSender{unlockSender},
+	}
+	logger := logging.NewNoopLogger()
+	locked := webhooks.LockResult{Workspace: "default", Locked: true}
+	unlocked := webhooks.LockResult{Workspace: "default"}
+	Ok(t, manager.SendLock(logger, locked))
+	Ok(t, manager.SendLock(logger, unlocked))
+	lockSender.VerifyWasCalledOnce().SendLock(logger, locked)
+	lockSender.VerifyWasCalled(Never()).SendLock(logger, unlocked)
+	unlockSender.VerifyWasCalledOnce().SendLock(logger, unlocked)
+	unlockSender.VerifyWasCalled(Never()).SendLock(logger, locked)
+}
+
+func TestSendPlan_MultipleSuccess(t *testing.T) {
+	RegisterMockTestingT(t)
+	senders := []*mocks.MockPlanSender{
+		mocks.NewMockPlanSender(),
+		mocks.NewMockPlanSender(),
+	}
+	manager := webhooks.MultiWebhookSender{
+		PlanWebhooks: []webhooks.PlanSender{senders[0], senders[1]},
+	}
+	logger := logging.NewNoopLogger()
+	result := webhooks.PlanResult{Success: true}
+	Ok(t, manager.SendPlan(logger, result))
+	for _, s := range senders {
+		s.VerifyWasCalledOnce().SendPlan(logger, result)
+	}
+}
+
+func TestNewWebhooksManager_MSTeamsNoURL(t *testing.T) {
+	configs := []webhooks.Config{{Event: webhooks.ApplyEvent, WorkspaceRegex: validRegex, Kind: webhooks.MSTeamsKind}}
+	_, err := webhooks.NewMultiWebhookSender(configs, nil, nil)
+	ErrEquals(t, "must specify \"url\" if using a webhook of \"kind: msteams\"", err)
+}
+
+func TestNewWebhooksManager_MSTeamsUnsupportedEvent(t *testing.T) {
+	configs := []webhooks.Config{{Event: webhooks.UnlockEvent, WorkspaceRegex: validRegex, Kind: webhooks.MSTeamsKind, URL: "https://example.com"}}
+	_, err := webhooks.NewMultiWebhookSender(configs, nil, nil)
+	ErrEquals(t, "\"event: unlock\" not supported for webhooks of \"kind: msteams\". Only \"event: apply\", \"event: plan\" and \"event: drift\" are supported", err)
+}
+
+func TestNewWebhooksManager_EmailNoSMTP(t *testing.T) {
+	configs := []webhooks.Config{{Event: webhooks.ApplyEvent, WorkspaceRegex: validRegex, Kind: webhooks.EmailKind, To: []string{"compliance@example.com"}}}
+	_, err := webhooks.NewMultiWebhookSender(configs, nil, nil)
+	ErrEquals(t, "must specify top-level \"smtp\" if using a webhook of \"kind: email\"", err)
+
+	_, err = webhooks.NewMultiWebhookSender(configs, nil, &webhooks.DefaultSMTPClient{})
+	ErrEquals(t, "must specify top-level \"smtp\" if using a webhook of \"kind: email\"", err)
+}
+
+func TestNewWebhooksManager_EmailNoTo(t *testing.T) {
+	configs := []webhooks.Config{{Event: webhooks.ApplyEvent, WorkspaceRegex: validRegex, Kind: webhooks.EmailKind}}
+	_, err := webhooks.NewMultiWebhookSender(configs, nil, &webhooks.DefaultSMTPClient{Host: "localhost"})
+	ErrEquals(t, "must specify \"to\" if using a webhook of \"kind: email\"", err)
+}
+
+func TestNewWebhooksManager_MSTeamsAndEmailConfig(t *testing.T) {
+	t.Log("msteams and email webhooks should be sent for the event they're configured for")
+	smtpClient := &webhooks.DefaultSMTPClient{Host: "localhost"}
+	var configs []webhooks.Config
+	for _, event := range []string{webhooks.ApplyEvent, webhooks.PlanEvent, webhooks.DriftEvent} {
+		configs = append(configs,
+			webhooks.Config{Event: event, Kind: webhooks.MSTeamsKind, URL: "https://example.webhook.office.com/webhookb2/abc"},
+			webhooks.Config{Event: event, Kind: webhooks.EmailKind, To: []string{"compliance@example.com"}},
+		)
+	}
+	m, err := webhooks.NewMultiWebhookSender(configs, nil, smtpClient)
+	Ok(t, err)
+	Equals(t, 2, len(m.Webhooks))
+	Equals(t, 2, len(m.PlanWebhooks))
+	Equals(t, 2, len(m.DriftWebhooks))
+	Equals(t, 0, len(m.LockWebhooks))
+}
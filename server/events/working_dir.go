@@ -155,7 +155,7 @@ func (w *FileWorkspace) DeleteForWorkspace(r models.Repo, p models.PullRequest,
 }
 
 func (w *FileWorkspace) repoPullDir(r models.Repo, p models.PullRequest) string {
-	return filepath.Join(w.DataDir, workingDirPrefix, r.FullName, strconv.Itoa(p.Num))
+	return filepath.Join(w.DataDir, workingDirPrefix, filepath.FromSlash(r.ID()), strconv.Itoa(p.Num))
 }
 
 func (w *FileWorkspace) cloneDir(r models.Repo, p models.PullRequest, workspace string) string {
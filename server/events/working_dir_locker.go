@@ -17,6 +17,8 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+
+	"github.com/runatlantis/atlantis/server/events/models"
 )
 
 //go:generate pegomock generate --use-experimental-model-gen --package mocks -o mocks/mock_working_dir_locker.go WorkingDirLocker
@@ -31,13 +33,13 @@ type WorkingDirLocker interface {
 	// It returns a function that should be used to unlock the workspace and
 	// an error if the workspace is already locked. The error is expected to
 	// be printed to the pull request.
-	TryLock(repoFullName string, pullNum int, workspace string) (func(), error)
+	TryLock(repo models.Repo, pullNum int, workspace string) (func(), error)
 	// TryLockPull tries to acquire a lock for all the workspaces in this repo
 	// and pull.
 	// It returns a function that should be used to unlock the workspace and
 	// an error if the workspace is already locked. The error is expected to
 	// be printed to the pull request.
-	TryLockPull(repoFullName string, pullNum int) (func(), error)
+	TryLockPull(repo models.Repo, pullNum int) (func(), error)
 }
 
 // DefaultWorkingDirLocker implements WorkingDirLocker.
@@ -56,11 +58,11 @@ func NewDefaultWorkingDirLocker() *DefaultWorkingDirLocker {
 	return &DefaultWorkingDirLocker{}
 }
 
-func (d *DefaultWorkingDirLocker) TryLockPull(repoFullName string, pullNum int) (func(), error) {
+func (d *DefaultWorkingDirLocker) TryLockPull(repo models.Repo, pullNum int) (func(), error) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
-	pullKey := d.pullKey(repoFullName, pullNum)
+	pullKey := d.pullKey(repo, pullNum)
 	for _, l := range d.locks {
 		if l == pullKey || strings.HasPrefix(l, pullKey+"/") {
 			return func() {}, fmt.Errorf("the Atlantis working dir is currently locked by another" +
@@ -70,16 +72,16 @@ func (d *DefaultWorkingDirLocker) TryLockPull(repoFullName string, pullNum int)
 	}
 	d.locks = append(d.locks, pullKey)
 	return func() {
-		d.UnlockPull(repoFullName, pullNum)
+		d.UnlockPull(repo, pullNum)
 	}, nil
 }
 
-func (d *DefaultWorkingDirLocker) TryLock(repoFullName string, pullNum int, workspace string) (func(), error) {
+func (d *DefaultWorkingDirLocker) TryLock(repo models.Repo, pullNum int, workspace string) (func(), error) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
-	pullKey := d.pullKey(repoFullName, pullNum)
-	workspaceKey := d.workspaceKey(repoFullName, pullNum, workspace)
+	pullKey := d.pullKey(repo, pullNum)
+	workspaceKey := d.workspaceKey(repo, pullNum, workspace)
 	for _, l := range d.locks {
 		if l == pullKey || l == workspaceKey {
 			return func() {}, fmt.Errorf("the %s workspace is currently locked by another"+
@@ -89,25 +91,25 @@ func (d *DefaultWorkingDirLocker) TryLock(repoFullName string, pullNum int, work
 	}
 	d.locks = append(d.locks, workspaceKey)
 	return func() {
-		d.unlock(repoFullName, pullNum, workspace)
+		d.unlock(repo, pullNum, workspace)
 	}, nil
 }
 
 // Unlock unlocks the workspace for this pull.
-func (d *DefaultWorkingDirLocker) unlock(repoFullName string, pullNum int, workspace string) {
+func (d *DefaultWorkingDirLocker) unlock(repo models.Repo, pullNum int, workspace string) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
-	workspaceKey := d.workspaceKey(repoFullName, pullNum, workspace)
+	workspaceKey := d.workspaceKey(repo, pullNum, workspace)
 	d.removeLock(workspaceKey)
 }
 
 // Unlock unlocks all workspaces for this pull.
-func (d *DefaultWorkingDirLocker) UnlockPull(repoFullName string, pullNum int) {
+func (d *DefaultWorkingDirLocker) UnlockPull(repo models.Repo, pullNum int) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
-	pullKey := d.pullKey(repoFullName, pullNum)
+	pullKey := d.pullKey(repo, pullNum)
 	d.removeLock(pullKey)
 }
 
@@ -121,10 +123,10 @@ func (d *DefaultWorkingDirLocker) removeLock(key string) {
 	d.locks = newLocks
 }
 
-func (d *DefaultWorkingDirLocker) workspaceKey(repo string, pull int, workspace string) string {
+func (d *DefaultWorkingDirLocker) workspaceKey(repo models.Repo, pull int, workspace string) string {
 	return fmt.Sprintf("%s/%s", d.pullKey(repo, pull), workspace)
 }
 
-func (d *DefaultWorkingDirLocker) pullKey(repo string, pull int) string {
-	return fmt.Sprintf("%s/%d", repo, pull)
+func (d *DefaultWorkingDirLocker) pullKey(repo models.Repo, pull int) string {
+	return fmt.Sprintf("%s/%d", repo.ID(), pull)
 }
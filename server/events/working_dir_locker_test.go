@@ -17,10 +17,11 @@ import (
 	"testing"
 
 	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
 	. "github.com/runatlantis/atlantis/testing"
 )
 
-var repo = "repo/owner"
+var repo = models.Repo{FullName: "repo/owner"}
 var workspace = "default"
 
 func TestTryLock(t *testing.T) {
@@ -64,7 +65,7 @@ func TestTryLockDifferentRepo(t *testing.T) {
 	t.Log("a lock for a different repo but the same workspace and pull should succeed")
 	_, err := locker.TryLock(repo, 1, workspace)
 	Ok(t, err)
-	newRepo := "owner/newrepo"
+	newRepo := models.Repo{FullName: "owner/newrepo"}
 	_, err = locker.TryLock(newRepo, 1, workspace)
 	Ok(t, err)
 
@@ -124,7 +125,7 @@ func TestUnlockDifferentRepos(t *testing.T) {
 	t.Log("unlocking should work for different repos")
 	unlockFn1, err1 := locker.TryLock(repo, 1, workspace)
 	Ok(t, err1)
-	newRepo := "owner/newrepo"
+	newRepo := models.Repo{FullName: "owner/newrepo"}
 	unlockFn2, err2 := locker.TryLock(newRepo, 1, workspace)
 	Ok(t, err2)
 	unlockFn1()
@@ -153,31 +154,43 @@ func TestUnlockDifferentPulls(t *testing.T) {
 	Ok(t, err)
 }
 
+func TestTryLockDifferentVCSHost(t *testing.T) {
+	locker := events.NewDefaultWorkingDirLocker()
+
+	t.Log("a lock for a repo with the same name on a different host should succeed")
+	githubRepo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "github.com", Type: models.Github}}
+	gitlabRepo := models.Repo{FullName: "owner/repo", VCSHost: models.VCSHost{Hostname: "gitlab.com", Type: models.Gitlab}}
+	_, err := locker.TryLockPull(githubRepo, 1)
+	Ok(t, err)
+	_, err = locker.TryLockPull(gitlabRepo, 1)
+	Ok(t, err)
+}
+
 func TestLockPull(t *testing.T) {
 	locker := events.NewDefaultWorkingDirLocker()
-	unlock, err := locker.TryLockPull("owner/repo", 1)
+	unlock, err := locker.TryLockPull(repo, 1)
 	Ok(t, err)
 
 	// Now a lock for the same pull or for a workspace should fail.
-	_, err = locker.TryLockPull("owner/repo", 1)
+	_, err = locker.TryLockPull(repo, 1)
 	Assert(t, err != nil, "exp err")
-	_, err = locker.TryLock("owner/repo", 1, "workspace")
+	_, err = locker.TryLock(repo, 1, "workspace")
 	Assert(t, err != nil, "exp err")
 
 	// Lock for a different pull and workspace should succeed.
-	_, err = locker.TryLockPull("owner/repo", 2)
+	_, err = locker.TryLockPull(repo, 2)
 	Ok(t, err)
-	_, err = locker.TryLock("owner/repo", 3, "workspace")
+	_, err = locker.TryLock(repo, 3, "workspace")
 	Ok(t, err)
 
 	// After unlocking, should be able to get a pull lock.
 	unlock()
-	unlock, err = locker.TryLockPull("owner/repo", 1)
+	unlock, err = locker.TryLockPull(repo, 1)
 	Ok(t, err)
 
 	// If we unlock that too, should be able to get the workspace lock.
 	unlock()
-	_, err = locker.TryLock("owner/repo", 1, "workspace")
+	_, err = locker.TryLock(repo, 1, "workspace")
 	Ok(t, err)
 	unlock()
 }
@@ -185,14 +198,14 @@ func TestLockPull(t *testing.T) {
 // If the workspace was locked first, we shouldn't be able to get the pull lock.
 func TestLockPull_WorkspaceFirst(t *testing.T) {
 	locker := events.NewDefaultWorkingDirLocker()
-	unlock, err := locker.TryLock("owner/repo", 1, "workspace")
+	unlock, err := locker.TryLock(repo, 1, "workspace")
 	Ok(t, err)
 
-	_, err = locker.TryLockPull("owner/repo", 1)
+	_, err = locker.TryLockPull(repo, 1)
 	Assert(t, err != nil, "exp err")
 
 	// After unlocking the workspace, should be able to get the lock.
 	unlock()
-	_, err = locker.TryLockPull("owner/repo", 1)
+	_, err = locker.TryLockPull(repo, 1)
 	Ok(t, err)
 }
@@ -5,6 +5,8 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/go-ozzo/ozzo-validation"
 	"github.com/pkg/errors"
@@ -16,7 +18,12 @@ import (
 // AtlantisYAMLFilename is the name of the config file for each repo.
 const AtlantisYAMLFilename = "atlantis.yaml"
 
-type ParserValidator struct{}
+type ParserValidator struct {
+	// AllowedEnvVars, if set, are the only env vars that atlantis.yaml files
+	// can set with env steps or a project's env key. Names ending in *
+	// allow every env var with that prefix, ex. TF_VAR_*.
+	AllowedEnvVars []string
+}
 
 // ReadConfig returns the parsed and validated atlantis.yaml config for repoDir.
 // If there was no config file, then this can be detected by checking the type
@@ -38,7 +45,7 @@ func (p *ParserValidator) ReadConfig(repoDir string) (valid.Config, error) {
 	}
 
 	// If the config file exists, parse it.
-	config, err := p.parseAndValidate(configData)
+	config, err := p.parseAndValidate(configData, repoDir)
 	if err != nil {
 		return valid.Config{}, errors.Wrapf(err, "parsing %s", AtlantisYAMLFilename)
 	}
@@ -60,7 +67,7 @@ func (p *ParserValidator) configFilePath(repoDir string) string {
 	return filepath.Join(repoDir, AtlantisYAMLFilename)
 }
 
-func (p *ParserValidator) parseAndValidate(configData []byte) (valid.Config, error) {
+func (p *ParserValidator) parseAndValidate(configData []byte, repoDir string) (valid.Config, error) {
 	var rawConfig raw.Config
 	if err := yaml.UnmarshalStrict(configData, &rawConfig); err != nil {
 		return valid.Config{}, err
@@ -78,10 +85,16 @@ func (p *ParserValidator) parseAndValidate(configData []byte) (valid.Config, err
 		return valid.Config{}, err
 	}
 
-	validConfig := rawConfig.ToValid()
+	validConfig, err := rawConfig.ToValid(repoDir)
+	if err != nil {
+		return valid.Config{}, err
+	}
 	if err := p.validateProjectNames(validConfig); err != nil {
 		return valid.Config{}, err
 	}
+	if err := p.validateEnvVars(validConfig); err != nil {
+		return valid.Config{}, err
+	}
 
 	return validConfig, nil
 }
@@ -124,7 +137,7 @@ func (p *ParserValidator) validateProjectNames(config valid.Config) error {
 }
 
 func (p *ParserValidator) validateWorkflows(config raw.Config) error {
-	for _, project := range config.Projects {
+	for _, project := range config.ResolvedProjects() {
 		if err := p.validateWorkflowExists(project, config.Workflows); err != nil {
 			return err
 		}
@@ -144,3 +157,49 @@ func (p *ParserValidator) validateWorkflowExists(project raw.Project, workflows
 	}
 	return fmt.Errorf("workflow %q is not defined", workflow)
 }
+
+// validateEnvVars returns an error if config sets env vars that aren't in
+// AllowedEnvVars.
+func (p *ParserValidator) validateEnvVars(config valid.Config) error {
+	if len(p.AllowedEnvVars) == 0 {
+		return nil
+	}
+	var names []string
+	for _, project := range config.Projects {
+		for name := range project.Env {
+			names = append(names, name)
+		}
+	}
+	for _, workflow := range config.Workflows {
+		for _, stage := range []*valid.Stage{workflow.Plan, workflow.Apply} {
+			if stage == nil {
+				continue
+			}
+			for _, step := range stage.Steps {
+				if step.StepName == raw.EnvStepName {
+					names = append(names, step.EnvVarName)
+				}
+			}
+		}
+	}
+	// Sort so the error is deterministic.
+	sort.Strings(names)
+	for _, name := range names {
+		if !p.envVarAllowed(name) {
+			return fmt.Errorf("env var %q is not allowed, allowed env vars are: %s", name, strings.Join(p.AllowedEnvVars, ", "))
+		}
+	}
+	return nil
+}
+
+func (p *ParserValidator) envVarAllowed(name string) bool {
+	for _, allowed := range p.AllowedEnvVars {
+		if strings.HasSuffix(allowed, "*") && strings.HasPrefix(name, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+		if allowed == name {
+			return true
+		}
+	}
+	return false
+}
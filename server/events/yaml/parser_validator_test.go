@@ -342,6 +342,54 @@ projects:
 				Workflows: map[string]valid.Workflow{},
 			},
 		},
+
+		// Project templates and defaults.
+		{
+			description: "workflow from project defaults not defined",
+			input: `
+version: 2
+project_defaults:
+  workflow: undefined
+projects:
+- dir: "."
+`,
+			expErr: "workflow \"undefined\" is not defined",
+		},
+		{
+			description: "project extends a template and the defaults",
+			input: `
+version: 2
+project_defaults:
+  terraform_version: v0.11.0
+project_templates:
+  staging:
+    workspace: staging
+    workflow: myworkflow
+projects:
+- dir: mydir
+  extends: staging
+workflows:
+  myworkflow: ~
+`,
+			exp: valid.Config{
+				Version: 2,
+				Projects: []valid.Project{
+					{
+						Dir:              "mydir",
+						Workspace:        "staging",
+						Workflow:         String("myworkflow"),
+						TerraformVersion: tfVersion,
+						Autoplan: valid.Autoplan{
+							WhenModified: []string{"**/*.tf*"},
+							Enabled:      true,
+						},
+					},
+				},
+				Workflows: map[string]valid.Workflow{
+					"myworkflow": {},
+				},
+			},
+		},
 	}
 
 	tmpDir, cleanup := TempDir(t)
@@ -606,6 +654,64 @@ workflows:
 				},
 			},
 		},
+		{
+			description: "env steps and project env",
+			input: `
+version: 2
+projects:
+- dir: "."
+  workflow: default
+  env:
+    TF_VAR_region: us-east-1
+workflows:
+  default:
+    plan:
+      steps:
+      - env:
+          name: TF_VAR_token
+          command: cat token.txt
+      - env:
+          name: TF_VAR_stage
+          value: prod
+      - plan
+`,
+			expOutput: valid.Config{
+				Version: 2,
+				Projects: []valid.Project{
+					{
+						Dir:       ".",
+						Workspace: "default",
+						Workflow:  String("default"),
+						Autoplan: valid.Autoplan{
+							Enabled:      true,
+							WhenModified: []string{"**/*.tf*"},
+						},
+						Env: map[string]string{"TF_VAR_region": "us-east-1"},
+					},
+				},
+				Workflows: map[string]valid.Workflow{
+					"default": {
+						Plan: &valid.Stage{
+							Steps: []valid.Step{
+								{
+									StepName:   "env",
+									EnvVarName: "TF_VAR_token",
+									RunCommand: []string{"cat", "token.txt"},
+								},
+								{
+									StepName:    "env",
+									EnvVarName:  "TF_VAR_stage",
+									EnvVarValue: "prod",
+								},
+								{
+									StepName: "plan",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	tmpDir, cleanup := TempDir(t)
@@ -624,6 +730,77 @@ workflows:
 	}
 }
 
+func TestReadConfig_AllowedEnvVars(t *testing.T) {
+	cases := []struct {
+		description string
+		input       string
+		expErr      string
+	}{
+		{
+			description: "allowed",
+			input: `
+version: 2
+projects:
+- dir: "."
+  env:
+    TF_VAR_region: us-east-1
+workflows:
+  default:
+    plan:
+      steps:
+      - env:
+          name: AWS_PROFILE
+          value: prod
+`,
+		},
+		{
+			description: "project env not allowed",
+			input: `
+version: 2
+projects:
+- dir: "."
+  env:
+    PATH: /tmp
+`,
+			expErr: "env var \"PATH\" is not allowed, allowed env vars are: TF_VAR_*, AWS_PROFILE",
+		},
+		{
+			description: "env step not allowed",
+			input: `
+version: 2
+projects:
+- dir: "."
+workflows:
+  default:
+    apply:
+      steps:
+      - env:
+          name: TF_VAR
+          value: prod
+`,
+			expErr: "env var \"TF_VAR\" is not allowed, allowed env vars are: TF_VAR_*, AWS_PROFILE",
+		},
+	}
+
+	tmpDir, cleanup := TempDir(t)
+	defer cleanup()
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := ioutil.WriteFile(filepath.Join(tmpDir, "atlantis.yaml"), []byte(c.input), 0600)
+			Ok(t, err)
+
+			r := yaml.ParserValidator{AllowedEnvVars: []string{"TF_VAR_*", "AWS_PROFILE"}}
+			_, err = r.ReadConfig(tmpDir)
+			if c.expErr != "" {
+				ErrEquals(t, "parsing atlantis.yaml: "+c.expErr, err)
+				return
+			}
+			Ok(t, err)
+		})
+	}
+}
+
 // String is a helper routine that allocates a new string value
 // to store v and returns a pointer to it.
 func String(v string) *string { return &v }
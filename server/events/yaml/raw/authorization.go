@@ -0,0 +1,28 @@
+package raw
+
+import (
+	"errors"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
+)
+
+// Authorization restricts who can run a command on a project.
+type Authorization struct {
+	Users []string `yaml:"users,omitempty"`
+	// Teams are GitHub teams, ex. owner/infra, or GitLab groups.
+	Teams []string `yaml:"teams,omitempty"`
+}
+
+func (a Authorization) Validate() error {
+	if len(a.Users) == 0 && len(a.Teams) == 0 {
+		return errors.New("at least one of users or teams must be set")
+	}
+	return nil
+}
+
+func (a Authorization) ToValid() valid.Authorization {
+	return valid.Authorization{
+		Users: a.Users,
+		Teams: a.Teams,
+	}
+}
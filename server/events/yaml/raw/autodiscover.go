@@ -0,0 +1,141 @@
+package raw
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/pkg/errors"
+)
+
+// rootBlockRegex matches the backend and provider blocks that only the root
+// of a Terraform project usually has, ex. provider "aws" {.
+var rootBlockRegex = regexp.MustCompile(`(?m)^\s*(backend|provider)\s+"?[\w-]+"?\s*\{`)
+
+// Autodiscover configures finding projects from the repo's layout instead of
+// listing them.
+type Autodiscover struct {
+	// Enabled defaults to true so autodiscover: {} is enough to turn it on.
+	Enabled *bool `yaml:"enabled,omitempty"`
+	// RootDirs are globs, ex. envs/*, of dirs that are projects even if they
+	// don't have a backend or provider block.
+	RootDirs []string `yaml:"root_dirs,omitempty"`
+}
+
+func (a Autodiscover) Validate() error {
+	validRootDirs := func(value interface{}) error {
+		for _, pattern := range value.([]string) {
+			if strings.Contains(pattern, "..") {
+				return fmt.Errorf("%q cannot contain '..'", pattern)
+			}
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("%q is not a valid glob: %s", pattern, err)
+			}
+		}
+		return nil
+	}
+	return validation.ValidateStruct(&a,
+		validation.Field(&a.RootDirs, validation.By(validRootDirs)),
+	)
+}
+
+// IsEnabled returns true if projects should be discovered.
+func (a Autodiscover) IsEnabled() bool {
+	return a.Enabled == nil || *a.Enabled
+}
+
+// Discover returns a project for every dir in repoDir that has a .tf file
+// with a backend or provider block, or that matches RootDirs. Dirs under a
+// modules dir are only projects if they match RootDirs. If a project has
+// env/{workspace}.tfvars files it gets a workspace for each of them, which
+// the plan step passes as a -var-file.
+func (a Autodiscover) Discover(repoDir string) ([]Project, error) {
+	var projects []Project
+	err := filepath.Walk(repoDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		relDir, err := filepath.Rel(repoDir, path)
+		if err != nil {
+			return err
+		}
+		relDir = filepath.ToSlash(relDir)
+		// Skip .git, .terraform and other hidden dirs.
+		if relDir != "." && strings.HasPrefix(info.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		isProject := a.matchesRootDirs(relDir)
+		if !isProject && !strings.Contains("/"+relDir+"/", "/modules/") {
+			isProject, err = hasRootBlock(path)
+			if err != nil {
+				return err
+			}
+		}
+		if !isProject {
+			return nil
+		}
+		workspaces, err := tfvarsWorkspaces(path)
+		if err != nil {
+			return err
+		}
+		projects = append(projects, Project{
+			Dir:        &relDir,
+			Workspaces: workspaces,
+		})
+		return nil
+	})
+	return projects, errors.Wrap(err, "discovering projects")
+}
+
+func (a Autodiscover) matchesRootDirs(relDir string) bool {
+	for _, pattern := range a.RootDirs {
+		// We ignore the error because the pattern was checked in Validate().
+		if match, _ := filepath.Match(pattern, relDir); match {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRootBlock returns true if any of the .tf files in dir has a backend or
+// provider block.
+func hasRootBlock(dir string) (bool, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.tf"))
+	if err != nil {
+		return false, err
+	}
+	for _, f := range files {
+		contents, err := ioutil.ReadFile(f) // nolint: gosec
+		if err != nil {
+			return false, err
+		}
+		if rootBlockRegex.Match(contents) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// tfvarsWorkspaces returns the workspaces named by the env/{workspace}.tfvars
+// files in dir, or nil if there are none.
+func tfvarsWorkspaces(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "env", "*.tfvars"))
+	if err != nil {
+		return nil, err
+	}
+	var workspaces []string
+	for _, f := range files {
+		workspaces = append(workspaces, strings.TrimSuffix(filepath.Base(f), ".tfvars"))
+	}
+	sort.Strings(workspaces)
+	return workspaces, nil
+}
@@ -0,0 +1,114 @@
+package raw_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-ozzo/ozzo-validation"
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+	. "github.com/runatlantis/atlantis/testing"
+	"gopkg.in/yaml.v2"
+)
+
+func TestAutodiscover_UnmarshalYAML(t *testing.T) {
+	var a raw.Autodiscover
+	Ok(t, yaml.UnmarshalStrict([]byte(`
+enabled: false
+root_dirs: ["envs/*"]
+`), &a))
+	Equals(t, raw.Autodiscover{
+		Enabled:  Bool(false),
+		RootDirs: []string{"envs/*"},
+	}, a)
+}
+
+func TestAutodiscover_Validate(t *testing.T) {
+	cases := []struct {
+		description string
+		input       raw.Autodiscover
+		expErr      string
+	}{
+		{
+			description: "nothing set",
+			input:       raw.Autodiscover{},
+		},
+		{
+			description: "root dirs",
+			input: raw.Autodiscover{
+				RootDirs: []string{"envs/*", "live/*/*"},
+			},
+		},
+		{
+			description: "root dir with ..",
+			input: raw.Autodiscover{
+				RootDirs: []string{"../envs"},
+			},
+			expErr: "root_dirs: \"../envs\" cannot contain '..'.",
+		},
+		{
+			description: "invalid root dir glob",
+			input: raw.Autodiscover{
+				RootDirs: []string{"envs/["},
+			},
+			expErr: "root_dirs: \"envs/[\" is not a valid glob: syntax error in pattern.",
+		},
+	}
+	validation.ErrorTag = "yaml"
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			err := c.input.Validate()
+			if c.expErr == "" {
+				Ok(t, err)
+			} else {
+				ErrEquals(t, c.expErr, err)
+			}
+		})
+	}
+}
+
+func TestAutodiscover_IsEnabled(t *testing.T) {
+	Equals(t, true, raw.Autodiscover{}.IsEnabled())
+	Equals(t, true, raw.Autodiscover{Enabled: Bool(true)}.IsEnabled())
+	Equals(t, false, raw.Autodiscover{Enabled: Bool(false)}.IsEnabled())
+}
+
+func TestAutodiscover_Discover(t *testing.T) {
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	files := map[string]string{
+		"main.tf":                    "terraform {\n  backend \"s3\" {}\n}\n",
+		"network/main.tf":            "provider aws {\n  region = \"us-east-1\"\n}\n",
+		"network/env/staging.tfvars": "",
+		"network/env/prod.tfvars":    "",
+		"network/variables.tf":       "variable \"region\" {}\n",
+		"modules/vpc/main.tf":        "provider \"aws\" {}\n",
+		"live/app/terragrunt.hcl":    "",
+		"live/app/README.md":         "",
+		"docs/README.md":             "provider \"aws\" {}\n",
+		"lib/outputs.tf":             "output \"id\" {}\n",
+		".terraform/modules/x/a.tf":  "provider \"aws\" {}\n",
+	}
+	for path, contents := range files {
+		Ok(t, os.MkdirAll(filepath.Join(repoDir, filepath.Dir(path)), 0700))
+		Ok(t, ioutil.WriteFile(filepath.Join(repoDir, path), []byte(contents), 0600))
+	}
+
+	projects, err := raw.Autodiscover{RootDirs: []string{"live/*"}}.Discover(repoDir)
+	Ok(t, err)
+	Equals(t, []raw.Project{
+		{Dir: String(".")},
+		{Dir: String("live/app")},
+		{Dir: String("network"), Workspaces: []string{"prod", "staging"}},
+	}, projects)
+
+	t.Log("dirs under modules should be projects if they match a root dir")
+	projects, err = raw.Autodiscover{RootDirs: []string{"modules/*"}}.Discover(repoDir)
+	Ok(t, err)
+	Equals(t, []raw.Project{
+		{Dir: String(".")},
+		{Dir: String("modules/vpc")},
+		{Dir: String("network"), Workspaces: []string{"prod", "staging"}},
+	}, projects)
+}
@@ -2,6 +2,11 @@ package raw
 
 import (
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 
 	"github.com/go-ozzo/ozzo-validation"
 	"github.com/runatlantis/atlantis/server/events/yaml/valid"
@@ -12,6 +17,15 @@ type Config struct {
 	Version   *int                `yaml:"version,omitempty"`
 	Projects  []Project           `yaml:"projects,omitempty"`
 	Workflows map[string]Workflow `yaml:"workflows,omitempty"`
+	// ProjectDefaults are used for the fields that neither a project nor the
+	// template it extends set.
+	ProjectDefaults *Project `yaml:"project_defaults,omitempty"`
+	// ProjectTemplates maps from a name to a template that projects can
+	// extend.
+	ProjectTemplates map[string]Project `yaml:"project_templates,omitempty"`
+	// Autodiscover, if set, adds a project for every dir in the repo that
+	// looks like one and isn't already listed in Projects.
+	Autodiscover *Autodiscover `yaml:"autodiscover,omitempty"`
 }
 
 func (c Config) Validate() error {
@@ -25,17 +39,112 @@ func (c Config) Validate() error {
 		}
 		return nil
 	}
-	return validation.ValidateStruct(&c,
-		validation.Field(&c.Version, validation.By(equals2)),
-		validation.Field(&c.Projects),
-		validation.Field(&c.Workflows),
-	)
+	// Projects are validated after their template and the defaults are
+	// applied so they can leave required keys, ex. dir, to them.
+	return validation.Errors{
+		"version":           validation.Validate(c.Version, validation.By(equals2)),
+		"projects":          c.validateProjects(),
+		"workflows":         validation.Validate(c.Workflows),
+		"project_defaults":  c.validateProjectDefaults(),
+		"project_templates": c.validateProjectTemplates(),
+		"autodiscover":      validation.Validate(c.Autodiscover),
+	}.Filter()
 }
 
-func (c Config) ToValid() valid.Config {
-	var validProjects []valid.Project
+func (c Config) validateProjects() error {
+	errs := validation.Errors{}
+	for i, p := range c.ResolvedProjects() {
+		key := strconv.Itoa(i)
+		if extends := c.Projects[i].Extends; extends != nil {
+			if _, ok := c.ProjectTemplates[*extends]; !ok {
+				errs[key] = validation.Errors{"extends": fmt.Errorf("project template %q is not defined", *extends)}
+				continue
+			}
+		}
+		errs[key] = p.Validate()
+	}
+	return errs.Filter()
+}
+
+func (c Config) validateProjectDefaults() error {
+	if c.ProjectDefaults == nil {
+		return nil
+	}
+	return c.ProjectDefaults.ValidateTemplate()
+}
+
+func (c Config) validateProjectTemplates() error {
+	errs := validation.Errors{}
+	for name, t := range c.ProjectTemplates {
+		errs[name] = t.ValidateTemplate()
+	}
+	return errs.Filter()
+}
+
+// ResolvedProjects returns the projects with the fields they don't set taken
+// from the template they extend and then from the project defaults. It
+// expects extends to have been validated.
+func (c Config) ResolvedProjects() []Project {
+	var projects []Project
 	for _, p := range c.Projects {
-		validProjects = append(validProjects, p.ToValid())
+		if p.Extends != nil {
+			p = p.WithDefaults(c.ProjectTemplates[*p.Extends])
+			p.Extends = nil
+		}
+		if c.ProjectDefaults != nil {
+			p = p.WithDefaults(*c.ProjectDefaults)
+		}
+		projects = append(projects, p)
+	}
+	return projects
+}
+
+// ToValid returns the valid config. Projects with a glob dir or workspaces
+// are expanded into a project for every directory in repoDir that matches and
+// every workspace. It errors if a glob doesn't match any directories. If
+// Autodiscover is enabled, the discovered projects whose dirs aren't
+// configured are added after the configured projects.
+func (c Config) ToValid(repoDir string) (valid.Config, error) {
+	var validProjects []valid.Project
+	for i, p := range c.ResolvedProjects() {
+		dirs := []string{*p.Dir}
+		if isGlob(*p.Dir) {
+			var err error
+			dirs, err = globDirs(repoDir, *p.Dir)
+			if err != nil {
+				return valid.Config{}, err
+			}
+			if len(dirs) == 0 {
+				return valid.Config{}, validation.Errors{
+					"projects": validation.Errors{
+						strconv.Itoa(i): validation.Errors{
+							"dir": fmt.Errorf("%q doesn't match any directories", *p.Dir),
+						},
+					},
+				}
+			}
+		}
+		validProjects = append(validProjects, p.expand(dirs)...)
+	}
+
+	if c.Autodiscover != nil && c.Autodiscover.IsEnabled() {
+		configuredDirs := make(map[string]bool)
+		for _, p := range validProjects {
+			configuredDirs[p.Dir] = true
+		}
+		discovered, err := c.Autodiscover.Discover(repoDir)
+		if err != nil {
+			return valid.Config{}, err
+		}
+		for _, p := range discovered {
+			if configuredDirs[*p.Dir] {
+				continue
+			}
+			if c.ProjectDefaults != nil {
+				p = p.WithDefaults(*c.ProjectDefaults)
+			}
+			validProjects = append(validProjects, p.expand([]string{*p.Dir})...)
+		}
 	}
 
 	validWorkflows := make(map[string]valid.Workflow)
@@ -46,5 +155,53 @@ func (c Config) ToValid() valid.Config {
 		Version:   *c.Version,
 		Projects:  validProjects,
 		Workflows: validWorkflows,
+	}, nil
+}
+
+// expand returns a valid project for each of dirs and each of p's workspaces.
+func (p Project) expand(dirs []string) []valid.Project {
+	workspaces := p.Workspaces
+	if workspaces == nil {
+		workspaces = []string{DefaultWorkspace}
+		if p.Workspace != nil {
+			workspaces = []string{*p.Workspace}
+		}
+	}
+	var projects []valid.Project
+	for _, dir := range dirs {
+		for _, workspace := range workspaces {
+			generated := p
+			generated.Dir = &dir
+			generated.Workspace = &workspace
+			generated.Workspaces = nil
+			projects = append(projects, generated.ToValid())
+		}
+	}
+	return projects
+}
+
+// globDirs returns the directories in repoDir that match pattern, relative to
+// repoDir and sorted.
+func globDirs(repoDir string, pattern string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(repoDir, pattern))
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, m := range matches {
+		info, err := os.Stat(m)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			continue
+		}
+		rel, err := filepath.Rel(repoDir, m)
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, filepath.ToSlash(rel))
 	}
+	sort.Strings(dirs)
+	return dirs, nil
 }
@@ -1,6 +1,9 @@
 package raw_test
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/go-ozzo/ozzo-validation"
@@ -173,6 +176,73 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			expErr: "version: must equal 2.",
 		},
+		{
+			description: "dir from a template",
+			input: raw.Config{
+				Version: Int(2),
+				ProjectTemplates: map[string]raw.Project{
+					"app": {Dir: String("app")},
+				},
+				Projects: []raw.Project{
+					{Extends: String("app")},
+				},
+			},
+			expErr: "",
+		},
+		{
+			description: "dir from the defaults",
+			input: raw.Config{
+				Version:         Int(2),
+				ProjectDefaults: &raw.Project{Dir: String("app")},
+				Projects: []raw.Project{
+					{Workspace: String("staging")},
+				},
+			},
+			expErr: "",
+		},
+		{
+			description: "extends an undefined template",
+			input: raw.Config{
+				Version: Int(2),
+				Projects: []raw.Project{
+					{Dir: String("app")},
+					{Extends: String("missing")},
+				},
+			},
+			expErr: "projects: (1: (extends: project template \"missing\" is not defined.).).",
+		},
+		{
+			description: "error in a project after its template is applied",
+			input: raw.Config{
+				Version: Int(2),
+				ProjectTemplates: map[string]raw.Project{
+					"envs": {Workspaces: []string{"staging", "prod"}},
+				},
+				Projects: []raw.Project{
+					{Dir: String("app")},
+					{Name: String("db"), Dir: String("db"), Extends: String("envs")},
+				},
+			},
+			expErr: "projects: (1: (name: cannot be set on projects with a glob dir or workspaces because every project they generate would have the same name.).).",
+		},
+		{
+			description: "error in a template",
+			input: raw.Config{
+				Version: Int(2),
+				ProjectTemplates: map[string]raw.Project{
+					"app": {Name: String("app")},
+				},
+			},
+			expErr: "project_templates: (app: (name: cannot be set on project templates or project_defaults.).).",
+		},
+		{
+			description: "error in the defaults",
+			input: raw.Config{
+				Version:         Int(2),
+				ProjectDefaults: &raw.Project{StepTimeout: String("0s")},
+			},
+			expErr: "project_defaults: (step_timeout: \"0s\" must be greater than 0.).",
+		},
 	}
 	validation.ErrorTag = "yaml"
 	for _, c := range cases {
@@ -277,7 +347,70 @@ func TestConfig_ToValid(t *testing.T) {
 	}
 	for _, c := range cases {
 		t.Run(c.description, func(t *testing.T) {
-			Equals(t, c.exp, c.input.ToValid())
+			act, err := c.input.ToValid("")
+			Ok(t, err)
+			Equals(t, c.exp, act)
 		})
 	}
 }
+
+func TestConfig_ToValidExpandsProjects(t *testing.T) {
+	repoDir, cleanup := TempDir(t)
+	defer cleanup()
+	for _, dir := range []string{"envs/staging", "envs/prod", "modules/vpc"} {
+		Ok(t, os.MkdirAll(filepath.Join(repoDir, dir), 0700))
+	}
+	Ok(t, ioutil.WriteFile(filepath.Join(repoDir, "envs", "README.md"), nil, 0600))
+
+	config := raw.Config{
+		Version: Int(2),
+		ProjectDefaults: &raw.Project{
+			Autoplan: &raw.Autoplan{Enabled: Bool(false)},
+		},
+		ProjectTemplates: map[string]raw.Project{
+			"env": {
+				Workspaces: []string{"blue", "green"},
+				Workflow:   String("custom"),
+			},
+		},
+		Projects: []raw.Project{
+			{Dir: String("envs/*"), Extends: String("env")},
+			{Dir: String("modules/vpc")},
+		},
+	}
+	act, err := config.ToValid(repoDir)
+	Ok(t, err)
+
+	var dirWorkspaces []string
+	for _, p := range act.Projects {
+		dirWorkspaces = append(dirWorkspaces, p.Dir+":"+p.Workspace)
+		Equals(t, false, p.Autoplan.Enabled)
+	}
+	Equals(t, []string{
+		"envs/prod:blue",
+		"envs/prod:green",
+		"envs/staging:blue",
+		"envs/staging:green",
+		"modules/vpc:default",
+	}, dirWorkspaces)
+	Equals(t, String("custom"), act.Projects[0].Workflow)
+	Assert(t, act.Projects[4].Workflow == nil, "exp modules/vpc to not have a workflow")
+
+	t.Log("discovered projects should be added unless their dir is configured")
+	Ok(t, ioutil.WriteFile(filepath.Join(repoDir, "modules", "vpc", "main.tf"), []byte("provider \"aws\" {}\n"), 0600))
+	Ok(t, os.MkdirAll(filepath.Join(repoDir, "db", "env"), 0700))
+	Ok(t, ioutil.WriteFile(filepath.Join(repoDir, "db", "main.tf"), []byte("provider \"aws\" {}\n"), 0600))
+	Ok(t, ioutil.WriteFile(filepath.Join(repoDir, "db", "env", "qa.tfvars"), nil, 0600))
+	config.Autodiscover = &raw.Autodiscover{RootDirs: []string{"modules/*"}}
+	act, err = config.ToValid(repoDir)
+	Ok(t, err)
+	Equals(t, 6, len(act.Projects))
+	Equals(t, "db", act.Projects[5].Dir)
+	Equals(t, "qa", act.Projects[5].Workspace)
+	Equals(t, false, act.Projects[5].Autoplan.Enabled)
+
+	t.Log("a glob that doesn't match any directories should error")
+	config.Projects = append(config.Projects, raw.Project{Dir: String("apps/*")})
+	_, err = config.ToValid(repoDir)
+	ErrEquals(t, "projects: (2: (dir: \"apps/*\" doesn't match any directories.).).", err)
+}
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"net/url"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/go-ozzo/ozzo-validation"
 	"github.com/hashicorp/go-version"
@@ -25,15 +27,54 @@ type Project struct {
 	TerraformVersion  *string   `yaml:"terraform_version,omitempty"`
 	Autoplan          *Autoplan `yaml:"autoplan,omitempty"`
 	ApplyRequirements []string  `yaml:"apply_requirements,omitempty"`
+	// StepTimeout is how long each step of a workflow can run before it's
+	// interrupted, ex. "10m".
+	StepTimeout *string `yaml:"step_timeout,omitempty"`
+	// Authorization maps from a command, ex. apply, to who can run it on the
+	// project.
+	Authorization map[string]Authorization `yaml:"authorization,omitempty"`
+	// LockKey overrides what the project is locked on. Projects with the same
+	// lock key share a lock, even if they're in different repos.
+	LockKey *string `yaml:"lock_key,omitempty"`
+	// Env are env vars set for every step run for the project.
+	Env map[string]string `yaml:"env,omitempty"`
+	// Extends is the name of the project template whose fields are used for
+	// the fields the project doesn't set.
+	Extends *string `yaml:"extends,omitempty"`
+	// Workspaces, if set, generates a project for each workspace instead of
+	// the single project that Workspace configures.
+	Workspaces []string `yaml:"workspaces,omitempty"`
 }
 
 func (p Project) Validate() error {
+	return p.validate(false)
+}
+
+// ValidateTemplate validates p as a project template or as the
+// project_defaults. Unlike projects, they don't need a dir but can't set a
+// name or extend another template.
+func (p Project) ValidateTemplate() error {
+	return p.validate(true)
+}
+
+func (p Project) validate(isTemplate bool) error {
 	hasDotDot := func(value interface{}) error {
-		if strings.Contains(*value.(*string), "..") {
+		strPtr := value.(*string)
+		if strPtr != nil && strings.Contains(*strPtr, "..") {
 			return errors.New("cannot contain '..'")
 		}
 		return nil
 	}
+	validGlob := func(value interface{}) error {
+		strPtr := value.(*string)
+		if strPtr == nil {
+			return nil
+		}
+		if _, err := filepath.Match(*strPtr, ""); err != nil {
+			return fmt.Errorf("%q is not a valid glob: %s", *strPtr, err)
+		}
+		return nil
+	}
 	validApplyReq := func(value interface{}) error {
 		reqs := value.([]string)
 		for _, r := range reqs {
@@ -48,8 +89,33 @@ func (p Project) Validate() error {
 		if strPtr == nil {
 			return nil
 		}
-		_, err := version.NewVersion(*strPtr)
-		return errors.Wrapf(err, "version %q could not be parsed", *strPtr)
+		if _, err := version.NewVersion(*strPtr); err == nil {
+			return nil
+		}
+		_, err := version.NewConstraint(*strPtr)
+		return errors.Wrapf(err, "version %q could not be parsed as a version or a version constraint", *strPtr)
+	}
+	validStepTimeout := func(value interface{}) error {
+		strPtr := value.(*string)
+		if strPtr == nil {
+			return nil
+		}
+		d, err := time.ParseDuration(*strPtr)
+		if err != nil {
+			return fmt.Errorf("%q could not be parsed as a duration, ex. 10m or 1h30m", *strPtr)
+		}
+		if d <= 0 {
+			return fmt.Errorf("%q must be greater than 0", *strPtr)
+		}
+		return nil
+	}
+	validAuthorization := func(value interface{}) error {
+		for command := range value.(map[string]Authorization) {
+			if command != "plan" && command != "apply" {
+				return fmt.Errorf("%q is not a command that can be restricted, must be plan or apply", command)
+			}
+		}
+		return nil
 	}
 	validName := func(value interface{}) error {
 		strPtr := value.(*string)
@@ -62,16 +128,122 @@ func (p Project) Validate() error {
 		if !validProjectName(*strPtr) {
 			return fmt.Errorf("%q is not allowed: must contain only URL safe characters", *strPtr)
 		}
+		if (p.Dir != nil && isGlob(*p.Dir)) || p.Workspaces != nil {
+			return errors.New("cannot be set on projects with a glob dir or workspaces because every project they generate would have the same name")
+		}
+		return nil
+	}
+	validWorkspaces := func(value interface{}) error {
+		workspaces := value.([]string)
+		if workspaces == nil {
+			return nil
+		}
+		if p.Workspace != nil {
+			return errors.New("cannot be set together with workspace")
+		}
+		if len(workspaces) == 0 {
+			return errors.New("if set cannot be empty")
+		}
+		for _, w := range workspaces {
+			if w == "" {
+				return errors.New("cannot contain an empty workspace")
+			}
+		}
+		return nil
+	}
+	notAllowedOnTemplates := func(value interface{}) error {
+		if !isTemplate || value.(*string) == nil {
+			return nil
+		}
+		return errors.New("cannot be set on project templates or project_defaults")
+	}
+	dirRules := []validation.Rule{validation.By(hasDotDot), validation.By(validGlob)}
+	if !isTemplate {
+		dirRules = append([]validation.Rule{validation.Required}, dirRules...)
+	}
+	validLockKey := func(value interface{}) error {
+		strPtr := value.(*string)
+		if strPtr == nil {
+			return nil
+		}
+		if strings.TrimSpace(*strPtr) == "" {
+			return errors.New("if set cannot be empty")
+		}
+		return nil
+	}
+	validEnv := func(value interface{}) error {
+		var names []string
+		for name := range value.(map[string]string) {
+			names = append(names, name)
+		}
+		// Sort so tests can be deterministic.
+		sort.Strings(names)
+		for _, name := range names {
+			if !envVarNameRegex.MatchString(name) {
+				return fmt.Errorf("%q is not a valid env var name", name)
+			}
+		}
 		return nil
 	}
 	return validation.ValidateStruct(&p,
-		validation.Field(&p.Dir, validation.Required, validation.By(hasDotDot)),
+		validation.Field(&p.Dir, dirRules...),
 		validation.Field(&p.ApplyRequirements, validation.By(validApplyReq)),
 		validation.Field(&p.TerraformVersion, validation.By(validTFVersion)),
-		validation.Field(&p.Name, validation.By(validName)),
+		validation.Field(&p.Name, validation.By(notAllowedOnTemplates), validation.By(validName)),
+		validation.Field(&p.StepTimeout, validation.By(validStepTimeout)),
+		validation.Field(&p.Authorization, validation.By(validAuthorization)),
+		validation.Field(&p.LockKey, validation.By(validLockKey)),
+		validation.Field(&p.Env, validation.By(validEnv)),
+		validation.Field(&p.Extends, validation.By(notAllowedOnTemplates)),
+		validation.Field(&p.Workspaces, validation.By(validWorkspaces)),
 	)
 }
 
+// WithDefaults returns p with the fields it doesn't set taken from defaults.
+// Env vars are merged, with p's values taking precedence.
+func (p Project) WithDefaults(defaults Project) Project {
+	if p.Dir == nil {
+		p.Dir = defaults.Dir
+	}
+	// A project that sets either workspace key mustn't inherit the other.
+	if p.Workspace == nil && p.Workspaces == nil {
+		p.Workspace = defaults.Workspace
+		p.Workspaces = defaults.Workspaces
+	}
+	if p.Workflow == nil {
+		p.Workflow = defaults.Workflow
+	}
+	if p.TerraformVersion == nil {
+		p.TerraformVersion = defaults.TerraformVersion
+	}
+	if p.Autoplan == nil {
+		p.Autoplan = defaults.Autoplan
+	}
+	if p.ApplyRequirements == nil {
+		p.ApplyRequirements = defaults.ApplyRequirements
+	}
+	if p.StepTimeout == nil {
+		p.StepTimeout = defaults.StepTimeout
+	}
+	if p.Authorization == nil {
+		p.Authorization = defaults.Authorization
+	}
+	if p.LockKey == nil {
+		p.LockKey = defaults.LockKey
+	}
+	if defaults.Env != nil {
+		env := make(map[string]string)
+		for k, v := range defaults.Env {
+			env[k] = v
+		}
+		for k, v := range p.Env {
+			env[k] = v
+		}
+		p.Env = env
+	}
+	return p
+}
+
 func (p Project) ToValid() valid.Project {
 	var v valid.Project
 	cleanedDir := filepath.Clean(*p.Dir)
@@ -88,7 +260,14 @@ func (p Project) ToValid() valid.Project {
 
 	v.Workflow = p.Workflow
 	if p.TerraformVersion != nil {
-		v.TerraformVersion, _ = version.NewVersion(*p.TerraformVersion)
+		// We ignore the errors here because the version should have been
+		// checked in Validate(). An exact version is preferred over the
+		// equivalent constraint.
+		if tfVersion, err := version.NewVersion(*p.TerraformVersion); err == nil {
+			v.TerraformVersion = tfVersion
+		} else {
+			v.TerraformVersionConstraint, _ = version.NewConstraint(*p.TerraformVersion)
+		}
 	}
 	if p.Autoplan == nil {
 		v.Autoplan = DefaultAutoPlan()
@@ -101,9 +280,34 @@ func (p Project) ToValid() valid.Project {
 
 	v.Name = p.Name
 
+	if p.StepTimeout != nil {
+		// We ignore the error here because it should have been checked in
+		// Validate().
+		v.StepTimeout, _ = time.ParseDuration(*p.StepTimeout)
+	}
+
+	if p.Authorization != nil {
+		v.Authorization = make(map[string]valid.Authorization)
+		for command, a := range p.Authorization {
+			v.Authorization[command] = a.ToValid()
+		}
+	}
+
+	if p.LockKey != nil {
+		v.LockKey = strings.TrimSpace(*p.LockKey)
+	}
+
+	v.Env = p.Env
+
 	return v
 }
 
+// isGlob returns true if dir has any glob meta characters and so could match
+// more than one directory.
+func isGlob(dir string) bool {
+	return strings.ContainsAny(dir, "*?[")
+}
+
 // validProjectName returns true if the project name is valid.
 // Since the name might be used in URLs and definitely in files we don't
 // support any characters that must be url escaped *except* for '/' because
@@ -2,6 +2,7 @@ package raw_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/go-ozzo/ozzo-validation"
 	"github.com/hashicorp/go-version"
@@ -42,7 +43,11 @@ autoplan:
   when_modified: []
   enabled: false
 apply_requirements:
-- mergeable`,
+- mergeable
+step_timeout: 10m
+lock_key: network
+env:
+  TF_VAR_region: us-east-1`,
 			exp: raw.Project{
 				Name:             String("myname"),
 				Dir:              String("mydir"),
@@ -54,6 +59,9 @@ apply_requirements:
 					Enabled:      Bool(false),
 				},
 				ApplyRequirements: []string{"mergeable"},
+				StepTimeout:       String("10m"),
+				LockKey:           String("network"),
+				Env:               map[string]string{"TF_VAR_region": "us-east-1"},
 			},
 		},
 	}
@@ -111,13 +119,77 @@ func TestProject_Validate(t *testing.T) {
 			},
 			expErr: "",
 		},
+		{
+			description: "step timeout valid",
+			input: raw.Project{
+				Dir:         String("."),
+				StepTimeout: String("1h30m"),
+			},
+			expErr: "",
+		},
+		{
+			description: "step timeout not a duration",
+			input: raw.Project{
+				Dir:         String("."),
+				StepTimeout: String("ten minutes"),
+			},
+			expErr: "step_timeout: \"ten minutes\" could not be parsed as a duration, ex. 10m or 1h30m.",
+		},
+		{
+			description: "step timeout not positive",
+			input: raw.Project{
+				Dir:         String("."),
+				StepTimeout: String("0s"),
+			},
+			expErr: "step_timeout: \"0s\" must be greater than 0.",
+		},
+		{
+			description: "lock key empty",
+			input: raw.Project{
+				Dir:     String("."),
+				LockKey: String(" "),
+			},
+			expErr: "lock_key: if set cannot be empty.",
+		},
+		{
+			description: "invalid env var name",
+			input: raw.Project{
+				Dir: String("."),
+				Env: map[string]string{"VALID": "", "NOT-VALID": ""},
+			},
+			expErr: "env: \"NOT-VALID\" is not a valid env var name.",
+		},
 		{
 			description: "empty tf version string",
 			input: raw.Project{
 				Dir:              String("."),
 				TerraformVersion: String(""),
 			},
-			expErr: "terraform_version: version \"\" could not be parsed: Malformed version: .",
+			expErr: "terraform_version: version \"\" could not be parsed as a version or a version constraint: Malformed constraint: .",
+		},
+		{
+			description: "tf version constraint",
+			input: raw.Project{
+				Dir:              String("."),
+				TerraformVersion: String("~> 0.11.0"),
+			},
+			expErr: "",
+		},
+		{
+			description: "multiple tf version constraints",
+			input: raw.Project{
+				Dir:              String("."),
+				TerraformVersion: String(">= 0.11.0, < 0.12"),
+			},
+			expErr: "",
+		},
+		{
+			description: "invalid tf version",
+			input: raw.Project{
+				Dir:              String("."),
+				TerraformVersion: String("latest"),
+			},
+			expErr: "terraform_version: version \"latest\" could not be parsed as a version or a version constraint: Malformed constraint: latest.",
 		},
 		{
 			description: "tf version with v prepended",
@@ -183,6 +255,76 @@ func TestProject_Validate(t *testing.T) {
 			},
 			expErr: `name: "namewith\\" is not allowed: must contain only URL safe characters.`,
 		},
+		{
+			description: "authorization",
+			input: raw.Project{
+				Dir: String("."),
+				Authorization: map[string]raw.Authorization{
+					"apply": {Users: []string{"alice"}, Teams: []string{"owner/infra"}},
+				},
+			},
+			expErr: "",
+		},
+		{
+			description: "authorization for unsupported command",
+			input: raw.Project{
+				Dir: String("."),
+				Authorization: map[string]raw.Authorization{
+					"unlock": {Users: []string{"alice"}},
+				},
+			},
+			expErr: "authorization: \"unlock\" is not a command that can be restricted, must be plan or apply.",
+		},
+		{
+			description: "authorization without users or teams",
+			input: raw.Project{
+				Dir: String("."),
+				Authorization: map[string]raw.Authorization{
+					"apply": {},
+				},
+			},
+			expErr: "authorization: (apply: at least one of users or teams must be set.).",
+		},
+		{
+			description: "glob dir with workspaces",
+			input: raw.Project{
+				Dir:        String("envs/*"),
+				Workspaces: []string{"staging", "prod"},
+			},
+			expErr: "",
+		},
+		{
+			description: "invalid glob dir",
+			input: raw.Project{
+				Dir: String("envs/["),
+			},
+			expErr: "dir: \"envs/[\" is not a valid glob: syntax error in pattern.",
+		},
+		{
+			description: "workspaces and workspace",
+			input: raw.Project{
+				Dir:        String("."),
+				Workspace:  String("staging"),
+				Workspaces: []string{"prod"},
+			},
+			expErr: "workspaces: cannot be set together with workspace.",
+		},
+		{
+			description: "workspaces empty",
+			input: raw.Project{
+				Dir:        String("."),
+				Workspaces: []string{},
+			},
+			expErr: "workspaces: if set cannot be empty.",
+		},
+		{
+			description: "name with glob dir",
+			input: raw.Project{
+				Name: String("myname"),
+				Dir:  String("envs/*"),
+			},
+			expErr: "name: cannot be set on projects with a glob dir or workspaces because every project they generate would have the same name.",
+		},
 	}
 	validation.ErrorTag = "yaml"
 	for _, c := range cases {
@@ -197,8 +339,64 @@ func TestProject_Validate(t *testing.T) {
 	}
 }
 
+func TestProject_ValidateTemplate(t *testing.T) {
+	validation.ErrorTag = "yaml"
+
+	t.Log("templates don't need a dir")
+	Ok(t, raw.Project{Workflow: String("myworkflow")}.ValidateTemplate())
+
+	t.Log("templates can't set a name")
+	ErrEquals(t, "name: cannot be set on project templates or project_defaults.", raw.Project{Name: String("myname")}.ValidateTemplate())
+
+	t.Log("templates can't extend other templates")
+	ErrEquals(t, "extends: cannot be set on project templates or project_defaults.", raw.Project{Extends: String("base")}.ValidateTemplate())
+
+	t.Log("the other fields are still validated")
+	ErrEquals(t, "step_timeout: \"soon\" could not be parsed as a duration, ex. 10m or 1h30m.", raw.Project{StepTimeout: String("soon")}.ValidateTemplate())
+}
+
+func TestProject_WithDefaults(t *testing.T) {
+	defaults := raw.Project{
+		Dir:        String("envs/*"),
+		Workspaces: []string{"staging", "prod"},
+		Workflow:   String("default"),
+		Env: map[string]string{
+			"TF_VAR_region": "us-east-1",
+			"TF_VAR_team":   "infra",
+		},
+	}
+
+	t.Log("unset fields should be taken from the defaults")
+	Equals(t, raw.Project{
+		Dir:        String("envs/*"),
+		Workspaces: []string{"staging", "prod"},
+		Workflow:   String("custom"),
+		Env: map[string]string{
+			"TF_VAR_region": "us-west-2",
+			"TF_VAR_team":   "infra",
+		},
+	}, raw.Project{
+		Workflow: String("custom"),
+		Env: map[string]string{
+			"TF_VAR_region": "us-west-2",
+		},
+	}.WithDefaults(defaults))
+
+	t.Log("workspaces should not be inherited by a project that sets workspace")
+	Equals(t, raw.Project{
+		Dir:       String("app"),
+		Workspace: String("default"),
+		Workflow:  String("default"),
+		Env:       defaults.Env,
+	}, raw.Project{
+		Dir:       String("app"),
+		Workspace: String("default"),
+	}.WithDefaults(defaults))
+}
+
 func TestProject_ToValid(t *testing.T) {
 	tfVersionPointEleven, _ := version.NewVersion("v0.11.0")
+	tfConstraintPointEleven, _ := version.NewConstraint("~> 0.11.0")
 	cases := []struct {
 		description string
 		input       raw.Project
@@ -235,6 +433,9 @@ func TestProject_ToValid(t *testing.T) {
 				},
 				ApplyRequirements: []string{"approved"},
 				Name:              String("myname"),
+				StepTimeout:       String("10m"),
+				LockKey:           String("s3://bucket/key"),
+				Env:               map[string]string{"NAME": "value"},
 			},
 			exp: valid.Project{
 				Dir:              ".",
@@ -247,6 +448,9 @@ func TestProject_ToValid(t *testing.T) {
 				},
 				ApplyRequirements: []string{"approved"},
 				Name:              String("myname"),
+				StepTimeout:       10 * time.Minute,
+				LockKey:           "s3://bucket/key",
+				Env:               map[string]string{"NAME": "value"},
 			},
 		},
 		{
@@ -265,6 +469,22 @@ func TestProject_ToValid(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "tf version constraint",
+			input: raw.Project{
+				Dir:              String("."),
+				TerraformVersion: String("~> 0.11.0"),
+			},
+			exp: valid.Project{
+				Dir:                        ".",
+				Workspace:                  "default",
+				TerraformVersionConstraint: tfConstraintPointEleven,
+				Autoplan: valid.Autoplan{
+					WhenModified: []string{"**/*.tf*"},
+					Enabled:      true,
+				},
+			},
+		},
 		{
 			description: "dir with /",
 			input: raw.Project{
@@ -323,6 +543,26 @@ func TestProject_ToValid(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "authorization",
+			input: raw.Project{
+				Dir: String("."),
+				Authorization: map[string]raw.Authorization{
+					"apply": {Users: []string{"alice"}, Teams: []string{"owner/infra"}},
+				},
+			},
+			exp: valid.Project{
+				Dir:       ".",
+				Workspace: "default",
+				Autoplan: valid.Autoplan{
+					WhenModified: []string{"**/*.tf*"},
+					Enabled:      true,
+				},
+				Authorization: map[string]valid.Authorization{
+					"apply": {Users: []string{"alice"}, Teams: []string{"owner/infra"}},
+				},
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.description, func(t *testing.T) {
@@ -3,8 +3,10 @@ package raw
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
+	"text/template"
 
 	"github.com/flynn-archive/go-shlex"
 	"github.com/go-ozzo/ozzo-validation"
@@ -17,8 +19,15 @@ const (
 	PlanStepName  = "plan"
 	ApplyStepName = "apply"
 	InitStepName  = "init"
+	EnvStepName   = "env"
+	NameKey       = "name"
+	ValueKey      = "value"
+	CommandKey    = "command"
 )
 
+// envVarNameRegex matches valid env var names.
+var envVarNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // Step represents a single action/command to perform. In YAML, it can be set as
 // 1. A single string for a built-in command:
 //    - init
@@ -28,6 +37,11 @@ const (
 //        extra_args: [-var-file=staging.tfvars]
 // 3. A map for a custom run command:
 //    - run: my custom command
+// 4. A map for an env step that sets an env var for later steps, either to a
+//    static value or to the output of a command:
+//    - env:
+//        name: TF_VAR_token
+//        command: cat token.txt
 // Here we parse step in the most generic fashion possible. See fields for more
 // details.
 type Step struct {
@@ -38,6 +52,8 @@ type Step struct {
 	Map map[string]map[string][]string
 	// StringVal will be set in case #3 above.
 	StringVal map[string]string
+	// EnvVal will be set in case #4 above.
+	EnvVal map[string]map[string]string
 }
 
 func (s *Step) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -65,6 +81,18 @@ func (s *Step) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		return nil
 	}
 
+	// This represents an env step, ex:
+	//   env:
+	//     name: NAME
+	//     value: value
+	// We validate if the key is env and the keys of the value later.
+	var envStep map[string]map[string]string
+	err = unmarshal(&envStep)
+	if err == nil {
+		s.EnvVal = envStep
+		return nil
+	}
+
 	// Try to unmarshal as a custom run step, ex.
 	// steps:
 	// - run: my command
@@ -120,6 +148,12 @@ func (s Step) Validate() error {
 					return fmt.Errorf("built-in steps only support a single %s key, found %q in step %s", ExtraArgsKey, k, stepName)
 				}
 			}
+			// extra_args are rendered as templates before the step runs.
+			for _, arg := range args[ExtraArgsKey] {
+				if _, err := template.New(ExtraArgsKey).Parse(arg); err != nil {
+					return fmt.Errorf("unable to parse %q in step %s as a template: %s", arg, stepName, err)
+				}
+			}
 		}
 		return nil
 	}
@@ -149,12 +183,58 @@ func (s Step) Validate() error {
 		return nil
 	}
 
+	envStep := func(value interface{}) error {
+		elem := value.(map[string]map[string]string)
+		var keys []string
+		for k := range elem {
+			keys = append(keys, k)
+		}
+		// Sort so tests can be deterministic.
+		sort.Strings(keys)
+
+		if len(keys) > 1 {
+			return fmt.Errorf("step element can only contain a single key, found %d: %s",
+				len(keys), strings.Join(keys, ","))
+		}
+		for stepName, args := range elem {
+			if stepName != EnvStepName {
+				return fmt.Errorf("%q is not a valid step type", stepName)
+			}
+			for k := range args {
+				if k != NameKey && k != ValueKey && k != CommandKey {
+					return fmt.Errorf("env steps only support %s, %s and %s keys, found %q", NameKey, ValueKey, CommandKey, k)
+				}
+			}
+			name, ok := args[NameKey]
+			if !ok {
+				return fmt.Errorf("env steps must have a %s key", NameKey)
+			}
+			if !envVarNameRegex.MatchString(name) {
+				return fmt.Errorf("%q is not a valid env var name", name)
+			}
+			_, hasValue := args[ValueKey]
+			command, hasCommand := args[CommandKey]
+			if hasValue == hasCommand {
+				return fmt.Errorf("env steps must have either a %s or a %s key", ValueKey, CommandKey)
+			}
+			if hasCommand {
+				if _, err := shlex.Split(command); err != nil {
+					return fmt.Errorf("unable to parse as shell command: %s", err)
+				}
+			}
+		}
+		return nil
+	}
+
 	if s.Key != nil {
 		return validation.Validate(s.Key, validation.By(validStep))
 	}
 	if len(s.Map) > 0 {
 		return validation.Validate(s.Map, validation.By(extraArgs))
 	}
+	if len(s.EnvVal) > 0 {
+		return validation.Validate(s.EnvVal, validation.By(envStep))
+	}
 	if len(s.StringVal) > 0 {
 		return validation.Validate(s.StringVal, validation.By(runStep))
 	}
@@ -196,5 +276,78 @@ func (s Step) ToValid() valid.Step {
 		}
 	}
 
+	// This will trigger in case #4 (see Step docs).
+	if len(s.EnvVal) > 0 {
+		// After validation we assume there's only one key and it's env.
+		for _, args := range s.EnvVal {
+			step := valid.Step{
+				StepName:    EnvStepName,
+				EnvVarName:  args[NameKey],
+				EnvVarValue: args[ValueKey],
+			}
+			if command, ok := args[CommandKey]; ok {
+				// We ignore the error here because it should have been
+				// checked in Validate().
+				step.RunCommand, _ = shlex.Split(command)
+			}
+			return step
+		}
+	}
+
 	panic("step was not valid. This is a bug!")
 }
+
+// JSONSchema returns the JSON schema for a step. It's written by hand because
+// a step can be any of the cases in the Step docs rather than a struct.
+func (s Step) JSONSchema() map[string]interface{} {
+	str := map[string]interface{}{"type": "string"}
+	extraArgs := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			ExtraArgsKey: map[string]interface{}{"type": "array", "items": str},
+		},
+		"additionalProperties": false,
+	}
+	return map[string]interface{}{
+		"oneOf": []interface{}{
+			map[string]interface{}{
+				"type": "string",
+				"enum": []string{InitStepName, PlanStepName, ApplyStepName},
+			},
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					InitStepName:  extraArgs,
+					PlanStepName:  extraArgs,
+					ApplyStepName: extraArgs,
+				},
+				"minProperties":        1,
+				"maxProperties":        1,
+				"additionalProperties": false,
+			},
+			map[string]interface{}{
+				"type":                 "object",
+				"properties":           map[string]interface{}{RunStepName: str},
+				"required":             []string{RunStepName},
+				"additionalProperties": false,
+			},
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					EnvStepName: map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							NameKey:    str,
+							ValueKey:   str,
+							CommandKey: str,
+						},
+						"required":             []string{NameKey},
+						"additionalProperties": false,
+					},
+				},
+				"required":             []string{EnvStepName},
+				"additionalProperties": false,
+			},
+		},
+	}
+}
@@ -98,6 +98,38 @@ key: value`,
 			},
 		},
 
+		// Env-step style
+		{
+			description: "env step value",
+			input: `
+env:
+  name: NAME
+  value: value`,
+			exp: raw.Step{
+				EnvVal: map[string]map[string]string{
+					"env": {
+						"name":  "NAME",
+						"value": "value",
+					},
+				},
+			},
+		},
+		{
+			description: "env step command",
+			input: `
+env:
+  name: NAME
+  command: echo value`,
+			exp: raw.Step{
+				EnvVal: map[string]map[string]string{
+					"env": {
+						"name":    "NAME",
+						"command": "echo value",
+					},
+				},
+			},
+		},
+
 		// Empty
 		{
 			description: "empty",
@@ -204,6 +236,30 @@ func TestStep_Validate(t *testing.T) {
 			},
 			expErr: "",
 		},
+		{
+			description: "env step value",
+			input: raw.Step{
+				EnvVal: map[string]map[string]string{
+					"env": {
+						"name":  "NAME",
+						"value": "value",
+					},
+				},
+			},
+			expErr: "",
+		},
+		{
+			description: "env step command",
+			input: raw.Step{
+				EnvVal: map[string]map[string]string{
+					"env": {
+						"name":    "NAME",
+						"command": "echo value",
+					},
+				},
+			},
+			expErr: "",
+		},
 
 		// Invalid inputs.
 		{
@@ -267,6 +323,17 @@ func TestStep_Validate(t *testing.T) {
 			},
 			expErr: "built-in steps only support a single extra_args key, found \"invalid\" in step init",
 		},
+		{
+			description: "unparseable extra_args template",
+			input: raw.Step{
+				Map: MapType{
+					"plan": {
+						"extra_args": {"-var-file={{end}}.tfvars"},
+					},
+				},
+			},
+			expErr: "unable to parse \"-var-file={{end}}.tfvars\" in step plan as a template: template: extra_args:1: unexpected {{end}}",
+		},
 		{
 			description: "unparseable shell command",
 			input: raw.Step{
@@ -276,6 +343,79 @@ func TestStep_Validate(t *testing.T) {
 			},
 			expErr: "unable to parse as shell command: EOF found when expecting closing quote.",
 		},
+		{
+			description: "multiple keys in env val",
+			input: raw.Step{
+				EnvVal: map[string]map[string]string{
+					"env":  {"name": "NAME", "value": "value"},
+					"env2": {"name": "NAME", "value": "value"},
+				},
+			},
+			expErr: "step element can only contain a single key, found 2: env,env2",
+		},
+		{
+			description: "invalid key in env val",
+			input: raw.Step{
+				EnvVal: map[string]map[string]string{
+					"invalid": {"name": "NAME", "value": "value"},
+				},
+			},
+			expErr: "\"invalid\" is not a valid step type",
+		},
+		{
+			description: "env step invalid arg",
+			input: raw.Step{
+				EnvVal: map[string]map[string]string{
+					"env": {"name": "NAME", "value": "value", "invalid": ""},
+				},
+			},
+			expErr: "env steps only support name, value and command keys, found \"invalid\"",
+		},
+		{
+			description: "env step no name",
+			input: raw.Step{
+				EnvVal: map[string]map[string]string{
+					"env": {"value": "value"},
+				},
+			},
+			expErr: "env steps must have a name key",
+		},
+		{
+			description: "env step invalid name",
+			input: raw.Step{
+				EnvVal: map[string]map[string]string{
+					"env": {"name": "1-NAME", "value": "value"},
+				},
+			},
+			expErr: "\"1-NAME\" is not a valid env var name",
+		},
+		{
+			description: "env step value and command",
+			input: raw.Step{
+				EnvVal: map[string]map[string]string{
+					"env": {"name": "NAME", "value": "value", "command": "echo value"},
+				},
+			},
+			expErr: "env steps must have either a value or a command key",
+		},
+		{
+			description: "env step no value or command",
+			input: raw.Step{
+				EnvVal: map[string]map[string]string{
+					"env": {"name": "NAME"},
+				},
+			},
+			expErr: "env steps must have either a value or a command key",
+		},
+		{
+			description: "env step unparseable shell command",
+			input: raw.Step{
+				EnvVal: map[string]map[string]string{
+					"env": {"name": "NAME", "command": "my 'c"},
+				},
+			},
+			expErr: "unable to parse as shell command: EOF found when expecting closing quote.",
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.description, func(t *testing.T) {
@@ -376,6 +516,32 @@ func TestStep_ToValid(t *testing.T) {
 				RunCommand: []string{"my", "run command"},
 			},
 		},
+		{
+			description: "env step value",
+			input: raw.Step{
+				EnvVal: map[string]map[string]string{
+					"env": {"name": "NAME", "value": "value"},
+				},
+			},
+			exp: valid.Step{
+				StepName:    "env",
+				EnvVarName:  "NAME",
+				EnvVarValue: "value",
+			},
+		},
+		{
+			description: "env step command",
+			input: raw.Step{
+				EnvVal: map[string]map[string]string{
+					"env": {"name": "NAME", "command": "my 'env command'"},
+				},
+			},
+			exp: valid.Step{
+				StepName:   "env",
+				EnvVarName: "NAME",
+				RunCommand: []string{"my", "env command"},
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.description, func(t *testing.T) {
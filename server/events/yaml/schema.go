@@ -0,0 +1,89 @@
+package yaml
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/runatlantis/atlantis/server/events/yaml/raw"
+)
+
+// JSONSchemaURL is where the JSON schema for atlantis.yaml files is published.
+const JSONSchemaURL = "https://www.runatlantis.io/schemas/atlantis-yaml.json"
+
+// jsonSchemaer is implemented by raw types whose YAML can't be described by
+// their fields, ex. steps, which can be strings or maps.
+type jsonSchemaer interface {
+	JSONSchema() map[string]interface{}
+}
+
+// JSONSchema returns a JSON schema for atlantis.yaml files, generated from
+// raw.Config so editors can autocomplete and check them.
+func JSONSchema() ([]byte, error) {
+	definitions := make(map[string]interface{})
+	schemaFor(reflect.TypeOf(raw.Config{}), definitions)
+	// The root is inlined rather than referenced so editors show its keys.
+	root := definitions["Config"].(map[string]interface{})
+	delete(definitions, "Config")
+	root["$schema"] = "http://json-schema.org/draft-07/schema#"
+	root["$id"] = JSONSchemaURL
+	root["title"] = AtlantisYAMLFilename
+	root["required"] = []string{"version"}
+	root["definitions"] = definitions
+	out, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// schemaFor returns the schema for t. Structs are added to definitions by
+// name and referenced so they're only described once.
+func schemaFor(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return schemaFor(t.Elem(), definitions)
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaFor(t.Elem(), definitions),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaFor(t.Elem(), definitions),
+		}
+	case reflect.Struct:
+		ref := map[string]interface{}{"$ref": "#/definitions/" + t.Name()}
+		if _, ok := definitions[t.Name()]; ok {
+			return ref
+		}
+		if s, ok := reflect.Zero(t).Interface().(jsonSchemaer); ok {
+			definitions[t.Name()] = s.JSONSchema()
+			return ref
+		}
+		properties := make(map[string]interface{})
+		obj := map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+		// Add before recursing in case the struct refers to itself.
+		definitions[t.Name()] = obj
+		for i := 0; i < t.NumField(); i++ {
+			name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			properties[name] = schemaFor(t.Field(i).Type, definitions)
+		}
+		return ref
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,48 @@
+package yaml_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server/events/yaml"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	schema, err := yaml.JSONSchema()
+	Ok(t, err)
+
+	var parsed struct {
+		ID          string                     `json:"$id"`
+		Required    []string                   `json:"required"`
+		Properties  map[string]interface{}     `json:"properties"`
+		Definitions map[string]json.RawMessage `json:"definitions"`
+	}
+	Ok(t, json.Unmarshal(schema, &parsed))
+	Equals(t, yaml.JSONSchemaURL, parsed.ID)
+	Equals(t, []string{"version"}, parsed.Required)
+	for _, key := range []string{"version", "projects", "workflows", "project_defaults", "project_templates", "autodiscover"} {
+		_, ok := parsed.Properties[key]
+		Assert(t, ok, "exp %q to be in the schema's properties", key)
+	}
+	for _, def := range []string{"Project", "Workflow", "Stage", "Step", "Autoplan", "Authorization", "Autodiscover"} {
+		_, ok := parsed.Definitions[def]
+		Assert(t, ok, "exp %q to be in the schema's definitions", def)
+	}
+	var step struct {
+		OneOf []interface{} `json:"oneOf"`
+	}
+	Ok(t, json.Unmarshal(parsed.Definitions["Step"], &step))
+	Equals(t, 4, len(step.OneOf))
+}
+
+// The schema published on the website must be regenerated when raw.Config
+// changes.
+func TestJSONSchema_Published(t *testing.T) {
+	schema, err := yaml.JSONSchema()
+	Ok(t, err)
+	published, err := ioutil.ReadFile("../../../runatlantis.io/.vuepress/public/schemas/atlantis-yaml.json")
+	Ok(t, err)
+	Assert(t, string(published) == string(schema), "the published JSON schema is out of date, run make schema")
+}
@@ -2,7 +2,11 @@
 // after it's been parsed and validated.
 package valid
 
-import "github.com/hashicorp/go-version"
+import (
+	"time"
+
+	"github.com/hashicorp/go-version"
+)
 
 // Config is the atlantis.yaml config after it's been parsed and validated.
 type Config struct {
@@ -58,6 +62,21 @@ type Project struct {
 	TerraformVersion  *version.Version
 	Autoplan          Autoplan
 	ApplyRequirements []string
+	// TerraformVersionConstraint is set instead of TerraformVersion when
+	// terraform_version is a constraint, ex. "~> 0.11.0", rather than an exact
+	// version.
+	TerraformVersionConstraint version.Constraints
+	// StepTimeout is how long each step can run before it's interrupted.
+	// If 0, steps never time out.
+	StepTimeout time.Duration
+	// Authorization maps from a command, ex. apply, to who can run it on the
+	// project. Commands that aren't in the map can be run by anyone.
+	Authorization map[string]Authorization
+	// LockKey, if set, is what the project is locked on instead of its repo
+	// and dir.
+	LockKey string
+	// Env are env vars set for every step run for the project.
+	Env map[string]string
 }
 
 // GetName returns the name of the project or an empty string if there is no
@@ -69,6 +88,13 @@ func (p Project) GetName() string {
 	return ""
 }
 
+// Authorization restricts who can run a command on a project. Users can run
+// the command if they're in Users or a member of one of Teams.
+type Authorization struct {
+	Users []string
+	Teams []string
+}
+
 type Autoplan struct {
 	WhenModified []string
 	Enabled      bool
@@ -82,6 +108,11 @@ type Step struct {
 	StepName   string
 	ExtraArgs  []string
 	RunCommand []string
+	// EnvVarName is the name of the env var set by an env step.
+	EnvVarName string
+	// EnvVarValue is the static value of the env var set by an env step. If
+	// RunCommand is set instead, the env var is set to its output.
+	EnvVarValue string
 }
 
 type Workflow struct {
@@ -64,7 +64,6 @@ type EventsController struct {
 	// startup to support.
 	SupportedVCSHosts []models.VCSHostType
 	VCSClient         vcs.ClientProxy
-	TestingMode       bool
 	// BitbucketWebhookSecret is the secret added to this webhook via the Bitbucket
 	// UI that identifies this call as coming from Bitbucket. If empty, no
 	// request validation is done.
@@ -280,18 +279,12 @@ func (e *EventsController) handlePullRequestEvent(w http.ResponseWriter, baseRep
 	case models.OpenedPullEvent, models.UpdatedPullEvent:
 		// If the pull request was opened or updated, we will try to autoplan.
 
-		// Respond with success and then actually execute the command asynchronously.
-		// We use a goroutine so that this function returns and the connection is
-		// closed.
+		// The command runner queues the command and returns so the
+		// connection is closed before it's actually run.
 		fmt.Fprintln(w, "Processing...")
 
 		e.Logger.Info("executing autoplan")
-		if !e.TestingMode {
-			go e.CommandRunner.RunAutoplanCommand(baseRepo, headRepo, pull, user)
-		} else {
-			// When testing we want to wait for everything to complete.
-			e.CommandRunner.RunAutoplanCommand(baseRepo, headRepo, pull, user)
-		}
+		e.CommandRunner.RunAutoplanCommand(baseRepo, headRepo, pull, user)
 		return
 	case models.ClosedPullEvent:
 		// If the pull request was closed, we delete locks.
@@ -377,15 +370,9 @@ func (e *EventsController) handleCommentEvent(w http.ResponseWriter, baseRepo mo
 
 	e.Logger.Debug("executing command")
 	fmt.Fprintln(w, "Processing...")
-	if !e.TestingMode {
-		// Respond with success and then actually execute the command asynchronously.
-		// We use a goroutine so that this function returns and the connection is
-		// closed.
-		go e.CommandRunner.RunCommentCommand(baseRepo, maybeHeadRepo, maybePull, user, pullNum, parseResult.Command)
-	} else {
-		// When testing we want to wait for everything to complete.
-		e.CommandRunner.RunCommentCommand(baseRepo, maybeHeadRepo, maybePull, user, pullNum, parseResult.Command)
-	}
+	// The command runner queues the command and returns so the connection is
+	// closed before it's actually run.
+	e.CommandRunner.RunCommentCommand(baseRepo, maybeHeadRepo, maybePull, user, pullNum, parseResult.Command)
 }
 
 // HandleGitlabMergeRequestEvent will delete any locks associated with the pull
@@ -273,7 +273,7 @@ func setupE2E(t *testing.T) (server.EventsController, *vcsmocks.MockClientProxy,
 		GitlabUser:  "gitlab-user",
 		GitlabToken: "gitlab-token",
 	}
-	terraformClient, err := terraform.NewClient(dataDir)
+	terraformClient, err := terraform.NewClient(dataDir, terraform.DefaultDownloadURL)
 	Ok(t, err)
 	boltdb, err := boltdb.New(dataDir)
 	Ok(t, err)
@@ -320,6 +320,7 @@ func setupE2E(t *testing.T) (server.EventsController, *vcsmocks.MockClientProxy,
 		Logger:                   logger,
 		AllowForkPRs:             allowForkPRs,
 		AllowForkPRsFlag:         "allow-fork-prs",
+		CommandCanceller:         events.NewCommandCanceller(),
 		ProjectCommandBuilder: &events.DefaultProjectCommandBuilder{
 			ParserValidator:     &yaml.ParserValidator{},
 			ProjectFinder:       &events.DefaultProjectFinder{},
@@ -337,7 +338,6 @@ func setupE2E(t *testing.T) (server.EventsController, *vcsmocks.MockClientProxy,
 	Ok(t, err)
 
 	ctrl := server.EventsController{
-		TestingMode:   true,
 		CommandRunner: commandRunner,
 		PullCleaner: &events.PullClosedExecutor{
 			Locker:     lockingClient,
@@ -370,6 +370,10 @@ func (w *mockWebhookSender) Send(log *logging.SimpleLogger, result webhooks.Appl
 	return nil
 }
 
+func (w *mockWebhookSender) SendPlan(log *logging.SimpleLogger, result webhooks.PlanResult) error {
+	return nil
+}
+
 func GitHubCommentEvent(t *testing.T, comment string) *http.Request {
 	requestJSON, err := ioutil.ReadFile(filepath.Join("testfixtures", "githubIssueCommentEvent.json"))
 	Ok(t, err)
@@ -579,7 +579,6 @@ func setup(t *testing.T) (server.EventsController, *mocks.MockGithubRequestValid
 	repoWhitelistChecker, err := events.NewRepoWhitelistChecker("*")
 	Ok(t, err)
 	e := server.EventsController{
-		TestingMode:                  true,
 		Logger:                       logging.NewNoopLogger(),
 		GithubRequestValidator:       v,
 		Parser:                       p,
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha1" // nolint: gosec
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HtpasswdAuthenticator authenticates requests with HTTP basic auth against
+// the users in an htpasswd file.
+type HtpasswdAuthenticator struct {
+	// users maps usernames to password hashes.
+	users map[string]string
+}
+
+// NewHtpasswdAuthenticator parses the htpasswd file at path. Only bcrypt and
+// {SHA} hashes are supported, ie. files created with htpasswd -B or -s.
+func NewHtpasswdAuthenticator(path string) (*HtpasswdAuthenticator, error) {
+	f, err := os.Open(path) // nolint: gosec
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // nolint: errcheck
+
+	users := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sep := strings.Index(line, ":")
+		if sep < 1 {
+			return nil, fmt.Errorf("line %d of %s is not in the format user:hash", lineNum, path)
+		}
+		user, hash := line[:sep], line[sep+1:]
+		if !isBcryptHash(hash) && !strings.HasPrefix(hash, "{SHA}") {
+			return nil, fmt.Errorf("password of user %q in %s is not a bcrypt or {SHA} hash: create the file with htpasswd -B", user, path)
+		}
+		users[user] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "reading %s", path)
+	}
+	return &HtpasswdAuthenticator{users: users}, nil
+}
+
+// Authenticate implements Authenticator. Users from htpasswd files have no
+// groups.
+func (h *HtpasswdAuthenticator) Authenticate(r *http.Request) (string, []string, bool) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return "", nil, false
+	}
+	hash, ok := h.users[user]
+	if !ok || !checkPassword(hash, password) {
+		return "", nil, false
+	}
+	return user, nil, true
+}
+
+// Challenge implements Authenticator by asking for basic auth credentials.
+func (h *HtpasswdAuthenticator) Challenge(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="atlantis"`)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2y$") || strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$")
+}
+
+func checkPassword(hash string, password string) bool {
+	if isBcryptHash(hash) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	}
+	sum := sha1.Sum([]byte(password)) // nolint: gosec
+	expHash := "{SHA}" + base64.StdEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expHash), []byte(hash)) == 1
+}
@@ -0,0 +1,92 @@
+package server_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server"
+	. "github.com/runatlantis/atlantis/testing"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHtpasswdAuthenticator_Authenticate(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("bcrypt-pass"), bcrypt.MinCost)
+	Ok(t, err)
+	path := writeHtpasswd(t, fmt.Sprintf(`# comment
+alice:%s
+
+bob:{SHA}nU4eI71bcnBGqeO0t9tXvY1u5oQ=
+`, hash))
+	h, err := server.NewHtpasswdAuthenticator(path)
+	Ok(t, err)
+
+	cases := []struct {
+		description string
+		user        string
+		password    string
+		noAuth      bool
+		expOk       bool
+	}{
+		{"bcrypt", "alice", "bcrypt-pass", false, true},
+		{"sha", "bob", "pass", false, true},
+		{"wrong password", "alice", "pass", false, false},
+		{"unknown user", "carol", "pass", false, false},
+		{"no credentials", "", "", true, false},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if !c.noAuth {
+				r.SetBasicAuth(c.user, c.password)
+			}
+			user, groups, ok := h.Authenticate(r)
+			Equals(t, c.expOk, ok)
+			if c.expOk {
+				Equals(t, c.user, user)
+				Equals(t, 0, len(groups))
+			}
+		})
+	}
+}
+
+func TestNewHtpasswdAuthenticator_Errors(t *testing.T) {
+	cases := []struct {
+		contents string
+		expErr   string
+	}{
+		{"alice", "line 1 of {path} is not in the format user:hash"},
+		{"alice:$apr1$abc$def", `password of user "alice" in {path} is not a bcrypt or {SHA} hash: create the file with htpasswd -B`},
+		{"alice:plaintext", `password of user "alice" in {path} is not a bcrypt or {SHA} hash: create the file with htpasswd -B`},
+	}
+	for _, c := range cases {
+		t.Run(c.contents, func(t *testing.T) {
+			path := writeHtpasswd(t, c.contents)
+			_, err := server.NewHtpasswdAuthenticator(path)
+			ErrEquals(t, strings.Replace(c.expErr, "{path}", path, -1), err)
+		})
+	}
+}
+
+func TestHtpasswdAuthenticator_Challenge(t *testing.T) {
+	h, err := server.NewHtpasswdAuthenticator(writeHtpasswd(t, ""))
+	Ok(t, err)
+	w := httptest.NewRecorder()
+	h.Challenge(w, httptest.NewRequest("GET", "/", nil))
+	Equals(t, http.StatusUnauthorized, w.Code)
+	Equals(t, `Basic realm="atlantis"`, w.Header().Get("WWW-Authenticate"))
+}
+
+// writeHtpasswd writes an htpasswd file with contents to a temp dir and
+// returns its path.
+func writeHtpasswd(t *testing.T, contents string) string {
+	dir, err := ioutil.TempDir("", "")
+	Ok(t, err)
+	path := filepath.Join(dir, "htpasswd")
+	Ok(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	return path
+}
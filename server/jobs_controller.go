@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+// JobsController handles requests for the jobs in the job queue.
+type JobsController struct {
+	AtlantisVersion string
+	AtlantisURL     *url.URL
+	Logger          *logging.SimpleLogger
+	JobQueue        *events.JobQueue
+	JobsTemplate    TemplateWriter
+}
+
+// GetJobs is the GET /jobs route. It renders the running and queued jobs.
+func (j *JobsController) GetJobs(w http.ResponseWriter, _ *http.Request) {
+	data := JobsIndexData{
+		AtlantisVersion: j.AtlantisVersion,
+		CleanedBasePath: j.AtlantisURL.Path,
+	}
+	for _, job := range j.JobQueue.Jobs() {
+		jobData := JobData{
+			ID:           job.ID,
+			RepoFullName: job.BaseRepo.FullName,
+			PullNum:      job.PullNum,
+			Ref:          job.Ref,
+			Command:      job.Comment(),
+			User:         job.User.Username,
+			QueuedAt:     job.QueuedAt.Format("2006-01-02 15:04:05 MST"),
+		}
+		if job.IsAutoplan() {
+			jobData.Command = "autoplan"
+		}
+		if job.Running() {
+			jobData.StartedAt = job.StartedAt.Format("2006-01-02 15:04:05 MST")
+			data.Running = append(data.Running, jobData)
+		} else {
+			data.Queued = append(data.Queued, jobData)
+		}
+	}
+
+	if err := j.JobsTemplate.Execute(w, data); err != nil {
+		j.Logger.Err("rendering jobs page: %s", err)
+	}
+}
@@ -0,0 +1,74 @@
+package server_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/locking/boltdb"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	sMocks "github.com/runatlantis/atlantis/server/mocks"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestGetJobs(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmp, cleanup := TempDir(t)
+	defer cleanup()
+	locker, err := boltdb.New(tmp)
+	Ok(t, err)
+	store, err := boltdb.NewJobStore(locker)
+	Ok(t, err)
+	queue := &events.JobQueue{
+		Store:  store,
+		Logger: logging.NewNoopLogger(),
+	}
+	repo := models.Repo{FullName: "owner/repo"}
+	queue.RunAutoplanCommand(repo, repo, models.PullRequest{Num: 1, BaseRepo: repo}, models.User{Username: "bob"})
+	queue.RunCommentCommand(repo, nil, nil, models.User{Username: "carol"}, 2, &events.CommentCommand{Name: events.ApplyCommand, RepoRelDir: "dir"})
+	jobs := queue.Jobs()
+	Equals(t, 2, len(jobs))
+
+	tmpl := sMocks.NewMockTemplateWriter()
+	u, err := url.Parse("https://example.com/basepath")
+	Ok(t, err)
+	jc := server.JobsController{
+		AtlantisVersion: "1.0.0",
+		AtlantisURL:     u,
+		Logger:          logging.NewNoopLogger(),
+		JobQueue:        queue,
+		JobsTemplate:    tmpl,
+	}
+	req, _ := http.NewRequest("GET", "/jobs", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	jc.GetJobs(w, req)
+	tmpl.VerifyWasCalledOnce().Execute(w, server.JobsIndexData{
+		Queued: []server.JobData{
+			{
+				ID:           jobs[0].ID,
+				RepoFullName: "owner/repo",
+				PullNum:      1,
+				Command:      "autoplan",
+				User:         "bob",
+				QueuedAt:     jobs[0].QueuedAt.Format("2006-01-02 15:04:05 MST"),
+			},
+			{
+				ID:           jobs[1].ID,
+				RepoFullName: "owner/repo",
+				PullNum:      2,
+				Command:      "atlantis apply -d dir",
+				User:         "carol",
+				QueuedAt:     jobs[1].QueuedAt.Format("2006-01-02 15:04:05 MST"),
+			},
+		},
+		AtlantisVersion: "1.0.0",
+		CleanedBasePath: "/basepath",
+	})
+	responseContains(t, w, http.StatusOK, "")
+}
@@ -56,7 +56,9 @@ func (l *LocksController) GetLock(w http.ResponseWriter, r *http.Request) {
 		RepoName:        repo,
 		PullRequestLink: lock.Pull.URL,
 		LockedBy:        lock.Pull.Author,
+		Directory:       lock.Project.Path,
 		Workspace:       lock.Workspace,
+		ProjectLockKey:  lock.Project.LockKey,
 		AtlantisVersion: l.AtlantisVersion,
 		CleanedBasePath: l.AtlantisURL.Path,
 	}
@@ -80,7 +82,7 @@ func (l *LocksController) DeleteLock(w http.ResponseWriter, r *http.Request) {
 		l.respond(w, logging.Warn, http.StatusBadRequest, "Invalid lock id %q. Failed with error: %s", id, err)
 		return
 	}
-	lock, err := l.Locker.Unlock(idUnencoded)
+	lock, commentErr, err := l.deleteLock(idUnencoded, "the Atlantis UI")
 	if err != nil {
 		l.respond(w, logging.Error, http.StatusInternalServerError, "deleting lock failed with: %s", err)
 		return
@@ -89,34 +91,44 @@ func (l *LocksController) DeleteLock(w http.ResponseWriter, r *http.Request) {
 		l.respond(w, logging.Info, http.StatusNotFound, "No lock found at id %q", idUnencoded)
 		return
 	}
+	if commentErr != nil {
+		l.respond(w, logging.Error, http.StatusInternalServerError, "Failed commenting on pull request: %s", commentErr)
+		return
+	}
+	l.respond(w, logging.Info, http.StatusOK, "Deleted lock id %q", id)
+}
+
+// deleteLock deletes the lock at id along with its plan, and comments on the
+// pull request that the plan was discarded via source, ex. "the Atlantis UI".
+// lock is nil if there was no lock at id. If the lock was deleted but
+// commenting failed, commentErr is set.
+func (l *LocksController) deleteLock(id string, source string) (lock *models.ProjectLock, commentErr error, err error) {
+	lock, err = l.Locker.Unlock(id)
+	if err != nil || lock == nil {
+		return nil, nil, err
+	}
 
 	// NOTE: Because BaseRepo was added to the PullRequest model later, previous
 	// installations of Atlantis will have locks in their DB that do not have
 	// this field on PullRequest. We skip commenting and deleting the working dir in this case.
-	if lock.Pull.BaseRepo != (models.Repo{}) {
-		unlock, err := l.WorkingDirLocker.TryLock(lock.Pull.BaseRepo.FullName, lock.Pull.Num, lock.Workspace)
-		if err != nil {
-			l.Logger.Err("unable to obtain working dir lock when trying to delete old plans: %s", err)
-		} else {
-			defer unlock()
-			// nolint: vetshadow
-			if err := l.WorkingDir.DeleteForWorkspace(lock.Pull.BaseRepo, lock.Pull, lock.Workspace); err != nil {
-				l.Logger.Err("unable to delete workspace: %s", err)
-			}
-		}
-
-		// Once the lock has been deleted, comment back on the pull request.
-		comment := fmt.Sprintf("**Warning**: The plan for dir: `%s` workspace: `%s` was **discarded** via the Atlantis UI.\n\n"+
-			"To `apply` this plan you must run `plan` again.", lock.Project.Path, lock.Workspace)
-		err = l.VCSClient.CreateComment(lock.Pull.BaseRepo, lock.Pull.Num, comment)
-		if err != nil {
-			l.respond(w, logging.Error, http.StatusInternalServerError, "Failed commenting on pull request: %s", err)
-			return
-		}
-	} else {
+	if lock.Pull.BaseRepo == (models.Repo{}) {
 		l.Logger.Debug("skipping commenting on pull request and deleting workspace because BaseRepo field is empty")
+		return lock, nil, nil
 	}
-	l.respond(w, logging.Info, http.StatusOK, "Deleted lock id %q", id)
+	unlock, err := l.WorkingDirLocker.TryLock(lock.Pull.BaseRepo, lock.Pull.Num, lock.Workspace)
+	if err != nil {
+		l.Logger.Err("unable to obtain working dir lock when trying to delete old plans: %s", err)
+	} else {
+		defer unlock()
+		if err := l.WorkingDir.DeleteForWorkspace(lock.Pull.BaseRepo, lock.Pull, lock.Workspace); err != nil {
+			l.Logger.Err("unable to delete workspace: %s", err)
+		}
+	}
+
+	// Once the lock has been deleted, comment back on the pull request.
+	comment := fmt.Sprintf("**Warning**: The plan for dir: `%s` workspace: `%s` was **discarded** via %s.\n\n"+
+		"To `apply` this plan you must run `plan` again.", lock.Project.Path, lock.Workspace, source)
+	return lock, l.VCSClient.CreateComment(lock.Pull.BaseRepo, lock.Pull.Num, comment), nil
 }
 
 // respond is a helper function to respond and log the response. lvl is the log
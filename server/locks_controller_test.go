@@ -112,6 +112,7 @@ func TestGetLock_Success(t *testing.T) {
 		RepoName:        "repo",
 		PullRequestLink: "url",
 		LockedBy:        "lkysow",
+		Directory:       "path",
 		Workspace:       "workspace",
 		AtlantisVersion: "1300135",
 		CleanedBasePath: "/basepath",
@@ -119,6 +120,45 @@ func TestGetLock_Success(t *testing.T) {
 	responseContains(t, w, http.StatusOK, "")
 }
 
+func TestGetLock_LockKey(t *testing.T) {
+	t.Log("A project locked on a lock key should show it")
+	RegisterMockTestingT(t)
+	l := mocks.NewMockLocker()
+	When(l.GetLock("s3://bucket/key/workspace")).ThenReturn(&models.ProjectLock{
+		Project:   models.Project{RepoFullName: "owner/repo", Path: "path", LockKey: "s3://bucket/key"},
+		Pull:      models.PullRequest{URL: "url", Author: "lkysow"},
+		Workspace: "workspace",
+	}, nil)
+	tmpl := sMocks.NewMockTemplateWriter()
+	atlantisURL, err := url.Parse("https://example.com")
+	Ok(t, err)
+	lc := server.LocksController{
+		Logger:             logging.NewNoopLogger(),
+		Locker:             l,
+		LockDetailTemplate: tmpl,
+		AtlantisVersion:    "1300135",
+		AtlantisURL:        atlantisURL,
+	}
+	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
+	req = mux.SetURLVars(req, map[string]string{"id": "s3%3A%2F%2Fbucket%2Fkey%2Fworkspace"})
+	w := httptest.NewRecorder()
+	lc.GetLock(w, req)
+	tmpl.VerifyWasCalledOnce().Execute(w, server.LockDetailData{
+		LockKeyEncoded:  "s3%3A%2F%2Fbucket%2Fkey%2Fworkspace",
+		LockKey:         "s3://bucket/key/workspace",
+		RepoOwner:       "owner",
+		RepoName:        "repo",
+		PullRequestLink: "url",
+		LockedBy:        "lkysow",
+		Directory:       "path",
+		Workspace:       "workspace",
+		ProjectLockKey:  "s3://bucket/key",
+		AtlantisVersion: "1300135",
+		CleanedBasePath: "",
+	})
+	responseContains(t, w, http.StatusOK, "")
+}
+
 func TestDeleteLock_NoLockID(t *testing.T) {
 	t.Log("If there is no lock ID in the request then we should get a 400")
 	req, _ := http.NewRequest("GET", "", bytes.NewBuffer(nil))
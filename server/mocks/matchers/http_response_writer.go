@@ -0,0 +1,20 @@
+package matchers
+
+import (
+	http "net/http"
+	"reflect"
+
+	"github.com/petergtz/pegomock"
+)
+
+func AnyHttpResponseWriter() http.ResponseWriter {
+	pegomock.RegisterMatcher(pegomock.NewAnyMatcher(reflect.TypeOf((*(http.ResponseWriter))(nil)).Elem()))
+	var nullValue http.ResponseWriter
+	return nullValue
+}
+
+func EqHttpResponseWriter(value http.ResponseWriter) http.ResponseWriter {
+	pegomock.RegisterMatcher(&pegomock.EqMatcher{Value: value})
+	var nullValue http.ResponseWriter
+	return nullValue
+}
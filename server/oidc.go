@@ -0,0 +1,362 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+const (
+	// OIDCLoginPath is where users are sent to log in with the OIDC provider.
+	OIDCLoginPath = "/auth/oidc/login"
+	// OIDCCallbackPath is where the OIDC provider redirects users back to
+	// after they log in. It must be registered as a redirect URI with the
+	// provider.
+	OIDCCallbackPath = "/auth/oidc/callback"
+	// LogoutPath clears the user's session. It only accepts POSTs.
+	LogoutPath = "/auth/logout"
+
+	sessionCookieName = "atlantis_session"
+	stateCookieName   = "atlantis_oidc_state"
+	// sessionDuration is how long users stay logged in for.
+	sessionDuration = 12 * time.Hour
+)
+
+// OIDCAuthenticator authenticates users of the web UI with an OpenID Connect
+// provider using the authorization code flow. Once a user has logged in, they
+// are identified by a signed session cookie.
+//
+// The user's identity is read from the provider's userinfo endpoint, which is
+// called over TLS with the access token, so the ID token doesn't need to be
+// verified.
+type OIDCAuthenticator struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// AtlantisURL is used to build the redirect URI sent to the provider.
+	AtlantisURL *url.URL
+	HTTPClient  *http.Client
+	Logger      *logging.SimpleLogger
+
+	// sessionKey signs session cookies. It's generated on startup so
+	// sessions don't survive restarts.
+	sessionKey []byte
+	// endpoints is discovered from the issuer the first time it's needed.
+	endpoints      *oidcEndpoints
+	endpointsMutex sync.Mutex
+}
+
+type oidcEndpoints struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// oidcSession is stored in the session cookie.
+type oidcSession struct {
+	Username string   `json:"u"`
+	Groups   []string `json:"g,omitempty"`
+	Expiry   int64    `json:"e"`
+}
+
+// NewOIDCAuthenticator returns an OIDCAuthenticator with a new random session
+// key.
+func NewOIDCAuthenticator(issuerURL string, clientID string, clientSecret string, atlantisURL *url.URL, logger *logging.SimpleLogger) (*OIDCAuthenticator, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, errors.Wrap(err, "generating session key")
+	}
+	return &OIDCAuthenticator{
+		IssuerURL:    strings.TrimSuffix(issuerURL, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		AtlantisURL:  atlantisURL,
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+		Logger:       logger,
+		sessionKey:   key,
+	}, nil
+}
+
+// Authenticate implements Authenticator by checking the session cookie.
+func (o *OIDCAuthenticator) Authenticate(r *http.Request) (string, []string, bool) {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return "", nil, false
+	}
+	session, ok := o.decodeSession(cookie.Value)
+	if !ok || time.Now().Unix() > session.Expiry {
+		return "", nil, false
+	}
+	return session.Username, session.Groups, true
+}
+
+// Challenge implements Authenticator. Page loads are redirected to the login
+// page and other requests get a 401.
+func (o *OIDCAuthenticator) Challenge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	login := url.URL{Path: OIDCLoginPath, RawQuery: url.Values{"redirect": {r.URL.RequestURI()}}.Encode()}
+	http.Redirect(w, r, o.absoluteURL(login.String()), http.StatusFound)
+}
+
+// Login redirects the user to the provider to log in.
+func (o *OIDCAuthenticator) Login(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := o.discover()
+	if err != nil {
+		o.respond(w, http.StatusBadGateway, "Discovering OIDC endpoints: %s", err)
+		return
+	}
+	stateBytes := make([]byte, 16)
+	if _, err := rand.Read(stateBytes); err != nil {
+		o.respond(w, http.StatusInternalServerError, "Generating state: %s", err)
+		return
+	}
+	state := hex.EncodeToString(stateBytes)
+	// The page to return to after logging in is stored alongside the state.
+	http.SetCookie(w, o.cookie(stateCookieName, state+":"+safeRedirect(r.URL.Query().Get("redirect")), 10*time.Minute))
+
+	authURL, err := url.Parse(endpoints.AuthorizationEndpoint)
+	if err != nil {
+		o.respond(w, http.StatusBadGateway, "Parsing authorization endpoint: %s", err)
+		return
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", o.ClientID)
+	query.Set("redirect_uri", o.absoluteURL(OIDCCallbackPath))
+	query.Set("scope", "openid profile email groups")
+	query.Set("state", state)
+	authURL.RawQuery = query.Encode()
+	http.Redirect(w, r, authURL.String(), http.StatusFound)
+}
+
+// Callback exchanges the code from the provider for the user's identity and
+// starts their session.
+func (o *OIDCAuthenticator) Callback(w http.ResponseWriter, r *http.Request) {
+	stateCookie, err := r.Cookie(stateCookieName)
+	if err != nil {
+		o.respond(w, http.StatusBadRequest, "Missing state cookie, try logging in again")
+		return
+	}
+	http.SetCookie(w, o.deletedCookie(stateCookieName))
+	sep := strings.Index(stateCookie.Value, ":")
+	if sep < 0 || r.URL.Query().Get("state") == "" ||
+		!hmac.Equal([]byte(stateCookie.Value[:sep]), []byte(r.URL.Query().Get("state"))) {
+		o.respond(w, http.StatusBadRequest, "Invalid state, try logging in again")
+		return
+	}
+	if errParam := r.URL.Query().Get("error"); errParam != "" {
+		o.respond(w, http.StatusUnauthorized, "Login failed: %s", errParam)
+		return
+	}
+
+	session, err := o.exchange(r.URL.Query().Get("code"))
+	if err != nil {
+		o.Logger.Err("OIDC login failed: %s", err)
+		o.respond(w, http.StatusUnauthorized, "Login failed: %s", err)
+		return
+	}
+	session.Expiry = time.Now().Add(sessionDuration).Unix()
+	value, err := o.encodeSession(session)
+	if err != nil {
+		o.respond(w, http.StatusInternalServerError, "Creating session: %s", err)
+		return
+	}
+	o.Logger.Info("user %q logged in", session.Username)
+	http.SetCookie(w, o.cookie(sessionCookieName, value, sessionDuration))
+	http.Redirect(w, r, o.absoluteURL(stateCookie.Value[sep+1:]), http.StatusFound)
+}
+
+// Logout clears the user's session. It redirects with 303 so the browser
+// follows the POST with a GET.
+func (o *OIDCAuthenticator) Logout(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, o.deletedCookie(sessionCookieName))
+	http.Redirect(w, r, o.absoluteURL("/"), http.StatusSeeOther)
+}
+
+// exchange exchanges code for an access token and uses it to get the user's
+// claims from the userinfo endpoint.
+func (o *OIDCAuthenticator) exchange(code string) (oidcSession, error) {
+	endpoints, err := o.discover()
+	if err != nil {
+		return oidcSession{}, err
+	}
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {o.absoluteURL(OIDCCallbackPath)},
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoints.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return oidcSession{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(o.ClientID), url.QueryEscape(o.ClientSecret))
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := o.doJSON(req, &token); err != nil {
+		return oidcSession{}, errors.Wrap(err, "exchanging code")
+	}
+	if token.AccessToken == "" {
+		return oidcSession{}, errors.New("exchanging code: response had no access_token")
+	}
+
+	req, err = http.NewRequest(http.MethodGet, endpoints.UserinfoEndpoint, nil)
+	if err != nil {
+		return oidcSession{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	var claims struct {
+		Sub               string   `json:"sub"`
+		PreferredUsername string   `json:"preferred_username"`
+		Email             string   `json:"email"`
+		Groups            []string `json:"groups"`
+	}
+	if err := o.doJSON(req, &claims); err != nil {
+		return oidcSession{}, errors.Wrap(err, "getting userinfo")
+	}
+	session := oidcSession{Username: claims.PreferredUsername, Groups: claims.Groups}
+	if session.Username == "" {
+		session.Username = claims.Email
+	}
+	if session.Username == "" {
+		session.Username = claims.Sub
+	}
+	if session.Username == "" {
+		return oidcSession{}, errors.New("userinfo had no preferred_username, email or sub claim")
+	}
+	return session, nil
+}
+
+// discover fetches the provider's endpoints from its discovery document.
+func (o *OIDCAuthenticator) discover() (*oidcEndpoints, error) {
+	o.endpointsMutex.Lock()
+	defer o.endpointsMutex.Unlock()
+	if o.endpoints != nil {
+		return o.endpoints, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, o.IssuerURL+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var endpoints oidcEndpoints
+	if err := o.doJSON(req, &endpoints); err != nil {
+		return nil, err
+	}
+	if endpoints.AuthorizationEndpoint == "" || endpoints.TokenEndpoint == "" || endpoints.UserinfoEndpoint == "" {
+		return nil, fmt.Errorf("discovery document from %s is missing the authorization, token or userinfo endpoint", o.IssuerURL)
+	}
+	o.endpoints = &endpoints
+	return o.endpoints, nil
+}
+
+func (o *OIDCAuthenticator) doJSON(req *http.Request, v interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := o.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint: errcheck
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s returned status %d: %s", req.Method, req.URL, resp.StatusCode, string(body))
+	}
+	return json.Unmarshal(body, v)
+}
+
+// encodeSession returns the cookie value for session: the base64 encoded JSON
+// followed by its HMAC.
+func (o *OIDCAuthenticator) encodeSession(session oidcSession) (string, error) {
+	payload, err := json.Marshal(session)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + o.sign(encoded), nil
+}
+
+func (o *OIDCAuthenticator) decodeSession(value string) (oidcSession, bool) {
+	var session oidcSession
+	sep := strings.LastIndex(value, ".")
+	if sep < 0 || !hmac.Equal([]byte(o.sign(value[:sep])), []byte(value[sep+1:])) {
+		return session, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(value[:sep])
+	if err != nil {
+		return session, false
+	}
+	if err := json.Unmarshal(payload, &session); err != nil {
+		return session, false
+	}
+	return session, true
+}
+
+func (o *OIDCAuthenticator) sign(s string) string {
+	mac := hmac.New(sha256.New, o.sessionKey)
+	mac.Write([]byte(s)) // nolint: errcheck
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// cookie returns a cookie scoped to Atlantis.
+func (o *OIDCAuthenticator) cookie(name string, value string, maxAge time.Duration) *http.Cookie {
+	path := o.AtlantisURL.Path
+	if path == "" {
+		path = "/"
+	}
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		MaxAge:   int(maxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   o.AtlantisURL.Scheme == "https",
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// deletedCookie returns a cookie that deletes the cookie called name. Its
+// MaxAge is set directly because a MaxAge of 0 means no Max-Age attribute.
+func (o *OIDCAuthenticator) deletedCookie(name string) *http.Cookie {
+	c := o.cookie(name, "", 0)
+	c.MaxAge = -1
+	return c
+}
+
+// absoluteURL returns the URL of path on Atlantis, taking into account that
+// Atlantis may be served under a base path.
+func (o *OIDCAuthenticator) absoluteURL(path string) string {
+	return strings.TrimSuffix(o.AtlantisURL.String(), "/") + path
+}
+
+func (o *OIDCAuthenticator) respond(w http.ResponseWriter, code int, format string, args ...interface{}) {
+	w.WriteHeader(code)
+	fmt.Fprintf(w, format, args...)
+}
+
+// safeRedirect returns path if it's a path on this server, otherwise "/".
+// This stops the login flow being used to redirect users to other sites.
+func safeRedirect(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,211 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/logging"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestOIDCAuthenticator_LoginFlow(t *testing.T) {
+	idp := newFakeIdP(t)
+	defer idp.Close()
+	o := newOIDCAuthenticator(t, idp.URL)
+
+	t.Log("unauthenticated page loads should be redirected to the login page")
+	w := httptest.NewRecorder()
+	o.Challenge(w, httptest.NewRequest("GET", "/lock?id=abc", nil))
+	Equals(t, http.StatusFound, w.Code)
+	Equals(t, "https://atlantis.example.com/basepath/auth/oidc/login?redirect=%2Flock%3Fid%3Dabc", w.Header().Get("Location"))
+
+	t.Log("login should redirect to the provider")
+	w = httptest.NewRecorder()
+	o.Login(w, httptest.NewRequest("GET", "/auth/oidc/login?redirect=%2Flock%3Fid%3Dabc", nil))
+	Equals(t, http.StatusFound, w.Code)
+	authURL, err := url.Parse(w.Header().Get("Location"))
+	Ok(t, err)
+	Equals(t, idp.URL+"/authorize", authURL.Scheme+"://"+authURL.Host+authURL.Path)
+	Equals(t, "client-id", authURL.Query().Get("client_id"))
+	Equals(t, "https://atlantis.example.com/basepath/auth/oidc/callback", authURL.Query().Get("redirect_uri"))
+	stateCookie := findCookie(t, w, "atlantis_oidc_state")
+	Equals(t, "/basepath", stateCookie.Path)
+	Assert(t, stateCookie.Secure, "exp cookie to be secure")
+
+	t.Log("the callback should start a session and redirect back")
+	r := httptest.NewRequest("GET", "/auth/oidc/callback?code=good-code&state="+authURL.Query().Get("state"), nil)
+	r.AddCookie(stateCookie)
+	w = httptest.NewRecorder()
+	o.Callback(w, r)
+	Equals(t, http.StatusFound, w.Code)
+	Equals(t, "https://atlantis.example.com/basepath/lock?id=abc", w.Header().Get("Location"))
+	sessionCookie := findCookie(t, w, "atlantis_session")
+	Equals(t, -1, findCookie(t, w, "atlantis_oidc_state").MaxAge)
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(sessionCookie)
+	user, groups, ok := o.Authenticate(r)
+	Assert(t, ok, "exp session to be valid")
+	Equals(t, "alice", user)
+	Equals(t, []string{"ops"}, groups)
+
+	t.Log("a tampered session should be rejected")
+	r = httptest.NewRequest("GET", "/", nil)
+	sessionCookie.Value = "x" + sessionCookie.Value
+	r.AddCookie(sessionCookie)
+	_, _, ok = o.Authenticate(r)
+	Assert(t, !ok, "exp tampered session to be invalid")
+
+	t.Log("a session from another server should be rejected")
+	r = httptest.NewRequest("GET", "/", nil)
+	sessionCookie.Value = sessionCookie.Value[1:]
+	r.AddCookie(sessionCookie)
+	_, _, ok = newOIDCAuthenticator(t, idp.URL).Authenticate(r)
+	Assert(t, !ok, "exp session signed with another key to be invalid")
+}
+
+func TestOIDCAuthenticator_Logout(t *testing.T) {
+	o := newOIDCAuthenticator(t, "https://idp.example.com")
+	w := httptest.NewRecorder()
+	o.Logout(w, httptest.NewRequest("POST", "/auth/logout", nil))
+	Equals(t, http.StatusSeeOther, w.Code)
+	Equals(t, "https://atlantis.example.com/basepath/", w.Header().Get("Location"))
+	sessionCookie := findCookie(t, w, "atlantis_session")
+	Equals(t, "", sessionCookie.Value)
+	Equals(t, "/basepath", sessionCookie.Path)
+	// A MaxAge of -1 is sent as Max-Age=0, which deletes the cookie.
+	Equals(t, -1, sessionCookie.MaxAge)
+	Assert(t, strings.Contains(w.Header().Get("Set-Cookie"), "Max-Age=0"), "exp cookie to be deleted")
+}
+
+func TestOIDCAuthenticator_CallbackErrors(t *testing.T) {
+	idp := newFakeIdP(t)
+	defer idp.Close()
+	o := newOIDCAuthenticator(t, idp.URL)
+	cases := []struct {
+		description string
+		query       string
+		cookie      string
+		expCode     int
+		expBody     string
+	}{
+		{
+			description: "no state cookie",
+			query:       "code=good-code&state=abc",
+			expCode:     http.StatusBadRequest,
+			expBody:     "Missing state cookie",
+		},
+		{
+			description: "state mismatch",
+			query:       "code=good-code&state=abc",
+			cookie:      "def:/",
+			expCode:     http.StatusBadRequest,
+			expBody:     "Invalid state",
+		},
+		{
+			description: "provider error",
+			query:       "error=access_denied&state=abc",
+			cookie:      "abc:/",
+			expCode:     http.StatusUnauthorized,
+			expBody:     "Login failed: access_denied",
+		},
+		{
+			description: "bad code",
+			query:       "code=bad-code&state=abc",
+			cookie:      "abc:/",
+			expCode:     http.StatusUnauthorized,
+			expBody:     "Login failed: exchanging code",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/auth/oidc/callback?"+c.query, nil)
+			if c.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: "atlantis_oidc_state", Value: c.cookie})
+			}
+			w := httptest.NewRecorder()
+			o.Callback(w, r)
+			responseContains(t, w, c.expCode, c.expBody)
+			for _, cookie := range w.Result().Cookies() {
+				Assert(t, cookie.Name != "atlantis_session", "exp no session cookie")
+			}
+		})
+	}
+}
+
+func TestOIDCAuthenticator_LoginRedirectsOnlyToAtlantis(t *testing.T) {
+	idp := newFakeIdP(t)
+	defer idp.Close()
+	o := newOIDCAuthenticator(t, idp.URL)
+	for _, redirect := range []string{"https://evil.com", "//evil.com", "/\\evil.com"} {
+		t.Run(redirect, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			o.Login(w, httptest.NewRequest("GET", "/auth/oidc/login?"+url.Values{"redirect": {redirect}}.Encode(), nil))
+			cookie := findCookie(t, w, "atlantis_oidc_state")
+			Assert(t, strings.HasSuffix(cookie.Value, ":/"), "exp redirect to be replaced with /, got %q", cookie.Value)
+		})
+	}
+}
+
+func TestOIDCAuthenticator_ChallengeNonGet(t *testing.T) {
+	o := newOIDCAuthenticator(t, "https://idp.example.com")
+	w := httptest.NewRecorder()
+	o.Challenge(w, httptest.NewRequest("DELETE", "/locks?id=abc", nil))
+	Equals(t, http.StatusUnauthorized, w.Code)
+}
+
+func newOIDCAuthenticator(t *testing.T, issuerURL string) *server.OIDCAuthenticator {
+	atlantisURL, err := url.Parse("https://atlantis.example.com/basepath")
+	Ok(t, err)
+	o, err := server.NewOIDCAuthenticator(issuerURL, "client-id", "client-secret", atlantisURL, logging.NewNoopLogger())
+	Ok(t, err)
+	return o
+}
+
+// newFakeIdP returns an OIDC provider that issues an access token for
+// "good-code" and returns alice's claims for it.
+func newFakeIdP(t *testing.T) *httptest.Server {
+	var idp *httptest.Server
+	idp = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{ // nolint: errcheck
+				"authorization_endpoint": idp.URL + "/authorize",
+				"token_endpoint":         idp.URL + "/token",
+				"userinfo_endpoint":      idp.URL + "/userinfo",
+			})
+		case "/token":
+			user, pass, _ := r.BasicAuth()
+			if r.FormValue("code") != "good-code" || user != "client-id" || pass != "client-secret" ||
+				r.FormValue("redirect_uri") != "https://atlantis.example.com/basepath/auth/oidc/callback" {
+				http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"access_token":"token","token_type":"Bearer"}`)) // nolint: errcheck
+		case "/userinfo":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				http.Error(w, "", http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"sub":"123","preferred_username":"alice","groups":["ops"]}`)) // nolint: errcheck
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return idp
+}
+
+func findCookie(t *testing.T, w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	t.Fatalf("no %s cookie set", name)
+	return nil
+}
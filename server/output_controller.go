@@ -0,0 +1,183 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+)
+
+const (
+	// OutputRepoQueryParam is the query parameter holding the full name of the
+	// repo whose output should be shown.
+	OutputRepoQueryParam = "repo"
+	// OutputVCSQueryParam is the query parameter holding the VCS host of the
+	// repo, ex. gitlab. It defaults to github.
+	OutputVCSQueryParam = "vcs"
+	// OutputPullQueryParam is the query parameter holding the number of the
+	// pull request whose output should be shown.
+	OutputPullQueryParam = "pull"
+)
+
+// OutputController handles requests for the live output of the commands
+// running on pull requests.
+type OutputController struct {
+	AtlantisVersion    string
+	AtlantisURL        *url.URL
+	Logger             *logging.SimpleLogger
+	OutputStore        *events.ProjectOutputStore
+	PullOutputTemplate TemplateWriter
+	// NewRepo returns the repo fullName on the VCS host vcs, ex. gitlab.
+	NewRepo func(fullName string, vcs string) (models.Repo, error)
+}
+
+// projectOutputEvent is sent when a project's command starts.
+type projectOutputEvent struct {
+	ID        int    `json:"id"`
+	Dir       string `json:"dir"`
+	Workspace string `json:"workspace"`
+	Project   string `json:"project"`
+	Command   string `json:"command"`
+	StartTime string `json:"start_time"`
+}
+
+// linesOutputEvent is sent when a project's command has output new lines.
+type linesOutputEvent struct {
+	ID    int      `json:"id"`
+	Lines []string `json:"lines"`
+}
+
+// doneOutputEvent is sent when a project's command is complete.
+type doneOutputEvent struct {
+	ID int `json:"id"`
+}
+
+// GetPullOutput is the GET /output route. It renders a page that streams the
+// output of the commands running on a pull request.
+func (o *OutputController) GetPullOutput(w http.ResponseWriter, r *http.Request) {
+	repo, pullNum, ok := o.parsePull(w, r)
+	if !ok {
+		return
+	}
+	streamQuery := url.Values{
+		OutputRepoQueryParam: []string{repo.FullName},
+		OutputVCSQueryParam:  []string{vcsNames[repo.VCSHost.Type]},
+		OutputPullQueryParam: []string{strconv.Itoa(pullNum)},
+	}
+	err := o.PullOutputTemplate.Execute(w, PullOutputData{
+		RepoFullName:    repo.FullName,
+		PullNum:         pullNum,
+		StreamPath:      fmt.Sprintf("%s/output/stream?%s", o.AtlantisURL.Path, streamQuery.Encode()),
+		AtlantisVersion: o.AtlantisVersion,
+		CleanedBasePath: o.AtlantisURL.Path,
+	})
+	if err != nil {
+		o.Logger.Err("rendering output page: %s", err)
+	}
+}
+
+// StreamPullOutput is the GET /output/stream route. It streams the output of
+// the commands running on a pull request as server-sent events until the
+// client disconnects.
+func (o *OutputController) StreamPullOutput(w http.ResponseWriter, r *http.Request) {
+	repo, pullNum, ok := o.parsePull(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		o.respond(w, logging.Error, http.StatusInternalServerError, "Streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Stop proxies like nginx from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// offsets maps from output ID to the next line to send.
+	offsets := make(map[int]int)
+	done := make(map[int]bool)
+	for {
+		outputs, changed := o.OutputStore.ListForPull(repo, pullNum)
+		for _, out := range outputs {
+			if _, seen := offsets[out.ID]; !seen {
+				o.writeEvent(w, "project", projectOutputEvent{
+					ID:        out.ID,
+					Dir:       out.RepoRelDir,
+					Workspace: out.Workspace,
+					Project:   out.ProjectName,
+					Command:   out.Command.String(),
+					StartTime: out.StartTime.Format("2006-01-02 15:04:05 MST"),
+				})
+				offsets[out.ID] = 0
+			}
+			if done[out.ID] {
+				continue
+			}
+			lines, next, closed := out.Buffer.Tail(offsets[out.ID])
+			if len(lines) > 0 {
+				o.writeEvent(w, "lines", linesOutputEvent{ID: out.ID, Lines: lines})
+			}
+			offsets[out.ID] = next
+			if closed {
+				o.writeEvent(w, "done", doneOutputEvent{ID: out.ID})
+				done[out.ID] = true
+			}
+		}
+		flusher.Flush()
+
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeEvent writes a server-sent event named name with data encoded as JSON.
+func (o *OutputController) writeEvent(w http.ResponseWriter, name string, data interface{}) {
+	bytes, err := json.Marshal(data)
+	if err != nil {
+		o.Logger.Err("encoding %s event: %s", name, err)
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, bytes)
+}
+
+// parsePull returns the repo and pull number in r's query parameters. If they
+// are invalid it responds with an error and ok is false.
+func (o *OutputController) parsePull(w http.ResponseWriter, r *http.Request) (repo models.Repo, pullNum int, ok bool) {
+	query := r.URL.Query()
+	repoFullName := query.Get(OutputRepoQueryParam)
+	if repoFullName == "" {
+		o.respond(w, logging.Warn, http.StatusBadRequest, "No repo in request")
+		return models.Repo{}, 0, false
+	}
+	pullNum, err := strconv.Atoi(query.Get(OutputPullQueryParam))
+	if err != nil {
+		o.respond(w, logging.Warn, http.StatusBadRequest, "Invalid pull number %q", query.Get(OutputPullQueryParam))
+		return models.Repo{}, 0, false
+	}
+	repo, err = o.NewRepo(repoFullName, query.Get(OutputVCSQueryParam))
+	if err != nil {
+		o.respond(w, logging.Warn, http.StatusBadRequest, "%s", err)
+		return models.Repo{}, 0, false
+	}
+	return repo, pullNum, true
+}
+
+// respond is a helper function to respond and log the response. lvl is the log
+// level to log at, code is the HTTP response code.
+func (o *OutputController) respond(w http.ResponseWriter, lvl logging.LogLevel, responseCode int, format string, args ...interface{}) {
+	response := fmt.Sprintf(format, args...)
+	o.Logger.Log(lvl, "%s", response)
+	w.WriteHeader(responseCode)
+	fmt.Fprintln(w, response)
+}
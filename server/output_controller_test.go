@@ -0,0 +1,140 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+
+	. "github.com/petergtz/pegomock"
+	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events"
+	"github.com/runatlantis/atlantis/server/events/models"
+	"github.com/runatlantis/atlantis/server/logging"
+	sMocks "github.com/runatlantis/atlantis/server/mocks"
+	. "github.com/runatlantis/atlantis/testing"
+)
+
+func TestGetPullOutput_InvalidParams(t *testing.T) {
+	cases := []struct {
+		query  string
+		expErr string
+	}{
+		{
+			"",
+			"No repo in request",
+		},
+		{
+			"repo=owner%2Frepo",
+			"Invalid pull number \"\"",
+		},
+		{
+			"repo=owner%2Frepo&pull=abc",
+			"Invalid pull number \"abc\"",
+		},
+		{
+			"repo=owner%2Frepo&pull=1&vcs=gitlab",
+			"vcs \"gitlab\" for repo \"owner/repo\" not supported",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			oc := server.OutputController{
+				Logger:  logging.NewNoopLogger(),
+				NewRepo: newGithubRepo,
+			}
+			req, _ := http.NewRequest("GET", "/output?"+c.query, bytes.NewBuffer(nil))
+			w := httptest.NewRecorder()
+			oc.GetPullOutput(w, req)
+			responseContains(t, w, http.StatusBadRequest, c.expErr)
+
+			req, _ = http.NewRequest("GET", "/output/stream?"+c.query, bytes.NewBuffer(nil))
+			w = httptest.NewRecorder()
+			oc.StreamPullOutput(w, req)
+			responseContains(t, w, http.StatusBadRequest, c.expErr)
+		})
+	}
+}
+
+func TestGetPullOutput_Success(t *testing.T) {
+	RegisterMockTestingT(t)
+	tmpl := sMocks.NewMockTemplateWriter()
+	u, err := url.Parse("https://example.com/basepath")
+	Ok(t, err)
+	oc := server.OutputController{
+		AtlantisVersion:    "1.0.0",
+		AtlantisURL:        u,
+		Logger:             logging.NewNoopLogger(),
+		PullOutputTemplate: tmpl,
+		NewRepo:            newGithubRepo,
+	}
+	req, _ := http.NewRequest("GET", "/output?repo=owner%2Frepo&pull=2", bytes.NewBuffer(nil))
+	w := httptest.NewRecorder()
+	oc.GetPullOutput(w, req)
+	tmpl.VerifyWasCalledOnce().Execute(w, server.PullOutputData{
+		RepoFullName:    "owner/repo",
+		PullNum:         2,
+		StreamPath:      "/basepath/output/stream?pull=2&repo=owner%2Frepo&vcs=github",
+		AtlantisVersion: "1.0.0",
+		CleanedBasePath: "/basepath",
+	})
+	responseContains(t, w, http.StatusOK, "")
+}
+
+func TestStreamPullOutput(t *testing.T) {
+	t.Log("the output of the pull's projects should be streamed as events")
+	store := events.NewProjectOutputStore(10, nil)
+	repo, err := newGithubRepo("owner/repo", "")
+	Ok(t, err)
+	buf := store.Start(models.ProjectCommandContext{
+		BaseRepo:   repo,
+		Pull:       models.PullRequest{Num: 2},
+		RepoRelDir: "dir",
+		Workspace:  "default",
+	}, events.PlanCommand)
+	buf.Write([]byte("line1\nline2\n")) // nolint: errcheck
+	buf.Close()
+	store.Start(models.ProjectCommandContext{
+		BaseRepo:   repo,
+		Pull:       models.PullRequest{Num: 3},
+		RepoRelDir: "other",
+		Workspace:  "default",
+	}, events.PlanCommand)
+
+	gitlabRepo := repo
+	gitlabRepo.VCSHost = models.VCSHost{Type: models.Gitlab, Hostname: "gitlab.com"}
+	store.Start(models.ProjectCommandContext{
+		BaseRepo:   gitlabRepo,
+		Pull:       models.PullRequest{Num: 2},
+		RepoRelDir: "gitlab",
+		Workspace:  "default",
+	}, events.PlanCommand)
+
+	oc := server.OutputController{
+		Logger:      logging.NewNoopLogger(),
+		OutputStore: store,
+		NewRepo:     newGithubRepo,
+	}
+	// Cancel the request's context so the handler returns once it's sent
+	// the output so far.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequest("GET", "/output/stream?repo=owner%2Frepo&pull=2", bytes.NewBuffer(nil))
+	req = req.WithContext(ctx)
+	w := httptest.NewRecorder()
+	oc.StreamPullOutput(w, req)
+
+	Equals(t, "text/event-stream", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	// Clear out the start time since it changes.
+	body = regexp.MustCompile(`"start_time":"[^"]*"`).ReplaceAllString(body, `"start_time":""`)
+	Equals(t, "event: project\n"+
+		`data: {"id":1,"dir":"dir","workspace":"default","project":"","command":"plan","start_time":""}`+"\n\n"+
+		"event: lines\n"+
+		`data: {"id":1,"lines":["line1","line2"]}`+"\n\n"+
+		"event: done\n"+
+		`data: {"id":1}`+"\n\n", body)
+}
@@ -2,8 +2,10 @@ package server
 
 import (
 	"net/url"
+	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/runatlantis/atlantis/server/events/models"
 )
 
 // Router can be used to retrieve Atlantis URLs. It acts as an intermediary
@@ -21,6 +23,9 @@ type Router struct {
 	// AtlantisURL is the fully qualified URL that Atlantis is
 	// accessible from externally.
 	AtlantisURL *url.URL
+	// PullOutputRouteName is the named route for the page showing the live
+	// output of a pull request's commands.
+	PullOutputRouteName string
 }
 
 // GenerateLockURL returns a fully qualified URL to view the lock at lockID.
@@ -33,3 +38,15 @@ func (r *Router) GenerateLockURL(lockID string) string {
 	// golang likes to double escape the lockURL path when using url.Parse().
 	return r.AtlantisURL.String() + lockURL.String()
 }
+
+// GeneratePullOutputURL returns a fully qualified URL to view the live output
+// of the commands running on pull request pullNum of repo.
+func (r *Router) GeneratePullOutputURL(repo models.Repo, pullNum int) string {
+	outputURL, _ := r.Underlying.Get(r.PullOutputRouteName).URL()
+	outputURL.RawQuery = url.Values{
+		OutputRepoQueryParam: []string{repo.FullName},
+		OutputVCSQueryParam:  []string{vcsNames[repo.VCSHost.Type]},
+		OutputPullQueryParam: []string{strconv.Itoa(pullNum)},
+	}.Encode()
+	return r.AtlantisURL.String() + outputURL.String()
+}
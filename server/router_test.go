@@ -6,6 +6,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/runatlantis/atlantis/server"
+	"github.com/runatlantis/atlantis/server/events/models"
 	. "github.com/runatlantis/atlantis/testing"
 )
 
@@ -60,3 +61,18 @@ func TestRouter_GenerateLockURL(t *testing.T) {
 		})
 	}
 }
+
+func TestRouter_GeneratePullOutputURL(t *testing.T) {
+	underlyingRouter := mux.NewRouter()
+	underlyingRouter.HandleFunc("/output", func(_ http.ResponseWriter, _ *http.Request) {}).Methods("GET").Name(server.PullOutputRouteName)
+	atlantisURL, err := server.ParseAtlantisURL("https://example.com/basepath/")
+	Ok(t, err)
+
+	router := &server.Router{
+		AtlantisURL:         atlantisURL,
+		Underlying:          underlyingRouter,
+		PullOutputRouteName: server.PullOutputRouteName,
+	}
+	repo := models.Repo{FullName: "owner/group/repo", VCSHost: models.VCSHost{Type: models.Gitlab}}
+	Equals(t, "https://example.com/basepath/output?pull=1&repo=owner%2Fgroup%2Frepo&vcs=gitlab", router.GeneratePullOutputURL(repo, 1))
+}
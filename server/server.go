@@ -25,6 +25,7 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
@@ -43,6 +44,7 @@ import (
 	"github.com/runatlantis/atlantis/server/events/vcs/bitbucketserver"
 	"github.com/runatlantis/atlantis/server/events/webhooks"
 	"github.com/runatlantis/atlantis/server/events/yaml"
+	"github.com/runatlantis/atlantis/server/events/yaml/valid"
 	"github.com/runatlantis/atlantis/server/logging"
 	"github.com/runatlantis/atlantis/server/static"
 	"github.com/urfave/cli"
@@ -58,6 +60,9 @@ const (
 	// route. ex:
 	//   mux.Router.Get(LockViewRouteName).URL(LockViewRouteIDQueryParam, "my id")
 	LockViewRouteIDQueryParam = "id"
+	// PullOutputRouteName is the named route in mux.Router for the page that
+	// shows the live output of the commands running on a pull request.
+	PullOutputRouteName = "pull-output"
 )
 
 // Server runs the Atlantis web server.
@@ -67,10 +72,21 @@ type Server struct {
 	Router             *mux.Router
 	Port               int
 	CommandRunner      *events.DefaultCommandRunner
+	JobQueue           *events.JobQueue
 	Logger             *logging.SimpleLogger
 	Locker             locking.Locker
 	EventsController   *EventsController
 	LocksController    *LocksController
+	OutputController   *OutputController
+	APIController      *APIController
+	DriftController    *DriftController
+	JobsController     *JobsController
+	DriftDetector      *events.DriftDetector
+	DriftSchedules     []events.DriftSchedule
+	LockReaper         *events.LockReaper
+	DrainTimeout       time.Duration
+	AuthMiddleware     *AuthMiddleware
+	OIDCAuthenticator  *OIDCAuthenticator
 	IndexTemplate      TemplateWriter
 	LockDetailTemplate TemplateWriter
 	SSLCertFile        string
@@ -89,6 +105,8 @@ type UserConfig struct {
 	BitbucketUser          string `mapstructure:"bitbucket-user"`
 	BitbucketWebhookSecret string `mapstructure:"bitbucket-webhook-secret"`
 	DataDir                string `mapstructure:"data-dir"`
+	DisableAtlantisTFVars  bool   `mapstructure:"disable-atlantis-tf-vars"`
+	DrainTimeout           string `mapstructure:"drain-timeout"`
 	GithubHostname         string `mapstructure:"gh-hostname"`
 	GithubToken            string `mapstructure:"gh-token"`
 	GithubUser             string `mapstructure:"gh-user"`
@@ -97,8 +115,15 @@ type UserConfig struct {
 	GitlabToken            string `mapstructure:"gitlab-token"`
 	GitlabUser             string `mapstructure:"gitlab-user"`
 	GitlabWebhookSecret    string `mapstructure:"gitlab-webhook-secret"`
+	JobWorkers             int    `mapstructure:"job-workers"`
+	LockKeyStrategy        string `mapstructure:"lock-key-strategy"`
+	LockTTL                string `mapstructure:"lock-ttl"`
 	LogLevel               string `mapstructure:"log-level"`
+	OIDCClientID           string `mapstructure:"oidc-client-id"`
+	OIDCClientSecret       string `mapstructure:"oidc-client-secret"`
+	OIDCIssuerURL          string `mapstructure:"oidc-issuer-url"`
 	Port                   int    `mapstructure:"port"`
+	RepoEnvVarWhitelist    string `mapstructure:"repo-env-var-whitelist"`
 	RepoWhitelist          string `mapstructure:"repo-whitelist"`
 	// RequireApproval is whether to require pull request approval before
 	// allowing terraform apply's to be run.
@@ -107,7 +132,26 @@ type UserConfig struct {
 	SlackToken             string          `mapstructure:"slack-token"`
 	SSLCertFile            string          `mapstructure:"ssl-cert-file"`
 	SSLKeyFile             string          `mapstructure:"ssl-key-file"`
+	TFDownloadURL          string          `mapstructure:"tf-download-url"`
+	WebAdminGroups         string          `mapstructure:"web-admin-groups"`
+	WebAdminUsers          string          `mapstructure:"web-admin-users"`
+	WebHtpasswdFile        string          `mapstructure:"web-htpasswd-file"`
 	Webhooks               []WebhookConfig `mapstructure:"webhooks"`
+	// MarkdownTemplateOverridesDir is a directory of templates that replace
+	// the default comment templates.
+	MarkdownTemplateOverridesDir string `mapstructure:"markdown-template-overrides-dir"`
+	// SMTP configures the relay that email webhooks are sent through.
+	SMTP SMTPConfig `mapstructure:"smtp"`
+	// DriftDetection configures the repos that are periodically planned to
+	// detect drift.
+	DriftDetection []DriftDetectionConfig `mapstructure:"drift-detection"`
+	// APITokens are the tokens that can be used to call the API.
+	APITokens []APITokenConfig `mapstructure:"api-tokens"`
+	// CommandAuthorization restricts who can run commands.
+	CommandAuthorization []CommandAuthorizationConfig `mapstructure:"command-authorization"`
+	// Redact configures the secrets removed from output before it's
+	// commented back to pull requests.
+	Redact RedactConfig `mapstructure:"redact"`
 }
 
 // Config holds config for server that isn't passed in by the user.
@@ -131,6 +175,96 @@ type WebhookConfig struct {
 	// Channel is the channel to send this webhook to. It only applies to
 	// slack webhooks. Should be without '#'.
 	Channel string `mapstructure:"channel"`
+	// URL is the URL to POST this webhook to. It only applies to http
+	// webhooks.
+	URL string `mapstructure:"url"`
+	// Secret is used to sign http webhooks so the receiver can check they
+	// came from Atlantis. It's optional.
+	Secret string `mapstructure:"secret"`
+	// RepoRegex, BranchRegex, ProjectName and Only optionally limit which
+	// events the webhook is sent for. Only is either success or failure.
+	RepoRegex   string `mapstructure:"repo-regex"`
+	BranchRegex string `mapstructure:"branch-regex"`
+	ProjectName string `mapstructure:"project-name"`
+	Only        string `mapstructure:"only"`
+	// To are the addresses to send this webhook to. It only applies to
+	// email webhooks.
+	To []string `mapstructure:"to"`
+}
+
+// SMTPConfig is nested within UserConfig. It's used to configure the SMTP
+// relay that email webhooks are sent through.
+type SMTPConfig struct {
+	Host string `mapstructure:"host"`
+	// Port defaults to 587.
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+	// TLS is true if the relay uses TLS from the start, usually on port 465,
+	// instead of STARTTLS.
+	TLS bool `mapstructure:"tls"`
+}
+
+// RedactConfig is nested within UserConfig. It's used to configure what's
+// redacted from the output that's commented back to pull requests. Some
+// common secrets, like AWS keys and private keys, are always redacted.
+type RedactConfig struct {
+	// Patterns are regexes to redact. If a pattern has a capture group only
+	// the first group is redacted.
+	Patterns []string `mapstructure:"patterns"`
+	// EnvVars are the names of environment variables whose values are
+	// redacted.
+	EnvVars []string `mapstructure:"env-vars"`
+}
+
+// DriftDetectionConfig is nested within UserConfig. It's used to configure
+// which repos are checked for drift.
+type DriftDetectionConfig struct {
+	// Repo is the full name of the repo, ex. runatlantis/atlantis.
+	Repo string `mapstructure:"repo"`
+	// VCS is the VCS host of the repo. One of github, gitlab, bitbucket-cloud
+	// or bitbucket-server. Defaults to github.
+	VCS string `mapstructure:"vcs"`
+	// CloneURL is the https url the repo is cloned from. It's only required
+	// for bitbucket-server since it can't be derived from the repo name.
+	CloneURL string `mapstructure:"clone-url"`
+	// Branch is the branch to check, usually the repo's default branch.
+	Branch string `mapstructure:"branch"`
+	// Interval is how often to check the repo, ex. 24h.
+	Interval string `mapstructure:"interval"`
+}
+
+// APITokenConfig is nested within UserConfig. It's used to configure the
+// tokens that can call the API.
+type APITokenConfig struct {
+	// Name identifies the token in logs, ex. dashboard.
+	Name string `mapstructure:"name"`
+	// Token is the secret sent in the Authorization header.
+	Token string `mapstructure:"token"`
+	// Repos limits the repos the token can be used with. It's in the same
+	// format as --repo-whitelist. If empty, it can be used with every repo.
+	Repos string `mapstructure:"repos"`
+}
+
+// CommandAuthorizationConfig is nested within UserConfig. It's used to
+// restrict who can run commands on matching projects.
+type CommandAuthorizationConfig struct {
+	// Repos limits the repos the rule applies to. It's in the same format as
+	// --repo-whitelist. If empty, it applies to every repo.
+	Repos string `mapstructure:"repos"`
+	// Dir, Workspace and Project limit the projects the rule applies to.
+	Dir       string `mapstructure:"dir"`
+	Workspace string `mapstructure:"workspace"`
+	Project   string `mapstructure:"project"`
+	// Commands are the commands the rule restricts, plan or apply. If empty,
+	// it restricts both.
+	Commands []string `mapstructure:"commands"`
+	// Users can run the commands.
+	Users []string `mapstructure:"users"`
+	// Teams are GitHub teams, ex. org/team-slug, or GitLab groups whose
+	// members can run the commands.
+	Teams []string `mapstructure:"teams"`
 }
 
 // NewServer returns a new server. If there are issues starting the server or
@@ -189,53 +323,131 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 			Event:          c.Event,
 			Kind:           c.Kind,
 			WorkspaceRegex: c.WorkspaceRegex,
+			URL:            c.URL,
+			Secret:         c.Secret,
+			RepoRegex:      c.RepoRegex,
+			BranchRegex:    c.BranchRegex,
+			ProjectName:    c.ProjectName,
+			Only:           c.Only,
+			To:             c.To,
 		}
 		webhooksConfig = append(webhooksConfig, config)
 	}
-	webhooksManager, err := webhooks.NewMultiWebhookSender(webhooksConfig, webhooks.NewSlackClient(userConfig.SlackToken))
+	smtpPort := userConfig.SMTP.Port
+	if smtpPort == 0 {
+		smtpPort = 587
+	}
+	smtpClient := &webhooks.DefaultSMTPClient{
+		Host:     userConfig.SMTP.Host,
+		Port:     smtpPort,
+		Username: userConfig.SMTP.Username,
+		Password: userConfig.SMTP.Password,
+		From:     userConfig.SMTP.From,
+		TLS:      userConfig.SMTP.TLS,
+	}
+	webhooksManager, err := webhooks.NewMultiWebhookSender(webhooksConfig, webhooks.NewSlackClient(userConfig.SlackToken), smtpClient)
 	if err != nil {
 		return nil, errors.Wrap(err, "initializing webhooks")
 	}
+	driftSchedules, err := newDriftSchedules(userConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing drift-detection config")
+	}
+	apiTokens, err := newAPITokens(userConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing api-tokens config")
+	}
+	commandAuthorizationRules, err := newCommandAuthorizationRules(userConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing command-authorization config")
+	}
 	vcsClient := vcs.NewDefaultClientProxy(githubClient, gitlabClient, bitbucketCloudClient, bitbucketServerClient)
-	commitStatusUpdater := &events.DefaultCommitStatusUpdater{Client: vcsClient}
-	terraformClient, err := terraform.NewClient(userConfig.DataDir)
+	terraformClient, err := terraform.NewClient(userConfig.DataDir, userConfig.TFDownloadURL)
 	// The flag.Lookup call is to detect if we're running in a unit test. If we
 	// are, then we don't error out because we don't have/want terraform
 	// installed on our CI system where the unit tests run.
 	if err != nil && flag.Lookup("test.v") == nil {
 		return nil, errors.Wrap(err, "initializing terraform")
 	}
-	markdownRenderer := &events.MarkdownRenderer{
-		GitlabSupportsCommonMark: gitlabClient.SupportsCommonMark(),
+	markdownRenderer, err := events.NewMarkdownRenderer(gitlabClient.SupportsCommonMark(), userConfig.MarkdownTemplateOverridesDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading markdown template overrides")
+	}
+	redactor, err := events.NewRedactor(userConfig.Redact.Patterns, userConfig.Redact.EnvVars)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing redact config")
 	}
-	boltdb, err := boltdb.New(userConfig.DataDir)
+	boltLocker, err := boltdb.New(userConfig.DataDir)
 	if err != nil {
 		return nil, err
 	}
-	lockingClient := locking.NewClient(boltdb)
+	var lockTTL time.Duration
+	if userConfig.LockTTL != "" {
+		lockTTL, err = time.ParseDuration(userConfig.LockTTL)
+		if err != nil || lockTTL <= 0 {
+			return nil, fmt.Errorf("parsing lock-ttl %q: must be a positive duration, ex. 72h", userConfig.LockTTL)
+		}
+	}
+	var drainTimeout time.Duration
+	if userConfig.DrainTimeout != "" {
+		drainTimeout, err = time.ParseDuration(userConfig.DrainTimeout)
+		if err != nil || drainTimeout <= 0 {
+			return nil, fmt.Errorf("parsing drain-timeout %q: must be a positive duration, ex. 5m", userConfig.DrainTimeout)
+		}
+	}
+	lockQueue := &events.LockQueue{
+		VCSClient: vcsClient,
+		Logger:    logger,
+	}
+	// lockingClient notifies the queue when locks are released and sends
+	// lock and unlock webhooks.
+	lockingClient := lockQueue.Locker(webhooksManager.Locker(locking.NewClientWithTTL(boltLocker, lockTTL), logger))
 	workingDirLocker := events.NewDefaultWorkingDirLocker()
 	workingDir := &events.FileWorkspace{
 		DataDir: userConfig.DataDir,
 	}
 	projectLocker := &events.DefaultProjectLocker{
 		Locker: lockingClient,
+		Queue:  lockQueue,
+	}
+	var lockReaper *events.LockReaper
+	if lockTTL > 0 {
+		lockReaper = &events.LockReaper{
+			Locker:           lockingClient,
+			WorkingDir:       workingDir,
+			WorkingDirLocker: workingDirLocker,
+			VCSClient:        vcsClient,
+			Logger:           logger,
+		}
 	}
 	parsedURL, err := ParseAtlantisURL(userConfig.AtlantisURL)
 	if err != nil {
 		return nil, errors.Wrapf(err,
 			"parsing --%s flag %q", config.AtlantisURLFlag, userConfig.AtlantisURL)
 	}
+	authMiddleware, oidcAuthenticator, err := newAuthMiddleware(userConfig, parsedURL, logger)
+	if err != nil {
+		return nil, errors.Wrap(err, "initializing web UI authentication")
+	}
 	underlyingRouter := mux.NewRouter()
 	router := &Router{
 		AtlantisURL:               parsedURL,
 		LockViewRouteIDQueryParam: LockViewRouteIDQueryParam,
 		LockViewRouteName:         LockViewRouteName,
 		Underlying:                underlyingRouter,
+		PullOutputRouteName:       PullOutputRouteName,
 	}
+	outputStore := events.NewProjectOutputStore(events.DefaultOutputBufferLines, redactor)
+	commitStatusUpdater := &events.DefaultCommitStatusUpdater{
+		Client:             vcsClient,
+		OutputURLGenerator: router,
+	}
+	pendingPlanFinder := &events.PendingPlanFinder{}
 	pullClosedExecutor := &events.PullClosedExecutor{
-		VCSClient:  vcsClient,
-		Locker:     lockingClient,
-		WorkingDir: workingDir,
+		VCSClient:   vcsClient,
+		Locker:      lockingClient,
+		WorkingDir:  workingDir,
+		OutputStore: outputStore,
 	}
 	eventParser := &events.EventParser{
 		GithubUser:         userConfig.GithubUser,
@@ -253,6 +465,29 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		GitlabToken: userConfig.GitlabToken,
 	}
 	defaultTfVersion := terraformClient.Version()
+	initStepRunner := &runtime.InitStepRunner{
+		TerraformExecutor: terraformClient,
+		DefaultTFVersion:  defaultTfVersion,
+	}
+	planStepRunner := &runtime.PlanStepRunner{
+		TerraformExecutor: terraformClient,
+		DefaultTFVersion:  defaultTfVersion,
+		DisableTFVars:     userConfig.DisableAtlantisTFVars,
+	}
+	runStepRunner := &runtime.RunStepRunner{
+		DefaultTFVersion: defaultTfVersion,
+	}
+	envStepRunner := &runtime.EnvStepRunner{
+		RunStepRunner: runStepRunner,
+	}
+	parserValidator := &yaml.ParserValidator{
+		AllowedEnvVars: splitList(userConfig.RepoEnvVarWhitelist),
+	}
+	commandCanceller := events.NewCommandCanceller()
+	commandAuthorizer := &events.DefaultCommandAuthorizer{
+		Rules:     commandAuthorizationRules,
+		VCSClient: vcsClient,
+	}
 	commandRunner := &events.DefaultCommandRunner{
 		VCSClient:                vcsClient,
 		GithubPullGetter:         githubClient,
@@ -263,41 +498,73 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		Logger:                   logger,
 		AllowForkPRs:             userConfig.AllowForkPRs,
 		AllowForkPRsFlag:         config.AllowForkPRsFlag,
+		CommandCanceller:         commandCanceller,
+		CommandAuthorizer:        commandAuthorizer,
+		Redactor:                 redactor,
 		ProjectCommandBuilder: &events.DefaultProjectCommandBuilder{
-			ParserValidator:     &yaml.ParserValidator{},
+			ParserValidator:     parserValidator,
 			ProjectFinder:       &events.DefaultProjectFinder{},
 			VCSClient:           vcsClient,
 			WorkingDir:          workingDir,
 			WorkingDirLocker:    workingDirLocker,
 			AllowRepoConfig:     userConfig.AllowRepoConfig,
 			AllowRepoConfigFlag: config.AllowRepoConfigFlag,
-			PendingPlanFinder:   &events.PendingPlanFinder{},
+			PendingPlanFinder:   pendingPlanFinder,
 			CommentBuilder:      commentParser,
 		},
 		ProjectCommandRunner: &events.DefaultProjectCommandRunner{
 			Locker:           projectLocker,
 			LockURLGenerator: router,
-			InitStepRunner: &runtime.InitStepRunner{
-				TerraformExecutor: terraformClient,
-				DefaultTFVersion:  defaultTfVersion,
-			},
-			PlanStepRunner: &runtime.PlanStepRunner{
-				TerraformExecutor: terraformClient,
-				DefaultTFVersion:  defaultTfVersion,
-			},
+			InitStepRunner:   initStepRunner,
+			PlanStepRunner:   planStepRunner,
 			ApplyStepRunner: &runtime.ApplyStepRunner{
 				TerraformExecutor: terraformClient,
 			},
-			RunStepRunner: &runtime.RunStepRunner{
-				DefaultTFVersion: defaultTfVersion,
-			},
+			RunStepRunner:           runStepRunner,
+			EnvStepRunner:           envStepRunner,
 			PullApprovedChecker:     vcsClient,
 			WorkingDir:              workingDir,
 			Webhooks:                webhooksManager,
 			WorkingDirLocker:        workingDirLocker,
 			RequireApprovalOverride: userConfig.RequireApproval,
+			OutputStore:             outputStore,
+			CommandAuthorizer:       commandAuthorizer,
+			LockKeyStrategy:         userConfig.LockKeyStrategy,
+			OutputURLGenerator:      router,
 		},
 	}
+	jobStore, err := boltdb.NewJobStore(boltLocker)
+	if err != nil {
+		return nil, err
+	}
+	// Commands from webhooks, the API and the lock queue go through the job
+	// queue so only userConfig.JobWorkers of them run at the same time.
+	jobQueue := &events.JobQueue{
+		CommandRunner:       commandRunner,
+		APICommandRunner:    commandRunner,
+		Store:               jobStore,
+		VCSClient:           vcsClient,
+		CommitStatusUpdater: commitStatusUpdater,
+		CommandCanceller:    commandCanceller,
+		Logger:              logger,
+		Workers:             userConfig.JobWorkers,
+	}
+	lockQueue.CommandRunner = jobQueue
+	driftDetector := &events.DriftDetector{
+		WorkingDir:       workingDir,
+		WorkingDirLocker: workingDirLocker,
+		ParserValidator:  parserValidator,
+		InitStepRunner:   initStepRunner,
+		PlanStepRunner:   planStepRunner,
+		RunStepRunner:    runStepRunner,
+		EnvStepRunner:    envStepRunner,
+		Webhooks:         webhooksManager,
+		Logger:           logger,
+		Redactor:         redactor,
+		// Plans of refs from the API are authorized like pull requests.
+		CommandAuthorizer: commandAuthorizer,
+	}
+	commandRunner.DriftDetector = driftDetector
 	repoWhitelist, err := events.NewRepoWhitelistChecker(userConfig.RepoWhitelist)
 	if err != nil {
 		return nil, err
@@ -312,8 +579,53 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		WorkingDir:         workingDir,
 		WorkingDirLocker:   workingDirLocker,
 	}
+	outputController := &OutputController{
+		AtlantisVersion:    config.AtlantisVersion,
+		AtlantisURL:        parsedURL,
+		Logger:             logger,
+		OutputStore:        outputStore,
+		PullOutputTemplate: pullOutputTemplate,
+		NewRepo: func(fullName string, vcs string) (models.Repo, error) {
+			// The repo is only used to look up output so Bitbucket Server
+			// repos don't need their real clone URL, just the right hostname.
+			var cloneURL string
+			if vcs == vcsNames[models.BitbucketServer] {
+				cloneURL = fmt.Sprintf("%s/scm/%s.git", userConfig.BitbucketBaseURL, fullName)
+			}
+			return newRepo(userConfig, fullName, vcs, cloneURL)
+		},
+	}
+	apiController := &APIController{
+		Tokens:               apiTokens,
+		Locker:               lockingClient,
+		LocksController:      locksController,
+		Logger:               logger,
+		WorkingDir:           workingDir,
+		PendingPlanFinder:    pendingPlanFinder,
+		OutputStore:          outputStore,
+		CommandRunner:        jobQueue,
+		IsDraining:           jobQueue.IsDraining,
+		RepoWhitelistChecker: repoWhitelist,
+		NewRepo: func(fullName string, vcs string) (models.Repo, error) {
+			return newRepo(userConfig, fullName, vcs, "")
+		},
+	}
+	driftController := &DriftController{
+		AtlantisVersion: config.AtlantisVersion,
+		AtlantisURL:     parsedURL,
+		Logger:          logger,
+		DriftDetector:   driftDetector,
+		DriftTemplate:   driftTemplate,
+	}
+	jobsController := &JobsController{
+		AtlantisVersion: config.AtlantisVersion,
+		AtlantisURL:     parsedURL,
+		Logger:          logger,
+		JobQueue:        jobQueue,
+		JobsTemplate:    jobsTemplate,
+	}
 	eventsController := &EventsController{
-		CommandRunner:                commandRunner,
+		CommandRunner:                jobQueue,
 		PullCleaner:                  pullClosedExecutor,
 		Parser:                       eventParser,
 		CommentParser:                commentParser,
@@ -334,10 +646,21 @@ func NewServer(userConfig UserConfig, config Config) (*Server, error) {
 		Router:             underlyingRouter,
 		Port:               userConfig.Port,
 		CommandRunner:      commandRunner,
+		JobQueue:           jobQueue,
 		Logger:             logger,
 		Locker:             lockingClient,
 		EventsController:   eventsController,
 		LocksController:    locksController,
+		OutputController:   outputController,
+		APIController:      apiController,
+		DriftController:    driftController,
+		JobsController:     jobsController,
+		DriftDetector:      driftDetector,
+		DriftSchedules:     driftSchedules,
+		LockReaper:         lockReaper,
+		DrainTimeout:       drainTimeout,
+		AuthMiddleware:     authMiddleware,
+		OIDCAuthenticator:  oidcAuthenticator,
 		IndexTemplate:      indexTemplate,
 		LockDetailTemplate: lockTemplate,
 		SSLKeyFile:         userConfig.SSLKeyFile,
@@ -353,15 +676,35 @@ func (s *Server) Start() error {
 	s.Router.HandleFunc("/healthz", s.Healthz).Methods("GET")
 	s.Router.PathPrefix("/static/").Handler(http.FileServer(&assetfs.AssetFS{Asset: static.Asset, AssetDir: static.AssetDir, AssetInfo: static.AssetInfo}))
 	s.Router.HandleFunc("/events", s.EventsController.Post).Methods("POST")
-	s.Router.HandleFunc("/locks", s.LocksController.DeleteLock).Methods("DELETE").Queries("id", "{id:.*}")
+	s.Router.HandleFunc("/locks", s.AuthMiddleware.RequireAdmin(s.LocksController.DeleteLock)).Methods("DELETE").Queries("id", "{id:.*}")
 	s.Router.HandleFunc("/lock", s.LocksController.GetLock).Methods("GET").
 		Queries(LockViewRouteIDQueryParam, fmt.Sprintf("{%s}", LockViewRouteIDQueryParam)).Name(LockViewRouteName)
+	s.Router.HandleFunc("/output", s.OutputController.GetPullOutput).Methods("GET").Name(PullOutputRouteName)
+	s.Router.HandleFunc("/output/stream", s.OutputController.StreamPullOutput).Methods("GET")
+	s.Router.HandleFunc("/drift", s.DriftController.GetDrift).Methods("GET")
+	s.Router.HandleFunc("/jobs", s.JobsController.GetJobs).Methods("GET")
+	api := s.Router.PathPrefix(APIPathPrefix).Subrouter()
+	api.HandleFunc("/locks", s.APIController.RequireToken(s.APIController.ListLocks)).Methods("GET")
+	api.HandleFunc("/lock", s.APIController.RequireToken(s.APIController.GetLock)).Methods("GET")
+	api.HandleFunc("/lock", s.APIController.RequireToken(s.APIController.DeleteLock)).Methods("DELETE")
+	api.HandleFunc("/pull", s.APIController.RequireToken(s.APIController.GetPullStatus)).Methods("GET")
+	api.HandleFunc("/pull/plans", s.APIController.RequireToken(s.APIController.ListPendingPlans)).Methods("GET")
+	api.HandleFunc("/plan", s.APIController.RequireToken(s.APIController.Plan)).Methods("POST")
+	api.HandleFunc("/apply", s.APIController.RequireToken(s.APIController.Apply)).Methods("POST")
+	api.HandleFunc("/run", s.APIController.RequireToken(s.APIController.GetRun)).Methods("GET")
+	if s.OIDCAuthenticator != nil {
+		s.Router.HandleFunc(OIDCLoginPath, s.OIDCAuthenticator.Login).Methods("GET")
+		s.Router.HandleFunc(OIDCCallbackPath, s.OIDCAuthenticator.Callback).Methods("GET")
+		// Logout is a POST so other sites can't log users out with a link or
+		// an image.
+		s.Router.HandleFunc(LogoutPath, s.OIDCAuthenticator.Logout).Methods("POST")
+	}
 	n := negroni.New(&negroni.Recovery{
 		Logger:     log.New(os.Stdout, "", log.LstdFlags),
 		PrintStack: false,
 		StackAll:   false,
 		StackSize:  1024 * 8,
-	}, NewRequestLogger(s.Logger))
+	}, NewRequestLogger(s.Logger), s.AuthMiddleware)
 	n.UseHandler(s.Router)
 
 	// Ensure server gracefully drains connections when stopped.
@@ -385,9 +728,23 @@ func (s *Server) Start() error {
 			s.Logger.Err(err.Error())
 		}
 	}()
+
+	backgroundCtx, stopBackgroundJobs := context.WithCancel(context.Background())
+	defer stopBackgroundJobs()
+	s.JobQueue.Start(backgroundCtx)
+	s.DriftDetector.Start(backgroundCtx, s.DriftSchedules)
+	if s.LockReaper != nil {
+		s.LockReaper.Start(backgroundCtx, events.DefaultLockReapInterval)
+	}
+
 	<-stop
 
-	s.Logger.Warn("Received interrupt. Safely shutting down")
+	s.Logger.Warn("Received interrupt. Waiting up to %s for running commands to finish", s.DrainTimeout)
+	stopBackgroundJobs()
+	// The web server keeps running while draining so new commands can be
+	// answered with a comment that Atlantis is restarting.
+	s.JobQueue.Drain(s.DrainTimeout)
+	s.Logger.Warn("Safely shutting down")
 	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second) // nolint: vet
 	if err := server.Shutdown(ctx); err != nil {
 		return cli.NewExitError(fmt.Sprintf("while shutting down: %s", err), 1)
@@ -395,6 +752,92 @@ func (s *Server) Start() error {
 	return nil
 }
 
+// newDriftSchedules validates the drift-detection config and returns the
+// schedules it describes.
+func newDriftSchedules(userConfig UserConfig) ([]events.DriftSchedule, error) {
+	var schedules []events.DriftSchedule
+	for _, c := range userConfig.DriftDetection {
+		if c.Repo == "" {
+			return nil, errors.New("repo must be set")
+		}
+		if c.Interval == "" {
+			return nil, fmt.Errorf("interval must be set for repo %q", c.Repo)
+		}
+		interval, err := time.ParseDuration(c.Interval)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing interval for repo %q", c.Repo)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("interval for repo %q must be positive", c.Repo)
+		}
+		// We don't default the branch because repos' default branches differ,
+		// ex. master or main, and checking the wrong one would miss drift.
+		if c.Branch == "" {
+			return nil, fmt.Errorf("branch must be set for repo %q", c.Repo)
+		}
+
+		repo, err := newRepo(userConfig, c.Repo, c.VCS, c.CloneURL)
+		if err != nil {
+			return nil, err
+		}
+
+		schedules = append(schedules, events.DriftSchedule{
+			Repo:     repo,
+			Branch:   c.Branch,
+			Interval: interval,
+		})
+	}
+	return schedules, nil
+}
+
+// vcsNames maps from each VCS host type to the name newRepo takes for it.
+var vcsNames = map[models.VCSHostType]string{
+	models.Github:          "github",
+	models.Gitlab:          "gitlab",
+	models.BitbucketCloud:  "bitbucket-cloud",
+	models.BitbucketServer: "bitbucket-server",
+}
+
+// newRepo returns the repo fullName on the VCS host vcs, authenticated with
+// the credentials for that host. vcs is one of github, gitlab,
+// bitbucket-cloud or bitbucket-server and defaults to github. If cloneURL is
+// empty it's derived from the host's hostname, except for Bitbucket Server
+// where it's required.
+func newRepo(userConfig UserConfig, fullName string, vcs string, cloneURL string) (models.Repo, error) {
+	var vcsHostType models.VCSHostType
+	var user, token, defaultCloneURL string
+	switch vcs {
+	case "", "github":
+		vcsHostType = models.Github
+		user, token = userConfig.GithubUser, userConfig.GithubToken
+		defaultCloneURL = fmt.Sprintf("https://%s/%s.git", userConfig.GithubHostname, fullName)
+	case "gitlab":
+		vcsHostType = models.Gitlab
+		user, token = userConfig.GitlabUser, userConfig.GitlabToken
+		defaultCloneURL = fmt.Sprintf("https://%s/%s.git", userConfig.GitlabHostname, fullName)
+	case "bitbucket-cloud":
+		vcsHostType = models.BitbucketCloud
+		user, token = userConfig.BitbucketUser, userConfig.BitbucketToken
+		defaultCloneURL = fmt.Sprintf("https://bitbucket.org/%s.git", fullName)
+	case "bitbucket-server":
+		vcsHostType = models.BitbucketServer
+		user, token = userConfig.BitbucketUser, userConfig.BitbucketToken
+		if cloneURL == "" {
+			return models.Repo{}, fmt.Errorf("clone-url must be set for bitbucket-server repo %q", fullName)
+		}
+	default:
+		return models.Repo{}, fmt.Errorf("vcs %q for repo %q not supported, must be one of github, gitlab, bitbucket-cloud or bitbucket-server", vcs, fullName)
+	}
+	if cloneURL == "" {
+		cloneURL = defaultCloneURL
+	}
+	repo, err := models.NewRepo(vcsHostType, fullName, cloneURL, user, token)
+	if err != nil {
+		return models.Repo{}, errors.Wrapf(err, "repo %q", fullName)
+	}
+	return repo, nil
+}
+
 // Index is the / route.
 func (s *Server) Index(w http.ResponseWriter, _ *http.Request) {
 	locks, err := s.Locker.List()
@@ -414,6 +857,7 @@ func (s *Server) Index(w http.ResponseWriter, _ *http.Request) {
 			RepoFullName: v.Project.RepoFullName,
 			PullNum:      v.Pull.Num,
 			Time:         v.Time,
+			LockKey:      v.Project.LockKey,
 		})
 	}
 	err = s.IndexTemplate.Execute(w, IndexData{
@@ -442,6 +886,114 @@ func (s *Server) Healthz(w http.ResponseWriter, _ *http.Request) {
 	w.Write(data) // nolint: errcheck
 }
 
+// newAuthMiddleware returns the middleware that authenticates requests to the
+// web UI. If OIDC is configured, its authenticator is also returned so its
+// login routes can be registered. The webhook endpoint is always exempt since
+// VCS hosts authenticate with webhook secrets, as is the API since it requires
+// API tokens.
+func newAuthMiddleware(userConfig UserConfig, atlantisURL *url.URL, logger *logging.SimpleLogger) (*AuthMiddleware, *OIDCAuthenticator, error) {
+	m := &AuthMiddleware{
+		AdminUsers:  splitList(userConfig.WebAdminUsers),
+		AdminGroups: splitList(userConfig.WebAdminGroups),
+		ExemptPaths: []string{"/events", "/healthz", "/static/", "/auth/", APIPathPrefix + "/"},
+		Logger:      logger,
+	}
+	var oidcAuthenticator *OIDCAuthenticator
+	if userConfig.OIDCIssuerURL != "" {
+		var err error
+		oidcAuthenticator, err = NewOIDCAuthenticator(userConfig.OIDCIssuerURL, userConfig.OIDCClientID, userConfig.OIDCClientSecret, atlantisURL, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		m.Authenticators = append(m.Authenticators, oidcAuthenticator)
+	}
+	if userConfig.WebHtpasswdFile != "" {
+		htpasswd, err := NewHtpasswdAuthenticator(userConfig.WebHtpasswdFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		m.Authenticators = append(m.Authenticators, htpasswd)
+	}
+	return m, oidcAuthenticator, nil
+}
+
+// newAPITokens validates the api-tokens config.
+func newAPITokens(userConfig UserConfig) ([]APIToken, error) {
+	var tokens []APIToken
+	names := make(map[string]bool)
+	for _, c := range userConfig.APITokens {
+		if c.Name == "" {
+			return nil, errors.New("name must be set")
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("name %q is used by more than one token", c.Name)
+		}
+		names[c.Name] = true
+		if c.Token == "" {
+			return nil, fmt.Errorf("token must be set for %q", c.Name)
+		}
+		token := APIToken{Name: c.Name, Token: c.Token}
+		if c.Repos != "" {
+			repos, err := events.NewRepoWhitelistChecker(c.Repos)
+			if err != nil {
+				return nil, errors.Wrapf(err, "repos for %q", c.Name)
+			}
+			token.Repos = repos
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// newCommandAuthorizationRules validates the command-authorization config.
+func newCommandAuthorizationRules(userConfig UserConfig) ([]events.CommandAuthorizationRule, error) {
+	var rules []events.CommandAuthorizationRule
+	for i, c := range userConfig.CommandAuthorization {
+		if len(c.Users) == 0 && len(c.Teams) == 0 {
+			return nil, fmt.Errorf("rule %d: at least one of users or teams must be set", i)
+		}
+		rule := events.CommandAuthorizationRule{
+			Dir:           c.Dir,
+			Workspace:     c.Workspace,
+			ProjectName:   c.Project,
+			Authorization: valid.Authorization{Users: c.Users, Teams: c.Teams},
+		}
+		if rule.Dir != "" {
+			rule.Dir = filepath.Clean(rule.Dir)
+		}
+		for _, cmd := range c.Commands {
+			switch cmd {
+			case events.PlanCommand.String():
+				rule.Commands = append(rule.Commands, events.PlanCommand)
+			case events.ApplyCommand.String():
+				rule.Commands = append(rule.Commands, events.ApplyCommand)
+			default:
+				return nil, fmt.Errorf("rule %d: %q is not a command that can be restricted, must be plan or apply", i, cmd)
+			}
+		}
+		if c.Repos != "" {
+			repos, err := events.NewRepoWhitelistChecker(c.Repos)
+			if err != nil {
+				return nil, errors.Wrapf(err, "rule %d: repos", i)
+			}
+			rule.Repos = repos
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// splitList splits a comma separated list, ignoring empty items.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // ParseAtlantisURL parses the user-passed atlantis URL to ensure it is valid
 // and we can use it in our templates.
 // It removes any trailing slashes from the path so we can concatenate it
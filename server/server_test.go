@@ -56,6 +56,104 @@ func TestNewServer_InvalidAtlantisURL(t *testing.T) {
 	ErrEquals(t, "parsing --atlantis-url flag \"example.com\": http or https must be specified", err)
 }
 
+func TestNewServer_InvalidDriftDetection(t *testing.T) {
+	cases := []struct {
+		description string
+		config      server.DriftDetectionConfig
+		expErr      string
+	}{
+		{
+			"no repo",
+			server.DriftDetectionConfig{Interval: "1h"},
+			"parsing drift-detection config: repo must be set",
+		},
+		{
+			"no interval",
+			server.DriftDetectionConfig{Repo: "owner/repo"},
+			"parsing drift-detection config: interval must be set for repo \"owner/repo\"",
+		},
+		{
+			"invalid interval",
+			server.DriftDetectionConfig{Repo: "owner/repo", Interval: "daily"},
+			"parsing drift-detection config: parsing interval for repo \"owner/repo\": time: invalid duration",
+		},
+		{
+			"no branch",
+			server.DriftDetectionConfig{Repo: "owner/repo", Interval: "1h"},
+			"parsing drift-detection config: branch must be set for repo \"owner/repo\"",
+		},
+		{
+			"invalid vcs",
+			server.DriftDetectionConfig{Repo: "owner/repo", Interval: "1h", Branch: "main", VCS: "svn"},
+			"parsing drift-detection config: vcs \"svn\" for repo \"owner/repo\" not supported, must be one of github, gitlab, bitbucket-cloud or bitbucket-server",
+		},
+		{
+			"bitbucket server without clone url",
+			server.DriftDetectionConfig{Repo: "owner/repo", Interval: "1h", Branch: "main", VCS: "bitbucket-server"},
+			"parsing drift-detection config: clone-url must be set for bitbucket-server repo \"owner/repo\"",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			tmpDir, err := ioutil.TempDir("", "")
+			Ok(t, err)
+			_, err = server.NewServer(server.UserConfig{
+				DataDir:        tmpDir,
+				AtlantisURL:    "http://example.com",
+				DriftDetection: []server.DriftDetectionConfig{c.config},
+			}, server.Config{})
+			ErrContains(t, c.expErr, err)
+		})
+	}
+}
+
+func TestNewServer_InvalidAPITokens(t *testing.T) {
+	cases := []struct {
+		tokens []server.APITokenConfig
+		expErr string
+	}{
+		{
+			[]server.APITokenConfig{{Token: "secret"}},
+			"parsing api-tokens config: name must be set",
+		},
+		{
+			[]server.APITokenConfig{{Name: "dashboard"}},
+			"parsing api-tokens config: token must be set for \"dashboard\"",
+		},
+		{
+			[]server.APITokenConfig{{Name: "dashboard", Token: "a"}, {Name: "dashboard", Token: "b"}},
+			"parsing api-tokens config: name \"dashboard\" is used by more than one token",
+		},
+		{
+			[]server.APITokenConfig{{Name: "ci", Token: "a", Repos: "https://github.com/owner/repo"}},
+			"parsing api-tokens config: repos for \"ci\": whitelist \"https://github.com/owner/repo\" contained ://",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.expErr, func(t *testing.T) {
+			tmpDir, err := ioutil.TempDir("", "")
+			Ok(t, err)
+			_, err = server.NewServer(server.UserConfig{
+				DataDir:     tmpDir,
+				AtlantisURL: "http://example.com",
+				APITokens:   c.tokens,
+			}, server.Config{})
+			ErrEquals(t, c.expErr, err)
+		})
+	}
+}
+
+func TestNewServer_InvalidHtpasswdFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	Ok(t, err)
+	_, err = server.NewServer(server.UserConfig{
+		DataDir:         tmpDir,
+		AtlantisURL:     "http://example.com",
+		WebHtpasswdFile: tmpDir + "/htpasswd",
+	}, server.Config{})
+	ErrContains(t, "initializing web UI authentication: open "+tmpDir+"/htpasswd", err)
+}
+
 func TestIndex_LockErr(t *testing.T) {
 	t.Log("index should return a 503 if unable to list locks")
 	RegisterMockTestingT(t)
@@ -221,3 +319,35 @@ func responseContains(t *testing.T, r *httptest.ResponseRecorder, status int, bo
 	Assert(t, status == r.Result().StatusCode, "exp %d got %d, body: %s", status, r.Result().StatusCode, string(body))
 	Assert(t, strings.Contains(string(body), bodySubstr), "exp %q to be contained in %q", bodySubstr, string(body))
 }
+
+func TestNewServer_InvalidCommandAuthorization(t *testing.T) {
+	cases := []struct {
+		rule   server.CommandAuthorizationConfig
+		expErr string
+	}{
+		{
+			server.CommandAuthorizationConfig{Project: "prod"},
+			"parsing command-authorization config: rule 0: at least one of users or teams must be set",
+		},
+		{
+			server.CommandAuthorizationConfig{Users: []string{"alice"}, Commands: []string{"unlock"}},
+			"parsing command-authorization config: rule 0: \"unlock\" is not a command that can be restricted, must be plan or apply",
+		},
+		{
+			server.CommandAuthorizationConfig{Users: []string{"alice"}, Repos: "https://github.com/owner/repo"},
+			"parsing command-authorization config: rule 0: repos: whitelist \"https://github.com/owner/repo\" contained ://",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.expErr, func(t *testing.T) {
+			tmpDir, err := ioutil.TempDir("", "")
+			Ok(t, err)
+			_, err = server.NewServer(server.UserConfig{
+				DataDir:              tmpDir,
+				AtlantisURL:          "http://example.com",
+				CommandAuthorization: []server.CommandAuthorizationConfig{c.rule},
+			}, server.Config{})
+			ErrEquals(t, c.expErr, err)
+		})
+	}
+}
@@ -35,6 +35,9 @@ type LockIndexData struct {
 	RepoFullName string
 	PullNum      int
 	Time         time.Time
+	// LockKey is set if the project is locked on a lock key instead of its
+	// repo and dir, ex. s3://bucket/key.
+	LockKey string
 }
 
 // IndexData holds the data for rendering the index page
@@ -90,7 +93,7 @@ var indexTemplate = template.Must(template.New("index.html.tmpl").Parse(`
     {{ range .Locks }}
       <a href="{{ $basePath }}{{.LockPath}}">
         <div class="twelve columns button content lock-row">
-        <div class="list-title">{{.RepoFullName}} - <span class="heading-font-size">#{{.PullNum}}</span></div>
+        <div class="list-title">{{.RepoFullName}} - <span class="heading-font-size">#{{.PullNum}}</span>{{ if .LockKey }} <code>{{.LockKey}}</code>{{ end }}</div>
         <div class="list-status"><code>Locked</code></div>
         <div class="list-timestamp"><span class="heading-font-size">{{.Time}}</span></div>
         </div>
@@ -116,7 +119,11 @@ type LockDetailData struct {
 	RepoName        string
 	PullRequestLink string
 	LockedBy        string
+	Directory       string
 	Workspace       string
+	// ProjectLockKey is set if the project is locked on a lock key instead of
+	// its repo and dir, ex. s3://bucket/key.
+	ProjectLockKey  string
 	Time            time.Time
 	AtlantisVersion string
 	// CleanedBasePath is the path Atlantis is accessible at externally. If
@@ -155,7 +162,9 @@ var lockTemplate = template.Must(template.New("lock.html.tmpl").Parse(`
         <h6><code>Repo Name</code>: <strong>{{.RepoName}}</strong></h6>
         <h6><code>Pull Request Link</code>: <a href="{{.PullRequestLink}}" target="_blank"><strong>{{.PullRequestLink}}</strong></a></h6>
         <h6><code>Locked By</code>: <strong>{{.LockedBy}}</strong></h6>
+        <h6><code>Directory</code>: <strong>{{.Directory}}</strong></h6>
         <h6><code>Workspace</code>: <strong>{{.Workspace}}</strong></h6>
+        {{ if .ProjectLockKey }}<h6><code>Lock Key</code>: <strong>{{.ProjectLockKey}}</strong></h6>{{ end }}
         <br>
       </div>
       <div class="four columns">
@@ -226,3 +235,277 @@ v{{ .AtlantisVersion }}
 </body>
 </html>
 `))
+
+// PullOutputData holds the fields needed to display the live output of the
+// commands running on a pull request.
+type PullOutputData struct {
+	RepoFullName string
+	PullNum      int
+	// StreamPath is the path to the server-sent events stream of the output.
+	StreamPath      string
+	AtlantisVersion string
+	// CleanedBasePath is the path Atlantis is accessible at externally. If
+	// not using a path-based proxy, this will be an empty string. Never ends
+	// in a '/' (hence "cleaned").
+	CleanedBasePath string
+}
+
+var pullOutputTemplate = template.Must(template.New("output.html.tmpl").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>atlantis</title>
+  <meta name="description" content="">
+  <meta name="author" content="">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/normalize.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/skeleton.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/custom.css">
+  <link rel="icon" type="image/png" href="{{ .CleanedBasePath }}/static/images/atlantis-icon.png">
+  <script src="{{ .CleanedBasePath }}/static/js/jquery-3.2.1.min.js"></script>
+</head>
+<body>
+  <div class="container">
+    <section class="header">
+    <a title="atlantis" href="{{ .CleanedBasePath }}/"><img src="{{ .CleanedBasePath }}/static/images/atlantis-icon.png"/></a>
+    <p class="title-heading">atlantis</p>
+    <p class="title-heading"><strong>{{ .RepoFullName }} - #{{ .PullNum }}</strong></p>
+    </section>
+    <div class="navbar-spacer"></div>
+    <br>
+    <section>
+      <p class="placeholder js-no-output">No output yet. Output will appear here once a plan or apply starts.</p>
+      <div class="js-outputs"></div>
+    </section>
+  </div>
+<footer>
+v{{ .AtlantisVersion }}
+</footer>
+<script>
+  var outputs = $(".js-outputs");
+  var noOutput = $(".js-no-output");
+  // byID maps from output id to the elements for that output.
+  var byID = {};
+  // byProject maps from project key to the output id being shown for it.
+  var byProject = {};
+
+  var source = new EventSource("{{ .StreamPath }}");
+  // When the connection is (re)opened the server sends all the output again.
+  source.addEventListener("open", function() {
+    outputs.empty();
+    noOutput.show();
+    byID = {};
+    byProject = {};
+  });
+  source.addEventListener("project", function(e) {
+    var p = JSON.parse(e.data);
+    var key = p.dir + "/" + p.workspace + "/" + p.project;
+    if (byProject[key] !== undefined) {
+      byID[byProject[key]].section.remove();
+      delete byID[byProject[key]];
+    }
+    var title = "dir: " + p.dir + " workspace: " + p.workspace;
+    if (p.project !== "") {
+      title = "project: " + p.project + " " + title;
+    }
+    var section = $("<div></div>");
+    var status = $("<code></code>").text("Running");
+    section.append($("<h6></h6>").append($("<strong></strong>").text(p.command + " " + title + " ")).append(status));
+    section.append($("<p></p>").text("Started " + p.start_time));
+    var pre = $("<pre></pre>");
+    section.append(pre);
+    outputs.append(section);
+    noOutput.hide();
+    byID[p.id] = {section: section, status: status, pre: pre};
+    byProject[key] = p.id;
+  });
+  source.addEventListener("lines", function(e) {
+    var l = JSON.parse(e.data);
+    if (byID[l.id] === undefined) {
+      return;
+    }
+    byID[l.id].pre.append(document.createTextNode(l.lines.join("\n") + "\n"));
+  });
+  source.addEventListener("done", function(e) {
+    var d = JSON.parse(e.data);
+    if (byID[d.id] === undefined) {
+      return;
+    }
+    byID[d.id].status.text("Complete");
+  });
+</script>
+</body>
+</html>
+`))
+
+// DriftIndexData holds the fields needed to display the results of drift
+// detection.
+type DriftIndexData struct {
+	Repos           []DriftRepoData
+	AtlantisVersion string
+	// CleanedBasePath is the path Atlantis is accessible at externally. If
+	// not using a path-based proxy, this will be an empty string. Never ends
+	// in a '/' (hence "cleaned").
+	CleanedBasePath string
+}
+
+// DriftRepoData holds the result of checking a repo's branch for drift.
+type DriftRepoData struct {
+	RepoFullName string
+	Branch       string
+	Time         string
+	// Error is set if the repo couldn't be checked.
+	Error    string
+	Projects []DriftProjectData
+}
+
+// DriftProjectData holds the result of checking a project for drift.
+type DriftProjectData struct {
+	RepoRelDir  string
+	Workspace   string
+	ProjectName string
+	// Status is one of "No drift", "Drifted" or "Error".
+	Status string
+	Error  string
+	Output string
+}
+
+var driftTemplate = template.Must(template.New("drift.html.tmpl").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>atlantis</title>
+  <meta name="description" content="">
+  <meta name="author" content="">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/normalize.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/skeleton.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/custom.css">
+  <link rel="icon" type="image/png" href="{{ .CleanedBasePath }}/static/images/atlantis-icon.png">
+</head>
+<body>
+  <div class="container">
+    <section class="header">
+    <a title="atlantis" href="{{ .CleanedBasePath }}/"><img src="{{ .CleanedBasePath }}/static/images/atlantis-icon.png"/></a>
+    <p class="title-heading">atlantis</p>
+    <p class="title-heading"><strong>Drift Detection</strong></p>
+    </section>
+    <div class="navbar-spacer"></div>
+    <br>
+    <section>
+    {{ if .Repos }}
+    {{ range .Repos }}
+      <h6><strong>{{ .RepoFullName }}@{{ .Branch }}</strong></h6>
+      <p>Checked {{ .Time }}</p>
+      {{ if .Error }}
+      <p>Error: <code>{{ .Error }}</code></p>
+      {{ end }}
+      {{ range .Projects }}
+      <details>
+        <summary>{{ if .ProjectName }}project: <strong>{{ .ProjectName }}</strong> {{ end }}dir: <strong>{{ .RepoRelDir }}</strong> workspace: <strong>{{ .Workspace }}</strong> <code>{{ .Status }}</code></summary>
+        {{ if .Error }}<p>Error: <code>{{ .Error }}</code></p>{{ end }}
+        <pre>{{ .Output }}</pre>
+      </details>
+      {{ end }}
+      <br>
+    {{ end }}
+    {{ else }}
+    <p class="placeholder">No repos have been checked for drift yet.</p>
+    {{ end }}
+    </section>
+  </div>
+<footer>
+v{{ .AtlantisVersion }}
+</footer>
+</body>
+</html>
+`))
+
+// JobsIndexData holds the fields needed to display the job queue.
+type JobsIndexData struct {
+	Running         []JobData
+	Queued          []JobData
+	AtlantisVersion string
+	// CleanedBasePath is the path Atlantis is accessible at externally. If
+	// not using a path-based proxy, this will be an empty string. Never ends
+	// in a '/' (hence "cleaned").
+	CleanedBasePath string
+}
+
+// JobData holds a job in the job queue.
+type JobData struct {
+	ID           uint64
+	RepoFullName string
+	PullNum      int
+	// Ref is set instead of PullNum for plans of a ref from the API.
+	Ref string
+	// Command is the comment that ran the job or "autoplan".
+	Command  string
+	User     string
+	QueuedAt string
+	// StartedAt is empty if the job hasn't started.
+	StartedAt string
+}
+
+var jobsTemplate = template.Must(template.New("jobs.html.tmpl").Parse(`
+<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>atlantis</title>
+  <meta name="description" content="">
+  <meta name="author" content="">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/normalize.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/skeleton.css">
+  <link rel="stylesheet" href="{{ .CleanedBasePath }}/static/css/custom.css">
+  <link rel="icon" type="image/png" href="{{ .CleanedBasePath }}/static/images/atlantis-icon.png">
+</head>
+<body>
+  <div class="container">
+    <section class="header">
+    <a title="atlantis" href="{{ .CleanedBasePath }}/"><img src="{{ .CleanedBasePath }}/static/images/atlantis-icon.png"/></a>
+    <p class="title-heading">atlantis</p>
+    <p class="title-heading"><strong>Jobs</strong></p>
+    </section>
+    <div class="navbar-spacer"></div>
+    <br>
+    <section>
+    <p><strong>Running</strong></p>
+    {{ if .Running }}
+    <table class="u-full-width">
+      <thead><tr><th>Repo</th><th>Pull or Ref</th><th>Command</th><th>User</th><th>Queued</th><th>Started</th></tr></thead>
+      <tbody>
+      {{ range .Running }}
+        <tr><td>{{ .RepoFullName }}</td><td>{{ if .Ref }}<code>{{ .Ref }}</code>{{ else }}#{{ .PullNum }}{{ end }}</td><td><code>{{ .Command }}</code></td><td>{{ .User }}</td><td>{{ .QueuedAt }}</td><td>{{ .StartedAt }}</td></tr>
+      {{ end }}
+      </tbody>
+    </table>
+    {{ else }}
+    <p class="placeholder">No jobs are running.</p>
+    {{ end }}
+    </section>
+    <section>
+    <p><strong>Queued</strong></p>
+    {{ if .Queued }}
+    <table class="u-full-width">
+      <thead><tr><th>Repo</th><th>Pull or Ref</th><th>Command</th><th>User</th><th>Queued</th></tr></thead>
+      <tbody>
+      {{ range .Queued }}
+        <tr><td>{{ .RepoFullName }}</td><td>{{ if .Ref }}<code>{{ .Ref }}</code>{{ else }}#{{ .PullNum }}{{ end }}</td><td><code>{{ .Command }}</code></td><td>{{ .User }}</td><td>{{ .QueuedAt }}</td></tr>
+      {{ end }}
+      </tbody>
+    </table>
+    {{ else }}
+    <p class="placeholder">No jobs are queued.</p>
+    {{ end }}
+    </section>
+  </div>
+<footer>
+v{{ .AtlantisVersion }}
+</footer>
+</body>
+</html>
+`))